@@ -62,8 +62,32 @@ type Config struct {
 		Allowlist []string `yaml:"allowlist"`
 		Timeout   string   `yaml:"timeout"`
 	} `yaml:"exec"`
-	DevicesDir string `yaml:"devices_dir"`
-	ScriptsDir string `yaml:"scripts_dir"`
+	Alarm struct {
+		Enabled         bool     `yaml:"enabled"`
+		Codes           []string `yaml:"codes"`
+		CodeArmRequired bool     `yaml:"code_arm_required"`
+		ExitDelay       string   `yaml:"exit_delay"`
+		EntryDelay      string   `yaml:"entry_delay"`
+		HomeBypass      []string `yaml:"home_bypass"`
+		NightBypass     []string `yaml:"night_bypass"`
+	} `yaml:"alarm"`
+	Availability struct {
+		Enabled        bool              `yaml:"enabled"`
+		MainsTimeout   string            `yaml:"mains_timeout"`
+		BatteryTimeout string            `yaml:"battery_timeout"`
+		Devices        map[string]string `yaml:"devices"`
+	} `yaml:"availability"`
+	Confirm struct {
+		Enabled bool   `yaml:"enabled"`
+		Timeout string `yaml:"timeout"`
+	} `yaml:"confirm"`
+	Poll struct {
+		Enabled bool   `yaml:"enabled"`
+		Gap     string `yaml:"gap"`
+	} `yaml:"poll"`
+	DevicesDir   string `yaml:"devices_dir"`
+	DevicesWatch bool   `yaml:"devices_watch"`
+	ScriptsDir   string `yaml:"scripts_dir"`
 }
 
 func (c *Config) validate() error {
@@ -79,9 +103,110 @@ func (c *Config) validate() error {
 	if c.MQTT.Enabled && c.MQTT.Broker == "" {
 		return fmt.Errorf("mqtt.broker is required when mqtt.enabled is true")
 	}
+	if _, err := c.alarmConfig(); err != nil {
+		return err
+	}
+	if _, err := c.availabilityConfig(); err != nil {
+		return err
+	}
+	if _, err := c.confirmConfig(); err != nil {
+		return err
+	}
+	if _, err := c.pollConfig(); err != nil {
+		return err
+	}
 	return nil
 }
 
+// alarmConfig converts the alarm section to the coordinator alarm panel config.
+func (c *Config) alarmConfig() (coordinator.AlarmConfig, error) {
+	ac := coordinator.AlarmConfig{
+		Enabled:         c.Alarm.Enabled,
+		Codes:           c.Alarm.Codes,
+		CodeArmRequired: c.Alarm.CodeArmRequired,
+		HomeBypass:      c.Alarm.HomeBypass,
+		NightBypass:     c.Alarm.NightBypass,
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"alarm.exit_delay", c.Alarm.ExitDelay, &ac.ExitDelay},
+		{"alarm.entry_delay", c.Alarm.EntryDelay, &ac.EntryDelay},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil || v < 0 {
+			return ac, fmt.Errorf("%s: invalid duration %q", d.name, d.value)
+		}
+		*d.dst = v
+	}
+	return ac, nil
+}
+
+// availabilityConfig converts the availability section to the coordinator
+// availability config.
+func (c *Config) availabilityConfig() (coordinator.AvailabilityConfig, error) {
+	ac := coordinator.AvailabilityConfig{Enabled: c.Availability.Enabled}
+	parse := func(name, value string) (time.Duration, error) {
+		v, err := time.ParseDuration(value)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("%s: invalid duration %q", name, value)
+		}
+		return v, nil
+	}
+	var err error
+	if c.Availability.MainsTimeout != "" {
+		if ac.MainsTimeout, err = parse("availability.mains_timeout", c.Availability.MainsTimeout); err != nil {
+			return ac, err
+		}
+	}
+	if c.Availability.BatteryTimeout != "" {
+		if ac.BatteryTimeout, err = parse("availability.battery_timeout", c.Availability.BatteryTimeout); err != nil {
+			return ac, err
+		}
+	}
+	if len(c.Availability.Devices) > 0 {
+		ac.Devices = make(map[string]time.Duration, len(c.Availability.Devices))
+		for dev, value := range c.Availability.Devices {
+			if ac.Devices[dev], err = parse("availability.devices."+dev, value); err != nil {
+				return ac, err
+			}
+		}
+	}
+	return ac, nil
+}
+
+// confirmConfig converts the confirm section to the coordinator command
+// confirmation config.
+func (c *Config) confirmConfig() (coordinator.ConfirmConfig, error) {
+	cc := coordinator.ConfirmConfig{Enabled: c.Confirm.Enabled}
+	if c.Confirm.Timeout != "" {
+		v, err := time.ParseDuration(c.Confirm.Timeout)
+		if err != nil || v < 0 {
+			return cc, fmt.Errorf("confirm.timeout: invalid duration %q", c.Confirm.Timeout)
+		}
+		cc.Timeout = v
+	}
+	return cc, nil
+}
+
+// pollConfig converts the poll section to the coordinator polling config.
+func (c *Config) pollConfig() (coordinator.PollConfig, error) {
+	pc := coordinator.PollConfig{Enabled: c.Poll.Enabled}
+	if c.Poll.Gap != "" {
+		v, err := time.ParseDuration(c.Poll.Gap)
+		if err != nil || v < 0 {
+			return pc, fmt.Errorf("poll.gap: invalid duration %q", c.Poll.Gap)
+		}
+		pc.Gap = v
+	}
+	return pc, nil
+}
+
 func main() {
 	// Temporary logger for config loading errors.
 	bootLogger := slog.New(slog.NewTextHandler(os.Stderr, nil))
@@ -143,11 +268,21 @@ func main() {
 	}
 
 	// Create coordinator
+	alarmCfg, _ := cfg.alarmConfig() // validated above
+	availabilityCfg, _ := cfg.availabilityConfig()
+	confirmCfg, _ := cfg.confirmConfig()
+	pollCfg, _ := cfg.pollConfig()
 	events := coordinator.NewEventBus(logger)
 	coord := coordinator.New(backend, db, registry, deviceDB, events, coordinator.Config{
 		Channel:  cfg.Network.Channel,
 		PanID:    cfg.Network.PanID,
 		ExtPanID: extPanID,
+		Alarm:    alarmCfg,
+		Availability: availabilityCfg,
+		Confirm:      confirmCfg,
+		Poll:         pollCfg,
+		DevicesDir:   cfg.DevicesDir,
+		WatchDevices: cfg.DevicesWatch,
 	}, coordinator.NCPConfig{
 		Type: cfg.NCP.Type,
 		Port: cfg.NCP.Port,
@@ -205,10 +340,23 @@ func main() {
 	// Start MQTT bridge (no-op when built with no_mqtt tag).
 	mqtt := initMQTT(coord, cfg, logger)
 
+	// SIGHUP reloads device definitions without restarting the network.
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			logger.Info("SIGHUP received, reloading device definitions")
+			if _, err := coord.ReloadDefinitions(); err != nil {
+				logger.Error("reload device definitions", "err", err)
+			}
+		}
+	}()
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigCh
 	signal.Stop(sigCh)
+	signal.Stop(hupCh)
 	logger.Info("shutting down", "signal", sig)
 
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,23 @@
+-- Button events of the Philips Hue dimmer switch and smart button: command
+-- 0x00 of the manufacturer-specific cluster 0xFC00.
+--
+-- Payload: button(1) unknown(3) type(1) unknown(1) duration(1) unknown(1)
+-- Emits action, e.g. "on_press", "up_hold" or "off_press_release".
+
+commands = {0x00}
+
+local buttons = {"on", "up", "down", "off"}
+local types = {[0] = "press", [1] = "hold", [2] = "press_release", [3] = "hold_release"}
+
+function decode(input)
+  local data = input.data
+  if #data < 5 then
+    return nil
+  end
+  local button = buttons[bytes.u8(data, 1)]
+  local kind = types[bytes.u8(data, 5)]
+  if button == nil or kind == nil then
+    return nil
+  end
+  return {action = button .. "_" .. kind}
+end
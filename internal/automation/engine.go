@@ -30,6 +30,7 @@ type luaEventHandler struct {
 	eventType string
 	ieee      string // filter: only match this IEEE (empty = any)
 	property  string // filter: only match this property (empty = any)
+	action    string // filter: only match this action of device_action events (empty = any)
 	fn        *lua.LFunction
 }
 
@@ -180,6 +181,7 @@ func (e *Engine) RunLuaCode(code string) *RunResult {
 	registerZigbeeModule(L, vm, e)
 	registerSystemModule(L, e)
 	registerTelegramModule(L, e)
+	registerAlarmModule(L, e)
 
 	// Override zigbee.log to capture output
 	mod := L.GetGlobal("zigbee")
@@ -251,6 +253,7 @@ func (e *Engine) startScript(s *Script) error {
 	registerZigbeeModule(L, vm, e)
 	registerSystemModule(L, e)
 	registerTelegramModule(L, e)
+	registerAlarmModule(L, e)
 
 	// Execute the script to register handlers
 	if err := L.DoString(s.LuaCode); err != nil {
@@ -334,7 +337,7 @@ func matchesHandler(h luaEventHandler, event coordinator.Event) bool {
 
 	data, ok := event.Data.(map[string]interface{})
 	if !ok {
-		return h.ieee == "" && h.property == ""
+		return h.ieee == "" && h.property == "" && h.action == ""
 	}
 
 	if h.ieee != "" {
@@ -349,6 +352,12 @@ func matchesHandler(h luaEventHandler, event coordinator.Event) bool {
 		}
 	}
 
+	if h.action != "" {
+		if action, _ := data["action"].(string); action != h.action {
+			return false
+		}
+	}
+
 	return true
 }
 
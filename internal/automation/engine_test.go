@@ -171,6 +171,20 @@ func TestMatchesHandler(t *testing.T) {
 			map[string]interface{}{"ieee": "AABB", "property": "anything"},
 			true,
 		},
+		{
+			"action filter match",
+			luaEventHandler{eventType: "device_action", ieee: "AABB", action: "double"},
+			"device_action",
+			map[string]interface{}{"ieee": "AABB", "action": "double"},
+			true,
+		},
+		{
+			"action filter mismatch",
+			luaEventHandler{eventType: "device_action", action: "double"},
+			"device_action",
+			map[string]interface{}{"ieee": "AABB", "action": "single"},
+			false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,99 @@
+//go:build !no_automation
+
+package automation
+
+import (
+	"zigbee-go-home/internal/coordinator"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// registerAlarmModule registers the `alarm` global table in a Lua state.
+// State changes reach scripts as "alarm_panel" events via zigbee.on.
+func registerAlarmModule(L *lua.LState, e *Engine) {
+	mod := L.NewTable()
+
+	mod.RawSetString("state", L.NewFunction(func(L *lua.LState) int {
+		return alarmState(L, e)
+	}))
+
+	mod.RawSetString("arm", L.NewFunction(func(L *lua.LState) int {
+		return alarmArm(L, e)
+	}))
+
+	mod.RawSetString("disarm", L.NewFunction(func(L *lua.LState) int {
+		return alarmDisarm(L, e)
+	}))
+
+	mod.RawSetString("trigger", L.NewFunction(func(L *lua.LState) int {
+		return alarmTrigger(L, e)
+	}))
+
+	L.SetGlobal("alarm", mod)
+}
+
+func (e *Engine) alarmPanel() *coordinator.AlarmPanel {
+	if e.coord == nil {
+		return nil
+	}
+	return e.coord.AlarmPanel()
+}
+
+// pushAlarmResult pushes true, or nil and the error message.
+func pushAlarmResult(L *lua.LState, err error) int {
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// alarm.state() — returns {state, mode, alarm, seconds_remaining} or nil
+func alarmState(L *lua.LState, e *Engine) int {
+	p := e.alarmPanel()
+	if p == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	st := p.Status()
+	t := L.NewTable()
+	t.RawSetString("state", lua.LString(st.State))
+	t.RawSetString("mode", lua.LString(st.Mode))
+	t.RawSetString("alarm", lua.LString(st.Alarm))
+	t.RawSetString("seconds_remaining", lua.LNumber(st.SecondsRemaining))
+	L.Push(t)
+	return 1
+}
+
+// alarm.arm(mode, [code]) — mode is "home", "night" or "away"
+func alarmArm(L *lua.LState, e *Engine) int {
+	mode := L.CheckString(1)
+	code := L.OptString(2, "")
+	p := e.alarmPanel()
+	if p == nil {
+		return pushAlarmResult(L, coordinator.ErrAlarmPanelDisabled)
+	}
+	return pushAlarmResult(L, p.Arm(coordinator.AlarmMode(mode), code, "lua"))
+}
+
+// alarm.disarm([code])
+func alarmDisarm(L *lua.LState, e *Engine) int {
+	code := L.OptString(1, "")
+	p := e.alarmPanel()
+	if p == nil {
+		return pushAlarmResult(L, coordinator.ErrAlarmPanelDisabled)
+	}
+	return pushAlarmResult(L, p.Disarm(code, "lua"))
+}
+
+// alarm.trigger([cause]) — cause defaults to "burglar"
+func alarmTrigger(L *lua.LState, e *Engine) int {
+	cause := L.OptString(1, "burglar")
+	p := e.alarmPanel()
+	if p == nil {
+		return pushAlarmResult(L, coordinator.ErrAlarmPanelDisabled)
+	}
+	return pushAlarmResult(L, p.Trigger(cause, "lua"))
+}
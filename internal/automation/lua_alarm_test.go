@@ -0,0 +1,31 @@
+//go:build !no_automation
+
+package automation
+
+import (
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestAlarmModuleDisabled(t *testing.T) {
+	L := lua.NewState()
+	defer L.Close()
+
+	e := newTestEngine()
+	registerAlarmModule(L, e)
+
+	if err := L.DoString(`_state = alarm.state()
+_ok, _err = alarm.arm("away", "1234")`); err != nil {
+		t.Fatal(err)
+	}
+	if v := L.GetGlobal("_state"); v != lua.LNil {
+		t.Errorf("state = %v, want nil", v)
+	}
+	if v := L.GetGlobal("_ok"); v != lua.LNil {
+		t.Errorf("ok = %v, want nil", v)
+	}
+	if v := L.GetGlobal("_err"); v.String() != "alarm panel disabled" {
+		t.Errorf("err = %v", v)
+	}
+}
@@ -0,0 +1,252 @@
+//go:build !no_automation
+
+package automation
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"zigbee-go-home/internal/coordinator"
+	"zigbee-go-home/internal/zcl"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Lua decoders run on the frame path, so each call gets a short deadline.
+const (
+	luaDecoderLoadTimeout = time.Second
+	luaDecoderTimeout     = 100 * time.Millisecond
+	maxLuaValueDepth      = 8
+)
+
+func init() {
+	coordinator.RegisterDecoderLoader(".lua", loadLuaDecoder)
+}
+
+// luaDecoder is a decoder file running in its own sandboxed VM. A VM is
+// not safe for concurrent use, so calls are serialized.
+type luaDecoder struct {
+	mu sync.Mutex
+	L  *lua.LState
+	fn *lua.LFunction
+}
+
+// loadLuaDecoder loads a decoder file from the devices directory. The file
+// defines decode(input), where input is a table with cluster, attribute
+// and type (attribute reports) or command (cluster commands), data (a byte
+// string), manufacturer and model. decode returns a table whose integer
+// keys are tags and string keys named properties, or nil. A global
+// commands = {...} makes it decode those cluster commands by default.
+func loadLuaDecoder(path string) (*coordinator.PropertyDecoder, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+
+	L := newSandboxedLuaState()
+	registerBytesModule(L)
+	ctx, cancel := context.WithTimeout(context.Background(), luaDecoderLoadTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	if err := L.DoString(string(code)); err != nil {
+		L.Close()
+		return nil, fmt.Errorf("load %s: %w", path, err)
+	}
+	L.RemoveContext()
+
+	fn, ok := L.GetGlobal("decode").(*lua.LFunction)
+	if !ok {
+		L.Close()
+		return nil, fmt.Errorf("load %s: no decode function", path)
+	}
+	var commands []uint8
+	if t, ok := L.GetGlobal("commands").(*lua.LTable); ok {
+		for i := 1; i <= t.Len(); i++ {
+			n, ok := t.RawGetInt(i).(lua.LNumber)
+			if !ok || n < 0 || n > 255 {
+				L.Close()
+				return nil, fmt.Errorf("load %s: commands[%d] is not a command ID", path, i)
+			}
+			commands = append(commands, uint8(n))
+		}
+	}
+
+	d := &luaDecoder{L: L, fn: fn}
+	return &coordinator.PropertyDecoder{Commands: commands, Decode: d.decode}, nil
+}
+
+func (d *luaDecoder) decode(in coordinator.DecoderInput) (coordinator.Decoded, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	L := d.L
+	ctx, cancel := context.WithTimeout(context.Background(), luaDecoderTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	input := L.NewTable()
+	input.RawSetString("cluster", lua.LNumber(in.Cluster))
+	if in.IsCommand {
+		input.RawSetString("command", lua.LNumber(in.Command))
+	} else {
+		input.RawSetString("attribute", lua.LNumber(in.Attribute))
+		input.RawSetString("type", lua.LNumber(in.DataType))
+	}
+	input.RawSetString("data", lua.LString(in.Data))
+	input.RawSetString("manufacturer", lua.LString(in.Manufacturer))
+	input.RawSetString("model", lua.LString(in.Model))
+
+	if err := L.CallByParam(lua.P{Fn: d.fn, NRet: 1, Protect: true}, input); err != nil {
+		L.SetTop(0)
+		return coordinator.Decoded{}, err
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	var out coordinator.Decoded
+	switch t := ret.(type) {
+	case *lua.LNilType:
+		return out, nil
+	case *lua.LTable:
+		var err error
+		t.ForEach(func(k, v lua.LValue) {
+			if err != nil {
+				return
+			}
+			switch key := k.(type) {
+			case lua.LNumber:
+				if out.Tags == nil {
+					out.Tags = make(map[int]any)
+				}
+				out.Tags[int(key)] = luaToGo(v, 0)
+			case lua.LString:
+				if out.Properties == nil {
+					out.Properties = make(map[string]any)
+				}
+				out.Properties[string(key)] = luaToGo(v, 0)
+			default:
+				err = fmt.Errorf("decode returned a %s key", k.Type())
+			}
+		})
+		return out, err
+	default:
+		return out, fmt.Errorf("decode returned a %s, want a table", ret.Type())
+	}
+}
+
+// luaToGo converts a Lua value to a Go value: numbers become float64,
+// sequences []any and other tables map[string]any.
+func luaToGo(v lua.LValue, depth int) any {
+	switch val := v.(type) {
+	case lua.LBool:
+		return bool(val)
+	case lua.LNumber:
+		return float64(val)
+	case lua.LString:
+		return string(val)
+	case *lua.LTable:
+		if depth >= maxLuaValueDepth {
+			return nil
+		}
+		if n := val.Len(); n > 0 {
+			list := make([]any, 0, n)
+			for i := 1; i <= n; i++ {
+				list = append(list, luaToGo(val.RawGetInt(i), depth+1))
+			}
+			return list
+		}
+		m := make(map[string]any)
+		val.ForEach(func(k, vv lua.LValue) {
+			m[k.String()] = luaToGo(vv, depth+1)
+		})
+		return m
+	default:
+		return nil
+	}
+}
+
+// registerBytesModule adds the bytes module for reading binary payloads.
+// Offsets start at 1 like string.byte:
+//
+//	bytes.u8(s, i), bytes.i8(s, i)
+//	bytes.u16le/i16le/u16be/i16be(s, i)
+//	bytes.u32le/i32le/u32be/i32be(s, i)
+//	bytes.zcl(s, i, type) -> value, next offset
+func registerBytesModule(L *lua.LState) {
+	mod := L.NewTable()
+	for _, f := range []struct {
+		name   string
+		size   int
+		signed bool
+		big    bool
+	}{
+		{"u8", 1, false, false}, {"i8", 1, true, false},
+		{"u16le", 2, false, false}, {"i16le", 2, true, false},
+		{"u16be", 2, false, true}, {"i16be", 2, true, true},
+		{"u32le", 4, false, false}, {"i32le", 4, true, false},
+		{"u32be", 4, false, true}, {"i32be", 4, true, true},
+	} {
+		mod.RawSetString(f.name, L.NewFunction(func(L *lua.LState) int {
+			b := checkBytes(L, f.size)
+			var n uint32
+			switch {
+			case f.size == 1:
+				n = uint32(b[0])
+			case f.size == 2 && f.big:
+				n = uint32(binary.BigEndian.Uint16(b))
+			case f.size == 2:
+				n = uint32(binary.LittleEndian.Uint16(b))
+			case f.big:
+				n = binary.BigEndian.Uint32(b)
+			default:
+				n = binary.LittleEndian.Uint32(b)
+			}
+			if !f.signed {
+				L.Push(lua.LNumber(n))
+				return 1
+			}
+			shift := 32 - 8*f.size
+			L.Push(lua.LNumber(int32(n<<shift) >> shift))
+			return 1
+		}))
+	}
+	mod.RawSetString("zcl", L.NewFunction(func(L *lua.LState) int {
+		s := L.CheckString(1)
+		i := L.CheckInt(2)
+		typeID := L.CheckInt(3)
+		if i < 1 || i > len(s) {
+			L.ArgError(2, "offset out of range")
+			return 0
+		}
+		val, n, err := zcl.DecodeValue(uint8(typeID), []byte(s[i-1:]))
+		if err != nil {
+			L.RaiseError("%s", err.Error())
+			return 0
+		}
+		if b, ok := val.([]byte); ok {
+			L.Push(lua.LString(b))
+		} else {
+			L.Push(goToLua(L, val))
+		}
+		L.Push(lua.LNumber(i + n))
+		return 2
+	}))
+	L.SetGlobal("bytes", mod)
+}
+
+// checkBytes returns size bytes of the string argument 1 at the 1-based
+// offset in argument 2.
+func checkBytes(L *lua.LState, size int) []byte {
+	s := L.CheckString(1)
+	i := L.CheckInt(2)
+	if i < 1 || i-1+size > len(s) {
+		L.ArgError(2, "offset out of range")
+		return nil
+	}
+	return []byte(s[i-1 : i-1+size])
+}
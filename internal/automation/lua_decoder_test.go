@@ -0,0 +1,123 @@
+//go:build !no_automation
+
+package automation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zigbee-go-home/internal/coordinator"
+)
+
+func writeDecoder(t *testing.T, code string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test_decoder.lua")
+	if err := os.WriteFile(path, []byte(code), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLuaDecoderHueButton(t *testing.T) {
+	d, err := loadLuaDecoder("../../devices/philips_hue_button.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Commands) != 1 || d.Commands[0] != 0x00 {
+		t.Errorf("commands = %v, want [0]", d.Commands)
+	}
+
+	tests := []struct {
+		payload []byte
+		want    any
+	}{
+		{[]byte{0x01, 0x00, 0x00, 0x30, 0x00, 0x21, 0x00, 0x00}, "on_press"},
+		{[]byte{0x02, 0x00, 0x00, 0x30, 0x01, 0x21, 0x08, 0x00}, "up_hold"},
+		{[]byte{0x04, 0x00, 0x00, 0x30, 0x03, 0x21, 0x10, 0x00}, "off_hold_release"},
+		{[]byte{0x09, 0x00, 0x00, 0x30, 0x00}, nil},
+		{[]byte{0x01}, nil},
+	}
+	for _, tt := range tests {
+		out, err := d.Decode(coordinator.DecoderInput{Cluster: 0xFC00, IsCommand: true, Data: tt.payload})
+		if err != nil {
+			t.Fatalf("decode %X: %v", tt.payload, err)
+		}
+		if got := out.Properties["action"]; got != tt.want {
+			t.Errorf("decode %X: action = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestLuaDecoderBytesAndTags(t *testing.T) {
+	d, err := loadLuaDecoder(writeDecoder(t, `
+function decode(input)
+  local d = input.data
+  local v, next = bytes.zcl(d, 7, 0x21)
+  return {
+    [1] = bytes.i16le(d, 1),
+    [2] = bytes.u16be(d, 3),
+    [3] = bytes.i8(d, 5),
+    voltage = v,
+    next = next,
+    attribute = input.attribute,
+    model = input.model,
+    list = {1, 2},
+  }
+end`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := d.Decode(coordinator.DecoderInput{
+		Cluster: 0xFC00, Attribute: 0x00F7, DataType: 0x41, Model: "lumi.test",
+		Data: []byte{0x2E, 0xFF, 0x01, 0x02, 0xFE, 0x00, 0xB8, 0x0B},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Tags[1] != -210.0 || out.Tags[2] != 258.0 || out.Tags[3] != -2.0 {
+		t.Errorf("tags: %v", out.Tags)
+	}
+	p := out.Properties
+	if p["voltage"] != 3000.0 || p["next"] != 9.0 || p["attribute"] != 247.0 || p["model"] != "lumi.test" {
+		t.Errorf("properties: %v", p)
+	}
+	if list, ok := p["list"].([]any); !ok || len(list) != 2 {
+		t.Errorf("list: %v", p["list"])
+	}
+
+	// Reading past the end fails the call, not the decoder.
+	if _, err := d.Decode(coordinator.DecoderInput{Data: []byte{1}}); err == nil {
+		t.Error("expected error for short data")
+	}
+}
+
+func TestLuaDecoderErrors(t *testing.T) {
+	for name, code := range map[string]string{
+		"no decode":    `x = 1`,
+		"syntax":       `function decode(`,
+		"bad commands": `commands = {"x"} function decode() end`,
+		"sandbox":      `os.exit(1)`,
+	} {
+		if _, err := loadLuaDecoder(writeDecoder(t, code)); err == nil {
+			t.Errorf("%s: expected load error", name)
+		}
+	}
+
+	d, err := loadLuaDecoder(writeDecoder(t, `function decode() while true do end end`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Decode(coordinator.DecoderInput{}); err == nil {
+		t.Error("expected timeout")
+	}
+
+	d, err = loadLuaDecoder(writeDecoder(t, `function decode() return 42 end`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Decode(coordinator.DecoderInput{}); err == nil || !strings.Contains(err.Error(), "want a table") {
+		t.Errorf("non-table result: %v", err)
+	}
+}
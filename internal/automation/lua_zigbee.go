@@ -4,9 +4,13 @@ package automation
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"zigbee-go-home/internal/coordinator"
 	"zigbee-go-home/internal/store"
 
 	lua "github.com/yuin/gopher-lua"
@@ -44,10 +48,30 @@ func registerZigbeeModule(L *lua.LState, vm *scriptVM, e *Engine) {
 		return zigbeeSendCommand(L, e)
 	}))
 
+	mod.RawSetString("send_aps", L.NewFunction(func(L *lua.LState) int {
+		return zigbeeSendAPS(L, e)
+	}))
+
 	mod.RawSetString("get_property", L.NewFunction(func(L *lua.LState) int {
 		return zigbeeGetProperty(L, e)
 	}))
 
+	mod.RawSetString("set", L.NewFunction(func(L *lua.LState) int {
+		return zigbeeSetProperty(L, e)
+	}))
+
+	mod.RawSetString("available", L.NewFunction(func(L *lua.LState) int {
+		return zigbeeAvailable(L, e)
+	}))
+
+	mod.RawSetString("interview", L.NewFunction(func(L *lua.LState) int {
+		return zigbeeMaintenance(L, e, "interview", e.coord.Devices().Reinterview)
+	}))
+
+	mod.RawSetString("configure", L.NewFunction(func(L *lua.LState) int {
+		return zigbeeMaintenance(L, e, "configure", e.coord.Devices().Reconfigure)
+	}))
+
 	mod.RawSetString("after", L.NewFunction(func(L *lua.LState) int {
 		return zigbeeAfter(L, vm, e)
 	}))
@@ -82,6 +106,9 @@ func zigbeeOn(L *lua.LState, vm *scriptVM) int {
 	if v := filterTable.RawGetString("property"); v != lua.LNil {
 		h.property = v.String()
 	}
+	if v := filterTable.RawGetString("action"); v != lua.LNil {
+		h.action = v.String()
+	}
 
 	vm.mu.Lock()
 	if len(vm.handlers) >= maxHandlersPerScript {
@@ -110,7 +137,7 @@ func zigbeeSendOnOff(L *lua.LState, e *Engine, cmdID uint8) int {
 	defer cancel()
 
 	if err := e.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0006, cmdID, nil); err != nil {
-		e.logger.Error("send on/off command", "err", err, "target", target, "cmd", cmdID)
+		e.logCommandErr("send on/off command", err, "target", target, "cmd", cmdID)
 	}
 	return 0
 }
@@ -141,7 +168,7 @@ func zigbeeSetBrightness(L *lua.LState, e *Engine) int {
 	// Move to Level with On/Off (cmd 0x04): level (1 byte) + transition time (2 bytes, 1/10s)
 	payload := []byte{byte(level), 10, 0} // transition = 1s
 	if err := e.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0008, 0x04, payload); err != nil {
-		e.logger.Error("set brightness", "err", err, "target", target, "level", level)
+		e.logCommandErr("set brightness", err, "target", target, "level", level)
 	}
 	return 0
 }
@@ -179,7 +206,7 @@ func zigbeeSetColor(L *lua.LState, e *Engine) int {
 	// MoveToHueAndSaturation (cmd 0x06): hue (1 byte) + saturation (1 byte) + transition time (2 bytes, 1/10s)
 	payload := []byte{byte(hue), byte(sat), 10, 0} // transition = 1s
 	if err := e.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0300, 0x06, payload); err != nil {
-		e.logger.Error("set color", "err", err, "target", target, "hue", hue, "sat", sat)
+		e.logCommandErr("set color", err, "target", target, "hue", hue, "sat", sat)
 	}
 	return 0
 }
@@ -229,11 +256,145 @@ func zigbeeSendCommand(L *lua.LState, e *Engine) int {
 	defer cancel()
 
 	if err := e.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, cluster, cmd, payload); err != nil {
-		e.logger.Error("send command", "err", err, "target", target)
+		e.logCommandErr("send command", err, "target", target)
 	}
 	return 0
 }
 
+// zigbee.send_aps(ieee_or_name, {cluster=, endpoint=, profile=, src_endpoint=,
+// radius=, ack=, payload=, wait=, zcl=, timeout=}) sends a raw APS frame.
+// payload is a byte table or hex string. With wait=true it returns the
+// response table, or nil and an error message. Otherwise returns true, or
+// nil and an error message.
+func zigbeeSendAPS(L *lua.LState, e *Engine) int {
+	target := L.CheckString(1)
+	opts := L.CheckTable(2)
+
+	intOpt := func(key string, def, max int) int {
+		v := opts.RawGetString(key)
+		if v == lua.LNil {
+			return def
+		}
+		n, ok := v.(lua.LNumber)
+		if !ok || int(n) < 0 || int(n) > max {
+			L.ArgError(2, fmt.Sprintf("%s must be 0-%d", key, max))
+		}
+		return int(n)
+	}
+	boolOpt := func(key string, def bool) bool {
+		v := opts.RawGetString(key)
+		if v == lua.LNil {
+			return def
+		}
+		return lua.LVAsBool(v)
+	}
+
+	if opts.RawGetString("cluster") == lua.LNil {
+		L.ArgError(2, "cluster is required")
+		return 0
+	}
+	req := coordinator.RawAPSRequest{
+		ClusterID:    uint16(intOpt("cluster", 0, 0xFFFF)),
+		DstEP:        uint8(intOpt("endpoint", 1, 0xFF)),
+		SrcEP:        uint8(intOpt("src_endpoint", 0, 0xFF)),
+		ProfileID:    uint16(intOpt("profile", 0x0104, 0xFFFF)),
+		Radius:       uint8(intOpt("radius", 0, 0xFF)),
+		NoACK:        !boolOpt("ack", true),
+		WaitResponse: boolOpt("wait", false),
+		ZCL:          boolOpt("zcl", true),
+	}
+	timeout := time.Duration(intOpt("timeout", 5, 30)) * time.Second
+
+	switch p := opts.RawGetString("payload").(type) {
+	case *lua.LTable:
+		p.ForEach(func(_, v lua.LValue) {
+			if n, ok := v.(lua.LNumber); ok {
+				req.Payload = append(req.Payload, byte(n))
+			}
+		})
+	case lua.LString:
+		b, err := hex.DecodeString(strings.ReplaceAll(string(p), " ", ""))
+		if err != nil {
+			L.ArgError(2, "payload must be hex")
+			return 0
+		}
+		req.Payload = b
+	}
+
+	dev := resolveDevice(e, target)
+	if dev == nil {
+		e.logger.Warn("device not found", "target", target)
+		L.Push(lua.LNil)
+		L.Push(lua.LString("device not found"))
+		return 2
+	}
+	req.ShortAddr = dev.ShortAddress
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	rsp, err := e.coord.SendRawAPS(ctx, req)
+	if err != nil {
+		e.logCommandErr("send aps", err, "target", target, "cluster", req.ClusterID)
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if rsp == nil {
+		L.Push(lua.LTrue)
+		return 1
+	}
+	L.Push(rawAPSResponseToLua(L, rsp))
+	return 1
+}
+
+// rawAPSResponseToLua converts a raw APS response to a Lua table.
+func rawAPSResponseToLua(L *lua.LState, rsp *coordinator.RawAPSResponse) *lua.LTable {
+	t := L.NewTable()
+	t.RawSetString("src_endpoint", lua.LNumber(rsp.SrcEP))
+	t.RawSetString("profile", lua.LNumber(rsp.ProfileID))
+	t.RawSetString("cluster", lua.LNumber(rsp.ClusterID))
+	t.RawSetString("lqi", lua.LNumber(rsp.LQI))
+	t.RawSetString("rssi", lua.LNumber(rsp.RSSI))
+	t.RawSetString("payload", lua.LString(rsp.Payload))
+	if z := rsp.ZCL; z != nil {
+		zt := L.NewTable()
+		zt.RawSetString("frame_type", lua.LString(z.FrameType))
+		zt.RawSetString("direction", lua.LString(z.Direction))
+		zt.RawSetString("manufacturer_code", lua.LNumber(z.ManufacturerCode))
+		zt.RawSetString("seq", lua.LNumber(z.Seq))
+		zt.RawSetString("command_id", lua.LNumber(z.CommandID))
+		zt.RawSetString("command_name", lua.LString(z.CommandName))
+		zt.RawSetString("payload", lua.LString(z.Payload))
+		if z.Status != nil {
+			zt.RawSetString("status", lua.LNumber(*z.Status))
+		}
+		attrs := L.NewTable()
+		for i, a := range z.Attributes {
+			at := L.NewTable()
+			at.RawSetString("id", lua.LNumber(a.AttrID))
+			at.RawSetString("name", lua.LString(a.AttrName))
+			at.RawSetString("type", lua.LNumber(a.TypeID))
+			at.RawSetString("status", lua.LNumber(a.Status))
+			at.RawSetString("value", goToLua(L, a.Value))
+			attrs.RawSetInt(i+1, at)
+		}
+		zt.RawSetString("attributes", attrs)
+		t.RawSetString("zcl", zt)
+	}
+	return t
+}
+
+// logCommandErr logs a failed send. Commands queued for a sleepy device
+// are delivered at its next check-in and only logged at info level.
+func (e *Engine) logCommandErr(msg string, err error, args ...any) {
+	if errors.Is(err, coordinator.ErrCommandQueued) {
+		e.logger.Info(msg+": queued until device checks in", args...)
+		return
+	}
+	e.logger.Error(msg, append([]any{"err", err}, args...)...)
+}
+
 // zigbee.get_property(ieee_or_name, property)
 func zigbeeGetProperty(L *lua.LState, e *Engine) int {
 	target := L.CheckString(1)
@@ -256,6 +417,97 @@ func zigbeeGetProperty(L *lua.LState, e *Engine) int {
 	return 1
 }
 
+// zigbee.set(ieee_or_name, property, value) — sets a named property through
+// the device's converters, e.g. zigbee.set("lamp", "brightness", 128).
+// Returns true (also when queued for a sleepy device), or nil and an error.
+func zigbeeSetProperty(L *lua.LState, e *Engine) int {
+	target := L.CheckString(1)
+	prop := L.CheckString(2)
+
+	var value any
+	switch v := L.CheckAny(3).(type) {
+	case lua.LBool:
+		value = bool(v)
+	case lua.LNumber:
+		value = float64(v)
+	case lua.LString:
+		value = string(v)
+	default:
+		L.ArgError(3, "boolean, number or string expected")
+		return 0
+	}
+
+	dev := resolveDevice(e, target)
+	if dev == nil {
+		e.logger.Warn("device not found", "target", target)
+		L.Push(lua.LNil)
+		L.Push(lua.LString("device not found"))
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := e.coord.SetProperty(ctx, dev.IEEEAddress, prop, value); err != nil {
+		e.logCommandErr("set property", err, "target", target, "property", prop)
+		if !errors.Is(err, coordinator.ErrCommandQueued) {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
+// zigbee.available(ieee_or_name) — true when online, false when offline,
+// nil when the device is unknown or its availability is not tracked.
+func zigbeeAvailable(L *lua.LState, e *Engine) int {
+	target := L.CheckString(1)
+
+	dev := resolveDevice(e, target)
+	a := e.coord.Availability()
+	if dev == nil || a == nil {
+		L.Push(lua.LNil)
+		return 1
+	}
+	switch a.State(dev.IEEEAddress) {
+	case coordinator.AvailabilityOnline:
+		L.Push(lua.LTrue)
+	case coordinator.AvailabilityOffline:
+		L.Push(lua.LFalse)
+	default:
+		L.Push(lua.LNil)
+	}
+	return 1
+}
+
+// zigbee.interview/configure(ieee_or_name) — starts a re-interview or a
+// reconfiguration in the background. Returns true, or nil and an error;
+// a reconfiguration queued until a Poll Control check-in returns true.
+// Progress arrives as interview_progress events.
+func zigbeeMaintenance(L *lua.LState, e *Engine, op string, run func(ieee string) error) int {
+	target := L.CheckString(1)
+
+	dev := resolveDevice(e, target)
+	if dev == nil {
+		e.logger.Warn("device not found", "target", target)
+		L.Push(lua.LNil)
+		L.Push(lua.LString("device not found"))
+		return 2
+	}
+	if err := run(dev.IEEEAddress); err != nil {
+		e.logCommandErr(op, err, "target", target)
+		if !errors.Is(err, coordinator.ErrCommandQueued) {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+	}
+	L.Push(lua.LTrue)
+	return 1
+}
+
 // zigbee.after(seconds, callback) — delayed execution
 func zigbeeAfter(L *lua.LState, vm *scriptVM, e *Engine) int {
 	seconds := L.CheckNumber(1)
@@ -326,6 +578,9 @@ func zigbeeDevices(L *lua.LState, e *Engine) int {
 		d.RawSetString("name", lua.LString(name))
 		d.RawSetString("model", lua.LString(dev.Model))
 		d.RawSetString("manufacturer", lua.LString(dev.Manufacturer))
+		if dev.Availability != nil {
+			d.RawSetString("availability", lua.LString(dev.Availability.State))
+		}
 		tbl.RawSetInt(i+1, d)
 	}
 
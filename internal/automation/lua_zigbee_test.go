@@ -0,0 +1,81 @@
+//go:build !no_automation
+
+package automation
+
+import (
+	"path/filepath"
+	"testing"
+
+	"zigbee-go-home/internal/coordinator"
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// stubNCP is enough of an NCP for a coordinator that never starts.
+type stubNCP struct {
+	ncp.NCP
+}
+
+func (stubNCP) RegisterLocalEndpoint(ncp.SimpleDescriptor)                  {}
+func (stubNCP) OnDeviceJoined(func(ncp.DeviceJoinedEvent))                  {}
+func (stubNCP) OnDeviceLeft(func(ncp.DeviceLeftEvent))                      {}
+func (stubNCP) OnDeviceAnnounce(func(ncp.DeviceAnnounceEvent))              {}
+func (stubNCP) OnAttributeReport(func(ncp.AttributeReportEvent))            {}
+func (stubNCP) OnClusterCommand(func(ncp.ClusterCommandEvent))              {}
+func (stubNCP) OnGlobalCommand(func(ncp.GlobalCommandEvent))                {}
+func (stubNCP) OnNwkAddrUpdate(func(uint16))                                {}
+func (stubNCP) SetDefaultResponsePolicy(func(ncp.DefaultResponseInfo) bool) {}
+
+func newTestCoordinator(t *testing.T) (*coordinator.Coordinator, *store.BoltStore) {
+	t.Helper()
+	db, err := store.NewBoltStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	deviceDB := coordinator.NewDeviceDB()
+	deviceDB.Add(coordinator.DeviceDefinition{Manufacturer: "Acme", Model: "Valve"})
+	logger := testLogger()
+	coord := coordinator.New(stubNCP{}, db, zcl.NewRegistry(logger), deviceDB, coordinator.NewEventBus(logger),
+		coordinator.Config{}, coordinator.NCPConfig{Type: "nrf52840"}, logger)
+	t.Cleanup(coord.Stop)
+	return coord, db
+}
+
+func TestZigbeeConfigureQueued(t *testing.T) {
+	coord, db := newTestCoordinator(t)
+	if err := db.SaveDevice(&store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111, FriendlyName: "valve",
+		Manufacturer: "Acme", Model: "Valve", Interviewed: true,
+		Endpoints:   []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0x0020}}},
+		PollControl: &store.PollControlState{Endpoint: 1, Configured: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	e := newTestEngine()
+	e.coord, e.logger = coord, testLogger()
+	registerZigbeeModule(L, &scriptVM{}, e)
+
+	// A Poll Control device is reconfigured at its next check-in.
+	if err := L.DoString(`_ok, _err = zigbee.configure("valve")
+_ok2, _err2 = zigbee.configure("missing")`); err != nil {
+		t.Fatal(err)
+	}
+	if v := L.GetGlobal("_ok"); v != lua.LTrue {
+		t.Errorf("ok = %v, err = %v, want true", v, L.GetGlobal("_err"))
+	}
+	if v := L.GetGlobal("_ok2"); v != lua.LNil || L.GetGlobal("_err2").String() != "device not found" {
+		t.Errorf("unknown device: ok = %v, err = %v", v, L.GetGlobal("_err2"))
+	}
+	dev, err := db.GetDevice("00158D0001A2B3C4")
+	if err != nil || dev.PollControl.Configured {
+		t.Errorf("reconfigure not queued: %+v, %v", dev.PollControl, err)
+	}
+}
@@ -0,0 +1,277 @@
+package coordinator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+// Remotes and buttons send cluster commands (On/Off toggle, Level step,
+// Scenes recall, ...) or report attributes (multistate input, Xiaomi
+// multi-click) when pressed. The action layer names these presses: the
+// actions of the device's definition first, then the standard mappings
+// below. Every action becomes the device's action property and is emitted
+// as a device_action event. Decoders returning an action value (e.g.
+// philips_hue_button.lua) emit device_action events as well.
+
+// ActionDef names the presses of a remote. With Commands, the entry
+// matches those cluster commands, and with Payload (hex) only the ones
+// whose payload starts with it; Action names them. Without Commands,
+// Values names the values of the attribute. Endpoint limits the entry to
+// one endpoint. Entries for a command or attribute replace its standard
+// mapping, also for the payloads or values they do not name.
+type ActionDef struct {
+	Cluster   uint16         `json:"cluster"`
+	Attribute uint16         `json:"attribute,omitempty"`
+	Commands  []uint8        `json:"commands,omitempty"`
+	Payload   string         `json:"payload,omitempty"`
+	Endpoint  uint8          `json:"endpoint,omitempty"`
+	Action    string         `json:"action,omitempty"`
+	Values    map[int]string `json:"values,omitempty"`
+}
+
+// check validates an action entry.
+func (a *ActionDef) check() error {
+	if len(a.Commands) > 0 {
+		if a.Action == "" || len(a.Values) > 0 {
+			return errors.New("commands need an action and no values")
+		}
+		if _, err := hex.DecodeString(a.Payload); err != nil {
+			return fmt.Errorf("payload: %w", err)
+		}
+		return nil
+	}
+	if len(a.Values) == 0 || a.Action != "" || a.Payload != "" {
+		return errors.New("attributes need values and no action or payload")
+	}
+	for v, name := range a.Values {
+		if name == "" {
+			return fmt.Errorf("value %d has no action", v)
+		}
+	}
+	return nil
+}
+
+func (a *ActionDef) onEndpoint(ep uint8) bool {
+	return a.Endpoint == 0 || a.Endpoint == ep
+}
+
+// multistateActions names the presentValue of Multistate Input clusters
+// used for buttons (Xiaomi Opple and H1, Tuya, ...).
+var multistateActions = map[int]string{
+	0:   "hold",
+	1:   "single",
+	2:   "double",
+	3:   "triple",
+	4:   "quadruple",
+	255: "release",
+}
+
+// standardCommandAction names the standard ZCL commands remotes send.
+// Params carry the command arguments an automation may use.
+func standardCommandAction(evt ncp.ClusterCommandEvent) (string, map[string]any) {
+	if evt.ServerToClient || evt.ManufacturerCode != 0 {
+		return "", nil
+	}
+	p := evt.Payload
+	switch evt.ClusterID {
+	case 0x0006: // On/Off
+		switch evt.CommandID {
+		case 0x00, 0x40: // Off, Off with effect
+			return "off", nil
+		case 0x01, 0x42: // On, On with timed off
+			return "on", nil
+		case 0x02:
+			return "toggle", nil
+		}
+	case 0x0008: // Level Control; 0x04-0x07 are the "with on/off" variants.
+		cmd := evt.CommandID
+		if cmd >= 0x04 && cmd <= 0x07 {
+			cmd -= 0x04
+		}
+		switch cmd {
+		case 0x00: // Move to level: level(1) transition(2)
+			if len(p) >= 1 {
+				return "brightness_move_to_level", map[string]any{"level": p[0]}
+			}
+		case 0x01: // Move: mode(1) rate(1)
+			if len(p) >= 2 && p[0] <= 1 {
+				return "brightness_move_" + levelDirection(p[0]), map[string]any{"rate": p[1]}
+			}
+		case 0x02: // Step: mode(1) step size(1) transition(2)
+			if len(p) >= 2 && p[0] <= 1 {
+				return "brightness_" + levelDirection(p[0]), map[string]any{"step_size": p[1]}
+			}
+		case 0x03:
+			return "brightness_stop", nil
+		}
+	case 0x0005: // Scenes, Recall scene: group(2) scene(1)
+		if evt.CommandID == 0x05 && len(p) >= 3 {
+			return fmt.Sprintf("recall_%d", p[2]), map[string]any{
+				"group_id": binary.LittleEndian.Uint16(p[0:2]),
+				"scene_id": p[2],
+			}
+		}
+	case 0x0102: // Window Covering
+		switch evt.CommandID {
+		case 0x00:
+			return "open", nil
+		case 0x01:
+			return "close", nil
+		case 0x02:
+			return "stop", nil
+		}
+	}
+	return "", nil
+}
+
+func levelDirection(mode uint8) string {
+	if mode == 0 {
+		return "up"
+	}
+	return "down"
+}
+
+// commandAction returns the action of a cluster command from the device.
+func (dm *DeviceManager) commandAction(dev *store.Device, evt ncp.ClusterCommandEvent) (string, map[string]any) {
+	if def := dm.definition(dev); def != nil {
+		mapped := false
+		for i := range def.Actions {
+			a := &def.Actions[i]
+			if a.Cluster != evt.ClusterID || !slices.Contains(a.Commands, evt.CommandID) || !a.onEndpoint(evt.SrcEP) {
+				continue
+			}
+			mapped = true
+			if prefix, _ := hex.DecodeString(a.Payload); bytes.HasPrefix(evt.Payload, prefix) {
+				return a.Action, nil
+			}
+		}
+		if mapped {
+			return "", nil
+		}
+	}
+	return standardCommandAction(evt)
+}
+
+// attributeAction returns the action of a reported attribute value.
+func (dm *DeviceManager) attributeAction(dev *store.Device, evt ncp.AttributeReportEvent, decoded any) string {
+	n, ok := actionValue(decoded)
+	if def := dm.definition(dev); def != nil {
+		for i := range def.Actions {
+			a := &def.Actions[i]
+			if a.Cluster == evt.ClusterID && len(a.Commands) == 0 && a.Attribute == evt.AttrID && a.onEndpoint(evt.SrcEP) {
+				if !ok {
+					return ""
+				}
+				return a.Values[n]
+			}
+		}
+	}
+	if ok && evt.ClusterID == 0x0012 && evt.AttrID == 0x0055 { // Multistate Input presentValue
+		return multistateActions[n]
+	}
+	return ""
+}
+
+// actionValue returns an integer or boolean (0 or 1) attribute value.
+func actionValue(decoded any) (int, bool) {
+	if decoded == nil {
+		return 0, false
+	}
+	x, err := exprValue(decoded)
+	if err != nil {
+		return 0, false
+	}
+	switch v := x.(type) {
+	case int64:
+		return int(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// processCommandAction emits the action of a cluster command, if any.
+func (dm *DeviceManager) processCommandAction(ieee string, dev *store.Device, evt ncp.ClusterCommandEvent) {
+	if ieee == "" || dev == nil {
+		return
+	}
+	action, params := dm.commandAction(dev, evt)
+	if action == "" {
+		return
+	}
+	dm.emitAction(ieee, dev, evt.SrcEP, action, map[string]interface{}{
+		"cluster": evt.ClusterID,
+		"command": evt.CommandID,
+	}, params)
+}
+
+// processAttributeAction emits the action of an attribute report, if any.
+func (dm *DeviceManager) processAttributeAction(ieee string, dev *store.Device, evt ncp.AttributeReportEvent, decoded any) {
+	if ieee == "" || dev == nil {
+		return
+	}
+	action := dm.attributeAction(dev, evt, decoded)
+	if action == "" {
+		return
+	}
+	dm.emitAction(ieee, dev, evt.SrcEP, action, map[string]interface{}{
+		"cluster":   evt.ClusterID,
+		"attribute": evt.AttrID,
+	}, nil)
+}
+
+// emitAction stores the action as the device's action property and emits
+// its property_update and device_action events.
+func (dm *DeviceManager) emitAction(ieee string, dev *store.Device, endpoint uint8, action string, source, params map[string]any) {
+	if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if d.Properties == nil {
+			d.Properties = make(map[string]any)
+		}
+		d.Properties["action"] = action
+		return nil
+	}); err != nil {
+		dm.logger.Error("save device action", "err", err, "ieee", ieee)
+	}
+
+	dm.coord.Events().Emit(Event{
+		Type: EventPropertyUpdate,
+		Data: map[string]interface{}{
+			"ieee":     ieee,
+			"property": "action",
+			"value":    action,
+			"source":   source,
+		},
+	})
+
+	data := map[string]interface{}{
+		"endpoint": endpoint,
+		"source":   source,
+	}
+	if len(params) > 0 {
+		data["params"] = params
+	}
+	dm.emitDeviceAction(ieee, dev, action, data)
+}
+
+// emitDeviceAction emits a device_action event. data holds the fields
+// besides ieee and action.
+func (dm *DeviceManager) emitDeviceAction(ieee string, dev *store.Device, action string, data map[string]interface{}) {
+	data["ieee"] = ieee
+	data["action"] = action
+	dm.logger.Info("device action",
+		"ieee", ieee,
+		"name", deviceName(dev),
+		"action", action,
+	)
+	dm.coord.Events().Emit(Event{Type: EventDeviceAction, Data: data})
+}
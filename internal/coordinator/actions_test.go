@@ -0,0 +1,206 @@
+package coordinator
+
+import (
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+func TestStandardCommandAction(t *testing.T) {
+	tests := []struct {
+		cluster uint16
+		cmd     uint8
+		payload []byte
+		want    string
+	}{
+		{0x0006, 0x00, nil, "off"},
+		{0x0006, 0x01, nil, "on"},
+		{0x0006, 0x02, nil, "toggle"},
+		{0x0006, 0x40, []byte{0, 0}, "off"},
+		{0x0008, 0x02, []byte{0, 43, 5, 0}, "brightness_up"},
+		{0x0008, 0x06, []byte{1, 43, 5, 0}, "brightness_down"},
+		{0x0008, 0x05, []byte{0, 84}, "brightness_move_up"},
+		{0x0008, 0x01, []byte{1, 84}, "brightness_move_down"},
+		{0x0008, 0x07, nil, "brightness_stop"},
+		{0x0008, 0x04, []byte{128, 0, 0}, "brightness_move_to_level"},
+		{0x0008, 0x01, []byte{3, 84}, ""},
+		{0x0005, 0x05, []byte{0x34, 0x12, 2}, "recall_2"},
+		{0x0102, 0x01, nil, "close"},
+		{0x0006, 0x03, nil, ""},
+		{0x0402, 0x00, nil, ""},
+	}
+	for _, tt := range tests {
+		got, _ := standardCommandAction(ncp.ClusterCommandEvent{ClusterID: tt.cluster, CommandID: tt.cmd, Payload: tt.payload})
+		if got != tt.want {
+			t.Errorf("0x%04X/0x%02X %X = %q, want %q", tt.cluster, tt.cmd, tt.payload, got, tt.want)
+		}
+	}
+
+	_, params := standardCommandAction(ncp.ClusterCommandEvent{ClusterID: 0x0005, CommandID: 0x05, Payload: []byte{0x34, 0x12, 2}})
+	if params["group_id"] != uint16(0x1234) || params["scene_id"] != uint8(2) {
+		t.Errorf("recall params = %v", params)
+	}
+	// Responses and manufacturer-specific commands are not presses.
+	if got, _ := standardCommandAction(ncp.ClusterCommandEvent{ClusterID: 0x0006, CommandID: 0x02, ServerToClient: true}); got != "" {
+		t.Errorf("server to client = %q", got)
+	}
+	if got, _ := standardCommandAction(ncp.ClusterCommandEvent{ClusterID: 0x0006, CommandID: 0x02, ManufacturerCode: 0x117C}); got != "" {
+		t.Errorf("manufacturer-specific = %q", got)
+	}
+}
+
+// recordActions collects the device_action events.
+func recordActions(dm *DeviceManager) *[]map[string]interface{} {
+	var got []map[string]interface{}
+	dm.coord.Events().On(EventDeviceAction, func(e Event) {
+		got = append(got, e.Data.(map[string]interface{}))
+	})
+	return &got
+}
+
+func newRemoteDM(t *testing.T, actions []ActionDef) (*DeviceManager, *memStore) {
+	t.Helper()
+	dm, ms := newTestDM(t)
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(DeviceDefinition{Manufacturer: "Acme", Model: "Remote", Actions: actions})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111,
+		Manufacturer: "Acme", Model: "Remote",
+	}
+	dm.RebuildAddrIndex()
+	return dm, ms
+}
+
+func TestCommandActions(t *testing.T) {
+	dm, ms := newRemoteDM(t, []ActionDef{
+		{Cluster: 0x0005, Commands: []uint8{0x07}, Payload: "01", Action: "arrow_left_click"},
+		{Cluster: 0x0005, Commands: []uint8{0x07}, Payload: "00", Action: "arrow_right_click"},
+		{Cluster: 0x0006, Commands: []uint8{0x01}, Endpoint: 2, Action: "on_right"},
+	})
+	got := recordActions(dm)
+	send := func(ep, cmd uint8, cluster uint16, payload ...byte) {
+		dm.HandleClusterCommand(ncp.ClusterCommandEvent{SrcAddr: 0x1111, SrcEP: ep, ClusterID: cluster, CommandID: cmd, Payload: payload})
+	}
+
+	send(1, 0x02, 0x0006)
+	send(1, 0x07, 0x0005, 0x01, 0x01, 0x0D, 0x00)
+	send(1, 0x07, 0x0005, 0x00, 0x01, 0x0D, 0x00)
+	send(1, 0x07, 0x0005, 0x02) // mapped command, unnamed payload
+	send(2, 0x01, 0x0006)
+	send(1, 0x01, 0x0006)
+
+	want := []string{"toggle", "arrow_left_click", "arrow_right_click", "on_right", "on"}
+	if len(*got) != len(want) {
+		t.Fatalf("actions = %v, want %v", *got, want)
+	}
+	for i, w := range want {
+		if (*got)[i]["action"] != w {
+			t.Errorf("action %d = %v, want %s", i, (*got)[i]["action"], w)
+		}
+	}
+	first := (*got)[0]
+	if first["ieee"] != "00158D0001A2B3C4" || first["endpoint"] != uint8(1) {
+		t.Errorf("event = %v", first)
+	}
+	if src := first["source"].(map[string]interface{}); src["cluster"] != uint16(0x0006) || src["command"] != uint8(0x02) {
+		t.Errorf("source = %v", src)
+	}
+	if props := ms.devices["00158D0001A2B3C4"].Properties; props["action"] != "on" {
+		t.Errorf("stored action = %v", props["action"])
+	}
+}
+
+func TestAttributeActions(t *testing.T) {
+	dm, _ := newRemoteDM(t, []ActionDef{
+		{Cluster: 0x0006, Attribute: 0x8000, Values: map[int]string{2: "double", 3: "triple", 128: "many"}},
+	})
+	got := recordActions(dm)
+	props := reportAttributes(dm, 0x1111, []ncp.AttributeReportEvent{
+		{ClusterID: 0x0012, AttrID: 0x0055, DataType: zcl.TypeUint16, Value: []byte{2, 0}},
+		{ClusterID: 0x0006, AttrID: 0x8000, DataType: zcl.TypeUint8, Value: []byte{3}},
+		{ClusterID: 0x0006, AttrID: 0x8000, DataType: zcl.TypeUint8, Value: []byte{5}},
+		{ClusterID: 0x0012, AttrID: 0x0055, DataType: zcl.TypeUint16, Value: []byte{255, 0}},
+	})
+
+	want := []string{"double", "triple", "release"}
+	if len(*got) != len(want) {
+		t.Fatalf("actions = %v, want %v", *got, want)
+	}
+	for i, w := range want {
+		if (*got)[i]["action"] != w {
+			t.Errorf("action %d = %v, want %s", i, (*got)[i]["action"], w)
+		}
+	}
+	if src := (*got)[1]["source"].(map[string]interface{}); src["attribute"] != uint16(0x8000) {
+		t.Errorf("source = %v", src)
+	}
+	if props["action"] == nil || props["action"]["value"] != "release" {
+		t.Errorf("action property = %v", props["action"])
+	}
+}
+
+func TestDecodedActionEvent(t *testing.T) {
+	RegisterDecoder("test_action_button", &PropertyDecoder{
+		Commands: []uint8{0x00},
+		Decode: func(in DecoderInput) (Decoded, error) {
+			return Decoded{Properties: map[string]any{"action": "on_press"}}, nil
+		},
+	})
+	dm, ms := newTestDM(t)
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "Button",
+		Properties:   []PropertySource{{Cluster: 0xFC00, Decoder: "test_action_button"}},
+	})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111,
+		Manufacturer: "Acme", Model: "Button",
+	}
+	dm.RebuildAddrIndex()
+	got := recordActions(dm)
+
+	dm.HandleClusterCommand(ncp.ClusterCommandEvent{SrcAddr: 0x1111, SrcEP: 2, ClusterID: 0xFC00, CommandID: 0x00, ManufacturerCode: 0x100B, Payload: []byte{1}})
+	if len(*got) != 1 || (*got)[0]["action"] != "on_press" {
+		t.Fatalf("actions = %v", *got)
+	}
+	if src := (*got)[0]["source"].(map[string]interface{}); src["decoder"] != "test_action_button" {
+		t.Errorf("source = %v", src)
+	}
+}
+
+func TestDefinitionActionValidation(t *testing.T) {
+	for _, a := range []ActionDef{
+		{Cluster: 5, Commands: []uint8{7}},
+		{Cluster: 5, Commands: []uint8{7}, Action: "x", Payload: "0"},
+		{Cluster: 18, Attribute: 85},
+		{Cluster: 18, Attribute: 85, Action: "single"},
+		{Cluster: 18, Attribute: 85, Values: map[int]string{1: ""}},
+	} {
+		def := DeviceDefinition{Model: "Bad", Actions: []ActionDef{a}}
+		if err := def.compile(); err == nil {
+			t.Errorf("%+v: expected error", a)
+		}
+	}
+	def := DeviceDefinition{Model: "Good", Actions: []ActionDef{
+		{Cluster: 5, Commands: []uint8{7}, Payload: "0101", Action: "arrow_left_click"},
+		{Cluster: 18, Attribute: 85, Values: map[int]string{1: "single"}},
+	}}
+	if err := def.compile(); err != nil {
+		t.Errorf("compile: %v", err)
+	}
+}
+
+func TestMergeDefinitionActions(t *testing.T) {
+	base := DeviceDefinition{Actions: []ActionDef{{Cluster: 6, Commands: []uint8{2}, Action: "toggle"}}}
+	over := DeviceDefinition{Actions: []ActionDef{{Cluster: 6, Commands: []uint8{2}, Action: "center"}}}
+	got := mergeDefinition(base, over, nil).Actions
+	if len(got) != 2 || got[0].Action != "center" {
+		t.Errorf("merged actions = %+v", got)
+	}
+	if got := mergeDefinition(base, over, []string{listActions}).Actions; len(got) != 1 || got[0].Action != "center" {
+		t.Errorf("overridden actions = %+v", got)
+	}
+}
@@ -0,0 +1,505 @@
+package coordinator
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+// AlarmMode is an armed mode of the alarm panel.
+type AlarmMode string
+
+const (
+	AlarmModeHome  AlarmMode = "home"  // perimeter only, motion zones ignored
+	AlarmModeNight AlarmMode = "night" // all zones except the night bypass list
+	AlarmModeAway  AlarmMode = "away"  // all zones
+)
+
+// Alarm panel states, named after the Home Assistant alarm_control_panel states.
+const (
+	AlarmDisarmed   = "disarmed"
+	AlarmArmedHome  = "armed_home"
+	AlarmArmedNight = "armed_night"
+	AlarmArmedAway  = "armed_away"
+	AlarmArming     = "arming"  // exit delay
+	AlarmPending    = "pending" // entry delay
+	AlarmTriggered  = "triggered"
+)
+
+// Alarm causes and their IAS ACE AlarmStatus values.
+var alarmCauses = map[string]uint8{
+	"burglar":         0x01,
+	"fire":            0x02,
+	"emergency":       0x03,
+	"police_panic":    0x04,
+	"fire_panic":      0x05,
+	"emergency_panic": 0x06,
+}
+
+var (
+	ErrInvalidCode        = errors.New("invalid code")
+	ErrInvalidArmMode     = errors.New("invalid arm mode")
+	ErrInvalidCause       = errors.New("invalid alarm cause")
+	ErrNotReady           = errors.New("not ready to arm")
+	ErrAlreadyDisarmed    = errors.New("already disarmed")
+	ErrAlarmPanelDisabled = errors.New("alarm panel disabled")
+)
+
+// IAS zone types relevant to the alarm panel.
+const (
+	zoneTypeMotion     uint16 = 0x000D
+	zoneTypeContact    uint16 = 0x0015
+	zoneTypeDoorWindow uint16 = 0x0016
+	zoneTypeFire       uint16 = 0x0028
+	zoneTypeCO         uint16 = 0x002B
+	zoneTypeVibration  uint16 = 0x002D
+	zoneTypeGlassBreak uint16 = 0x0226
+)
+
+// AlarmConfig configures the alarm panel.
+type AlarmConfig struct {
+	Enabled bool
+
+	// Codes are the valid PIN codes. Empty disables code checks.
+	Codes []string
+	// CodeArmRequired requires a valid code to arm, not only to disarm.
+	CodeArmRequired bool
+
+	ExitDelay  time.Duration
+	EntryDelay time.Duration
+
+	// HomeBypass and NightBypass list zones (IEEE address or friendly
+	// name) ignored in the respective mode.
+	HomeBypass  []string
+	NightBypass []string
+}
+
+// AlarmStatus is a snapshot of the alarm panel.
+type AlarmStatus struct {
+	State            string    `json:"state"`
+	Mode             AlarmMode `json:"mode,omitempty"`
+	Alarm            string    `json:"alarm,omitempty"`
+	SecondsRemaining int       `json:"seconds_remaining"`
+	Changed          time.Time `json:"changed"`
+}
+
+// AlarmPanel is the alarm system state machine. Enrolled IAS zones are its
+// sensors; IAS ACE keypads, MQTT and Lua arm and disarm it.
+type AlarmPanel struct {
+	coord  *Coordinator
+	logger *slog.Logger
+	cfg    AlarmConfig
+
+	mu       sync.Mutex
+	state    string
+	mode     AlarmMode
+	alarm    string
+	changed  time.Time
+	deadline time.Time
+	timer    *time.Timer
+	gen      uint64 // invalidates stale delay timers
+
+	unsub func()
+}
+
+func newAlarmPanel(c *Coordinator, cfg AlarmConfig) *AlarmPanel {
+	p := &AlarmPanel{
+		coord:  c,
+		logger: c.logger.With("component", "alarm"),
+		cfg:    cfg,
+		state:  AlarmDisarmed,
+	}
+	p.restore()
+	p.unsub = c.events.On(EventPropertyUpdate, p.handlePropertyUpdate)
+	return p
+}
+
+// restore loads the persisted state. Interrupted exit and entry delays
+// resume with their remaining time, so a restart never skips an alarm.
+func (p *AlarmPanel) restore() {
+	st, err := p.coord.store.GetAlarmPanel()
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			p.logger.Error("load alarm panel state", "err", err)
+		}
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state, p.mode, p.alarm, p.changed = st.State, AlarmMode(st.Mode), st.Alarm, st.Changed
+	if p.state == AlarmArming || p.state == AlarmPending {
+		p.deadline = st.Deadline
+		p.startTimerLocked(time.Until(st.Deadline))
+	}
+	p.logger.Info("alarm panel restored", "state", p.state, "mode", p.mode)
+}
+
+func (p *AlarmPanel) stop() {
+	if p.unsub != nil {
+		p.unsub()
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gen++
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// Config returns the panel configuration.
+func (p *AlarmPanel) Config() AlarmConfig {
+	return p.cfg
+}
+
+// Status returns the current panel state.
+func (p *AlarmPanel) Status() AlarmStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.statusLocked()
+}
+
+func (p *AlarmPanel) statusLocked() AlarmStatus {
+	st := AlarmStatus{State: p.state, Mode: p.mode, Alarm: p.alarm, Changed: p.changed}
+	if !p.deadline.IsZero() {
+		if left := time.Until(p.deadline); left > 0 {
+			st.SecondsRemaining = int((left + time.Second - 1) / time.Second)
+		}
+	}
+	return st
+}
+
+// Arm arms the panel in the given mode. With an exit delay configured the
+// panel passes through the arming state first. Changing modes while armed
+// takes effect immediately.
+func (p *AlarmPanel) Arm(mode AlarmMode, code, source string) error {
+	return p.arm(mode, code, source, "")
+}
+
+// Disarm disarms the panel, cancelling any delay or active alarm.
+func (p *AlarmPanel) Disarm(code, source string) error {
+	return p.disarm(code, source, "")
+}
+
+// Trigger raises an alarm with the given cause (see alarmCauses)
+// regardless of the armed state.
+func (p *AlarmPanel) Trigger(cause, source string) error {
+	return p.trigger(cause, source, "")
+}
+
+func (p *AlarmPanel) arm(mode AlarmMode, code, source, ieee string) error {
+	state := armedState(mode)
+	if state == "" {
+		return ErrInvalidArmMode
+	}
+	if p.cfg.CodeArmRequired && !p.checkCode(code) {
+		p.logger.Warn("arm rejected: invalid code", "mode", mode, "source", source, "ieee", ieee)
+		return ErrInvalidCode
+	}
+	if open := p.openZones(mode); len(open) > 0 {
+		p.logger.Warn("arm rejected: zones open", "mode", mode, "zones", open)
+		return fmt.Errorf("%w: %s open", ErrNotReady, strings.Join(open, ", "))
+	}
+
+	p.mu.Lock()
+	prev := p.state
+	switch {
+	case prev == AlarmPending || prev == AlarmTriggered:
+		p.mu.Unlock()
+		return fmt.Errorf("%w: alarm active, disarm first", ErrNotReady)
+	case prev == state || (prev == AlarmArming && p.mode == mode):
+		p.mu.Unlock()
+		return nil
+	case prev == AlarmArming:
+		p.setLocked(AlarmArming, mode, "", time.Until(p.deadline))
+	case prev == AlarmDisarmed && p.cfg.ExitDelay > 0:
+		p.setLocked(AlarmArming, mode, "", p.cfg.ExitDelay)
+	default:
+		p.setLocked(state, mode, "", 0)
+	}
+	st := p.statusLocked()
+	p.mu.Unlock()
+
+	p.publish(prev, st, source, ieee)
+	return nil
+}
+
+func (p *AlarmPanel) disarm(code, source, ieee string) error {
+	if len(p.cfg.Codes) > 0 && !p.checkCode(code) {
+		p.logger.Warn("disarm rejected: invalid code", "source", source, "ieee", ieee)
+		return ErrInvalidCode
+	}
+
+	p.mu.Lock()
+	prev := p.state
+	if prev == AlarmDisarmed {
+		p.mu.Unlock()
+		return ErrAlreadyDisarmed
+	}
+	p.setLocked(AlarmDisarmed, "", "", 0)
+	st := p.statusLocked()
+	p.mu.Unlock()
+
+	p.publish(prev, st, source, ieee)
+	return nil
+}
+
+func (p *AlarmPanel) trigger(cause, source, ieee string) error {
+	if _, ok := alarmCauses[cause]; !ok {
+		return ErrInvalidCause
+	}
+
+	p.mu.Lock()
+	prev := p.state
+	if prev == AlarmTriggered && p.alarm == cause {
+		p.mu.Unlock()
+		return nil
+	}
+	p.setLocked(AlarmTriggered, p.mode, cause, 0)
+	st := p.statusLocked()
+	p.mu.Unlock()
+
+	p.publish(prev, st, source, ieee)
+	return nil
+}
+
+// checkCode reports whether code is one of the configured codes.
+func (p *AlarmPanel) checkCode(code string) bool {
+	if len(p.cfg.Codes) == 0 {
+		return true
+	}
+	for _, c := range p.cfg.Codes {
+		if subtle.ConstantTimeCompare([]byte(c), []byte(code)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// setLocked moves the panel to a new state and persists it, starting the
+// delay timer when delay > 0. Caller must hold mu.
+func (p *AlarmPanel) setLocked(state string, mode AlarmMode, alarm string, delay time.Duration) {
+	p.state, p.mode, p.alarm = state, mode, alarm
+	p.changed = time.Now()
+	p.deadline = time.Time{}
+	if delay > 0 {
+		p.deadline = p.changed.Add(delay)
+	}
+	p.startTimerLocked(delay)
+
+	if err := p.coord.store.SaveAlarmPanel(&store.AlarmPanelState{
+		State:    p.state,
+		Mode:     string(p.mode),
+		Alarm:    p.alarm,
+		Changed:  p.changed,
+		Deadline: p.deadline,
+	}); err != nil {
+		p.logger.Error("save alarm panel state", "err", err)
+	}
+}
+
+// startTimerLocked cancels any pending delay and, for delay states,
+// schedules the transition at the end of the delay. Caller must hold mu.
+func (p *AlarmPanel) startTimerLocked(delay time.Duration) {
+	p.gen++
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if p.state != AlarmArming && p.state != AlarmPending {
+		return
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	gen := p.gen
+	p.timer = time.AfterFunc(delay, func() { p.expire(gen) })
+}
+
+// expire ends an exit or entry delay.
+func (p *AlarmPanel) expire(gen uint64) {
+	p.mu.Lock()
+	if gen != p.gen {
+		p.mu.Unlock()
+		return
+	}
+	prev := p.state
+	switch prev {
+	case AlarmArming:
+		p.setLocked(armedState(p.mode), p.mode, "", 0)
+	case AlarmPending:
+		p.setLocked(AlarmTriggered, p.mode, "burglar", 0)
+	default:
+		p.mu.Unlock()
+		return
+	}
+	st := p.statusLocked()
+	p.mu.Unlock()
+
+	p.publish(prev, st, "timer", "")
+}
+
+// handlePropertyUpdate watches IAS zone alarms.
+func (p *AlarmPanel) handlePropertyUpdate(event Event) {
+	data, ok := event.Data.(map[string]interface{})
+	if !ok || data["property"] != "alarm1" || data["value"] != true {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	dev, err := p.coord.store.GetDevice(ieee)
+	if err != nil || dev.IASZone == nil {
+		return
+	}
+	p.zoneAlarm(dev)
+}
+
+// zoneAlarm reacts to an alarmed zone: fire and CO zones always trigger,
+// intrusion zones start the entry delay when armed in a mode covering them.
+func (p *AlarmPanel) zoneAlarm(dev *store.Device) {
+	switch dev.IASZone.ZoneType {
+	case zoneTypeFire:
+		p.trigger("fire", "zone", dev.IEEEAddress)
+		return
+	case zoneTypeCO:
+		p.trigger("emergency", "zone", dev.IEEEAddress)
+		return
+	}
+
+	p.mu.Lock()
+	prev, mode := p.state, p.mode
+	if prev != armedState(mode) || !p.zoneActive(dev, mode) {
+		p.mu.Unlock()
+		return
+	}
+	if p.cfg.EntryDelay > 0 {
+		p.setLocked(AlarmPending, mode, "", p.cfg.EntryDelay)
+	} else {
+		p.setLocked(AlarmTriggered, mode, "burglar", 0)
+	}
+	st := p.statusLocked()
+	p.mu.Unlock()
+
+	p.logger.Warn("zone alarm", "ieee", dev.IEEEAddress, "name", deviceName(dev), "mode", mode)
+	p.publish(prev, st, "zone", dev.IEEEAddress)
+}
+
+// zoneActive reports whether an intrusion zone is armed in the given mode.
+func (p *AlarmPanel) zoneActive(dev *store.Device, mode AlarmMode) bool {
+	var bypass []string
+	switch dev.IASZone.ZoneType {
+	case zoneTypeContact, zoneTypeDoorWindow, zoneTypeVibration, zoneTypeGlassBreak:
+	case zoneTypeMotion:
+		if mode == AlarmModeHome {
+			return false
+		}
+	default:
+		return false
+	}
+	switch mode {
+	case AlarmModeHome:
+		bypass = p.cfg.HomeBypass
+	case AlarmModeNight:
+		bypass = p.cfg.NightBypass
+	}
+	for _, z := range bypass {
+		if strings.EqualFold(z, dev.IEEEAddress) || (dev.FriendlyName != "" && z == dev.FriendlyName) {
+			return false
+		}
+	}
+	return true
+}
+
+// openZones returns the names of contact zones that are open and would be
+// armed in the given mode.
+func (p *AlarmPanel) openZones(mode AlarmMode) []string {
+	devices, err := p.coord.store.ListDevices()
+	if err != nil {
+		p.logger.Error("list devices", "err", err)
+		return nil
+	}
+	var open []string
+	for _, d := range devices {
+		if d.IASZone == nil || !d.IASZone.Enrolled || d.Properties["alarm1"] != true {
+			continue
+		}
+		if t := d.IASZone.ZoneType; t != zoneTypeContact && t != zoneTypeDoorWindow {
+			continue
+		}
+		if p.zoneActive(d, mode) {
+			open = append(open, deviceName(d))
+		}
+	}
+	return open
+}
+
+// publish logs a state change, emits an alarm_panel event and notifies
+// keypads.
+func (p *AlarmPanel) publish(prev string, st AlarmStatus, source, ieee string) {
+	p.logger.Info("alarm panel state", "state", st.State, "previous", prev, "mode", st.Mode,
+		"alarm", st.Alarm, "seconds_remaining", st.SecondsRemaining, "source", source, "ieee", ieee)
+
+	p.coord.events.Emit(Event{
+		Type: EventAlarmPanel,
+		Data: map[string]interface{}{
+			"state":             st.State,
+			"previous":          prev,
+			"mode":              string(st.Mode),
+			"alarm":             st.Alarm,
+			"seconds_remaining": st.SecondsRemaining,
+			"source":            source,
+			"ieee":              ieee,
+		},
+	})
+
+	p.notifyKeypads(st)
+}
+
+// notifyKeypads sends Panel Status Changed to every device with an IAS ACE
+// client cluster.
+func (p *AlarmPanel) notifyKeypads(st AlarmStatus) {
+	if p.coord.local == nil {
+		return
+	}
+	devices, err := p.coord.store.ListDevices()
+	if err != nil {
+		p.logger.Error("list devices", "err", err)
+		return
+	}
+	payload := acePanelStatusPayload(st)
+	for _, d := range devices {
+		for _, ep := range d.Endpoints {
+			if !hasOutCluster(ep, 0x0501) {
+				continue
+			}
+			p.coord.local.Send(ncp.ZCLFrameRequest{
+				DstAddr:            d.ShortAddress,
+				DstEP:              ep.ID,
+				SrcEP:              localEndpoint,
+				ClusterID:          0x0501,
+				CommandID:          0x04, // PanelStatusChanged
+				Payload:            payload,
+				ServerToClient:     true,
+				DisableDefaultResp: true,
+			})
+		}
+	}
+}
+
+// armedState returns the armed state for a mode, or "" for an invalid mode.
+func armedState(mode AlarmMode) string {
+	switch mode {
+	case AlarmModeHome:
+		return AlarmArmedHome
+	case AlarmModeNight:
+		return AlarmArmedNight
+	case AlarmModeAway:
+		return AlarmArmedAway
+	}
+	return ""
+}
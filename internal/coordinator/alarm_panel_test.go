@@ -0,0 +1,310 @@
+package coordinator
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+func newTestAlarmPanel(t *testing.T, cfg AlarmConfig) (*AlarmPanel, *memStore, *[]ncp.ZCLFrameRequest) {
+	t.Helper()
+	dm, ms := newTestDM(t)
+	local, sent := newTestLocalServer(t)
+	dm.coord.devices = dm
+	dm.coord.local = local
+	cfg.Enabled = true
+	p := newAlarmPanel(dm.coord, cfg)
+	t.Cleanup(p.stop)
+	return p, ms, sent
+}
+
+func addZone(ms *memStore, ieee string, short uint16, zoneType uint16, zoneID uint8) *store.Device {
+	d := &store.Device{
+		IEEEAddress:  ieee,
+		ShortAddress: short,
+		IASZone:      &store.IASZoneState{Enrolled: true, ZoneType: zoneType, ZoneID: zoneID},
+	}
+	ms.devices[ieee] = d
+	return d
+}
+
+// zoneOpen simulates an alarm1 report from a zone.
+func zoneOpen(p *AlarmPanel, ms *memStore, ieee string) {
+	ms.devices[ieee].Properties = map[string]any{"alarm1": true}
+	p.coord.events.Emit(Event{Type: EventPropertyUpdate, Data: map[string]interface{}{
+		"ieee": ieee, "property": "alarm1", "value": true,
+	}})
+}
+
+func waitAlarmState(t *testing.T, p *AlarmPanel, want string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if p.Status().State == want {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("state = %q, want %q", p.Status().State, want)
+}
+
+func TestAlarmPanelExitDelay(t *testing.T) {
+	p, ms, _ := newTestAlarmPanel(t, AlarmConfig{ExitDelay: 30 * time.Millisecond})
+
+	states := make(chan string, 4)
+	p.coord.events.On(EventAlarmPanel, func(e Event) {
+		states <- e.Data.(map[string]interface{})["state"].(string)
+	})
+
+	if err := p.Arm(AlarmModeAway, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	st := p.Status()
+	if st.State != AlarmArming || st.Mode != AlarmModeAway || st.SecondsRemaining != 1 {
+		t.Errorf("after arm: %+v", st)
+	}
+	for _, want := range []string{AlarmArming, AlarmArmedAway} {
+		select {
+		case got := <-states:
+			if got != want {
+				t.Errorf("event state = %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %q event", want)
+		}
+	}
+	if ms.alarm == nil || ms.alarm.State != AlarmArmedAway {
+		t.Errorf("persisted: %+v", ms.alarm)
+	}
+}
+
+func TestAlarmPanelCodes(t *testing.T) {
+	p, _, _ := newTestAlarmPanel(t, AlarmConfig{Codes: []string{"1234"}})
+
+	// Arming does not need a code unless CodeArmRequired is set.
+	if err := p.Arm(AlarmModeHome, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Disarm("0000", "test"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("wrong code: err = %v", err)
+	}
+	if err := p.Disarm("1234", "test"); err != nil {
+		t.Errorf("right code: err = %v", err)
+	}
+	if err := p.Disarm("1234", "test"); !errors.Is(err, ErrAlreadyDisarmed) {
+		t.Errorf("second disarm: err = %v", err)
+	}
+
+	p.cfg.CodeArmRequired = true
+	if err := p.Arm(AlarmModeAway, "", "test"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("arm without code: err = %v", err)
+	}
+	if err := p.Arm(AlarmMode("party"), "1234", "test"); !errors.Is(err, ErrInvalidArmMode) {
+		t.Errorf("bad mode: err = %v", err)
+	}
+}
+
+func TestAlarmPanelEntryDelay(t *testing.T) {
+	p, ms, _ := newTestAlarmPanel(t, AlarmConfig{EntryDelay: 30 * time.Millisecond})
+	addZone(ms, "00158D0000000001", 0x1001, zoneTypeContact, 0)
+
+	if err := p.Arm(AlarmModeAway, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	zoneOpen(p, ms, "00158D0000000001")
+	if st := p.Status(); st.State != AlarmPending {
+		t.Fatalf("after zone alarm: %+v", st)
+	}
+	waitAlarmState(t, p, AlarmTriggered)
+	if st := p.Status(); st.Alarm != "burglar" {
+		t.Errorf("alarm = %q, want burglar", st.Alarm)
+	}
+
+	// Disarming during the entry delay cancels the alarm.
+	p.Disarm("", "test")
+	ms.devices["00158D0000000001"].Properties = nil
+	p.Arm(AlarmModeAway, "", "test")
+	zoneOpen(p, ms, "00158D0000000001")
+	p.Disarm("", "test")
+	time.Sleep(60 * time.Millisecond)
+	if st := p.Status(); st.State != AlarmDisarmed {
+		t.Errorf("after disarm in entry delay: %+v", st)
+	}
+}
+
+func TestAlarmPanelZoneModes(t *testing.T) {
+	p, ms, _ := newTestAlarmPanel(t, AlarmConfig{NightBypass: []string{"Hall"}})
+	addZone(ms, "00158D0000000001", 0x1001, zoneTypeMotion, 0)
+	hall := addZone(ms, "00158D0000000002", 0x1002, zoneTypeMotion, 1)
+	hall.FriendlyName = "Hall"
+	addZone(ms, "00158D0000000003", 0x1003, 0x002A, 2) // water leak
+
+	p.Arm(AlarmModeHome, "", "test")
+	zoneOpen(p, ms, "00158D0000000001")
+	zoneOpen(p, ms, "00158D0000000003")
+	if st := p.Status(); st.State != AlarmArmedHome {
+		t.Errorf("home mode, motion: %+v", st)
+	}
+
+	p.Arm(AlarmModeNight, "", "test")
+	zoneOpen(p, ms, "00158D0000000002")
+	if st := p.Status(); st.State != AlarmArmedNight {
+		t.Errorf("night mode, bypassed zone: %+v", st)
+	}
+	zoneOpen(p, ms, "00158D0000000001")
+	if st := p.Status(); st.State != AlarmTriggered {
+		t.Errorf("night mode, motion: %+v", st)
+	}
+}
+
+func TestAlarmPanelNotReady(t *testing.T) {
+	p, ms, _ := newTestAlarmPanel(t, AlarmConfig{HomeBypass: []string{"00158d0000000001"}})
+	d := addZone(ms, "00158D0000000001", 0x1001, zoneTypeContact, 0)
+	d.FriendlyName = "Garage door"
+	d.Properties = map[string]any{"alarm1": true}
+
+	err := p.Arm(AlarmModeAway, "", "test")
+	if !errors.Is(err, ErrNotReady) {
+		t.Fatalf("err = %v, want ErrNotReady", err)
+	}
+	// Bypassed in home mode, so it does not block arming.
+	if err := p.Arm(AlarmModeHome, "", "test"); err != nil {
+		t.Errorf("home: err = %v", err)
+	}
+}
+
+func TestAlarmPanelFireZoneAlwaysTriggers(t *testing.T) {
+	p, ms, _ := newTestAlarmPanel(t, AlarmConfig{})
+	addZone(ms, "00158D0000000001", 0x1001, zoneTypeFire, 0)
+
+	zoneOpen(p, ms, "00158D0000000001")
+	st := p.Status()
+	if st.State != AlarmTriggered || st.Alarm != "fire" {
+		t.Errorf("status: %+v", st)
+	}
+}
+
+func TestAlarmPanelRestore(t *testing.T) {
+	p, ms, _ := newTestAlarmPanel(t, AlarmConfig{})
+	ms.alarm = &store.AlarmPanelState{
+		State:    AlarmPending,
+		Mode:     string(AlarmModeAway),
+		Deadline: time.Now().Add(20 * time.Millisecond),
+	}
+
+	restored := newAlarmPanel(p.coord, p.cfg)
+	defer restored.stop()
+	if st := restored.Status(); st.State != AlarmPending || st.Mode != AlarmModeAway {
+		t.Fatalf("restored: %+v", st)
+	}
+	waitAlarmState(t, restored, AlarmTriggered)
+}
+
+func TestACEArmCommand(t *testing.T) {
+	p, ms, sent := newTestAlarmPanel(t, AlarmConfig{Codes: []string{"1234"}})
+	ms.devices["00158D00000000AA"] = &store.Device{
+		IEEEAddress:  "00158D00000000AA",
+		ShortAddress: 0x2000,
+		Endpoints:    []store.Endpoint{{ID: 1, OutClusters: []uint16{0x0501}}},
+	}
+	p.coord.devices.RebuildAddrIndex()
+	p.coord.local.Register(1, p.newACEServer())
+
+	var source, ieee string
+	p.coord.events.On(EventAlarmPanel, func(e Event) {
+		data := e.Data.(map[string]interface{})
+		source, ieee = data["source"].(string), data["ieee"].(string)
+	})
+
+	arm := func(mode uint8, code string) []byte {
+		*sent = nil
+		payload := append([]byte{mode, uint8(len(code))}, code...)
+		p.coord.local.HandleClusterCommand(ncp.ClusterCommandEvent{
+			SrcAddr: 0x2000, SrcEP: 1, DstEP: 1, ClusterID: 0x0501, CommandID: 0x00, Seq: 3,
+			Payload: append(payload, 0),
+		})
+		for _, f := range *sent {
+			if f.CommandID == 0x00 && !f.Global {
+				return f.Payload
+			}
+		}
+		t.Fatalf("no ArmResponse in %+v", *sent)
+		return nil
+	}
+
+	if rsp := arm(aceArmAway, ""); !bytes.Equal(rsp, []byte{0x03}) {
+		t.Errorf("arm away: %X", rsp)
+	}
+	if source != "keypad" || ieee != "00158D00000000AA" {
+		t.Errorf("event source=%q ieee=%q", source, ieee)
+	}
+	// The keypad also gets Panel Status Changed: armed away, no alarm.
+	var changed *ncp.ZCLFrameRequest
+	for i, f := range *sent {
+		if f.CommandID == 0x04 {
+			changed = &(*sent)[i]
+		}
+	}
+	if changed == nil || changed.DstAddr != 0x2000 || !changed.ServerToClient ||
+		!bytes.Equal(changed.Payload, []byte{acePanelArmedAway, 0, 0, 0}) {
+		t.Errorf("panel status changed: %+v", changed)
+	}
+
+	if rsp := arm(aceArmDisarm, "9999"); !bytes.Equal(rsp, []byte{aceNotifyInvalidCode}) {
+		t.Errorf("disarm wrong code: %X", rsp)
+	}
+	if rsp := arm(aceArmDisarm, "1234"); !bytes.Equal(rsp, []byte{aceNotifyDisarmed}) {
+		t.Errorf("disarm: %X", rsp)
+	}
+	if rsp := arm(aceArmDisarm, "1234"); !bytes.Equal(rsp, []byte{aceNotifyAlreadyDisarmed}) {
+		t.Errorf("disarm again: %X", rsp)
+	}
+}
+
+func TestACEGetPanelStatusAndZones(t *testing.T) {
+	p, ms, sent := newTestAlarmPanel(t, AlarmConfig{})
+	z := addZone(ms, "00158D0000000001", 0x1001, zoneTypeContact, 0)
+	z.FriendlyName = "Door"
+	addZone(ms, "00158D0000000002", 0x1002, zoneTypeMotion, 17)
+	p.coord.local.Register(1, p.newACEServer())
+
+	p.Trigger("police_panic", "test")
+
+	req := func(cmdID uint8, payload ...byte) ncp.ZCLFrameRequest {
+		*sent = nil
+		p.coord.local.HandleClusterCommand(ncp.ClusterCommandEvent{
+			SrcAddr: 0x2000, SrcEP: 1, DstEP: 1, ClusterID: 0x0501, CommandID: cmdID, Payload: payload,
+		})
+		if len(*sent) != 1 {
+			t.Fatalf("cmd 0x%02X: expected 1 frame, got %d", cmdID, len(*sent))
+		}
+		return (*sent)[0]
+	}
+
+	rsp := req(0x07) // GetPanelStatus
+	if rsp.CommandID != 0x05 || !bytes.Equal(rsp.Payload, []byte{acePanelInAlarm, 0, 1, 0x04}) {
+		t.Errorf("panel status: cmd=0x%02X payload=%X", rsp.CommandID, rsp.Payload)
+	}
+
+	rsp = req(0x05) // GetZoneIDMap
+	if rsp.CommandID != 0x01 || len(rsp.Payload) != 32 {
+		t.Fatalf("zone map: cmd=0x%02X len=%d", rsp.CommandID, len(rsp.Payload))
+	}
+	if rsp.Payload[0] != 0x01 || rsp.Payload[2] != 0x02 {
+		t.Errorf("zone map: %X", rsp.Payload[:4])
+	}
+
+	rsp = req(0x06, 0) // GetZoneInformation
+	want := []byte{0, 0x15, 0x00, 0x00, 0x15, 0x8D, 0, 0, 0, 0, 0x01, 4, 'D', 'o', 'o', 'r'}
+	if rsp.CommandID != 0x02 || !bytes.Equal(rsp.Payload, want) {
+		t.Errorf("zone info: %X, want %X", rsp.Payload, want)
+	}
+	rsp = req(0x06, 9)
+	if !bytes.Equal(rsp.Payload[:3], []byte{9, 0xFF, 0xFF}) {
+		t.Errorf("unknown zone info: %X", rsp.Payload)
+	}
+}
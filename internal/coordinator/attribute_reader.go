@@ -63,31 +63,52 @@ func (c *Coordinator) ReadAttributes(ctx context.Context, shortAddr uint16, endp
 	return results, nil
 }
 
-// WriteAttribute writes a single attribute value.
+// WriteAttribute writes a single attribute value. Writes to sleepy Poll
+// Control devices are queued until check-in and return ErrCommandQueued.
 func (c *Coordinator) WriteAttribute(ctx context.Context, shortAddr uint16, endpoint uint8, clusterID uint16, attrID uint16, dataType uint8, value interface{}) error {
 	encoded, err := zcl.EncodeValue(dataType, value)
 	if err != nil {
 		return fmt.Errorf("encode value: %w", err)
 	}
-	return c.ncp.WriteAttributes(ctx, ncp.WriteAttributesRequest{
+	req := ncp.WriteAttributesRequest{
 		DstAddr:   shortAddr,
 		DstEP:     endpoint,
 		ClusterID: clusterID,
 		Records: []ncp.WriteRecord{
 			{AttrID: attrID, DataType: dataType, Value: encoded},
 		},
-	})
+	}
+	desc := fmt.Sprintf("write 0x%04X/0x%04X", clusterID, attrID)
+	if c.deferToCheckIn(shortAddr, desc, func(ctx context.Context) error {
+		return c.ncp.WriteAttributes(ctx, req)
+	}) {
+		return ErrCommandQueued
+	}
+	return c.ncp.WriteAttributes(ctx, req)
 }
 
-// SendClusterCommand sends a cluster-specific command.
+// SendClusterCommand sends a cluster-specific command. Commands to sleepy
+// Poll Control devices are queued until check-in and return ErrCommandQueued.
 func (c *Coordinator) SendClusterCommand(ctx context.Context, shortAddr uint16, endpoint uint8, clusterID uint16, commandID uint8, payload []byte) error {
-	return c.ncp.SendCommand(ctx, ncp.ClusterCommandRequest{
+	req := ncp.ClusterCommandRequest{
 		DstAddr:   shortAddr,
 		DstEP:     endpoint,
 		ClusterID: clusterID,
 		CommandID: commandID,
 		Payload:   payload,
-	})
+	}
+	desc := fmt.Sprintf("command 0x%04X/0x%02X", clusterID, commandID)
+	if c.deferToCheckIn(shortAddr, desc, func(ctx context.Context) error {
+		return c.ncp.SendCommand(ctx, req)
+	}) {
+		return ErrCommandQueued
+	}
+	return c.ncp.SendCommand(ctx, req)
+}
+
+// deferToCheckIn queues send for a sleepy Poll Control device.
+func (c *Coordinator) deferToCheckIn(shortAddr uint16, desc string, send func(ctx context.Context) error) bool {
+	return c.devices != nil && c.devices.deferToCheckIn(shortAddr, desc, send)
 }
 
 // ConfigureReporting sets up attribute reporting on a device.
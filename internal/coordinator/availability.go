@@ -0,0 +1,365 @@
+package coordinator
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+// Availability states.
+const (
+	AvailabilityOnline  = "online"
+	AvailabilityOffline = "offline"
+)
+
+// Power sources recorded from the announce MAC capability.
+const (
+	PowerSourceMains   = "mains"
+	PowerSourceBattery = "battery"
+)
+
+const (
+	defaultMainsTimeout       = 10 * time.Minute
+	defaultBatteryTimeout     = 25 * time.Hour
+	availabilityCheckInterval = 30 * time.Second
+	availabilityPingTimeout   = 10 * time.Second
+)
+
+// AvailabilityConfig configures device availability tracking.
+type AvailabilityConfig struct {
+	Enabled bool
+
+	// MainsTimeout and BatteryTimeout are how long a device may stay
+	// silent before it is offline. Zero uses the defaults (10m and 25h).
+	MainsTimeout   time.Duration
+	BatteryTimeout time.Duration
+
+	// Devices overrides the timeout per device (IEEE address or friendly
+	// name). Zero disables tracking for the device.
+	Devices map[string]time.Duration
+}
+
+// AvailabilityDef overrides availability tracking in a device definition.
+type AvailabilityDef struct {
+	Timeout  uint32 `json:"timeout,omitempty"` // seconds; zero keeps the configured timeout
+	Disabled bool   `json:"disabled,omitempty"`
+}
+
+// Availability decides whether devices are online from the time they were
+// last heard. Mains-powered devices (routers) are pinged with a Basic
+// ZCLVersion read once they have been silent for half their timeout, so
+// idle routers stay online; sleepy devices are only judged by their own
+// traffic.
+type Availability struct {
+	coord   *Coordinator
+	logger  *slog.Logger
+	cfg     AvailabilityConfig
+	started time.Time // silence before startup is not held against devices
+
+	mu       sync.Mutex
+	states   map[string]string    // IEEE -> online/offline
+	lastPing map[string]time.Time // IEEE -> last ping attempt
+	pinging  map[string]bool
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+	once   sync.Once
+	unsubs []func()
+}
+
+func newAvailability(c *Coordinator, cfg AvailabilityConfig) *Availability {
+	if cfg.MainsTimeout <= 0 {
+		cfg.MainsTimeout = defaultMainsTimeout
+	}
+	if cfg.BatteryTimeout <= 0 {
+		cfg.BatteryTimeout = defaultBatteryTimeout
+	}
+	a := &Availability{
+		coord:    c,
+		logger:   c.logger.With("component", "availability"),
+		cfg:      cfg,
+		started:  time.Now(),
+		states:   make(map[string]string),
+		lastPing: make(map[string]time.Time),
+		pinging:  make(map[string]bool),
+		stopCh:   make(chan struct{}),
+	}
+	a.restore()
+	for _, t := range []string{EventDeviceJoined, EventDeviceAnnounce, EventAttributeReport, EventClusterCommand} {
+		a.unsubs = append(a.unsubs, c.events.On(t, a.handleTraffic))
+	}
+	a.unsubs = append(a.unsubs, c.events.On(EventDeviceLeft, a.handleLeft))
+	return a
+}
+
+// restore loads the persisted states so a device that was offline before a
+// restart stays offline until it is heard from.
+func (a *Availability) restore() {
+	devices, err := a.coord.store.ListDevices()
+	if err != nil {
+		a.logger.Error("load availability state", "err", err)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, dev := range devices {
+		if dev.Availability != nil {
+			a.states[dev.IEEEAddress] = dev.Availability.State
+		}
+	}
+}
+
+// start runs the periodic check until stop.
+func (a *Availability) start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(availabilityCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				a.check(time.Now())
+			case <-a.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (a *Availability) stop() {
+	for _, unsub := range a.unsubs {
+		unsub()
+	}
+	a.once.Do(func() { close(a.stopCh) })
+	a.wg.Wait()
+}
+
+// Config returns the availability configuration with defaults applied.
+func (a *Availability) Config() AvailabilityConfig {
+	return a.cfg
+}
+
+// State returns "online", "offline", or "" when the device is not tracked
+// or has no verdict yet.
+func (a *Availability) State(ieee string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.states[ieee]
+}
+
+// Timeout returns how long the device may stay silent before it is
+// offline, or zero when tracking is disabled for it. Config overrides win
+// over the device definition, which wins over the power source default.
+func (a *Availability) Timeout(dev *store.Device) time.Duration {
+	if t, ok := a.cfg.Devices[dev.IEEEAddress]; ok {
+		return t
+	}
+	if dev.FriendlyName != "" {
+		if t, ok := a.cfg.Devices[dev.FriendlyName]; ok {
+			return t
+		}
+	}
+	if def := a.coord.devices.definition(dev); def != nil && def.Availability != nil {
+		if def.Availability.Disabled {
+			return 0
+		}
+		if def.Availability.Timeout > 0 {
+			return time.Duration(def.Availability.Timeout) * time.Second
+		}
+	}
+	if mainsPowered(dev) {
+		return a.cfg.MainsTimeout
+	}
+	return a.cfg.BatteryTimeout
+}
+
+// mainsPowered reports whether a device is mains powered. Without an
+// announce capability, devices with Power Configuration or Poll Control
+// are assumed to run on batteries.
+func mainsPowered(dev *store.Device) bool {
+	switch dev.PowerSource {
+	case PowerSourceMains:
+		return true
+	case PowerSourceBattery:
+		return false
+	}
+	if dev.PollControl != nil {
+		return false
+	}
+	for _, ep := range dev.Endpoints {
+		if slices.Contains(ep.InClusters, 0x0001) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleTraffic marks the sender of any frame online.
+func (a *Availability) handleTraffic(e Event) {
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	if ieee, _ := data["ieee"].(string); ieee != "" {
+		a.markSeen(ieee)
+	}
+}
+
+func (a *Availability) handleLeft(e Event) {
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	a.mu.Lock()
+	delete(a.states, ieee)
+	delete(a.lastPing, ieee)
+	a.mu.Unlock()
+}
+
+// markSeen brings a tracked device online after it was heard from.
+func (a *Availability) markSeen(ieee string) {
+	if a.State(ieee) == AvailabilityOnline {
+		return
+	}
+	dev, err := a.coord.store.GetDevice(ieee)
+	if err != nil || a.Timeout(dev) == 0 {
+		return
+	}
+	a.setState(dev, AvailabilityOnline)
+}
+
+// check judges every tracked device by its silence at now and pings the
+// quiet mains-powered ones. Offline devices only come back via markSeen.
+func (a *Availability) check(now time.Time) {
+	devices, err := a.coord.store.ListDevices()
+	if err != nil {
+		a.logger.Error("list devices for availability", "err", err)
+		return
+	}
+	for _, dev := range devices {
+		timeout := a.Timeout(dev)
+		if timeout <= 0 {
+			continue
+		}
+		last := dev.LastSeen
+		if last.Before(a.started) {
+			last = a.started
+		}
+		silent := now.Sub(last)
+
+		// A running ping decides the verdict; judge again on the next check.
+		if mainsPowered(dev) && silent >= timeout/2 && a.maybePing(dev, now, timeout/2) {
+			continue
+		}
+
+		switch state := a.State(dev.IEEEAddress); {
+		case silent >= timeout && state != AvailabilityOffline:
+			a.setState(dev, AvailabilityOffline)
+		case silent < timeout && state == "":
+			a.setState(dev, AvailabilityOnline)
+		}
+	}
+}
+
+// maybePing starts a ping unless the last attempt was less than interval
+// ago. Reports whether a ping is in flight.
+func (a *Availability) maybePing(dev *store.Device, now time.Time, interval time.Duration) bool {
+	ieee := dev.IEEEAddress
+	a.mu.Lock()
+	if a.pinging[ieee] {
+		a.mu.Unlock()
+		return true
+	}
+	if now.Sub(a.lastPing[ieee]) < interval {
+		a.mu.Unlock()
+		return false
+	}
+	a.pinging[ieee] = true
+	a.lastPing[ieee] = now
+	a.mu.Unlock()
+
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		defer func() {
+			a.mu.Lock()
+			delete(a.pinging, ieee)
+			a.mu.Unlock()
+		}()
+		a.ping(dev)
+	}()
+	return true
+}
+
+// ping reads Basic ZCLVersion. Any answer, even an error status, proves the
+// device is reachable.
+func (a *Availability) ping(dev *store.Device) {
+	ep := uint8(1)
+	if len(dev.Endpoints) > 0 {
+		ep = dev.Endpoints[0].ID
+	}
+	for _, e := range dev.Endpoints {
+		if slices.Contains(e.InClusters, 0x0000) {
+			ep = e.ID
+			break
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(a.coord.ctx, availabilityPingTimeout)
+	defer cancel()
+	_, err := a.coord.ncp.ReadAttributes(ctx, ncp.ReadAttributesRequest{
+		DstAddr:   dev.ShortAddress,
+		DstEP:     ep,
+		ClusterID: 0x0000,
+		AttrIDs:   []uint16{0x0000},
+	})
+	if err != nil {
+		a.logger.Debug("ping failed", "ieee", dev.IEEEAddress, "name", deviceName(dev), "err", err)
+		return
+	}
+	if err := a.coord.store.UpdateDevice(dev.IEEEAddress, func(d *store.Device) error {
+		d.LastSeen = time.Now()
+		return nil
+	}); err != nil {
+		a.logger.Error("update device after ping", "err", err, "ieee", dev.IEEEAddress)
+		return
+	}
+	a.markSeen(dev.IEEEAddress)
+}
+
+// setState records, persists and announces a state change.
+func (a *Availability) setState(dev *store.Device, state string) {
+	ieee := dev.IEEEAddress
+	a.mu.Lock()
+	if a.states[ieee] == state {
+		a.mu.Unlock()
+		return
+	}
+	a.states[ieee] = state
+	changed := time.Now()
+	err := a.coord.store.UpdateDevice(ieee, func(d *store.Device) error {
+		d.Availability = &store.AvailabilityState{State: state, Changed: changed}
+		return nil
+	})
+	a.mu.Unlock()
+	if err != nil {
+		a.logger.Error("save availability", "err", err, "ieee", ieee)
+	}
+
+	a.logger.Info("device "+state, "ieee", ieee, "name", deviceName(dev), "last_seen", dev.LastSeen)
+	a.coord.events.Emit(Event{
+		Type: EventDeviceAvailability,
+		Data: map[string]interface{}{
+			"ieee":      ieee,
+			"state":     state,
+			"last_seen": dev.LastSeen.Format(time.RFC3339),
+		},
+	})
+}
@@ -0,0 +1,186 @@
+package coordinator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+// newTestAvailability answers the Basic reads of the availability pings.
+func newTestAvailability(t *testing.T, cfg AvailabilityConfig, devs ...*store.Device) (*Availability, *memStore, *fakeNCP, *[]map[string]interface{}) {
+	t.Helper()
+	c, ms, n := newTestCoord(t)
+	n.onRead = func(ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+		return []ncp.AttributeResponse{{AttrID: 0x0000, Status: 0, DataType: 0x20, Value: []byte{0x03}}}
+	}
+	for _, d := range devs {
+		ms.devices[d.IEEEAddress] = d
+	}
+	var events []map[string]interface{}
+	c.events.On(EventDeviceAvailability, func(e Event) {
+		events = append(events, e.Data.(map[string]interface{}))
+	})
+	a := newAvailability(c, cfg)
+	a.started = time.Time{}
+	t.Cleanup(a.stop)
+	return a, ms, n, &events
+}
+
+func TestAvailabilityTimeouts(t *testing.T) {
+	now := time.Now()
+	a, ms, n, events := newTestAvailability(t, AvailabilityConfig{Enabled: true},
+		&store.Device{IEEEAddress: "0000000000000001", PowerSource: PowerSourceMains, LastSeen: now.Add(-11 * time.Minute)},
+		&store.Device{IEEEAddress: "0000000000000002", PowerSource: PowerSourceBattery, LastSeen: now.Add(-time.Hour)},
+	)
+	n.readErr = errors.New("no ack")
+
+	// The first check pings the silent mains device and waits for it.
+	a.check(now)
+	a.wg.Wait()
+	if len(n.reads) != 1 {
+		t.Fatalf("reads = %d, want 1", len(n.reads))
+	}
+	a.check(now.Add(availabilityCheckInterval))
+
+	if got := a.State("0000000000000001"); got != AvailabilityOffline {
+		t.Errorf("mains device = %q, want offline", got)
+	}
+	if got := a.State("0000000000000002"); got != AvailabilityOnline {
+		t.Errorf("battery device = %q, want online", got)
+	}
+	if st := ms.devices["0000000000000001"].Availability; st == nil || st.State != AvailabilityOffline {
+		t.Errorf("persisted = %+v, want offline", st)
+	}
+	if len(*events) != 2 {
+		t.Fatalf("events = %v, want 2", *events)
+	}
+
+	// A repeated check changes nothing.
+	a.check(now.Add(2 * availabilityCheckInterval))
+	if len(*events) != 2 {
+		t.Errorf("events after repeat check = %d, want 2", len(*events))
+	}
+}
+
+func TestAvailabilityPing(t *testing.T) {
+	now := time.Now()
+	dev := &store.Device{
+		IEEEAddress:  "0000000000000001",
+		ShortAddress: 0x1234,
+		PowerSource:  PowerSourceMains,
+		LastSeen:     now.Add(-6 * time.Minute),
+		Endpoints:    []store.Endpoint{{ID: 11, InClusters: []uint16{0x0000, 0x0006}}},
+	}
+	a, ms, n, _ := newTestAvailability(t, AvailabilityConfig{Enabled: true}, dev)
+
+	a.check(now)
+	a.wg.Wait()
+	if len(n.reads) != 1 || n.reads[0].DstAddr != 0x1234 || n.reads[0].DstEP != 11 || n.reads[0].ClusterID != 0x0000 {
+		t.Fatalf("reads = %+v, want one Basic read to 0x1234/11", n.reads)
+	}
+	if !ms.devices[dev.IEEEAddress].LastSeen.After(now) {
+		t.Error("successful ping did not update LastSeen")
+	}
+	if got := a.State(dev.IEEEAddress); got != AvailabilityOnline {
+		t.Errorf("state = %q, want online", got)
+	}
+
+	// No second ping within half the timeout.
+	a.check(now.Add(time.Minute))
+	a.wg.Wait()
+	if len(n.reads) != 1 {
+		t.Errorf("reads = %d, want 1", len(n.reads))
+	}
+
+	// Unanswered pings: offline after the timeout.
+	n.readErr = errors.New("no ack")
+	later := ms.devices[dev.IEEEAddress].LastSeen.Add(10 * time.Minute)
+	a.check(later)
+	a.wg.Wait()
+	if len(n.reads) != 2 {
+		t.Errorf("reads = %d, want 2", len(n.reads))
+	}
+	if got := a.State(dev.IEEEAddress); got != AvailabilityOnline {
+		t.Errorf("state while pinging = %q, want online", got)
+	}
+	a.check(later.Add(availabilityCheckInterval))
+	if got := a.State(dev.IEEEAddress); got != AvailabilityOffline {
+		t.Errorf("state = %q, want offline", got)
+	}
+
+	// Any frame brings it back.
+	a.coord.events.Emit(Event{Type: EventAttributeReport, Data: map[string]interface{}{"ieee": dev.IEEEAddress}})
+	if got := a.State(dev.IEEEAddress); got != AvailabilityOnline {
+		t.Errorf("state after report = %q, want online", got)
+	}
+}
+
+func TestAvailabilityBatteryNotPinged(t *testing.T) {
+	now := time.Now()
+	a, _, n, _ := newTestAvailability(t, AvailabilityConfig{Enabled: true},
+		// No announce yet: Power Configuration marks it as battery powered.
+		&store.Device{
+			IEEEAddress: "0000000000000001",
+			LastSeen:    now.Add(-20 * time.Hour),
+			Endpoints:   []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0x0001, 0x0402}}},
+		},
+	)
+	a.check(now)
+	a.wg.Wait()
+	if len(n.reads) != 0 {
+		t.Errorf("battery device pinged: %+v", n.reads)
+	}
+	if got := a.State("0000000000000001"); got != AvailabilityOnline {
+		t.Errorf("state = %q, want online", got)
+	}
+}
+
+func TestAvailabilityOverrides(t *testing.T) {
+	now := time.Now()
+	a, _, _, _ := newTestAvailability(t, AvailabilityConfig{
+		Enabled: true,
+		Devices: map[string]time.Duration{"Garage": 0, "0000000000000002": time.Hour},
+	},
+		&store.Device{IEEEAddress: "0000000000000001", FriendlyName: "Garage", PowerSource: PowerSourceBattery, LastSeen: now.Add(-48 * time.Hour)},
+		&store.Device{IEEEAddress: "0000000000000002", PowerSource: PowerSourceBattery, LastSeen: now.Add(-2 * time.Hour)},
+		&store.Device{IEEEAddress: "0000000000000003", Manufacturer: "ACME", Model: "door", PowerSource: PowerSourceBattery, LastSeen: now.Add(-2 * time.Hour)},
+	)
+	a.coord.deviceDB = NewDeviceDB()
+	a.coord.deviceDB.Add(DeviceDefinition{Manufacturer: "ACME", Model: "door", Availability: &AvailabilityDef{Timeout: 3600}})
+
+	a.check(now)
+	a.wg.Wait()
+
+	for ieee, want := range map[string]string{
+		"0000000000000001": "",
+		"0000000000000002": AvailabilityOffline,
+		"0000000000000003": AvailabilityOffline,
+	} {
+		if got := a.State(ieee); got != want {
+			t.Errorf("%s = %q, want %q", ieee, got, want)
+		}
+	}
+}
+
+func TestAvailabilityRestore(t *testing.T) {
+	a, _, _, events := newTestAvailability(t, AvailabilityConfig{Enabled: true},
+		&store.Device{
+			IEEEAddress:  "0000000000000001",
+			PowerSource:  PowerSourceBattery,
+			LastSeen:     time.Now().Add(-48 * time.Hour),
+			Availability: &store.AvailabilityState{State: AvailabilityOffline},
+		},
+	)
+	// Downtime does not count, but an offline device stays offline until heard.
+	a.started = time.Now()
+	a.check(time.Now())
+	if got := a.State("0000000000000001"); got != AvailabilityOffline {
+		t.Errorf("state = %q, want offline", got)
+	}
+	if len(*events) != 0 {
+		t.Errorf("events = %v, want none", *events)
+	}
+}
@@ -0,0 +1,276 @@
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+const (
+	defaultConfirmTimeout = 2 * time.Second
+	confirmReadTimeout    = 10 * time.Second
+)
+
+// ConfirmConfig configures the confirmation of property sets.
+type ConfirmConfig struct {
+	// Enabled confirms the sets of every device. The device option
+	// "confirm" (0 or 1) overrides it per device or definition.
+	Enabled bool
+
+	// Timeout is how long to wait for the device to report the new value
+	// before reading it back. Zero uses the default (2s).
+	Timeout time.Duration
+}
+
+// confirmKey identifies the attribute a set is waiting for.
+type confirmKey struct {
+	ieee      string
+	endpoint  uint8
+	cluster   uint16
+	attribute uint16
+}
+
+// pendingConfirm is a set waiting for its attribute to be reported.
+type pendingConfirm struct {
+	property string
+	value    any // as requested
+	expected any // raw attribute value; nil accepts any value ("toggle")
+	short    uint16
+	timer    *time.Timer
+}
+
+// commandConfirmer confirms that devices applied a property set. Lights
+// with a flaky route may drop a command although it was sent. After a set,
+// the attribute it changes must be reported with the new value within the
+// timeout; otherwise it is read back. The reported or read value updates
+// the property as any report does, and a set the device did not apply is
+// emitted as a command_failed event.
+type commandConfirmer struct {
+	coord  *Coordinator
+	logger *slog.Logger
+	cfg    ConfirmConfig
+
+	mu      sync.Mutex
+	pending map[confirmKey]*pendingConfirm
+	stopped bool
+
+	wg    sync.WaitGroup
+	unsub func()
+}
+
+func newCommandConfirmer(c *Coordinator, cfg ConfirmConfig) *commandConfirmer {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultConfirmTimeout
+	}
+	cf := &commandConfirmer{
+		coord:   c,
+		logger:  c.logger.With("component", "confirm"),
+		cfg:     cfg,
+		pending: make(map[confirmKey]*pendingConfirm),
+	}
+	cf.unsub = c.events.On(EventAttributeReport, cf.handleReport)
+	return cf
+}
+
+// stop drops the pending confirmations and waits for running read-backs.
+func (cf *commandConfirmer) stop() {
+	cf.unsub()
+	cf.mu.Lock()
+	cf.stopped = true
+	for key, p := range cf.pending {
+		p.timer.Stop()
+		delete(cf.pending, key)
+	}
+	cf.mu.Unlock()
+	cf.wg.Wait()
+}
+
+// ConfirmsCommands reports whether property sets of the device are
+// confirmed, in which case the new value is only published once the device
+// reported it.
+func (c *Coordinator) ConfirmsCommands(ieee string) bool {
+	if c.confirm == nil {
+		return false
+	}
+	dev, err := c.store.GetDevice(ieee)
+	if err != nil {
+		return false
+	}
+	return c.confirm.enabledFor(dev)
+}
+
+func (cf *commandConfirmer) enabledFor(dev *store.Device) bool {
+	if v, ok := cf.coord.devices.deviceOptions(dev)[optionConfirm]; ok {
+		return v != 0
+	}
+	return cf.cfg.Enabled
+}
+
+// expect waits for the device to apply a set sent with set. Manufacturer-
+// specific attributes cannot be read back and are not confirmed.
+func (cf *commandConfirmer) expect(dev *store.Device, set *propertySetter, name string, value any) {
+	if set.conv.ManufacturerCode != 0 || !cf.enabledFor(dev) {
+		return
+	}
+	// Values without a raw attribute value, like "toggle", are confirmed
+	// by any report of the attribute.
+	expected, err := reverseConverter(set.conv, value)
+	if err != nil {
+		expected = nil
+	}
+	key := confirmKey{dev.IEEEAddress, set.endpoint, set.cluster, set.attribute}
+	p := &pendingConfirm{property: name, value: value, expected: expected, short: dev.ShortAddress}
+
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+	if cf.stopped {
+		return
+	}
+	if old := cf.pending[key]; old != nil {
+		// The newer set supersedes the one still waiting.
+		old.timer.Stop()
+	}
+	cf.pending[key] = p
+	p.timer = time.AfterFunc(cf.cfg.Timeout, func() { cf.timeout(key, p) })
+}
+
+// handleReport confirms the set waiting for a reported attribute. Other
+// values, such as the steps of a level transition, keep it waiting.
+func (cf *commandConfirmer) handleReport(e Event) {
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	endpoint, _ := data["endpoint"].(uint8)
+	cluster, _ := data["cluster_id"].(uint16)
+	attr, _ := data["attr_id"].(uint16)
+	key := confirmKey{ieee, endpoint, cluster, attr}
+
+	cf.mu.Lock()
+	p := cf.pending[key]
+	if p == nil || !confirmMatches(p.expected, data["value"]) {
+		cf.mu.Unlock()
+		return
+	}
+	p.timer.Stop()
+	delete(cf.pending, key)
+	cf.mu.Unlock()
+	cf.logger.Debug("set confirmed by report", "ieee", ieee, "property", p.property, "value", p.value)
+}
+
+// timeout reads back the attribute of a set that was not reported in time.
+func (cf *commandConfirmer) timeout(key confirmKey, p *pendingConfirm) {
+	cf.mu.Lock()
+	if cf.pending[key] != p || cf.stopped {
+		cf.mu.Unlock()
+		return
+	}
+	delete(cf.pending, key)
+	cf.wg.Add(1)
+	cf.mu.Unlock()
+	defer cf.wg.Done()
+
+	ctx, cancel := context.WithTimeout(cf.coord.ctx, confirmReadTimeout)
+	defer cancel()
+	responses, err := cf.coord.readAttributeReports(ctx, p.short, key.endpoint, key.cluster, []uint16{key.attribute})
+	if err != nil {
+		cf.fail(key, p, fmt.Sprintf("no report and read back failed: %v", err), nil)
+		return
+	}
+	for _, r := range responses {
+		if r.AttrID != key.attribute {
+			continue
+		}
+		if r.Status != 0 {
+			cf.fail(key, p, fmt.Sprintf("no report and read back status 0x%02X", r.Status), nil)
+			return
+		}
+		actual, _, err := zcl.DecodeValue(r.DataType, r.Value)
+		if err != nil {
+			cf.fail(key, p, fmt.Sprintf("no report and read back undecodable: %v", err), nil)
+			return
+		}
+		if !confirmMatches(p.expected, actual) {
+			cf.fail(key, p, "device did not apply the value", actual)
+			return
+		}
+		cf.logger.Debug("set confirmed by read back", "ieee", key.ieee, "property", p.property, "value", p.value)
+		return
+	}
+	cf.fail(key, p, "no report and read back returned no value", nil)
+}
+
+// fail emits the command_failed event of a set. actual is the raw value
+// read back, if any.
+func (cf *commandConfirmer) fail(key confirmKey, p *pendingConfirm, reason string, actual any) {
+	cf.logger.Warn("set not confirmed", "ieee", key.ieee, "property", p.property,
+		"value", p.value, "reason", reason)
+	data := map[string]interface{}{
+		"ieee":      key.ieee,
+		"property":  p.property,
+		"value":     p.value,
+		"reason":    reason,
+		"endpoint":  key.endpoint,
+		"cluster":   key.cluster,
+		"attribute": key.attribute,
+	}
+	if actual != nil {
+		data["actual"] = actual
+	}
+	cf.coord.events.Emit(Event{Type: EventCommandFailed, Data: data})
+}
+
+// confirmMatches reports whether a reported raw value is the expected one.
+// Numbers compare by value whatever their type.
+func confirmMatches(expected, actual any) bool {
+	if expected == nil {
+		return true
+	}
+	e, err1 := exprValue(expected)
+	a, err2 := exprValue(actual)
+	if err1 != nil || err2 != nil {
+		return reflect.DeepEqual(expected, actual)
+	}
+	if ef, ok := toFloat(e); ok {
+		af, ok := toFloat(a)
+		return ok && ef == af
+	}
+	return e == a
+}
+
+// readAttributeReports reads attributes and processes the values read as
+// attribute reports, so they update the device's properties like reports
+// do. The raw responses are returned.
+func (c *Coordinator) readAttributeReports(ctx context.Context, shortAddr uint16, endpoint uint8, clusterID uint16, attrIDs []uint16) ([]ncp.AttributeResponse, error) {
+	responses, err := c.ncp.ReadAttributes(ctx, ncp.ReadAttributesRequest{
+		DstAddr:   shortAddr,
+		DstEP:     endpoint,
+		ClusterID: clusterID,
+		AttrIDs:   attrIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read attributes: %w", err)
+	}
+	for _, r := range responses {
+		if r.Status != 0 || len(r.Value) == 0 {
+			continue
+		}
+		c.devices.HandleAttributeReport(ncp.AttributeReportEvent{
+			SrcAddr:   shortAddr,
+			SrcEP:     endpoint,
+			ClusterID: clusterID,
+			AttrID:    r.AttrID,
+			DataType:  r.DataType,
+			Value:     r.Value,
+		})
+	}
+	return responses, nil
+}
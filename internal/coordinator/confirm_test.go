@@ -0,0 +1,187 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/zcl"
+)
+
+// onOffState answers On/Off reads with the state the light has.
+func onOffState(on byte) func(ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+	return func(ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+		return []ncp.AttributeResponse{{AttrID: 0x0000, DataType: zcl.TypeBool, Value: []byte{on}}}
+	}
+}
+
+// failedRecorder collects command_failed events.
+type failedRecorder struct {
+	mu     sync.Mutex
+	events []map[string]interface{}
+}
+
+func (r *failedRecorder) wait(t *testing.T) map[string]interface{} {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		if len(r.events) > 0 {
+			e := r.events[0]
+			r.mu.Unlock()
+			return e
+		}
+		r.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no command_failed event")
+	return nil
+}
+
+func (r *failedRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+func newTestConfirm(t *testing.T, cfg ConfirmConfig) (*Coordinator, *fakeNCP, *failedRecorder) {
+	t.Helper()
+	c, n := newTestSetters(t)
+	n.onRead = onOffState(0)
+	c.devices.RebuildAddrIndex()
+	c.confirm = newCommandConfirmer(c, cfg)
+	t.Cleanup(c.confirm.stop)
+	rec := &failedRecorder{}
+	c.events.On(EventCommandFailed, func(e Event) {
+		rec.mu.Lock()
+		rec.events = append(rec.events, e.Data.(map[string]interface{}))
+		rec.mu.Unlock()
+	})
+	return c, n, rec
+}
+
+func reportOnOff(c *Coordinator, on byte) {
+	c.devices.HandleAttributeReport(ncp.AttributeReportEvent{
+		SrcAddr: 0x1111, SrcEP: 2, ClusterID: 0x0006, AttrID: 0x0000, DataType: zcl.TypeBool, Value: []byte{on},
+	})
+}
+
+func (cf *commandConfirmer) pendingCount() int {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+	return len(cf.pending)
+}
+
+func TestConfirmByReport(t *testing.T) {
+	c, n, rec := newTestConfirm(t, ConfirmConfig{Enabled: true, Timeout: time.Minute})
+	if !c.ConfirmsCommands("00158D0001A2B3C4") {
+		t.Fatal("ConfirmsCommands = false")
+	}
+	if err := c.SetProperty(context.Background(), "00158D0001A2B3C4", "on_off", "ON"); err != nil {
+		t.Fatal(err)
+	}
+	if c.confirm.pendingCount() != 1 {
+		t.Fatalf("pending = %d, want 1", c.confirm.pendingCount())
+	}
+	// The old state does not confirm the set, the new one does.
+	reportOnOff(c, 0)
+	if c.confirm.pendingCount() != 1 {
+		t.Fatal("set confirmed by the old state")
+	}
+	reportOnOff(c, 1)
+	if c.confirm.pendingCount() != 0 {
+		t.Error("set not confirmed by the report")
+	}
+	if n.readCount() != 0 || rec.count() != 0 {
+		t.Errorf("reads = %d, failures = %d", n.readCount(), rec.count())
+	}
+}
+
+func TestConfirmReadBack(t *testing.T) {
+	c, n, rec := newTestConfirm(t, ConfirmConfig{Enabled: true, Timeout: 10 * time.Millisecond})
+	state := recordProperty(c.devices, "on_off")
+
+	// The light ignored the command: the state read back is published and
+	// the set fails.
+	if err := c.SetProperty(context.Background(), "00158D0001A2B3C4", "on_off", true); err != nil {
+		t.Fatal(err)
+	}
+	e := rec.wait(t)
+	if e["property"] != "on_off" || e["value"] != true || e["actual"] != false || e["reason"] == "" {
+		t.Errorf("command_failed = %v", e)
+	}
+	if v := state.waitValues(t, 1); v[0] != false {
+		t.Errorf("published state = %v", v)
+	}
+
+	// The light switched but did not report: the read back confirms.
+	n.locked(func() { n.onRead = onOffState(1) })
+	if err := c.SetProperty(context.Background(), "00158D0001A2B3C4", "on_off", true); err != nil {
+		t.Fatal(err)
+	}
+	if v := state.waitValues(t, 2); v[1] != true {
+		t.Errorf("published state = %v", v)
+	}
+	if rec.count() != 1 {
+		t.Errorf("failures = %d, want 1", rec.count())
+	}
+}
+
+func TestConfirmReadBackError(t *testing.T) {
+	c, n, rec := newTestConfirm(t, ConfirmConfig{Enabled: true, Timeout: 10 * time.Millisecond})
+	n.readErr = errors.New("no route")
+	if err := c.SetProperty(context.Background(), "00158D0001A2B3C4", "brightness", 128.0); err != nil {
+		t.Fatal(err)
+	}
+	if e := rec.wait(t); e["property"] != "brightness" || e["cluster"] != uint16(0x0008) || e["actual"] != nil {
+		t.Errorf("command_failed = %v", e)
+	}
+}
+
+func TestConfirmDeviceOption(t *testing.T) {
+	c, _, _ := newTestConfirm(t, ConfirmConfig{})
+	ieee := "00158D0001A2B3C4"
+	if c.ConfirmsCommands(ieee) {
+		t.Error("confirming with confirmation disabled")
+	}
+	if err := c.SetDeviceOptions(ieee, map[string]float64{"confirm": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !c.ConfirmsCommands(ieee) {
+		t.Error("confirm option not applied")
+	}
+	c.confirm.cfg.Enabled = true
+	if err := c.SetDeviceOptions(ieee, map[string]float64{"confirm": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetProperty(context.Background(), ieee, "on_off", "toggle"); err != nil {
+		t.Fatal(err)
+	}
+	if c.ConfirmsCommands(ieee) || c.confirm.pendingCount() != 0 {
+		t.Error("confirming a device with confirm 0")
+	}
+}
+
+func TestConfirmMatches(t *testing.T) {
+	tests := []struct {
+		expected, actual any
+		want             bool
+	}{
+		{nil, uint8(3), true},
+		{true, true, true},
+		{true, false, false},
+		{128, uint8(128), true},
+		{int16(2150), int16(2150), true},
+		{uint8(1), uint8(2), false},
+		{"a", "a", true},
+		{[]byte{1}, []byte{1}, true},
+	}
+	for _, tt := range tests {
+		if got := confirmMatches(tt.expected, tt.actual); got != tt.want {
+			t.Errorf("confirmMatches(%v, %v) = %v, want %v", tt.expected, tt.actual, got, tt.want)
+		}
+	}
+}
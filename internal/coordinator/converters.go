@@ -0,0 +1,99 @@
+package coordinator
+
+import (
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+// AttributeConverter overrides the converter of one attribute for the
+// devices of a definition. A converter without property and flags turns
+// off the cluster default. Type is the ZCL type used to write attributes
+// missing from the registry, such as manufacturer-specific ones.
+type AttributeConverter struct {
+	Cluster   uint16 `json:"cluster"`
+	Attribute uint16 `json:"attribute"`
+	Type      uint8  `json:"type,omitempty"`
+	zcl.Converter
+}
+
+// converterFor returns the converter for an attribute of the device: the
+// definition's override, else the default of the registered cluster.
+func (dm *DeviceManager) converterFor(dev *store.Device, clusterID, attrID uint16) *zcl.Converter {
+	if dev != nil {
+		if def := dm.definition(dev); def != nil {
+			for i := range def.Converters {
+				if c := &def.Converters[i]; c.Cluster == clusterID && c.Attribute == attrID {
+					return &c.Converter
+				}
+			}
+		}
+	}
+	registry := dm.coord.Registry()
+	if registry == nil {
+		return nil
+	}
+	cluster := registry.Get(clusterID)
+	if cluster == nil {
+		return nil
+	}
+	if attr := cluster.FindAttribute(attrID); attr != nil {
+		return attr.Converter
+	}
+	return nil
+}
+
+// applyConverter turns an attribute value into normalized properties,
+// calibrates them with the device options, persists them and emits a
+// property_update event for each, so the web UI, MQTT and automations all
+// see the same names, units and scaling.
+func (dm *DeviceManager) applyConverter(ieee string, dev *store.Device, clusterID, attrID uint16, value any) []zcl.PropertyValue {
+	if ieee == "" || value == nil {
+		return nil
+	}
+	conv := dm.converterFor(dev, clusterID, attrID)
+	if conv == nil || conv.Disabled() {
+		return nil
+	}
+	if conv.Transform != "" {
+		value = dm.transform(ieee, conv.Transform, value)
+	}
+	props := conv.Convert(value)
+	if len(props) == 0 {
+		return nil
+	}
+	opts := dm.deviceOptions(dev)
+	for i := range props {
+		props[i].Value = calibrate(opts, props[i].Name, props[i].Value)
+	}
+
+	if dev != nil {
+		if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+			if d.Properties == nil {
+				d.Properties = make(map[string]any)
+			}
+			for _, p := range props {
+				d.Properties[p.Name] = p.Value
+			}
+			return nil
+		}); err != nil {
+			dm.logger.Error("save converted properties", "err", err, "ieee", ieee)
+		}
+	}
+
+	for _, p := range props {
+		data := map[string]interface{}{
+			"ieee":     ieee,
+			"property": p.Name,
+			"value":    p.Value,
+			"source": map[string]interface{}{
+				"cluster":   clusterID,
+				"attribute": attrID,
+			},
+		}
+		if p.Unit != "" {
+			data["unit"] = p.Unit
+		}
+		dm.coord.Events().Emit(Event{Type: EventPropertyUpdate, Data: data})
+	}
+	return props
+}
@@ -0,0 +1,109 @@
+package coordinator
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+// reportAttributes sends one attribute report per value and collects the
+// resulting property_update events by property name.
+func reportAttributes(dm *DeviceManager, shortAddr uint16, reports []ncp.AttributeReportEvent) map[string]map[string]interface{} {
+	got := make(map[string]map[string]interface{})
+	dm.coord.Events().On(EventPropertyUpdate, func(e Event) {
+		data := e.Data.(map[string]interface{})
+		got[data["property"].(string)] = data
+	})
+	for _, r := range reports {
+		r.SrcAddr = shortAddr
+		r.SrcEP = 1
+		dm.HandleAttributeReport(r)
+	}
+	return got
+}
+
+func int16Report(cluster, attr uint16, v int16) ncp.AttributeReportEvent {
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, uint16(v))
+	return ncp.AttributeReportEvent{ClusterID: cluster, AttrID: attr, DataType: zcl.TypeInt16, Value: b}
+}
+
+func TestAttributeReportConverter(t *testing.T) {
+	dm, ms := newTestDM(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	dm.RebuildAddrIndex()
+
+	got := reportAttributes(dm, 0x1111, []ncp.AttributeReportEvent{
+		int16Report(0x0402, 0x0000, 2345),
+		{ClusterID: 0x0006, AttrID: 0x0000, DataType: zcl.TypeBool, Value: []byte{1}},
+		int16Report(0x0402, 0x0001, 100), // MinMeasuredValue has no converter
+	})
+
+	temp := got["temperature"]
+	if temp == nil || temp["value"] != 23.45 || temp["unit"] != "°C" {
+		t.Errorf("temperature event: %v", temp)
+	}
+	if got["on_off"] == nil || got["on_off"]["value"] != true {
+		t.Errorf("on_off event: %v", got["on_off"])
+	}
+	if len(got) != 2 {
+		t.Errorf("events for %d properties, want 2: %v", len(got), got)
+	}
+	props := ms.devices["00158D0001A2B3C4"].Properties
+	if props["temperature"] != 23.45 || props["on_off"] != true {
+		t.Errorf("stored properties: %v", props)
+	}
+}
+
+func TestDefinitionConverterOverride(t *testing.T) {
+	var def DeviceDefinition
+	if err := json.Unmarshal([]byte(`{
+		"manufacturer": "_TZ3000_test",
+		"model": "TS0201",
+		"converters": [
+			{"cluster": 1026, "attribute": 0, "property": "temperature", "scale": 0.1, "unit": "°C"},
+			{"cluster": 6, "attribute": 0}
+		]
+	}`), &def); err != nil {
+		t.Fatal(err)
+	}
+
+	dm, ms := newTestDM(t)
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(def)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111,
+		Manufacturer: "_TZ3000_test", Model: "TS0201",
+	}
+	dm.RebuildAddrIndex()
+
+	got := reportAttributes(dm, 0x1111, []ncp.AttributeReportEvent{
+		int16Report(0x0402, 0x0000, 234),
+		{ClusterID: 0x0006, AttrID: 0x0000, DataType: zcl.TypeBool, Value: []byte{1}},
+	})
+
+	if got["temperature"] == nil || got["temperature"]["value"] != 23.4 {
+		t.Errorf("temperature event: %v", got["temperature"])
+	}
+	if got["on_off"] != nil {
+		t.Errorf("disabled converter emitted on_off: %v", got["on_off"])
+	}
+}
+
+func TestConverterTransformAndEnum(t *testing.T) {
+	if v := applyTransform("illuminance_lux", uint16(30001)); v != int64(1000) {
+		t.Errorf("illuminance_lux(30001) = %v, want 1000", v)
+	}
+	conv := zcl.Converter{Property: "system_mode", Enum: map[int]string{4: "heat"}}
+	if p := conv.Convert(uint8(4)); len(p) != 1 || p[0].Value != "heat" {
+		t.Errorf("enum: %v", p)
+	}
+	// Unknown enum values pass through unchanged.
+	if p := conv.Convert(uint8(2)); p[0].Value != uint8(2) {
+		t.Errorf("unknown enum value: %v", p)
+	}
+}
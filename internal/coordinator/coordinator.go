@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
 
 	"zigbee-go-home/internal/ncp"
 	"zigbee-go-home/internal/store"
@@ -17,6 +18,15 @@ type Config struct {
 	Channel  uint8
 	PanID    uint16
 	ExtPanID [8]byte
+	Alarm    AlarmConfig
+	Availability AvailabilityConfig
+	Confirm      ConfirmConfig
+	Poll         PollConfig
+
+	// DevicesDir is where device definition files are reloaded from.
+	// WatchDevices reloads them automatically when the files change.
+	DevicesDir   string
+	WatchDevices bool
 }
 
 // NCPConfig holds NCP hardware/port configuration for display purposes.
@@ -54,6 +64,13 @@ type Coordinator struct {
 	deviceDB   *DeviceDB
 	events     *EventBus
 	devices    *DeviceManager
+	local      *LocalServer
+	alarm      *AlarmPanel
+	availability *Availability
+	confirm      *commandConfirmer
+	poller       *poller
+	watcher    *definitionWatcher
+	reloadMu   sync.Mutex // serializes definition reloads
 	logger     *slog.Logger
 	config     Config
 	ncpConfig  NCPConfig
@@ -65,6 +82,9 @@ type Coordinator struct {
 // New creates a new Coordinator using the nRF52840 NCP backend.
 func New(backend ncp.NCP, st store.Store, registry *zcl.Registry, deviceDB *DeviceDB, events *EventBus, cfg Config, ncpCfg NCPConfig, logger *slog.Logger) *Coordinator {
 	ctx, cancel := context.WithCancel(context.Background())
+	if deviceDB == nil {
+		deviceDB = NewDeviceDB()
+	}
 	c := &Coordinator{
 		ncp:       backend,
 		store:     st,
@@ -79,6 +99,24 @@ func New(backend ncp.NCP, st store.Store, registry *zcl.Registry, deviceDB *Devi
 	}
 	c.devices = NewDeviceManager(c)
 	c.devices.RebuildAddrIndex()
+	c.local = newLocalServer(c)
+	if cfg.Alarm.Enabled {
+		c.alarm = newAlarmPanel(c, cfg.Alarm)
+	}
+	if cfg.Availability.Enabled {
+		c.availability = newAvailability(c, cfg.Availability)
+		c.availability.start()
+	}
+	c.confirm = newCommandConfirmer(c, cfg.Confirm)
+	if cfg.Poll.Enabled {
+		c.poller = newPoller(c, cfg.Poll)
+		c.poller.start()
+	}
+	if cfg.WatchDevices && cfg.DevicesDir != "" {
+		c.watcher = newDefinitionWatcher(c, cfg.DevicesDir)
+		c.watcher.start()
+	}
+	c.registerLocalClusters()
 	c.registerIndicationHandlers()
 	return c
 }
@@ -95,6 +133,11 @@ func (c *Coordinator) Context() context.Context {
 func (c *Coordinator) Start(ctx context.Context) error {
 	c.logger.Info("initializing NCP...")
 
+	// Local endpoints are registered by the NCP on StartNetwork.
+	for _, desc := range c.local.Descriptors() {
+		c.ncp.RegisterLocalEndpoint(desc)
+	}
+
 	// Try to resume an existing network if our DB says it was formed with matching params.
 	if c.canResumeNetwork() {
 		c.logger.Info("resuming existing network...")
@@ -203,6 +246,22 @@ func (c *Coordinator) canResumeNetwork() bool {
 func (c *Coordinator) Stop() {
 	c.cancel()
 	c.devices.CancelAllInterviews()
+	c.devices.stopThrottle()
+	if c.alarm != nil {
+		c.alarm.stop()
+	}
+	if c.availability != nil {
+		c.availability.stop()
+	}
+	if c.confirm != nil {
+		c.confirm.stop()
+	}
+	if c.poller != nil {
+		c.poller.stop()
+	}
+	if c.watcher != nil {
+		c.watcher.stop()
+	}
 }
 
 // PermitJoin opens or closes the network for device joining.
@@ -225,6 +284,7 @@ func (c *Coordinator) NetworkInfo() map[string]interface{} {
 		"port":             c.ncpConfig.Port,
 		"baud":             c.ncpConfig.Baud,
 		"coordinator_ieee": fmt.Sprintf("%016X", c.localIEEE),
+		"stats":            c.ncp.Stats(),
 	}
 	if ncpInfo := c.ncp.GetNCPInfo(); ncpInfo != nil {
 		info["fw_version"] = ncpInfo.FWVersion
@@ -234,6 +294,21 @@ func (c *Coordinator) NetworkInfo() map[string]interface{} {
 	return info
 }
 
+// LocalServer returns the server for clusters hosted on coordinator endpoints.
+func (c *Coordinator) LocalServer() *LocalServer {
+	return c.local
+}
+
+// AlarmPanel returns the alarm panel, or nil when it is disabled.
+func (c *Coordinator) AlarmPanel() *AlarmPanel {
+	return c.alarm
+}
+
+// Availability returns the availability tracker, or nil when it is disabled.
+func (c *Coordinator) Availability() *Availability {
+	return c.availability
+}
+
 // NCP returns the underlying NCP backend.
 func (c *Coordinator) NCP() ncp.NCP {
 	return c.ncp
@@ -278,10 +353,37 @@ func (c *Coordinator) registerIndicationHandlers() {
 		c.devices.HandleAttributeReport(evt)
 	})
 	c.ncp.OnClusterCommand(func(evt ncp.ClusterCommandEvent) {
+		// Local clusters answer the sender; the device manager still sees
+		// the command for events and property decoding.
+		c.local.HandleClusterCommand(evt)
 		c.devices.HandleClusterCommand(evt)
 	})
+	c.ncp.OnGlobalCommand(c.local.HandleGlobalCommand)
+	c.ncp.SetDefaultResponsePolicy(c.defaultResponsePolicy)
 	c.ncp.OnNwkAddrUpdate(func(newAddr uint16) {
 		c.logger.Info("NwkAddrUpdate: rebuilding address index", "new_short", fmt.Sprintf("0x%04X", newAddr))
 		c.devices.RebuildAddrIndex()
 	})
 }
+
+// defaultResponsePolicy acknowledges reports and cluster commands unless a
+// local cluster answers the command itself or the device definition opts out.
+func (c *Coordinator) defaultResponsePolicy(info ncp.DefaultResponseInfo) bool {
+	if !info.Global && c.local.Cluster(info.DstEP, info.ClusterID, info.ServerToClient) != nil {
+		return false
+	}
+	return c.devices.wantsDefaultResponse(info.SrcAddr)
+}
+
+// registerLocalClusters hosts the default server clusters on endpoint 1.
+func (c *Coordinator) registerLocalClusters() {
+	c.local.Register(localEndpoint, newBasicServer())
+	c.local.Register(localEndpoint, newTimeServer())
+	c.local.Register(localEndpoint, c.newOTAServer())
+	c.local.Register(localEndpoint, c.newIASZoneClient())
+	c.local.Register(localEndpoint, c.newPollControlClient())
+	c.local.Register(localEndpoint, c.newTuyaClient())
+	if c.alarm != nil {
+		c.local.Register(localEndpoint, c.alarm.newACEServer())
+	}
+}
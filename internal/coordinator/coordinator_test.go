@@ -6,6 +6,9 @@ import (
 	"sync"
 	"sync/atomic"
 	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
 )
 
 func TestParseIEEE(t *testing.T) {
@@ -238,3 +241,36 @@ func TestEventBusMultipleHandlersSameType(t *testing.T) {
 		t.Errorf("got %d, want 3", count.Load())
 	}
 }
+
+func TestDefaultResponsePolicy(t *testing.T) {
+	dm, ms := newTestDM(t)
+	c := dm.coord
+	c.devices = dm
+	c.local, _ = newTestLocalServer(t)
+	c.local.Register(1, c.newIASZoneClient())
+	c.deviceDB = NewDeviceDB()
+	off := false
+	c.deviceDB.Add(DeviceDefinition{Manufacturer: "LUMI", Model: "lumi.quiet", DefaultResponse: &off})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	ms.devices["00158D0001A2B3C5"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C5", ShortAddress: 0x2222, Manufacturer: "LUMI", Model: "lumi.quiet",
+	}
+	dm.RebuildAddrIndex()
+
+	tests := []struct {
+		name string
+		info ncp.DefaultResponseInfo
+		want bool
+	}{
+		{"report", ncp.DefaultResponseInfo{SrcAddr: 0x1111, DstEP: 1, ClusterID: 0x0402, Global: true}, true},
+		{"unhandled command", ncp.DefaultResponseInfo{SrcAddr: 0x1111, DstEP: 1, ClusterID: 0x0006, CommandID: 0x02}, true},
+		{"unknown device", ncp.DefaultResponseInfo{SrcAddr: 0x9999, DstEP: 1, ClusterID: 0x0006, Global: true}, true},
+		{"local cluster answers", ncp.DefaultResponseInfo{SrcAddr: 0x1111, DstEP: 1, ClusterID: 0x0500, ServerToClient: true}, false},
+		{"definition opts out", ncp.DefaultResponseInfo{SrcAddr: 0x2222, DstEP: 1, ClusterID: 0x0402, Global: true}, false},
+	}
+	for _, tt := range tests {
+		if got := c.defaultResponsePolicy(tt.info); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package coordinator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"zigbee-go-home/internal/zcl"
+)
+
+// DecoderInput is the raw data handed to a property decoder: the value of
+// a reported attribute or the payload of a cluster command.
+type DecoderInput struct {
+	Cluster   uint16
+	Attribute uint16 // attribute reports
+	Command   uint8  // cluster commands
+	IsCommand bool
+	DataType  uint8  // ZCL type of a reported attribute
+	Data      []byte // string contents, other attribute values raw, or the command payload
+
+	Manufacturer string
+	Model        string
+}
+
+// Decoded holds the values extracted by a decoder. Tags are picked by the
+// tag of a definition's values (Xiaomi TLV tags, Tuya DP IDs). Properties
+// are named values; they are emitted under their own name unless a value
+// of the definition selects them by key.
+type Decoded struct {
+	Tags       map[int]any
+	Properties map[string]any
+}
+
+// PropertyDecoder turns proprietary attributes or cluster commands into
+// property values. Commands lists the cluster commands it decodes when a
+// definition does not name them; a decoder without commands decodes
+// attribute reports.
+type PropertyDecoder struct {
+	Commands []uint8
+	Decode   func(in DecoderInput) (Decoded, error)
+}
+
+// DecoderLoader loads a decoder from a file in the devices directory.
+type DecoderLoader func(path string) (*PropertyDecoder, error)
+
+var (
+	decodersMu sync.RWMutex
+	decoders   = map[string]*PropertyDecoder{
+		"xiaomi_tlv":    {Decode: decodeXiaomiTLVInput},
+		"xiaomi_struct": {Decode: decodeXiaomiStructInput},
+		"tuya_dp": {
+			Commands: []uint8{tuyaCmdDataResponse, tuyaCmdDataReport, tuyaCmdActiveStatusReport},
+			Decode:   decodeTuyaDPInput,
+		},
+	}
+	decoderLoaders = make(map[string]DecoderLoader)
+)
+
+// RegisterDecoder makes a built-in decoder available to device definitions
+// under name. Decoder files in the devices directory cannot replace it.
+func RegisterDecoder(name string, d *PropertyDecoder) {
+	decodersMu.Lock()
+	decoders[name] = d
+	decodersMu.Unlock()
+}
+
+// RegisterDecoderLoader loads decoder files with the extension (".lua")
+// from the devices directory. The file name without extension is the
+// decoder name. Without a loader, such files are ignored.
+func RegisterDecoderLoader(ext string, load DecoderLoader) {
+	decodersMu.Lock()
+	decoderLoaders[ext] = load
+	decodersMu.Unlock()
+}
+
+// Decoder returns the named decoder: a built-in one, else one loaded from
+// the devices directory. Returns nil if unknown.
+func (db *DeviceDB) Decoder(name string) *PropertyDecoder {
+	decodersMu.RLock()
+	d := decoders[name]
+	decodersMu.RUnlock()
+	if d != nil || db == nil {
+		return d
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.decoders[name]
+}
+
+// decoderFilePatterns returns the glob patterns of decoder files that have
+// a loader.
+func decoderFilePatterns() []string {
+	decodersMu.RLock()
+	defer decodersMu.RUnlock()
+	patterns := make([]string, 0, len(decoderLoaders))
+	for ext := range decoderLoaders {
+		patterns = append(patterns, "*"+ext)
+	}
+	slices.Sort(patterns)
+	return patterns
+}
+
+// loadDecoderFiles loads the decoder files of dir. A file that fails keeps
+// the decoder it loaded before, taken from prev.
+func loadDecoderFiles(dir string, prev map[string]*PropertyDecoder) (map[string]*PropertyDecoder, int, []FileError) {
+	out := make(map[string]*PropertyDecoder)
+	var errs []FileError
+	files := 0
+	for _, pattern := range decoderFilePatterns() {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, path := range matches {
+			files++
+			base := filepath.Base(path)
+			ext := filepath.Ext(base)
+			name := strings.TrimSuffix(base, ext)
+
+			decodersMu.RLock()
+			load := decoderLoaders[ext]
+			builtin := decoders[name] != nil
+			decodersMu.RUnlock()
+
+			var d *PropertyDecoder
+			err := fmt.Errorf("decoder %q is built in", name)
+			if !builtin {
+				d, err = load(path)
+			}
+			if err != nil {
+				errs = append(errs, FileError{File: base, Error: err.Error()})
+				if old := prev[name]; old != nil {
+					out[name] = old
+				}
+				continue
+			}
+			out[name] = d
+		}
+	}
+	return out, files, errs
+}
+
+// decodeTuyaDPInput decodes the datapoints of a Tuya 0xEF00 command into
+// values by DP ID.
+func decodeTuyaDPInput(in DecoderInput) (Decoded, error) {
+	tags, err := decodeTuyaDPs(in.Data)
+	return Decoded{Tags: tags}, err
+}
+
+// decodeXiaomiTLVInput decodes the TLV string of the Xiaomi 0xFF01 and
+// 0x00F7 attributes into values by tag.
+func decodeXiaomiTLVInput(in DecoderInput) (Decoded, error) {
+	switch in.DataType {
+	case zcl.TypeOctetStr, zcl.TypeCharStr, zcl.TypeOctetStr16, zcl.TypeCharStr16:
+	default:
+		return Decoded{}, fmt.Errorf("xiaomi_tlv expects a string attribute, got %s", zcl.TypeName(in.DataType))
+	}
+	tags, err := decodeXiaomiTLV(in.Data)
+	return Decoded{Tags: tags}, err
+}
+
+// decodeXiaomiStructInput decodes the ZCL struct of the Xiaomi 0xFF02
+// attribute into values tagged by their position, starting at 1.
+func decodeXiaomiStructInput(in DecoderInput) (Decoded, error) {
+	if in.DataType != zcl.TypeStruct {
+		return Decoded{}, fmt.Errorf("xiaomi_struct expects a struct attribute, got %s", zcl.TypeName(in.DataType))
+	}
+	tags, err := decodeZCLStruct(in.Data)
+	return Decoded{Tags: tags}, err
+}
+
+// decodeZCLStruct parses a ZCL struct: a 2-byte element count followed by
+// [zcl_type:uint8][value] per element.
+func decodeZCLStruct(data []byte) (map[int]any, error) {
+	result := make(map[int]any)
+	if len(data) < 2 {
+		return result, fmt.Errorf("struct: no element count")
+	}
+	count := int(binary.LittleEndian.Uint16(data))
+	pos := 2
+	for i := 1; i <= count; i++ {
+		if pos >= len(data) {
+			return result, fmt.Errorf("struct: element %d of %d missing", i, count)
+		}
+		typeID := data[pos]
+		pos++
+		val, consumed, err := zcl.DecodeValue(typeID, data[pos:])
+		if err != nil {
+			return result, fmt.Errorf("struct element %d type 0x%02X at offset %d: %w", i, typeID, pos, err)
+		}
+		result[i] = val
+		pos += consumed
+	}
+	return result, nil
+}
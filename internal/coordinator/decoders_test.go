@@ -0,0 +1,165 @@
+package coordinator
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+func TestDecodeZCLStruct(t *testing.T) {
+	// Xiaomi 0xFF02: bool, uint16 battery voltage, uint8.
+	data := []byte{0x03, 0x00, 0x10, 0x01, 0x21, 0xB8, 0x0B, 0x20, 0x05}
+	got, err := decodeZCLStruct(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[1] != true || got[2] != uint16(3000) || got[3] != uint8(5) {
+		t.Errorf("struct: %v", got)
+	}
+	if _, err := decodeZCLStruct([]byte{0x02, 0x00, 0x20, 0x01}); err == nil {
+		t.Error("expected error for missing element")
+	}
+}
+
+func TestDecoderNamedProperties(t *testing.T) {
+	RegisterDecoder("test_button", &PropertyDecoder{
+		Commands: []uint8{0x00},
+		Decode: func(in DecoderInput) (Decoded, error) {
+			if len(in.Data) == 0 {
+				return Decoded{}, errors.New("empty")
+			}
+			return Decoded{
+				Tags:       map[int]any{1: uint16(2950)},
+				Properties: map[string]any{"action": "on_press", "raw_button": in.Data[0]},
+			}, nil
+		},
+	})
+
+	dm, ms := newTestDM(t)
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "Button",
+		Properties: []PropertySource{{
+			Cluster: 0xFC00,
+			Decoder: "test_button",
+			Values: []PropertyDef{
+				{Tag: 1, Name: "battery", Transform: "lumi_battery"},
+				{Key: "raw_button", Name: "button"},
+			},
+		}},
+	})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111,
+		Manufacturer: "Acme", Model: "Button",
+	}
+
+	got := make(map[string]map[string]interface{})
+	dm.coord.Events().On(EventPropertyUpdate, func(e Event) {
+		data := e.Data.(map[string]interface{})
+		got[data["property"].(string)] = data
+	})
+
+	dev := ms.devices["00158D0001A2B3C4"]
+	// Command 0x01 is not decoded.
+	dm.processClusterCommandProperties("00158D0001A2B3C4", dev, ncp.ClusterCommandEvent{ClusterID: 0xFC00, CommandID: 0x01, Payload: []byte{1}})
+	if len(got) != 0 {
+		t.Fatalf("events for undecoded command: %v", got)
+	}
+	dm.processClusterCommandProperties("00158D0001A2B3C4", dev, ncp.ClusterCommandEvent{ClusterID: 0xFC00, CommandID: 0x00, Payload: []byte{1}})
+
+	if got["battery"] == nil || got["battery"]["value"] != int64(66) {
+		t.Errorf("battery: %v", got["battery"])
+	}
+	if got["button"] == nil || got["button"]["value"] != uint8(1) || got["raw_button"] != nil {
+		t.Errorf("key selection: %v", got)
+	}
+	action := got["action"]
+	if action == nil || action["value"] != "on_press" {
+		t.Fatalf("action: %v", action)
+	}
+	if src := action["source"].(map[string]interface{}); src["key"] != "action" || src["command"] != uint8(0) {
+		t.Errorf("action source: %v", src)
+	}
+	if props := ms.devices["00158D0001A2B3C4"].Properties; props["action"] != "on_press" || props["battery"] != int64(66) {
+		t.Errorf("stored properties: %v", props)
+	}
+}
+
+func TestLoadDecoderFiles(t *testing.T) {
+	RegisterDecoderLoader(".fake", func(path string) (*PropertyDecoder, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if string(data) == "broken" {
+			return nil, errors.New("syntax error")
+		}
+		return &PropertyDecoder{Decode: func(DecoderInput) (Decoded, error) {
+			return Decoded{Properties: map[string]any{"version": string(data)}}, nil
+		}}, nil
+	})
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("acme.json", `{"devices": [{"manufacturer": "Acme", "model": "X", "bind": []}]}`)
+	write("acme_decoder.fake", "v1")
+	write("xiaomi_tlv.fake", "v1")
+
+	if _, err := LoadDeviceDir(dir, zcl.NewRegistry(logger), logger); err == nil {
+		t.Error("expected error for a decoder file named after a built-in decoder")
+	}
+	os.Remove(filepath.Join(dir, "xiaomi_tlv.fake"))
+
+	db, err := LoadDeviceDir(dir, zcl.NewRegistry(logger), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := func() any {
+		d := db.Decoder("acme_decoder")
+		if d == nil {
+			return nil
+		}
+		out, _ := d.Decode(DecoderInput{})
+		return out.Properties["version"]
+	}
+	if v := version(); v != "v1" {
+		t.Fatalf("decoder version = %v, want v1", v)
+	}
+
+	// A broken file keeps the decoder it loaded before.
+	write("acme_decoder.fake", "broken")
+	res, err := db.Reload(dir, zcl.NewRegistry(logger), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].File != "acme_decoder.fake" || res.Decoders != 1 || res.Files != 2 {
+		t.Errorf("reload result: %+v", res)
+	}
+	if v := version(); v != "v1" {
+		t.Errorf("decoder version after failed reload = %v, want v1", v)
+	}
+
+	write("acme_decoder.fake", "v2")
+	if _, err := db.Reload(dir, zcl.NewRegistry(logger), logger); err != nil {
+		t.Fatal(err)
+	}
+	if v := version(); v != "v2" {
+		t.Errorf("decoder version after reload = %v, want v2", v)
+	}
+	if db.Decoder("tuya_dp") == nil || db.Decoder("nope") != nil {
+		t.Error("built-in lookup")
+	}
+}
@@ -0,0 +1,156 @@
+package coordinator
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// definitionWatchInterval is how often the devices directory is scanned
+// for changes when watching is enabled.
+const definitionWatchInterval = 2 * time.Second
+
+// ErrNoDevicesDir is returned by ReloadDefinitions when the coordinator was
+// created without a devices directory.
+var ErrNoDevicesDir = errors.New("no devices directory configured")
+
+// ReloadDefinitions re-reads the device definition files and merges their
+// custom clusters into the ZCL registry without restarting the network.
+// Per-file errors are part of the result; the previous definitions of a
+// broken file stay in effect. Emits a definitions_reloaded event.
+func (c *Coordinator) ReloadDefinitions() (*ReloadResult, error) {
+	if c.config.DevicesDir == "" {
+		return nil, ErrNoDevicesDir
+	}
+
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	result, err := c.deviceDB.Reload(c.config.DevicesDir, c.registry, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]interface{}, 0, len(result.Errors))
+	for _, fe := range result.Errors {
+		errs = append(errs, map[string]interface{}{"file": fe.File, "error": fe.Error})
+	}
+	c.events.Emit(Event{Type: EventDefinitionsReloaded, Data: map[string]interface{}{
+		"files":    result.Files,
+		"devices":  result.Devices,
+		"clusters": result.Clusters,
+		"decoders": result.Decoders,
+		"errors":   errs,
+	}})
+	return result, nil
+}
+
+// fileStamp identifies one version of a definition file.
+type fileStamp struct {
+	mod  time.Time
+	size int64
+}
+
+// definitionWatcher polls the devices directory and reloads the definitions
+// once a change has settled, so an editor saving in several writes causes a
+// single reload.
+type definitionWatcher struct {
+	coord  *Coordinator
+	logger *slog.Logger
+	dir    string
+
+	last    map[string]fileStamp // state of the last reload
+	pending map[string]fileStamp // changed state waiting to settle
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+	once   sync.Once
+}
+
+func newDefinitionWatcher(c *Coordinator, dir string) *definitionWatcher {
+	w := &definitionWatcher{
+		coord:  c,
+		logger: c.logger.With("component", "definition_watcher"),
+		dir:    dir,
+		stopCh: make(chan struct{}),
+	}
+	w.last = w.scan()
+	return w
+}
+
+func (w *definitionWatcher) start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(definitionWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-w.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (w *definitionWatcher) stop() {
+	w.once.Do(func() { close(w.stopCh) })
+	w.wg.Wait()
+}
+
+// scan returns the modification time and size of every *.json and decoder
+// file.
+func (w *definitionWatcher) scan() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	var matches []string
+	for _, pattern := range append([]string{"*.json"}, decoderFilePatterns()...) {
+		m, _ := filepath.Glob(filepath.Join(w.dir, pattern))
+		matches = append(matches, m...)
+	}
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		stamps[filepath.Base(path)] = fileStamp{mod: info.ModTime(), size: info.Size()}
+	}
+	return stamps
+}
+
+// check compares the directory with the last reload. A change is reloaded
+// on the first check that finds it unchanged since the previous one.
+func (w *definitionWatcher) check() {
+	now := w.scan()
+	if sameStamps(now, w.last) {
+		w.pending = nil
+		return
+	}
+	if w.pending == nil || !sameStamps(now, w.pending) {
+		w.pending = now
+		return
+	}
+
+	w.logger.Info("device definitions changed, reloading")
+	if _, err := w.coord.ReloadDefinitions(); err != nil {
+		w.logger.Error("reload device definitions", "err", err)
+	}
+	w.last = now
+	w.pending = nil
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, s := range a {
+		if o, ok := b[name]; !ok || !o.mod.Equal(s.mod) || o.size != s.size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package coordinator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/zcl"
+)
+
+func TestReloadDefinitions(t *testing.T) {
+	dm, _ := newTestDM(t)
+	c := dm.coord
+	c.deviceDB = NewDeviceDB()
+	c.registry = zcl.NewRegistry(c.logger)
+
+	if _, err := c.ReloadDefinitions(); !errors.Is(err, ErrNoDevicesDir) {
+		t.Fatalf("err = %v, want ErrNoDevicesDir", err)
+	}
+
+	dir := t.TempDir()
+	c.config.DevicesDir = dir
+	os.WriteFile(filepath.Join(dir, "acme.json"), []byte(`{
+		"devices": [{"manufacturer": "Acme", "model": "TH1", "bind": [1026]}]
+	}`), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{`), 0644)
+
+	var got map[string]interface{}
+	c.events.On(EventDefinitionsReloaded, func(e Event) {
+		got = e.Data.(map[string]interface{})
+	})
+
+	result, err := c.ReloadDefinitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Devices != 1 || len(result.Errors) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+	if c.DeviceDB().Lookup("Acme", "TH1") == nil {
+		t.Error("Acme TH1 not loaded")
+	}
+	if got == nil {
+		t.Fatal("no definitions_reloaded event")
+	}
+	if got["devices"] != 1 || got["files"] != 2 {
+		t.Errorf("event = %v", got)
+	}
+	if errs := got["errors"].([]interface{}); len(errs) != 1 {
+		t.Errorf("event errors = %v", errs)
+	}
+}
+
+func TestDefinitionWatcherSettles(t *testing.T) {
+	dm, _ := newTestDM(t)
+	c := dm.coord
+	c.deviceDB = NewDeviceDB()
+	c.registry = zcl.NewRegistry(c.logger)
+	dir := t.TempDir()
+	c.config.DevicesDir = dir
+
+	reloads := 0
+	c.events.On(EventDefinitionsReloaded, func(e Event) { reloads++ })
+
+	w := newDefinitionWatcher(c, dir)
+	w.check()
+	if reloads != 0 {
+		t.Fatalf("reloads = %d on unchanged dir", reloads)
+	}
+
+	path := filepath.Join(dir, "acme.json")
+	os.WriteFile(path, []byte(`{"devices": [`), 0644)
+	w.check()
+	if reloads != 0 {
+		t.Fatal("reloaded before the change settled")
+	}
+
+	// The file is still being written: wait another check.
+	os.WriteFile(path, []byte(`{"devices": [{"manufacturer": "Acme", "model": "TH1", "bind": []}]}`), 0644)
+	os.Chtimes(path, time.Now(), time.Now().Add(time.Second))
+	w.check()
+	if reloads != 0 {
+		t.Fatal("reloaded while the file was changing")
+	}
+
+	w.check()
+	if reloads != 1 {
+		t.Fatalf("reloads = %d, want 1", reloads)
+	}
+	if c.DeviceDB().Lookup("Acme", "TH1") == nil {
+		t.Error("Acme TH1 not loaded")
+	}
+
+	w.check()
+	if reloads != 1 {
+		t.Errorf("reloads = %d after no change, want 1", reloads)
+	}
+}
@@ -2,6 +2,7 @@ package coordinator
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -32,9 +33,11 @@ type DeviceManager struct {
 	coord  *Coordinator
 	logger *slog.Logger
 
-	// Interview cancellation: tracks active interview cancel funcs by IEEE.
+	// Interview cancellation: tracks active interview cancel funcs by IEEE,
+	// and when interviews were last started, to pace resumes.
 	interviewMu      sync.Mutex
 	interviewCancels map[string]interviewEntry
+	interviewStarted map[string]time.Time
 	interviewGen     atomic.Uint64
 	interviewWg      sync.WaitGroup
 
@@ -45,6 +48,23 @@ type DeviceManager struct {
 	// In-memory short address -> IEEE index for fast lookup.
 	addrMu    sync.RWMutex
 	addrIndex map[uint16]string
+
+	// Serializes IAS zone ID allocation.
+	zoneMu sync.Mutex
+
+	// Commands held for Poll Control devices until their next check-in,
+	// and devices currently in a fast poll window.
+	pollMu    sync.Mutex
+	pollQueue map[string][]queuedCommand
+	fastPoll  map[string]bool
+
+	// Tuya MCU transaction sequence numbers.
+	tuyaSeq atomic.Uint32
+
+	// Attribute reports held back by the throttle options.
+	throttleMu      sync.Mutex
+	throttle        map[throttleKey]*throttleSlot
+	throttleStopped bool
 }
 
 // NewDeviceManager creates a new device manager.
@@ -53,8 +73,12 @@ func NewDeviceManager(coord *Coordinator) *DeviceManager {
 		coord:            coord,
 		logger:           coord.logger.With("component", "device_manager"),
 		interviewCancels: make(map[string]interviewEntry),
+		interviewStarted: make(map[string]time.Time),
 		lastJoin:         make(map[string]time.Time),
 		addrIndex:        make(map[uint16]string),
+		pollQueue:        make(map[string][]queuedCommand),
+		fastPoll:         make(map[string]bool),
+		throttle:         make(map[throttleKey]*throttleSlot),
 	}
 }
 
@@ -188,6 +212,7 @@ func (dm *DeviceManager) HandleLeave(evt ncp.DeviceLeftEvent) {
 		entry.cancel()
 		delete(dm.interviewCancels, ieee)
 	}
+	delete(dm.interviewStarted, ieee)
 	dm.interviewMu.Unlock()
 
 	dm.lastJoinMu.Lock()
@@ -237,6 +262,12 @@ func (dm *DeviceManager) HandleAnnounce(evt ncp.DeviceAnnounceEvent) {
 	}
 	dev.ShortAddress = evt.ShortAddr
 	dev.LastSeen = time.Now()
+	// MAC capability bit 2: mains powered.
+	if evt.Capability&0x04 != 0 {
+		dev.PowerSource = PowerSourceMains
+	} else {
+		dev.PowerSource = PowerSourceBattery
+	}
 
 	if err := dm.coord.Store().SaveDevice(dev); err != nil {
 		dm.logger.Error("save device on announce", "err", err)
@@ -285,6 +316,10 @@ func (dm *DeviceManager) HandleAnnounce(evt ncp.DeviceAnnounceEvent) {
 	}
 	dm.lastJoinMu.Unlock()
 
+	dm.interviewMu.Lock()
+	dm.interviewStarted[ieee] = time.Now()
+	dm.interviewMu.Unlock()
+
 	dm.interviewWg.Add(1)
 	go dm.Interview(ieee)
 }
@@ -325,7 +360,8 @@ func (dm *DeviceManager) lookupOrRebuild(shortAddr uint16) string {
 	return ieee
 }
 
-// HandleAttributeReport processes an attribute report event.
+// HandleAttributeReport processes an attribute report event, unless the
+// throttle options of the device hold it back.
 func (dm *DeviceManager) HandleAttributeReport(evt ncp.AttributeReportEvent) {
 	ieee := dm.lookupOrRebuild(evt.SrcAddr)
 
@@ -339,48 +375,66 @@ func (dm *DeviceManager) HandleAttributeReport(evt ncp.AttributeReportEvent) {
 		}
 	}
 
-	clusterName := fmt.Sprintf("0x%04X", evt.ClusterID)
-	attrName := fmt.Sprintf("0x%04X", evt.AttrID)
-	if cluster := dm.coord.Registry().Get(evt.ClusterID); cluster != nil {
-		clusterName = cluster.Name
-		if attr := cluster.FindAttribute(evt.AttrID); attr != nil {
-			attrName = attr.Name
+	var dev *store.Device
+	if ieee != "" {
+		// Throttled reports still show the device is alive.
+		dev = dm.updateFromReport(ieee, evt, decoded)
+		if !dm.admitReport(ieee, evt, decoded) {
+			return
 		}
 	}
+	dm.processAttributeReport(ieee, dev, evt, decoded)
+}
 
-	var dev *store.Device
-	if ieee != "" {
-		// Atomically update LastSeen/LQI/RSSI and capture Basic cluster attributes.
-		updateErr := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
-			d.LastSeen = time.Now()
-			if evt.LQI > 0 {
-				d.LQI = evt.LQI
-				d.RSSI = evt.RSSI
-			}
-			// Save ManufacturerName / ModelIdentifier from proactive Basic cluster reports.
-			// Sleepy devices (e.g., Xiaomi) may send these before the interview reads them.
-			if evt.ClusterID == 0x0000 {
-				if s, ok := decoded.(string); ok && s != "" {
-					switch evt.AttrID {
-					case 0x0004: // ManufacturerName
-						if d.Manufacturer == "" {
-							d.Manufacturer = s
-						}
-					case 0x0005: // ModelIdentifier
-						if d.Model == "" {
-							d.Model = s
-						}
+// updateFromReport records that the device reported an attribute and
+// returns the updated device, or nil if it cannot be read back.
+func (dm *DeviceManager) updateFromReport(ieee string, evt ncp.AttributeReportEvent, decoded interface{}) *store.Device {
+	// Atomically update LastSeen/LQI/RSSI and capture Basic cluster attributes.
+	updateErr := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		d.LastSeen = time.Now()
+		if evt.LQI > 0 {
+			d.LQI = evt.LQI
+			d.RSSI = evt.RSSI
+		}
+		// Save ManufacturerName / ModelIdentifier from proactive Basic cluster reports.
+		// Sleepy devices (e.g., Xiaomi) may send these before the interview reads them.
+		if evt.ClusterID == 0x0000 {
+			if s, ok := decoded.(string); ok && s != "" {
+				switch evt.AttrID {
+				case 0x0004: // ManufacturerName
+					if d.Manufacturer == "" {
+						d.Manufacturer = s
+					}
+				case 0x0005: // ModelIdentifier
+					if d.Model == "" {
+						d.Model = s
 					}
 				}
 			}
-			return nil
-		})
-		if updateErr != nil {
-			dm.logger.Error("update device on attr report", "err", updateErr, "ieee", ieee)
 		}
-		// Read back for event emission and property processing.
-		if d, err := dm.coord.Store().GetDevice(ieee); err == nil {
-			dev = d
+		return nil
+	})
+	if updateErr != nil {
+		dm.logger.Error("update device on attr report", "err", updateErr, "ieee", ieee)
+	}
+	// Read back for event emission and property processing.
+	dev, err := dm.coord.Store().GetDevice(ieee)
+	if err != nil {
+		return nil
+	}
+	dm.resumeInterview(dev)
+	return dev
+}
+
+// processAttributeReport emits the events and properties of a decoded
+// attribute report. dev is the reporting device, nil if unknown.
+func (dm *DeviceManager) processAttributeReport(ieee string, dev *store.Device, evt ncp.AttributeReportEvent, decoded interface{}) {
+	clusterName := fmt.Sprintf("0x%04X", evt.ClusterID)
+	attrName := fmt.Sprintf("0x%04X", evt.AttrID)
+	if cluster := dm.coord.Registry().Get(evt.ClusterID); cluster != nil {
+		clusterName = cluster.Name
+		if attr := cluster.FindAttribute(evt.AttrID); attr != nil {
+			attrName = attr.Name
 		}
 	}
 
@@ -406,226 +460,103 @@ func (dm *DeviceManager) HandleAttributeReport(evt ncp.AttributeReportEvent) {
 		},
 	})
 
-	// Emit property_update for well-known ZCL attributes so Blockly
-	// automations can trigger on standard properties like on_off, temperature, etc.
-	dm.emitStandardProperty(ieee, dev, evt, decoded)
+	// Convert standard attributes into normalized properties (on_off,
+	// temperature, alarm1, ...) shared by automations, MQTT and the web UI.
+	dm.applyConverter(ieee, dev, evt.ClusterID, evt.AttrID, decoded)
 
 	dm.processProperties(ieee, dev, evt, decoded)
+	dm.processAttributeAction(ieee, dev, evt, decoded)
 }
 
-// standardPropertyMap maps well-known ZCL cluster+attribute pairs to property names.
-// These are used to emit property_update events for common attributes so that
-// Blockly automations can trigger on simple property names like "on_off".
-var standardPropertyMap = map[uint16]map[uint16]string{
-	0x0006: {0x0000: "on_off"},                      // On/Off → OnOff
-	0x0008: {0x0000: "brightness"},                   // Level Control → CurrentLevel
-	0x0300: {0x0000: "hue", 0x0001: "saturation"},   // Color Control
-	0x0402: {0x0000: "temperature"},                  // Temperature Measurement
-	0x0403: {0x0000: "pressure"},                     // Pressure Measurement
-	0x0405: {0x0000: "humidity"},                     // Relative Humidity
-	0x0406: {0x0000: "occupancy"},                    // Occupancy Sensing
-	0x0400: {0x0000: "illuminance"},                  // Illuminance Measurement
-	0x0001: {0x0021: "battery"},                      // Power Configuration → BatteryPercentage
-	0x0500: {0x0002: "zone_status"},                  // IAS Zone
-	0x0B04: {0x050B: "power"},                        // Electrical Measurement → ActivePower
-	0x0702: {0x0000: "energy"},                       // Metering → CurrentSummation
-	0x000C: {0x0055: "analog_value"},                 // Analog Input → PresentValue
-	0x0012: {0x0055: "multistate_value"},             // Multistate Input → PresentValue
+// Interview queries a device for its endpoints and descriptors.
+// Retries up to 3 times, re-reading the device from store each time
+// to pick up any short address changes from re-joins.
+// Progress is persisted after every step: an unfinished interview resumes
+// from the failed step, a finished one starts over.
+func (dm *DeviceManager) Interview(ieee string) {
+	dm.interview(ieee, false)
 }
 
-// emitStandardProperty emits a property_update event for well-known ZCL attributes.
-func (dm *DeviceManager) emitStandardProperty(ieee string, dev *store.Device, evt ncp.AttributeReportEvent, decoded interface{}) {
-	if ieee == "" || decoded == nil {
-		return
-	}
+func (dm *DeviceManager) interview(ieee string, restart bool) {
+	defer dm.interviewWg.Done()
 
-	attrs, ok := standardPropertyMap[evt.ClusterID]
-	if !ok {
-		return
-	}
-	propName, ok := attrs[evt.AttrID]
-	if !ok {
-		return
-	}
+	// Cancels any previous interview for this device.
+	ctx, done := dm.trackInterview(ieee)
+	defer done()
 
-	// Store the property value on the device atomically.
-	if dev != nil {
-		if updateErr := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
-			if d.Properties == nil {
-				d.Properties = make(map[string]any)
-			}
-			d.Properties[propName] = decoded
-			return nil
-		}); updateErr != nil {
-			dm.logger.Error("save standard property", "err", updateErr, "ieee", ieee)
-		}
+	op := map[string]interface{}{"operation": "interview"}
+	st, resumed, err := dm.beginInterview(ieee, restart)
+	if err != nil {
+		dm.logger.Error("interview: device not found", "ieee", ieee)
+		dm.progress(ieee, StepComplete, err, op)
+		return
 	}
-
 	dm.coord.Events().Emit(Event{
-		Type: EventPropertyUpdate,
+		Type: EventInterviewStarted,
 		Data: map[string]interface{}{
-			"ieee":     ieee,
-			"property": propName,
-			"value":    decoded,
+			"ieee":    ieee,
+			"stage":   st.Stage,
+			"run":     st.Runs,
+			"resumed": resumed,
 		},
 	})
-}
-
-// Interview queries a device for its endpoints and descriptors.
-// Retries up to 3 times, re-reading the device from store each time
-// to pick up any short address changes from re-joins.
-func (dm *DeviceManager) Interview(ieee string) {
-	gen := dm.interviewGen.Add(1)
-
-	defer func() {
-		dm.interviewMu.Lock()
-		if entry, ok := dm.interviewCancels[ieee]; ok && entry.gen == gen {
-			delete(dm.interviewCancels, ieee)
-		}
-		dm.interviewMu.Unlock()
-		dm.interviewWg.Done()
-	}()
-
-	ctx, cancel := context.WithTimeout(dm.coord.Context(), interviewTimeout)
-	defer cancel()
-
-	dm.interviewMu.Lock()
-	// Cancel any previous interview for this device.
-	if prev, ok := dm.interviewCancels[ieee]; ok {
-		prev.cancel()
-	}
-	dm.interviewCancels[ieee] = interviewEntry{cancel: cancel, gen: gen}
-	dm.interviewMu.Unlock()
+	dm.progressStatus(ieee, StepStart, ProgressStarted, map[string]interface{}{"operation": "interview", "resumed": resumed})
 
 	const maxRetries = 3
+	var lastErr error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		// Re-read device from store each attempt to get latest short address.
 		dev, err := dm.coord.Store().GetDevice(ieee)
 		if err != nil {
 			dm.logger.Error("interview: device not found", "ieee", ieee)
+			dm.progress(ieee, StepComplete, err, op)
 			return
 		}
 
-		name := deviceName(dev)
-		dm.logger.Info("starting interview", "ieee", ieee, "name", name,
-			"short", fmt.Sprintf("0x%04X", dev.ShortAddress), "attempt", attempt)
+		dm.logger.Info("starting interview", "ieee", ieee, "name", deviceName(dev),
+			"short", fmt.Sprintf("0x%04X", dev.ShortAddress), "attempt", attempt,
+			"stage", st.Stage, "run", st.Runs)
 
-		endpoints, err := dm.coord.NCP().ActiveEndpoints(ctx, dev.ShortAddress)
-		if err != nil {
-			dm.logger.Warn("interview: active EP failed", "err", err, "ieee", ieee, "name", name, "attempt", attempt)
-			if ctx.Err() != nil {
-				return // Overall context expired, give up.
-			}
-			if attempt < maxRetries {
-				jitter := time.Duration(rand.IntN(3001)) * time.Millisecond
-				delay := interviewRetryDelay + jitter
-				dm.logger.Info("interview: will retry", "ieee", ieee, "delay", delay)
-				select {
-				case <-time.After(delay):
-				case <-ctx.Done():
-					return
-				}
-			}
-			continue
-		}
-
-		// Read model/manufacturer early so we have the real name for logging.
-		if len(endpoints) > 0 {
-			dm.readBasicAttributes(ctx, dev, endpoints[0])
+		err = dm.interviewSteps(ctx, dev, st)
+		if err == nil {
+			dm.completeInterview(ctx, dev, st)
+			dm.progress(ieee, StepComplete, nil, op)
+			return
 		}
-
-		// Look up device definition by manufacturer+model.
-		var def *DeviceDefinition
-		if db := dm.coord.DeviceDB(); db != nil {
-			def = db.Lookup(dev.Manufacturer, dev.Model)
+		lastErr = err
+		dm.logger.Warn("interview: step failed", "err", err, "ieee", ieee, "name", deviceName(dev),
+			"stage", st.Stage, "attempt", attempt)
+		if ctx.Err() != nil || attempt == maxRetries {
+			break // Overall context expired or cancelled, give up.
 		}
-
-		// Set friendly name: prefer definition, fall back to model.
-		if def != nil && def.FriendlyName != "" {
-			dev.FriendlyName = def.FriendlyName
-		} else if dev.FriendlyName == "" && dev.Model != "" {
-			dev.FriendlyName = dev.Model
+		jitter := time.Duration(rand.IntN(3001)) * time.Millisecond
+		delay := interviewRetryDelay + jitter
+		dm.logger.Info("interview: will retry", "ieee", ieee, "delay", delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			lastErr = ctx.Err()
 		}
-		name = deviceName(dev)
-
-		dev.Endpoints = make([]store.Endpoint, 0, len(endpoints))
-
-		for _, ep := range endpoints {
-			sd, err := dm.coord.NCP().SimpleDescriptor(ctx, dev.ShortAddress, ep)
-			if err != nil {
-				dm.logger.Warn("interview: simple desc", "err", err, "ieee", ieee, "name", name, "ep", ep)
-				continue
-			}
-
-			endpoint := store.Endpoint{
-				ID:          ep,
-				ProfileID:   sd.ProfileID,
-				DeviceID:    sd.DeviceID,
-				InClusters:  sd.InClusters,
-				OutClusters: sd.OutClusters,
-			}
-			dev.Endpoints = append(dev.Endpoints, endpoint)
-
-			dm.logger.Info("endpoint discovered",
-				"ieee", ieee, "name", name, "ep", ep,
-				"profile", fmt.Sprintf("0x%04X", sd.ProfileID),
-				"device", fmt.Sprintf("0x%04X", sd.DeviceID),
-				"in_clusters", len(sd.InClusters),
-				"out_clusters", len(sd.OutClusters),
-			)
-		}
-
-		// Configure bindings immediately while the device is still awake.
-		if def != nil {
-			dm.configureDevice(ctx, dev, def)
-		} else {
-			dm.logger.Info("no device definition found, skipping configure",
-				"ieee", ieee, "name", name,
-				"manufacturer", dev.Manufacturer, "model", dev.Model)
-		}
-
-		// Check if context was cancelled (e.g., by HandleLeave) before saving,
-		// to prevent resurrecting a deleted device.
 		if ctx.Err() != nil {
-			dm.logger.Info("interview: cancelled before save", "ieee", ieee, "name", name)
-			return
-		}
-
-		// Use UpdateDevice (atomic merge) instead of SaveDevice to avoid
-		// overwriting concurrent updates from HandleAttributeReport.
-		finalEndpoints := dev.Endpoints
-		finalInterviewed := true
-		finalFriendlyName := dev.FriendlyName
-		finalManufacturer := dev.Manufacturer
-		finalModel := dev.Model
-		if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
-			d.Endpoints = finalEndpoints
-			d.Interviewed = finalInterviewed
-			d.FriendlyName = finalFriendlyName
-			if finalManufacturer != "" {
-				d.Manufacturer = finalManufacturer
-			}
-			if finalModel != "" {
-				d.Model = finalModel
-			}
-			return nil
-		}); err != nil {
-			dm.logger.Error("interview: save", "err", err, "ieee", ieee, "name", name)
+			break
 		}
-		dm.logger.Info("interview complete", "ieee", ieee, "name", name, "endpoints", len(finalEndpoints))
-		return
 	}
 
-	dm.logger.Error("interview failed after retries", "ieee", ieee, "attempts", maxRetries)
+	dm.failInterview(ctx, ieee, st, lastErr)
+	dm.progress(ieee, StepComplete, lastErr, op)
 }
 
-func (dm *DeviceManager) readBasicAttributes(ctx context.Context, dev *store.Device, ep uint8) {
+// readBasicAttributes reads ManufacturerName and ModelIdentifier into dev.
+// The read error is returned only if neither a report nor the store
+// supplied them either.
+func (dm *DeviceManager) readBasicAttributes(ctx context.Context, dev *store.Device, ep uint8) error {
 	results, err := dm.coord.NCP().ReadAttributes(ctx, ncp.ReadAttributesRequest{
 		DstAddr:   dev.ShortAddress,
 		DstEP:     ep,
 		ClusterID: 0x0000,
 		AttrIDs:   []uint16{0x0004, 0x0005},
 	})
+	dm.progress(dev.IEEEAddress, StepBasicAttributes, err, map[string]interface{}{"endpoint": ep})
 	if err != nil {
 		dm.logger.Warn("read basic attributes", "err", err)
 	} else {
@@ -662,13 +593,19 @@ func (dm *DeviceManager) readBasicAttributes(ctx context.Context, dev *store.Dev
 			}
 		}
 	}
+	if err != nil && dev.Manufacturer == "" && dev.Model == "" {
+		return err
+	}
+	return nil
 }
 
 // configureDevice binds clusters and sets up reporting based on a device definition.
 // Must be called right after interview while the device is still awake.
-func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device, def *DeviceDefinition) {
+// With results, steps already recorded as done are skipped and outcomes are
+// recorded. Returns the number of failed bind and reporting requests.
+func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device, def *DeviceDefinition, results map[string]string) int {
 	if len(dev.Endpoints) == 0 {
-		return
+		return 0
 	}
 	name := deviceName(dev)
 	coordIEEE := dm.coord.LocalIEEE()
@@ -676,9 +613,10 @@ func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device,
 	devIEEE, err := ParseIEEE(dev.IEEEAddress)
 	if err != nil {
 		dm.logger.Warn("configure: parse device IEEE", "err", err)
-		return
+		return 1
 	}
 
+	failures := 0
 	// Bind and configure reporting across all endpoints.
 	for _, ep := range dev.Endpoints {
 		// Bind clusters listed in the device definition (only if present as OUT clusters).
@@ -686,6 +624,10 @@ func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device,
 			if !hasOutCluster(ep, cluster) {
 				continue
 			}
+			key := fmt.Sprintf("%s/%d/0x%04X", StepBind, ep.ID, cluster)
+			if results[key] == ProgressOK {
+				continue
+			}
 			err := dm.coord.NCP().Bind(ctx, ncp.BindRequest{
 				TargetShortAddr: dev.ShortAddress,
 				SrcIEEE:         devIEEE,
@@ -694,7 +636,10 @@ func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device,
 				DstIEEE:         coordIEEE,
 				DstEP:           1,
 			})
+			dm.progress(dev.IEEEAddress, StepBind, err, map[string]interface{}{"endpoint": ep.ID, "cluster": cluster})
+			recordResult(results, key, err)
 			if err != nil {
+				failures++
 				dm.logger.Warn("configure: bind", "err", err, "name", name, "ep", ep.ID, "cluster", fmt.Sprintf("0x%04X", cluster))
 			} else {
 				dm.logger.Info("bound cluster", "name", name, "ep", ep.ID, "cluster", fmt.Sprintf("0x%04X", cluster))
@@ -706,6 +651,10 @@ func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device,
 			if !hasInCluster(ep, r.Cluster) {
 				continue
 			}
+			key := fmt.Sprintf("%s/%d/0x%04X/0x%04X", StepReporting, ep.ID, r.Cluster, r.Attribute)
+			if results[key] == ProgressOK {
+				continue
+			}
 			change := encodeReportChange(r.Type, r.Change)
 			err := dm.coord.NCP().ConfigureReporting(ctx, ncp.ConfigureReportingRequest{
 				DstAddr:      dev.ShortAddress,
@@ -717,7 +666,14 @@ func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device,
 				MaxInterval:  r.Max,
 				ReportChange: change,
 			})
+			dm.progress(dev.IEEEAddress, StepReporting, err, map[string]interface{}{
+				"endpoint":  ep.ID,
+				"cluster":   r.Cluster,
+				"attribute": r.Attribute,
+			})
+			recordResult(results, key, err)
 			if err != nil {
+				failures++
 				dm.logger.Warn("configure: reporting", "err", err, "name", name,
 					"ep", ep.ID,
 					"cluster", fmt.Sprintf("0x%04X", r.Cluster),
@@ -730,6 +686,7 @@ func (dm *DeviceManager) configureDevice(ctx context.Context, dev *store.Device,
 			}
 		}
 	}
+	return failures
 }
 
 // encodeReportChange encodes the reportable change value based on ZCL data type size (little-endian).
@@ -823,6 +780,7 @@ func (dm *DeviceManager) RemoveDevice(ieee string) error {
 		entry.cancel()
 		delete(dm.interviewCancels, ieee)
 	}
+	delete(dm.interviewStarted, ieee)
 	dm.interviewMu.Unlock()
 
 	// Send ZDO Mgmt Leave to remove the device from the network.
@@ -893,6 +851,7 @@ func (dm *DeviceManager) HandleClusterCommand(evt ncp.ClusterCommandEvent) {
 		// Read back for event emission and property processing.
 		if d, err := dm.coord.Store().GetDevice(ieee); err == nil {
 			dev = d
+			dm.resumeInterview(dev)
 		}
 	}
 
@@ -916,7 +875,30 @@ func (dm *DeviceManager) HandleClusterCommand(evt ncp.ClusterCommandEvent) {
 		},
 	})
 
+	// IAS Zone Status Change Notification: zone_status(2) + extended_status(1) + zone_id(1) + delay(2).
+	if evt.ClusterID == 0x0500 && evt.CommandID == 0x00 && evt.ServerToClient && len(evt.Payload) >= 2 {
+		dm.handleZoneStatus(ieee, dev, binary.LittleEndian.Uint16(evt.Payload[0:2]))
+	}
+
 	dm.processClusterCommandProperties(ieee, dev, evt)
+	dm.processCommandAction(ieee, dev, evt)
+}
+
+// wantsDefaultResponse reports whether frames from a device should be
+// acknowledged with a Default Response. Unknown devices are acknowledged.
+func (dm *DeviceManager) wantsDefaultResponse(shortAddr uint16) bool {
+	ieee := dm.lookupIEEE(shortAddr)
+	if ieee == "" {
+		return true
+	}
+	dev, err := dm.coord.Store().GetDevice(ieee)
+	if err != nil {
+		return true
+	}
+	if def := dm.definition(dev); def != nil && def.DefaultResponse != nil {
+		return *def.DefaultResponse
+	}
+	return true
 }
 
 // SaveDevice persists a device to the store.
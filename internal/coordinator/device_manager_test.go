@@ -1,19 +1,28 @@
 package coordinator
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"zigbee-go-home/internal/ncp"
 	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
 )
 
-// memStore is a minimal in-memory store for device manager tests.
+// memStore is a minimal in-memory store for device manager tests. Like the
+// bolt store, reads return a copy of the device, so background interviews
+// and frame handlers do not share one struct.
 type memStore struct {
+	mu      sync.Mutex
 	devices map[string]*store.Device
 	netState *store.NetworkState
+	alarm    *store.AlarmPanelState
 }
 
 func newMemStore() *memStore {
@@ -21,35 +30,49 @@ func newMemStore() *memStore {
 }
 
 func (m *memStore) SaveDevice(dev *store.Device) error {
-	m.devices[dev.IEEEAddress] = dev
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *dev
+	m.devices[dev.IEEEAddress] = &cp
 	return nil
 }
 func (m *memStore) GetDevice(ieee string) (*store.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	d, ok := m.devices[ieee]
 	if !ok {
 		return nil, store.ErrNotFound
 	}
-	return d, nil
+	cp := *d
+	return &cp, nil
 }
 func (m *memStore) UpdateDevice(ieee string, fn func(dev *store.Device) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	d, ok := m.devices[ieee]
 	if !ok {
 		return store.ErrNotFound
 	}
-	if err := fn(d); err != nil {
+	cp := *d
+	if err := fn(&cp); err != nil {
 		return err
 	}
-	m.devices[ieee] = d
+	m.devices[ieee] = &cp
 	return nil
 }
 func (m *memStore) DeleteDevice(ieee string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	delete(m.devices, ieee)
 	return nil
 }
 func (m *memStore) ListDevices() ([]*store.Device, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	list := make([]*store.Device, 0, len(m.devices))
 	for _, d := range m.devices {
-		list = append(list, d)
+		cp := *d
+		list = append(list, &cp)
 	}
 	return list, nil
 }
@@ -63,22 +86,215 @@ func (m *memStore) GetNetworkState() (*store.NetworkState, error) {
 	}
 	return m.netState, nil
 }
+func (m *memStore) SaveAlarmPanel(s *store.AlarmPanelState) error {
+	cp := *s
+	m.alarm = &cp
+	return nil
+}
+func (m *memStore) GetAlarmPanel() (*store.AlarmPanelState, error) {
+	if m.alarm == nil {
+		return nil, store.ErrNotFound
+	}
+	cp := *m.alarm
+	return &cp, nil
+}
 func (m *memStore) Close() error { return nil }
 
+// fakeNCP is the NCP of coordinator tests. It records every request and
+// answers with success and no data, unless a test sets the answers below.
+// The answer funcs run with mu held, so tests change what they use under
+// mu too. Frames sent with SendAPS are answered from apsFrames; with none
+// matching, SendAPS waits for the context like a real NCP.
+type fakeNCP struct {
+	mu sync.Mutex
+
+	reads       []ncp.ReadAttributesRequest
+	writes      []ncp.WriteAttributesRequest
+	commands    []ncp.ClusterCommandRequest
+	frames      []ncp.ZCLFrameRequest
+	aps         []ncp.APSFrameRequest
+	binds       []ncp.BindRequest
+	reporting   []ncp.ConfigureReportingRequest
+	descriptors []uint8 // endpoints of SimpleDescriptor requests
+	activeEPs   int     // ActiveEndpoints requests
+
+	readErr     error
+	sendErr     error
+	onRead      func(ncp.ReadAttributesRequest) []ncp.AttributeResponse
+	onCommand   func(ncp.ClusterCommandRequest)
+	onReporting func(ncp.ConfigureReportingRequest) error
+	endpoints   []uint8
+	onDescribe  func(ep uint8) (*ncp.SimpleDescriptor, error)
+	apsFrames   []ncp.APSFrame
+	stats       ncp.Stats
+}
+
+func (n *fakeNCP) Reset(context.Context) error                          { return nil }
+func (n *fakeNCP) FactoryReset(context.Context) error                   { return nil }
+func (n *fakeNCP) Init(context.Context) error                           { return nil }
+func (n *fakeNCP) FormNetwork(context.Context, ncp.NetworkConfig) error { return nil }
+func (n *fakeNCP) StartNetwork(context.Context) error                   { return nil }
+func (n *fakeNCP) PermitJoin(context.Context, uint8) error              { return nil }
+func (n *fakeNCP) NetworkInfo(context.Context) (*ncp.NetworkInfo, error) {
+	return &ncp.NetworkInfo{}, nil
+}
+func (n *fakeNCP) NetworkScan(context.Context) ([]ncp.NetworkScanResult, error) { return nil, nil }
+func (n *fakeNCP) GetLocalIEEE(context.Context) ([8]byte, error)                { return [8]byte{}, nil }
+func (n *fakeNCP) Unbind(context.Context, ncp.BindRequest) error                { return nil }
+func (n *fakeNCP) MgmtLeave(context.Context, uint16, [8]byte) error             { return nil }
+func (n *fakeNCP) RegisterLocalEndpoint(ncp.SimpleDescriptor)                   {}
+func (n *fakeNCP) OnDeviceJoined(func(ncp.DeviceJoinedEvent))                   {}
+func (n *fakeNCP) OnDeviceLeft(func(ncp.DeviceLeftEvent))                       {}
+func (n *fakeNCP) OnDeviceAnnounce(func(ncp.DeviceAnnounceEvent))               {}
+func (n *fakeNCP) OnAttributeReport(func(ncp.AttributeReportEvent))             {}
+func (n *fakeNCP) OnClusterCommand(func(ncp.ClusterCommandEvent))               {}
+func (n *fakeNCP) OnNwkAddrUpdate(func(uint16))                                 {}
+func (n *fakeNCP) OnGlobalCommand(func(ncp.GlobalCommandEvent))                 {}
+func (n *fakeNCP) SetDefaultResponsePolicy(func(ncp.DefaultResponseInfo) bool)  {}
+func (n *fakeNCP) GetNCPInfo() *ncp.NCPInfo                                     { return &ncp.NCPInfo{} }
+func (n *fakeNCP) Close() error                                                 { return nil }
+
+func (n *fakeNCP) ActiveEndpoints(context.Context, uint16) ([]uint8, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.activeEPs++
+	return n.endpoints, nil
+}
+
+func (n *fakeNCP) SimpleDescriptor(_ context.Context, _ uint16, ep uint8) (*ncp.SimpleDescriptor, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.descriptors = append(n.descriptors, ep)
+	if n.onDescribe != nil {
+		return n.onDescribe(ep)
+	}
+	return &ncp.SimpleDescriptor{Endpoint: ep, ProfileID: 0x0104}, nil
+}
+
+func (n *fakeNCP) Bind(_ context.Context, req ncp.BindRequest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.binds = append(n.binds, req)
+	return nil
+}
+
+func (n *fakeNCP) ReadAttributes(_ context.Context, req ncp.ReadAttributesRequest) ([]ncp.AttributeResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reads = append(n.reads, req)
+	if n.readErr != nil {
+		return nil, n.readErr
+	}
+	if n.onRead != nil {
+		return n.onRead(req), nil
+	}
+	return nil, nil
+}
+
+func (n *fakeNCP) WriteAttributes(_ context.Context, req ncp.WriteAttributesRequest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.writes = append(n.writes, req)
+	return nil
+}
+
+func (n *fakeNCP) SendCommand(_ context.Context, req ncp.ClusterCommandRequest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.commands = append(n.commands, req)
+	if n.onCommand != nil {
+		n.onCommand(req)
+	}
+	return n.sendErr
+}
+
+func (n *fakeNCP) ConfigureReporting(_ context.Context, req ncp.ConfigureReportingRequest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.reporting = append(n.reporting, req)
+	if n.onReporting != nil {
+		return n.onReporting(req)
+	}
+	return nil
+}
+
+func (n *fakeNCP) SendZCLFrame(_ context.Context, req ncp.ZCLFrameRequest) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.frames = append(n.frames, req)
+	return nil
+}
+
+func (n *fakeNCP) SendAPS(ctx context.Context, req ncp.APSFrameRequest, match func(ncp.APSFrame) bool) (*ncp.APSFrame, error) {
+	n.mu.Lock()
+	n.aps = append(n.aps, req)
+	frames := n.apsFrames
+	n.mu.Unlock()
+	if match == nil {
+		return nil, nil
+	}
+	for _, f := range frames {
+		if match(f) {
+			return &f, nil
+		}
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (n *fakeNCP) Stats() ncp.Stats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats
+}
+
+// readCount returns the number of Read Attributes requests.
+func (n *fakeNCP) readCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.reads)
+}
+
+// locked runs fn with mu held, to change the answers while requests run.
+func (n *fakeNCP) locked(fn func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	fn()
+}
+
 func newTestDM(t *testing.T) (*DeviceManager, *memStore) {
 	t.Helper()
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
 	ms := newMemStore()
 	events := NewEventBus(logger)
+	registry := zcl.NewRegistry(logger)
+	for _, c := range []zcl.ClusterDef{clusters.OnOff, clusters.TemperatureMeasurement, clusters.IASZone} {
+		registry.Register(c)
+	}
 	coord := &Coordinator{
 		store:    ms,
 		events:   events,
 		logger:   logger,
+		registry: registry,
 	}
 	dm := NewDeviceManager(coord)
 	return dm, ms
 }
 
+// newTestCoord returns a coordinator with a device manager, talking to a
+// fakeNCP.
+func newTestCoord(t *testing.T) (*Coordinator, *memStore, *fakeNCP) {
+	t.Helper()
+	dm, ms := newTestDM(t)
+	n := &fakeNCP{}
+	c := dm.coord
+	c.devices = dm
+	c.ncp = n
+	c.ctx = context.Background()
+	c.deviceDB = NewDeviceDB()
+	return c, ms, n
+}
+
 func TestAddrIndexUpdateAndLookup(t *testing.T) {
 	dm, _ := newTestDM(t)
 
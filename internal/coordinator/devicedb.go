@@ -2,43 +2,81 @@ package coordinator
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
 
 	"zigbee-go-home/internal/zcl"
 )
 
-// PropertySource describes a proprietary attribute that contains multiple sub-values.
+// PropertySource describes a proprietary attribute or cluster command that
+// contains multiple sub-values. Commands overrides the cluster commands the
+// decoder reads by default.
 type PropertySource struct {
 	Cluster   uint16        `json:"cluster"`
 	Attribute uint16        `json:"attribute"`
-	Decoder   string        `json:"decoder"` // "xiaomi_tlv"
+	Commands  []uint8       `json:"commands,omitempty"`
+	Decoder   string        `json:"decoder"` // "xiaomi_tlv", "tuya_dp" or a decoder file
 	Values    []PropertyDef `json:"values"`
 }
 
-// PropertyDef describes a single named property extracted from a decoded attribute.
+// PropertyDef describes a single named property extracted from a decoded
+// attribute, selected by tag or, for named decoder values, by key.
+// ReverseTransform undoes Transform when a writable property is set.
 type PropertyDef struct {
-	Tag       int    `json:"tag"`
-	Name      string `json:"name"`
-	Transform string `json:"transform,omitempty"`
+	Tag              int    `json:"tag"`
+	Key              string `json:"key,omitempty"`
+	Name             string `json:"name"`
+	Transform        string `json:"transform,omitempty"`
+	ReverseTransform string `json:"reverse,omitempty"`
+	Type             string `json:"type,omitempty"` // tuya_dp DP type, makes the property writable
 }
 
 // ManufacturerGroup groups device models under one manufacturer name.
+// Aliases are added to the manufacturer aliases of every model.
 type ManufacturerGroup struct {
-	Name   string             `json:"name"`
-	Models []DeviceDefinition `json:"models"`
+	Name    string             `json:"name"`
+	Aliases []string           `json:"aliases,omitempty"`
+	Models  []DeviceDefinition `json:"models"`
 }
 
 // DeviceDefinition describes how to configure a specific device model.
 type DeviceDefinition struct {
-	Manufacturer string           `json:"manufacturer"`
-	Model        string           `json:"model"`
-	FriendlyName string           `json:"friendly_name,omitempty"`
-	Bind         []uint16         `json:"bind"`
-	Reporting    []ReportingEntry `json:"reporting,omitempty"`
-	Properties   []PropertySource `json:"properties,omitempty"`
+	Manufacturer    string               `json:"manufacturer"`
+	Model           string               `json:"model"`
+	FriendlyName    string               `json:"friendly_name,omitempty"`
+	Bind            []uint16             `json:"bind"`
+	Reporting       []ReportingEntry     `json:"reporting,omitempty"`
+	Properties      []PropertySource     `json:"properties,omitempty"`
+	Converters      []AttributeConverter `json:"converters,omitempty"`
+	PollControl     *PollControlConfig   `json:"poll_control,omitempty"`
+	DefaultResponse *bool                `json:"default_response,omitempty"` // nil: acknowledge
+	Availability    *AvailabilityDef     `json:"availability,omitempty"`
+	Options         map[string]float64   `json:"options,omitempty"` // default device options, see options.go
+	Actions         []ActionDef          `json:"actions,omitempty"` // named presses of remotes, see actions.go
+	Poll            []PollEntry          `json:"poll,omitempty"`    // attributes read periodically, see poller.go
+
+	// Extend names templates or other models to inherit from. Override
+	// lists inherited lists (bind, reporting, properties, converters,
+	// actions, poll) to replace rather than append to; Remove drops
+	// inherited entries.
+	Extend   extendList        `json:"extend,omitempty"`
+	Override []string          `json:"override,omitempty"`
+	Remove   *DefinitionRemove `json:"remove,omitempty"`
+
+	// ManufacturerAliases are other manufacturer strings (or * and ?
+	// patterns) shipped for the same model. Fingerprints match devices
+	// whose strings vary more than that. Priority breaks ties between
+	// matching definitions; higher wins.
+	ManufacturerAliases []string      `json:"manufacturer_aliases,omitempty"`
+	Fingerprints        []Fingerprint `json:"fingerprints,omitempty"`
+	Priority            int           `json:"priority,omitempty"`
+
+	Source string `json:"-"` // file the definition was loaded from
 }
 
 // ReportingEntry specifies attribute reporting configuration for a cluster.
@@ -51,9 +89,17 @@ type ReportingEntry struct {
 	Change    int    `json:"change"`
 }
 
-// DeviceDB holds device definitions keyed by manufacturer+model.
+// DeviceDB holds device definitions, indexed by manufacturer+model and
+// matched by fingerprint. It is safe for concurrent use; Reload swaps the
+// whole set at once.
 type DeviceDB struct {
-	defs map[string]*DeviceDefinition
+	mu    sync.RWMutex
+	defs  []*DeviceDefinition          // in load order
+	exact map[string]*DeviceDefinition // manufacturer+model -> definition
+	fuzzy []*DeviceDefinition          // definitions with aliases or fingerprints
+
+	templates map[string]*DeviceDefinition // unresolved, kept for reloads
+	decoders  map[string]*PropertyDecoder  // loaded from decoder files
 }
 
 func deviceKey(manufacturer, model string) string {
@@ -62,79 +108,317 @@ func deviceKey(manufacturer, model string) string {
 
 // NewDeviceDB creates an empty device database.
 func NewDeviceDB() *DeviceDB {
-	return &DeviceDB{defs: make(map[string]*DeviceDefinition)}
+	return &DeviceDB{
+		exact:     make(map[string]*DeviceDefinition),
+		templates: make(map[string]*DeviceDefinition),
+	}
 }
 
-// Add inserts a device definition into the database.
+// Add inserts a device definition into the database, replacing one with
+// the same manufacturer and model. Fingerprints that fail to compile never
+// match.
 func (db *DeviceDB) Add(def DeviceDefinition) {
 	cp := def
-	db.defs[deviceKey(def.Manufacturer, def.Model)] = &cp
+	_ = cp.compile()
+	db.mu.Lock()
+	db.add(&cp)
+	db.mu.Unlock()
+}
+
+func (db *DeviceDB) add(def *DeviceDefinition) {
+	if def.Manufacturer != "" || def.Model != "" {
+		key := deviceKey(def.Manufacturer, def.Model)
+		if old := db.exact[key]; old != nil {
+			db.defs[slices.Index(db.defs, old)] = def
+			db.exact[key] = def
+			db.fuzzy = slices.DeleteFunc(db.fuzzy, func(d *DeviceDefinition) bool { return d == old })
+			if def.fuzzy() {
+				db.fuzzy = append(db.fuzzy, def)
+			}
+			return
+		}
+		db.exact[key] = def
+	}
+	db.defs = append(db.defs, def)
+	if def.fuzzy() {
+		db.fuzzy = append(db.fuzzy, def)
+	}
 }
 
-// Lookup finds a device definition by manufacturer and model.
+// Lookup finds the device definition for a manufacturer and model: an
+// exact match, else the best manufacturer alias or fingerprint that needs
+// no endpoint information. Use Match when the endpoints are known.
 func (db *DeviceDB) Lookup(manufacturer, model string) *DeviceDefinition {
-	return db.defs[deviceKey(manufacturer, model)]
+	return db.Match(manufacturer, model, nil)
 }
 
 // Len returns the number of device definitions.
 func (db *DeviceDB) Len() int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
 	return len(db.defs)
 }
 
 // deviceFile is the JSON structure for files in the devices directory.
 type deviceFile struct {
-	Clusters      []zcl.ClusterDef    `json:"clusters,omitempty"`
-	Devices       []DeviceDefinition  `json:"devices,omitempty"`
-	Manufacturers []ManufacturerGroup `json:"manufacturers,omitempty"`
+	Clusters      []zcl.ClusterDef            `json:"clusters,omitempty"`
+	Templates     map[string]DeviceDefinition `json:"templates,omitempty"`
+	Devices       []DeviceDefinition          `json:"devices,omitempty"`
+	Manufacturers []ManufacturerGroup         `json:"manufacturers,omitempty"`
 }
 
-// LoadDeviceDir reads all *.json files from a directory, registering custom
-// clusters into the ZCL registry and loading device definitions into a DeviceDB.
-// Returns an empty DeviceDB (not an error) if the directory doesn't exist or is empty.
-func LoadDeviceDir(dir string, registry *zcl.Registry, logger *slog.Logger) (*DeviceDB, error) {
-	db := NewDeviceDB()
+// parsedFile is a device file whose definitions still have to be resolved.
+type parsedFile struct {
+	name      string
+	clusters  []zcl.ClusterDef
+	templates map[string]*DeviceDefinition
+	defs      []*DeviceDefinition
+}
 
-	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+// readDeviceFile parses one device file into its clusters, templates and a
+// flat list of definitions, each tagged with the file's base name.
+func readDeviceFile(path string) (*parsedFile, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return db, fmt.Errorf("glob devices dir: %w", err)
+		return nil, fmt.Errorf("read %s: %w", path, err)
 	}
-	if len(matches) == 0 {
-		logger.Info("no device definition files found", "dir", dir)
-		return db, nil
+
+	var df deviceFile
+	if err := json.Unmarshal(data, &df); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
 	}
 
-	for _, path := range matches {
-		data, err := os.ReadFile(path)
+	pf := &parsedFile{
+		name:      filepath.Base(path),
+		clusters:  df.Clusters,
+		templates: make(map[string]*DeviceDefinition, len(df.Templates)),
+	}
+	for name, t := range df.Templates {
+		t.Source = pf.name
+		pf.templates[name] = &t
+	}
+	for _, d := range df.Devices {
+		d.Source = pf.name
+		pf.defs = append(pf.defs, &d)
+	}
+	for _, mg := range df.Manufacturers {
+		for _, d := range mg.Models {
+			d.Manufacturer = mg.Name
+			if len(mg.Aliases) > 0 {
+				d.ManufacturerAliases = append(slices.Clone(mg.Aliases), d.ManufacturerAliases...)
+			}
+			d.Source = pf.name
+			pf.defs = append(pf.defs, &d)
+		}
+	}
+	return pf, nil
+}
+
+// loadDeviceFiles parses the files and resolves their definitions into a
+// new database. Templates and definitions of files that fail are taken
+// from prev, if given, so a broken file keeps what it loaded before.
+// Returns the clusters of the files that loaded and the per-file errors.
+func loadDeviceFiles(paths []string, prev *DeviceDB) (*DeviceDB, []zcl.ClusterDef, []FileError) {
+	var files []*parsedFile
+	var errs []FileError
+	failed := make(map[string]bool)
+	fail := func(name string, err error) {
+		failed[name] = true
+		errs = append(errs, FileError{File: name, Error: err.Error()})
+	}
+	for _, path := range paths {
+		pf, err := readDeviceFile(path)
 		if err != nil {
-			return db, fmt.Errorf("read %s: %w", path, err)
+			fail(filepath.Base(path), err)
+			continue
 		}
+		files = append(files, pf)
+	}
 
-		var df deviceFile
-		if err := json.Unmarshal(data, &df); err != nil {
-			return db, fmt.Errorf("parse %s: %w", path, err)
+	var prevDefs []*DeviceDefinition
+	prevTemplates := make(map[string]*DeviceDefinition)
+	if prev != nil {
+		prev.mu.RLock()
+		prevDefs = slices.Clone(prev.defs)
+		for name, t := range prev.templates {
+			prevTemplates[name] = t
 		}
+		prev.mu.RUnlock()
+	}
 
-		for _, c := range df.Clusters {
-			registry.Register(c)
+	// Templates share one namespace across files.
+	templates := make(map[string]*DeviceDefinition)
+	for name, t := range prevTemplates {
+		if failed[t.Source] {
+			templates[name] = t
 		}
-		for _, d := range df.Devices {
-			db.Add(d)
+	}
+	for _, pf := range files {
+		var dup error
+		for name := range pf.templates {
+			if t := templates[name]; t != nil {
+				dup = fmt.Errorf("template %q already defined in %s", name, t.Source)
+				break
+			}
+		}
+		if dup != nil {
+			fail(pf.name, dup)
+			continue
+		}
+		for name, t := range pf.templates {
+			templates[name] = t
+		}
+	}
+
+	var kept, all []*DeviceDefinition
+	for _, def := range prevDefs {
+		if failed[def.Source] {
+			kept = append(kept, def)
+		}
+	}
+	all = append(all, kept...)
+	for _, pf := range files {
+		if !failed[pf.name] {
+			all = append(all, pf.defs...)
+		}
+	}
+	r := newDefinitionResolver(templates, all)
+
+	next := NewDeviceDB()
+	var clusters []zcl.ClusterDef
+	for _, pf := range files {
+		if failed[pf.name] {
+			continue
 		}
-		for _, mg := range df.Manufacturers {
-			for _, d := range mg.Models {
-				d.Manufacturer = mg.Name
-				db.Add(d)
+		defs, err := resolveFile(r, pf)
+		if err != nil {
+			fail(pf.name, fmt.Errorf("%s: %w", pf.name, err))
+			continue
+		}
+		for _, def := range defs {
+			next.add(def)
+		}
+		for name, t := range pf.templates {
+			next.templates[name] = t
+		}
+		clusters = append(clusters, pf.clusters...)
+	}
+
+	for _, def := range kept {
+		if def.Manufacturer != "" || def.Model != "" {
+			if next.exact[deviceKey(def.Manufacturer, def.Model)] != nil {
+				continue
 			}
 		}
+		next.add(def)
+	}
+	for name, t := range prevTemplates {
+		if failed[t.Source] && next.templates[name] == nil {
+			next.templates[name] = t
+		}
+	}
+	return next, clusters, errs
+}
 
-		deviceCount := len(df.Devices)
-		for _, mg := range df.Manufacturers {
-			deviceCount += len(mg.Models)
+// resolveFile resolves and compiles the definitions of one file.
+func resolveFile(r *definitionResolver, pf *parsedFile) ([]*DeviceDefinition, error) {
+	defs := make([]*DeviceDefinition, 0, len(pf.defs))
+	for _, raw := range pf.defs {
+		def, err := r.resolve(raw, raw.Model)
+		if err != nil {
+			return nil, err
 		}
-		logger.Info("loaded device file", "path", filepath.Base(path),
-			"clusters", len(df.Clusters), "devices", deviceCount)
+		cp := *def
+		if err := cp.compile(); err != nil {
+			return nil, err
+		}
+		defs = append(defs, &cp)
+	}
+	return defs, nil
+}
+
+// LoadDeviceDir reads all *.json files from a directory, registering custom
+// clusters into the ZCL registry and loading device definitions into a DeviceDB.
+// Decoder files with a registered loader (*.lua) are loaded alongside.
+// Returns an empty DeviceDB (not an error) if the directory doesn't exist or is empty.
+func LoadDeviceDir(dir string, registry *zcl.Registry, logger *slog.Logger) (*DeviceDB, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return NewDeviceDB(), fmt.Errorf("glob devices dir: %w", err)
+	}
+	if len(matches) == 0 {
+		logger.Info("no device definition files found", "dir", dir)
+		return NewDeviceDB(), nil
+	}
+
+	db, clusters, errs := loadDeviceFiles(matches, nil)
+	decoders, _, decErrs := loadDecoderFiles(dir, nil)
+	db.decoders = decoders
+	if errs = append(errs, decErrs...); len(errs) > 0 {
+		return db, errors.New(errs[0].Error)
+	}
+	for _, c := range clusters {
+		registry.Register(c)
 	}
 
-	logger.Info("device database loaded", "files", len(matches), "devices", db.Len())
+	logger.Info("device database loaded", "files", len(matches), "devices", db.Len(),
+		"templates", len(db.templates), "clusters", len(clusters), "decoders", len(decoders))
 	return db, nil
 }
+
+// FileError is a device file that could not be loaded.
+type FileError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// ReloadResult summarizes a reload of the devices directory.
+type ReloadResult struct {
+	Files    int         `json:"files"`
+	Devices  int         `json:"devices"`
+	Clusters int         `json:"clusters"`
+	Decoders int         `json:"decoders"`
+	Errors   []FileError `json:"errors"`
+}
+
+// Reload re-reads all *.json files from dir and replaces the definitions in
+// one step, so lookups never see a half-loaded database. A file that fails
+// to read, parse or resolve is reported in the result and the definitions
+// and templates it contributed before are kept. Clusters from the good
+// files are merged into the registry; clusters are never removed, since
+// frames already decoded against them may still be in flight. Decoder
+// files are reloaded the same way.
+func (db *DeviceDB) Reload(dir string, registry *zcl.Registry, logger *slog.Logger) (*ReloadResult, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("glob devices dir: %w", err)
+	}
+
+	next, clusters, errs := loadDeviceFiles(matches, db)
+	db.mu.RLock()
+	prevDecoders := db.decoders
+	db.mu.RUnlock()
+	decoders, decoderFiles, decErrs := loadDecoderFiles(dir, prevDecoders)
+	errs = append(errs, decErrs...)
+	result := &ReloadResult{Files: len(matches) + decoderFiles, Clusters: len(clusters), Decoders: len(decoders), Errors: []FileError{}}
+	for _, fe := range errs {
+		logger.Warn("device file not reloaded, keeping previous definitions", "path", fe.File, "err", fe.Error)
+		result.Errors = append(result.Errors, fe)
+	}
+
+	// Register clusters before the swap so new definitions never refer to
+	// clusters the registry does not know yet.
+	for _, c := range clusters {
+		registry.Register(c)
+	}
+
+	db.mu.Lock()
+	db.defs, db.exact, db.fuzzy, db.templates = next.defs, next.exact, next.fuzzy, next.templates
+	db.decoders = decoders
+	result.Devices = len(db.defs)
+	db.mu.Unlock()
+
+	logger.Info("device database reloaded", "files", result.Files, "devices", result.Devices,
+		"clusters", result.Clusters, "decoders", result.Decoders, "errors", len(result.Errors))
+	return result, nil
+}
@@ -157,3 +157,80 @@ func TestLoadDeviceDirMissing(t *testing.T) {
 		t.Errorf("len = %d, want 0", db.Len())
 	}
 }
+
+func TestDeviceDBReload(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	registry := zcl.NewRegistry(logger)
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "lumi.json"), []byte(`{
+		"devices": [{"manufacturer": "LUMI", "model": "lumi.sensor_ht", "friendly_name": "Aqara Temp", "bind": []}]
+	}`), 0644)
+	os.WriteFile(filepath.Join(dir, "ikea.json"), []byte(`{
+		"devices": [{"manufacturer": "IKEA of Sweden", "model": "TRADFRI on/off switch", "bind": [6]}]
+	}`), 0644)
+
+	db, err := LoadDeviceDir(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit one file, break the other, and add a custom cluster.
+	os.WriteFile(filepath.Join(dir, "lumi.json"), []byte(`{
+		"clusters": [{"id": 64704, "name": "Lumi Private", "attributes": []}],
+		"devices": [{"manufacturer": "LUMI", "model": "lumi.weather", "friendly_name": "Aqara Weather", "bind": []}]
+	}`), 0644)
+	os.WriteFile(filepath.Join(dir, "ikea.json"), []byte(`{"devices": [`), 0644)
+
+	result, err := db.Reload(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 2 || result.Devices != 2 || result.Clusters != 1 {
+		t.Errorf("result = %+v, want 2 files, 2 devices, 1 cluster", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].File != "ikea.json" {
+		t.Fatalf("errors = %+v, want ikea.json", result.Errors)
+	}
+
+	if db.Lookup("LUMI", "lumi.sensor_ht") != nil {
+		t.Error("definition removed from lumi.json still present")
+	}
+	if def := db.Lookup("LUMI", "lumi.weather"); def == nil || def.FriendlyName != "Aqara Weather" {
+		t.Errorf("lumi.weather = %+v", def)
+	}
+	if def := db.Lookup("IKEA of Sweden", "TRADFRI on/off switch"); def == nil {
+		t.Error("definition from broken ikea.json was dropped")
+	}
+	if registry.Get(64704) == nil {
+		t.Error("Lumi Private cluster not registered")
+	}
+
+	// Once the broken file is removed its definitions go too.
+	os.Remove(filepath.Join(dir, "ikea.json"))
+	if _, err := db.Reload(dir, registry, logger); err != nil {
+		t.Fatal(err)
+	}
+	if db.Lookup("IKEA of Sweden", "TRADFRI on/off switch") != nil {
+		t.Error("definition from removed file still present")
+	}
+}
+
+func TestLoadShippedDeviceDefinitions(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	db, err := LoadDeviceDir("../../devices", zcl.NewRegistry(logger), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() == 0 {
+		t.Fatal("no definitions loaded")
+	}
+	if def := db.Lookup("LUMI", "lumi.remote.b1acn01"); def == nil || len(def.Properties) != 1 || def.Properties[0].Decoder != "xiaomi_tlv" {
+		t.Errorf("lumi.remote.b1acn01 did not inherit lumi_tlv_battery: %+v", def)
+	}
+	for _, manufacturer := range []string{"_TZE200_locansqp", "_TZE200_bjawzodf"} {
+		if def := db.Lookup(manufacturer, "TS0601"); def == nil || def.FriendlyName != "Tuya Temp & Humidity Sensor" {
+			t.Errorf("%s TS0601 = %+v", manufacturer, def)
+		}
+	}
+}
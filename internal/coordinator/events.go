@@ -13,8 +13,17 @@ const (
 	EventAttributeReport  = "attribute_report"
 	EventClusterCommand   = "cluster_command"
 	EventPropertyUpdate   = "property_update"
+	EventDeviceAction     = "device_action"
+	EventCommandFailed    = "command_failed"
 	EventNetworkState    = "network_state"
 	EventPermitJoin      = "permit_join"
+	EventAlarmPanel      = "alarm_panel"
+	EventDeviceAvailability = "device_availability"
+	EventInterviewProgress  = "interview_progress"
+	EventInterviewStarted   = "interview_started"
+	EventInterviewCompleted = "interview_completed"
+	EventInterviewFailed    = "interview_failed"
+	EventDefinitionsReloaded = "definitions_reloaded"
 )
 
 // Event represents a coordinator event.
@@ -0,0 +1,1036 @@
+package coordinator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Transforms are small expressions over the raw value x, e.g.
+// "round(x / 10, 1)", "clamp(x - 1, 0, 100)", "bit(x, 3)",
+// "lookup(x, {0: \"off\", 1: \"on\"})" or
+// "floor(linear(x, [[2850, 0], [3000, 100]]))". There are no loops or
+// assignments, so evaluation is bounded by the size of the expression.
+//
+// Values are int64, float64, bool, string and lists; tables ({k: v}) only
+// appear as arguments. Integer arithmetic stays integer except "/", which
+// always divides as float.
+
+// maxTransformLen bounds the source of a transform expression.
+const maxTransformLen = 1024
+
+// transformAliases are the named transforms, kept for existing definitions.
+var transformAliases = map[string]string{
+	"lumi_battery":    "floor(linear(x, [[2850, 0], [3000, 100]]))",
+	"minus_one":       "x - 1",
+	"lumi_trigger":    "(x & 0xFFFF) - 1",
+	"bool_invert":     "!x",
+	"divide_10":       "x / 10",
+	"divide_100":      "x / 100",
+	"illuminance_lux": "x <= 0 ? 0 : round(pow(10, (x - 1) / 10000))",
+}
+
+// transformCache holds compiled transforms by source.
+var transformCache sync.Map // string -> exprNode
+
+// compileTransform compiles a transform expression or alias.
+func compileTransform(src string) (exprNode, error) {
+	if cached, ok := transformCache.Load(src); ok {
+		return cached.(exprNode), nil
+	}
+	code := src
+	if alias, ok := transformAliases[src]; ok {
+		code = alias
+	}
+	if len(code) > maxTransformLen {
+		return nil, fmt.Errorf("transform longer than %d characters", maxTransformLen)
+	}
+	p := &exprParser{src: code}
+	p.next()
+	node, err := p.parseExpr()
+	if err == nil && p.tok.kind != tokEOF {
+		err = p.errorf("unexpected %s", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+	transformCache.Store(src, node)
+	return node, nil
+}
+
+// evalTransform applies a transform expression to a raw value.
+func evalTransform(src string, value any) (any, error) {
+	node, err := compileTransform(src)
+	if err != nil {
+		return nil, err
+	}
+	x, err := exprValue(value)
+	if err != nil {
+		return nil, err
+	}
+	out, err := node.eval(x)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := out.(exprTable); ok {
+		return nil, fmt.Errorf("transform returned a table")
+	}
+	return out, nil
+}
+
+// exprValue converts a decoded value to an expression value.
+func exprValue(v any) (any, error) {
+	switch n := v.(type) {
+	case int64, float64, bool, string:
+		return n, nil
+	case int:
+		return int64(n), nil
+	case int8:
+		return int64(n), nil
+	case int16:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case uint8:
+		return int64(n), nil
+	case uint16:
+		return int64(n), nil
+	case uint32:
+		return int64(n), nil
+	case uint64:
+		if n > math.MaxInt64 {
+			return float64(n), nil
+		}
+		return int64(n), nil
+	case float32:
+		return float64(n), nil
+	case []byte:
+		return string(n), nil
+	case []any:
+		list := make([]any, len(n))
+		for i, e := range n {
+			ev, err := exprValue(e)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = ev
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %T", v)
+	}
+}
+
+// exprTable is a table literal. Numeric keys are stored as float64 so
+// that 1 and 1.0 find the same entry.
+type exprTable map[any]any
+
+func tableKey(v any) any {
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+	return v
+}
+
+// --- Lexer ---
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNum
+	tokStr
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	num  any // int64 or float64
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// exprOps lists operators, longest first.
+var exprOps = []string{
+	"<<", ">>", "<=", ">=", "==", "!=", "&&", "||",
+	"+", "-", "*", "/", "%", "<", ">", "!", "~", "&", "|", "^",
+	"?", ":", "(", ")", "[", "]", "{", "}", ",",
+}
+
+type exprParser struct {
+	src string
+	pos int
+	tok token
+	err error
+}
+
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at %d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+// next reads the next token. Lexing errors surface as the parser's error.
+func (p *exprParser) next() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (isIdentChar(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		p.tok = token{kind: tokNum, text: text, pos: start}
+		if n, err := strconv.ParseInt(text, 0, 64); err == nil {
+			p.tok.num = n
+		} else if f, err := strconv.ParseFloat(text, 64); err == nil {
+			p.tok.num = f
+		} else if p.err == nil {
+			p.err = fmt.Errorf("at %d: invalid number %q", start+1, text)
+		}
+	case c == '"' || c == '\'':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != c {
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			if p.err == nil {
+				p.err = fmt.Errorf("at %d: unterminated string", start+1)
+			}
+			p.tok = token{kind: tokEOF, pos: start}
+			return
+		}
+		p.pos++
+		p.tok = token{kind: tokStr, text: p.src[start+1 : p.pos-1], pos: start}
+	case isIdentChar(c):
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+	default:
+		for _, op := range exprOps {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += len(op)
+				p.tok = token{kind: tokOp, text: op, pos: start}
+				return
+			}
+		}
+		if p.err == nil {
+			p.err = fmt.Errorf("at %d: unexpected character %q", start+1, c)
+		}
+		p.tok = token{kind: tokEOF, pos: start}
+	}
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// --- Parser ---
+
+// binaryPrec is the precedence of binary operators; higher binds tighter.
+var binaryPrec = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"|":  5,
+	"^":  6,
+	"&":  7,
+	"<<": 8, ">>": 8,
+	"+": 9, "-": 9,
+	"*": 10, "/": 10, "%": 10,
+}
+
+func (p *exprParser) is(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+func (p *exprParser) expect(op string) error {
+	if p.err != nil {
+		return p.err
+	}
+	if !p.is(op) {
+		return p.errorf("expected %q, got %s", op, p.tok)
+	}
+	p.next()
+	return nil
+}
+
+// parseExpr parses a full expression, including the conditional operator.
+func (p *exprParser) parseExpr() (exprNode, error) {
+	cond, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.is("?") {
+		return cond, p.err
+	}
+	p.next()
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	els, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond, then, els}, nil
+}
+
+func (p *exprParser) parseBinary(minPrec int) (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp {
+		op := p.tok.text
+		prec, ok := binaryPrec[op]
+		if !ok || prec < minPrec {
+			break
+		}
+		p.next()
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op, left, right}
+	}
+	return left, p.err
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.is("-") || p.is("!") || p.is("~") {
+		op := p.tok.text
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op, operand}, nil
+	}
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("[") {
+		p.next()
+		index, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		node = &indexNode{node, index}
+	}
+	return node, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	tok := p.tok
+	switch {
+	case tok.kind == tokNum:
+		p.next()
+		return literalNode{tok.num}, nil
+	case tok.kind == tokStr:
+		p.next()
+		return literalNode{tok.text}, nil
+	case tok.kind == tokIdent:
+		p.next()
+		switch tok.text {
+		case "x":
+			return varNode{}, nil
+		case "true", "false":
+			return literalNode{tok.text == "true"}, nil
+		}
+		fn, ok := exprFuncs[tok.text]
+		if !ok {
+			return nil, fmt.Errorf("at %d: unknown name %q", tok.pos+1, tok.text)
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		args, err := p.parseList(")")
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < fn.min || fn.max >= 0 && len(args) > fn.max {
+			return nil, fmt.Errorf("at %d: wrong number of arguments to %s", tok.pos+1, tok.text)
+		}
+		return &callNode{tok.text, fn.call, args}, nil
+	case p.is("("):
+		p.next()
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case p.is("["):
+		p.next()
+		items, err := p.parseList("]")
+		if err != nil {
+			return nil, err
+		}
+		return &listNode{items}, nil
+	case p.is("{"):
+		p.next()
+		return p.parseTable()
+	default:
+		return nil, p.errorf("unexpected %s", tok)
+	}
+}
+
+// parseList parses comma-separated expressions up to the closing token.
+func (p *exprParser) parseList(end string) ([]exprNode, error) {
+	var items []exprNode
+	for !p.is(end) {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return items, p.expect(end)
+}
+
+func (p *exprParser) parseTable() (exprNode, error) {
+	t := &tableNode{}
+	for !p.is("}") {
+		key, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		val, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		t.keys = append(t.keys, key)
+		t.vals = append(t.vals, val)
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	return t, p.expect("}")
+}
+
+// --- Evaluation ---
+
+type exprNode interface {
+	eval(x any) (any, error)
+}
+
+type literalNode struct{ v any }
+
+func (n literalNode) eval(any) (any, error) { return n.v, nil }
+
+type varNode struct{}
+
+func (varNode) eval(x any) (any, error) { return x, nil }
+
+type listNode struct{ items []exprNode }
+
+func (n *listNode) eval(x any) (any, error) {
+	out := make([]any, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(x)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+type tableNode struct{ keys, vals []exprNode }
+
+func (n *tableNode) eval(x any) (any, error) {
+	out := make(exprTable, len(n.keys))
+	for i := range n.keys {
+		k, err := n.keys[i].eval(x)
+		if err != nil {
+			return nil, err
+		}
+		v, err := n.vals[i].eval(x)
+		if err != nil {
+			return nil, err
+		}
+		out[tableKey(k)] = v
+	}
+	return out, nil
+}
+
+type condNode struct{ cond, then, els exprNode }
+
+func (n *condNode) eval(x any) (any, error) {
+	c, err := n.cond.eval(x)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(c) {
+		return n.then.eval(x)
+	}
+	return n.els.eval(x)
+}
+
+type indexNode struct{ target, index exprNode }
+
+func (n *indexNode) eval(x any) (any, error) {
+	t, err := n.target.eval(x)
+	if err != nil {
+		return nil, err
+	}
+	i, err := n.index.eval(x)
+	if err != nil {
+		return nil, err
+	}
+	v, ok, err := lookupValue(t, i)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no entry %v", i)
+	}
+	return v, nil
+}
+
+// lookupValue returns the entry of a table, or of a list by 0-based index.
+func lookupValue(t, key any) (any, bool, error) {
+	switch c := t.(type) {
+	case exprTable:
+		v, ok := c[tableKey(key)]
+		return v, ok, nil
+	case []any:
+		i, ok := toInt(key)
+		if !ok || i < 0 || i >= int64(len(c)) {
+			return nil, false, nil
+		}
+		return c[i], true, nil
+	default:
+		return nil, false, fmt.Errorf("cannot index %s", typeName(t))
+	}
+}
+
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(x any) (any, error) {
+	v, err := n.operand.eval(x)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		return !truthy(v), nil
+	case "~":
+		i, ok := toInt(v)
+		if !ok {
+			return nil, fmt.Errorf("~ needs an integer, got %s", typeName(v))
+		}
+		return ^i, nil
+	default: // "-"
+		switch num := v.(type) {
+		case int64:
+			return -num, nil
+		case float64:
+			return -num, nil
+		}
+		return nil, fmt.Errorf("- needs a number, got %s", typeName(v))
+	}
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(x any) (any, error) {
+	l, err := n.left.eval(x)
+	if err != nil {
+		return nil, err
+	}
+	// Short-circuit logic.
+	switch n.op {
+	case "&&":
+		if !truthy(l) {
+			return false, nil
+		}
+		r, err := n.right.eval(x)
+		return err == nil && truthy(r), err
+	case "||":
+		if truthy(l) {
+			return true, nil
+		}
+		r, err := n.right.eval(x)
+		return err == nil && truthy(r), err
+	}
+	r, err := n.right.eval(x)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return exprEqual(l, r), nil
+	case "!=":
+		return !exprEqual(l, r), nil
+	case "+":
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return ls + rs, nil
+			}
+		}
+	case "&", "|", "^", "<<", ">>":
+		li, lok := toInt(l)
+		ri, rok := toInt(r)
+		if !lok || !rok {
+			return nil, fmt.Errorf("%s needs integers, got %s and %s", n.op, typeName(l), typeName(r))
+		}
+		switch n.op {
+		case "&":
+			return li & ri, nil
+		case "|":
+			return li | ri, nil
+		case "^":
+			return li ^ ri, nil
+		case "<<":
+			if ri < 0 || ri > 63 {
+				return nil, fmt.Errorf("shift by %d", ri)
+			}
+			return li << ri, nil
+		default:
+			if ri < 0 || ri > 63 {
+				return nil, fmt.Errorf("shift by %d", ri)
+			}
+			return li >> ri, nil
+		}
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		if ls, ok := l.(string); ok {
+			if rs, ok := r.(string); ok {
+				return compareStrings(n.op, ls, rs)
+			}
+		}
+		return nil, fmt.Errorf("%s needs numbers, got %s and %s", n.op, typeName(l), typeName(r))
+	}
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	ints := lInt && rInt
+	switch n.op {
+	case "<":
+		return lf < rf, nil
+	case "<=":
+		return lf <= rf, nil
+	case ">":
+		return lf > rf, nil
+	case ">=":
+		return lf >= rf, nil
+	case "+":
+		if ints {
+			return li + ri, nil
+		}
+		return lf + rf, nil
+	case "-":
+		if ints {
+			return li - ri, nil
+		}
+		return lf - rf, nil
+	case "*":
+		if ints {
+			return li * ri, nil
+		}
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return lf / rf, nil
+	case "%":
+		if rf == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		if ints {
+			return li % ri, nil
+		}
+		return math.Mod(lf, rf), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", n.op)
+}
+
+func compareStrings(op, l, r string) (any, error) {
+	switch op {
+	case "<":
+		return l < r, nil
+	case "<=":
+		return l <= r, nil
+	case ">":
+		return l > r, nil
+	case ">=":
+		return l >= r, nil
+	}
+	return nil, fmt.Errorf("%s needs numbers, got strings", op)
+}
+
+func exprEqual(l, r any) bool {
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		return ok && lf == rf
+	}
+	switch lv := l.(type) {
+	case bool, string:
+		return l == r
+	case []any:
+		rv, ok := r.([]any)
+		if !ok || len(lv) != len(rv) {
+			return false
+		}
+		for i := range lv {
+			if !exprEqual(lv[i], rv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// truthy is false for false, zero and the empty string.
+func truthy(v any) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b != ""
+	case nil:
+		return false
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// toInt returns integers and whole floats as int64.
+func toInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<63 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case int64, float64:
+		return "number"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case exprTable:
+		return "table"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// --- Functions ---
+
+type callNode struct {
+	name string
+	call func(args []any) (any, error)
+	args []exprNode
+}
+
+func (n *callNode) eval(x any) (any, error) {
+	args := make([]any, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(x)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.call(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+type exprFunc struct {
+	min, max int // max -1: variadic
+	call     func(args []any) (any, error)
+}
+
+var exprFuncs map[string]exprFunc
+
+func init() {
+	exprFuncs = map[string]exprFunc{
+		"abs":    {1, 1, numFunc(math.Abs, true)},
+		"floor":  {1, 1, intFunc(math.Floor)},
+		"ceil":   {1, 1, intFunc(math.Ceil)},
+		"trunc":  {1, 1, intFunc(math.Trunc)},
+		"round":  {1, 2, exprRound},
+		"sqrt":   {1, 1, numFunc(math.Sqrt, false)},
+		"log10":  {1, 1, numFunc(math.Log10, false)},
+		"pow":    {2, 2, exprPow},
+		"min":    {1, -1, minMax(func(a, b float64) bool { return a < b })},
+		"max":    {1, -1, minMax(func(a, b float64) bool { return a > b })},
+		"clamp":  {3, 3, exprClamp},
+		"bit":    {2, 2, exprBit},
+		"bits":   {3, 3, exprBits},
+		"lookup": {2, 3, exprLookup},
+		"linear": {2, 2, exprLinear},
+		"int":    {1, 1, intFunc(math.Trunc)},
+		"bool":   {1, 1, func(a []any) (any, error) { return truthy(a[0]), nil }},
+	}
+}
+
+func number(v any) (float64, error) {
+	if f, ok := toFloat(v); ok {
+		return f, nil
+	}
+	if b, ok := v.(bool); ok {
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return 0, fmt.Errorf("expected a number, got %s", typeName(v))
+}
+
+// numFunc wraps a float function. With keepInt, integer arguments give an
+// integer result.
+func numFunc(f func(float64) float64, keepInt bool) func([]any) (any, error) {
+	return func(a []any) (any, error) {
+		if i, ok := a[0].(int64); ok && keepInt {
+			return int64(f(float64(i))), nil
+		}
+		n, err := number(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(n), nil
+	}
+}
+
+// intFunc wraps a rounding function returning an integer.
+func intFunc(f func(float64) float64) func([]any) (any, error) {
+	return func(a []any) (any, error) {
+		n, err := number(a[0])
+		if err != nil {
+			return nil, err
+		}
+		return int64(f(n)), nil
+	}
+}
+
+// exprRound rounds to an integer, or to the given number of decimals.
+func exprRound(a []any) (any, error) {
+	n, err := number(a[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(a) == 1 {
+		return int64(math.Round(n)), nil
+	}
+	d, ok := toInt(a[1])
+	if !ok || d < 0 || d > 10 {
+		return nil, fmt.Errorf("decimals must be 0-10")
+	}
+	p := math.Pow(10, float64(d))
+	return math.Round(n*p) / p, nil
+}
+
+func exprPow(a []any) (any, error) {
+	b, err := number(a[0])
+	if err != nil {
+		return nil, err
+	}
+	e, err := number(a[1])
+	if err != nil {
+		return nil, err
+	}
+	return math.Pow(b, e), nil
+}
+
+// minMax returns the argument that wins better, or the winner of a list.
+func minMax(better func(a, b float64) bool) func([]any) (any, error) {
+	return func(a []any) (any, error) {
+		if list, ok := a[0].([]any); ok && len(a) == 1 {
+			a = list
+		}
+		if len(a) == 0 {
+			return nil, fmt.Errorf("no values")
+		}
+		best := a[0]
+		bf, err := number(best)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range a[1:] {
+			f, err := number(v)
+			if err != nil {
+				return nil, err
+			}
+			if better(f, bf) {
+				best, bf = v, f
+			}
+		}
+		return best, nil
+	}
+}
+
+func exprClamp(a []any) (any, error) {
+	n, err := number(a[0])
+	if err != nil {
+		return nil, err
+	}
+	lo, err := number(a[1])
+	if err != nil {
+		return nil, err
+	}
+	hi, err := number(a[2])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case n < lo:
+		return a[1], nil
+	case n > hi:
+		return a[2], nil
+	}
+	return a[0], nil
+}
+
+// exprBit reports whether bit n (0 = least significant) is set.
+func exprBit(a []any) (any, error) {
+	v, ok := toInt(a[0])
+	n, nok := toInt(a[1])
+	if !ok || !nok || n < 0 || n > 63 {
+		return nil, fmt.Errorf("expected an integer and a bit 0-63")
+	}
+	return v>>n&1 == 1, nil
+}
+
+// exprBits extracts count bits starting at bit offset.
+func exprBits(a []any) (any, error) {
+	v, ok := toInt(a[0])
+	off, ook := toInt(a[1])
+	count, cok := toInt(a[2])
+	if !ok || !ook || !cok || off < 0 || count < 1 || off+count > 64 {
+		return nil, fmt.Errorf("expected an integer, an offset and a count within 64 bits")
+	}
+	if count == 64 {
+		return v, nil
+	}
+	return v >> off & (1<<count - 1), nil
+}
+
+// exprLookup returns the table entry for a key (a 0-based index for lists),
+// else the default, else the key itself, like enum converters.
+func exprLookup(a []any) (any, error) {
+	v, ok, err := lookupValue(a[1], a[0])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ok:
+		return v, nil
+	case len(a) == 3:
+		return a[2], nil
+	}
+	return a[0], nil
+}
+
+// exprLinear interpolates between [x, y] points sorted by x, clamping to
+// the first and last y.
+func exprLinear(a []any) (any, error) {
+	n, err := number(a[0])
+	if err != nil {
+		return nil, err
+	}
+	pts, ok := a[1].([]any)
+	if !ok || len(pts) == 0 {
+		return nil, fmt.Errorf("expected a list of [x, y] points")
+	}
+	xs := make([]float64, len(pts))
+	ys := make([]float64, len(pts))
+	for i, p := range pts {
+		pair, ok := p.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("point %d is not [x, y]", i+1)
+		}
+		if xs[i], err = number(pair[0]); err != nil {
+			return nil, err
+		}
+		if ys[i], err = number(pair[1]); err != nil {
+			return nil, err
+		}
+		if i > 0 && xs[i] <= xs[i-1] {
+			return nil, fmt.Errorf("points must be sorted by x")
+		}
+	}
+	switch {
+	case n <= xs[0]:
+		return ys[0], nil
+	case n >= xs[len(xs)-1]:
+		return ys[len(ys)-1], nil
+	}
+	for i := 1; i < len(xs); i++ {
+		if n <= xs[i] {
+			return ys[i-1] + (n-xs[i-1])*(ys[i]-ys[i-1])/(xs[i]-xs[i-1]), nil
+		}
+	}
+	return ys[len(ys)-1], nil
+}
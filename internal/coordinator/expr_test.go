@@ -0,0 +1,152 @@
+package coordinator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvalTransform(t *testing.T) {
+	tests := []struct {
+		expr  string
+		input any
+		want  any
+	}{
+		{"x * 2 + 1", uint8(4), int64(9)},
+		{"x / 4", uint16(10), 2.5},
+		{"x % 3", int16(-7), int64(-1)},
+		{"-x", float32(1.5), -1.5},
+		{"round(x / 3, 2)", int64(10), 3.33},
+		{"round(x)", 2.5, int64(3)},
+		{"floor(-x)", 1.2, int64(-2)},
+		{"ceil(x)", 1.2, int64(2)},
+		{"abs(x)", int64(-4), int64(4)},
+		{"min(x, 10)", uint8(20), int64(10)},
+		{"max(1, x, 3)", uint8(2), int64(3)},
+		{"max([x, 7])", uint8(9), int64(9)},
+		{"clamp(x - 1, 0, 100)", uint8(0), int64(0)},
+		{"clamp(x, 0, 100)", uint8(150), int64(100)},
+		{"pow(2, x)", uint8(10), 1024.0},
+		{"0x10 | x", uint8(1), int64(17)},
+		{"x >> 4 & 0x0F", uint8(0xA5), int64(0x0A)},
+		{"~x & 0xFF", uint8(0x0F), int64(0xF0)},
+		{"bit(x, 2)", uint8(0b100), true},
+		{"bit(x, 1)", uint8(0b100), false},
+		{"bits(x, 4, 3)", uint16(0x0170), int64(7)},
+		{"x > 10 && x < 20", int64(15), true},
+		{"x == 1 || x == 2", int64(3), false},
+		{"x ? 'on' : 'off'", true, "on"},
+		{"x >= 0 ? x : 0", int64(-5), int64(0)},
+		{`x + "!"`, "hi", "hi!"},
+		{`lookup(x, {0: "off", 1: "on", 2: "toggle"})`, uint8(1), "on"},
+		{`lookup(x, {0: "off"})`, uint8(5), int64(5)},
+		{`lookup(x, {0: "off"}, "unknown")`, uint8(5), "unknown"},
+		{`lookup(x, {"a": 1, "b": 2})`, "b", int64(2)},
+		{"lookup(x, [10, 20, 30])", uint8(2), int64(30)},
+		{"[10, 20, 30][x]", uint8(1), int64(20)},
+		{"{1.0: 'one'}[x]", int64(1), "one"},
+		{"linear(x, [[0, 0], [10, 100], [20, 150]])", int64(15), 125.0},
+		{"linear(x, [[0, 0], [10, 100]])", int64(-5), 0.0},
+		{"linear(x, [[0, 0], [10, 100]])", int64(50), 100.0},
+		{"int(x / 10)", int64(57), int64(5)},
+		{"bool(x)", uint8(2), true},
+		{"x", []byte("raw"), "raw"},
+	}
+	for _, tt := range tests {
+		got, err := evalTransform(tt.expr, tt.input)
+		if err != nil {
+			t.Errorf("%s (x = %v): %v", tt.expr, tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s (x = %v) = %v (%T), want %v (%T)", tt.expr, tt.input, got, got, tt.want, tt.want)
+		}
+	}
+}
+
+func TestTransformAliases(t *testing.T) {
+	tests := []struct {
+		name  string
+		input any
+		want  any
+	}{
+		{"lumi_battery", uint16(2960), int64(73)},
+		{"divide_10", int16(-25), -2.5},
+		{"minus_one", uint8(3), int64(2)},
+		{"lumi_trigger", uint64(0x00020003), int64(2)},
+		{"bool_invert", uint8(0), true},
+		{"illuminance_lux", uint16(0), int64(0)},
+		{"illuminance_lux", uint16(10001), int64(10)},
+	}
+	for _, tt := range tests {
+		if got := applyTransform(tt.name, tt.input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s(%v) = %v (%T), want %v", tt.name, tt.input, got, got, tt.want)
+		}
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	for _, src := range []string{
+		"x +",
+		"(x",
+		"y * 2",
+		"nope(x)",
+		"round()",
+		"clamp(x, 1)",
+		"x $ 2",
+		"'open",
+		"1.2.3",
+		"x x",
+		"{1: 2",
+		strings.Repeat("x+", maxTransformLen),
+	} {
+		if _, err := compileTransform(src); err == nil {
+			t.Errorf("%.20q: expected compile error", src)
+		}
+	}
+
+	for _, tt := range []struct {
+		expr  string
+		input any
+	}{
+		{"x / 0", int64(1)},
+		{"x & 1", 1.5},
+		{"x * 2", "text"},
+		{"[1, 2][x]", int64(5)},
+		{"linear(x, [[10, 0], [0, 100]])", int64(1)},
+		{"{1: 2}", int64(1)},
+		{"x << 64", int64(1)},
+		{"x", map[string]any{}},
+	} {
+		if _, err := evalTransform(tt.expr, tt.input); err == nil {
+			t.Errorf("%s (x = %v): expected error", tt.expr, tt.input)
+		}
+	}
+
+	// Values a transform cannot handle pass through applyTransform.
+	if got := applyTransform("x * 2", "text"); got != "text" {
+		t.Errorf("failed transform changed value: %v", got)
+	}
+}
+
+func TestDefinitionTransformValidation(t *testing.T) {
+	def := DeviceDefinition{
+		Model: "Bad",
+		Properties: []PropertySource{{
+			Decoder: "xiaomi_tlv",
+			Values:  []PropertyDef{{Tag: 1, Name: "battery", Transform: "linear(x, "}},
+		}},
+	}
+	if err := def.compile(); err == nil || !strings.Contains(err.Error(), "battery transform") {
+		t.Errorf("compile: %v", err)
+	}
+	def.Properties[0].Values[0].Transform = "lumi_battery"
+	if err := def.compile(); err != nil {
+		t.Errorf("compile alias: %v", err)
+	}
+	def.Converters = []AttributeConverter{{Cluster: 0x0402, Attribute: 0x0000}}
+	def.Converters[0].Transform = "x / "
+	if err := def.compile(); err == nil {
+		t.Error("expected converter transform error")
+	}
+}
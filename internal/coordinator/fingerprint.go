@@ -0,0 +1,271 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"zigbee-go-home/internal/store"
+)
+
+// Fingerprint matches devices by something other than their exact
+// manufacturer and model strings: a manufacturer family pattern, a model
+// prefix or regular expression, or the endpoint layout of devices that
+// report no model at all. Every condition that is set must match.
+type Fingerprint struct {
+	Manufacturer string                `json:"manufacturer,omitempty"` // exact, or a pattern with * and ?
+	Model        string                `json:"model,omitempty"`        // exact, or a pattern with * and ?
+	ModelPrefix  string                `json:"model_prefix,omitempty"`
+	ModelRegex   string                `json:"model_regex,omitempty"`
+	Endpoints    []EndpointFingerprint `json:"endpoints,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// EndpointFingerprint matches one endpoint of the simple descriptor. The
+// listed clusters must all be present; others are allowed.
+type EndpointFingerprint struct {
+	ID          uint8    `json:"id"`
+	ProfileID   *uint16  `json:"profile_id,omitempty"`
+	DeviceID    *uint16  `json:"device_id,omitempty"`
+	InClusters  []uint16 `json:"in_clusters,omitempty"`
+	OutClusters []uint16 `json:"out_clusters,omitempty"`
+}
+
+// compile validates the fingerprints, manufacturer aliases, transforms and
+// options of a definition and compiles its regular expressions. Transforms
+// of settable properties must be reversible.
+func (def *DeviceDefinition) compile() error {
+	if err := ValidateDeviceOptions(def.Options); err != nil {
+		return fmt.Errorf("%s: %w", def.Model, err)
+	}
+	for _, ps := range def.Properties {
+		for _, v := range ps.Values {
+			if _, err := compileTransform(v.Transform); v.Transform != "" && err != nil {
+				return fmt.Errorf("%s: property %s transform: %w", def.Model, v.Name, err)
+			}
+			if v.Type == "" && v.ReverseTransform == "" {
+				continue
+			}
+			if err := checkReversible(v.Transform, v.ReverseTransform); err != nil {
+				return fmt.Errorf("%s: property %s: %w", def.Model, v.Name, err)
+			}
+		}
+	}
+	for _, c := range def.Converters {
+		if _, err := compileTransform(c.Transform); c.Transform != "" && err != nil {
+			return fmt.Errorf("%s: converter 0x%04X/0x%04X transform: %w", def.Model, c.Cluster, c.Attribute, err)
+		}
+		if !c.Settable() && c.ReverseTransform == "" {
+			continue
+		}
+		if err := checkReversible(c.Transform, c.ReverseTransform); err != nil {
+			return fmt.Errorf("%s: converter 0x%04X/0x%04X: %w", def.Model, c.Cluster, c.Attribute, err)
+		}
+	}
+	for i := range def.Actions {
+		if err := def.Actions[i].check(); err != nil {
+			return fmt.Errorf("%s: action %d: %w", def.Model, i, err)
+		}
+	}
+	for _, e := range def.Poll {
+		if e.Interval < minPollInterval || e.Interval > maxThrottleSeconds {
+			return fmt.Errorf("%s: poll 0x%04X/0x%04X: interval must be %d to %d seconds", def.Model, e.Cluster, e.Attribute, minPollInterval, maxThrottleSeconds)
+		}
+	}
+	for _, alias := range def.ManufacturerAliases {
+		if err := checkPattern(alias); err != nil {
+			return fmt.Errorf("%s: manufacturer alias %q: %w", def.Model, alias, err)
+		}
+	}
+	// Copy so definitions added from the same slice do not share compiled state.
+	def.Fingerprints = slices.Clone(def.Fingerprints)
+	for i := range def.Fingerprints {
+		fp := &def.Fingerprints[i]
+		if fp.Manufacturer == "" && fp.Model == "" && fp.ModelPrefix == "" && fp.ModelRegex == "" && len(fp.Endpoints) == 0 {
+			return fmt.Errorf("%s: fingerprint %d has no conditions", def.Model, i)
+		}
+		if err := checkPattern(fp.Manufacturer); err != nil {
+			return fmt.Errorf("%s: fingerprint %d manufacturer: %w", def.Model, i, err)
+		}
+		if err := checkPattern(fp.Model); err != nil {
+			return fmt.Errorf("%s: fingerprint %d model: %w", def.Model, i, err)
+		}
+		if fp.ModelRegex != "" {
+			re, err := regexp.Compile(fp.ModelRegex)
+			if err != nil {
+				return fmt.Errorf("%s: fingerprint %d model_regex: %w", def.Model, i, err)
+			}
+			fp.re = re
+		}
+	}
+	return nil
+}
+
+func checkPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return errors.New("invalid pattern")
+	}
+	return nil
+}
+
+// matchString matches s against an exact string or a * and ? pattern.
+// Exact matches score higher than pattern matches.
+func matchString(pattern, s string) (bool, int) {
+	if pattern == s {
+		return true, 2
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return false, 0
+	}
+	ok, _ := path.Match(pattern, s)
+	return ok, 1
+}
+
+// match reports whether the fingerprint matches and how many conditions it
+// checked, so more specific fingerprints win over looser ones.
+func (fp *Fingerprint) match(manufacturer, model string, endpoints []store.Endpoint) (bool, int) {
+	score := 0
+	if fp.Manufacturer != "" {
+		ok, s := matchString(fp.Manufacturer, manufacturer)
+		if !ok {
+			return false, 0
+		}
+		score += s
+	}
+	if fp.Model != "" {
+		ok, s := matchString(fp.Model, model)
+		if !ok {
+			return false, 0
+		}
+		score += s
+	}
+	if fp.ModelPrefix != "" {
+		if !strings.HasPrefix(model, fp.ModelPrefix) {
+			return false, 0
+		}
+		score++
+	}
+	if fp.ModelRegex != "" {
+		if fp.re == nil || !fp.re.MatchString(model) {
+			return false, 0
+		}
+		score++
+	}
+	for _, want := range fp.Endpoints {
+		i := slices.IndexFunc(endpoints, func(e store.Endpoint) bool { return e.ID == want.ID })
+		if i < 0 || !want.match(endpoints[i]) {
+			return false, 0
+		}
+		score++
+	}
+	return true, score
+}
+
+func (want *EndpointFingerprint) match(ep store.Endpoint) bool {
+	if want.ProfileID != nil && *want.ProfileID != ep.ProfileID {
+		return false
+	}
+	if want.DeviceID != nil && *want.DeviceID != ep.DeviceID {
+		return false
+	}
+	for _, c := range want.InClusters {
+		if !slices.Contains(ep.InClusters, c) {
+			return false
+		}
+	}
+	for _, c := range want.OutClusters {
+		if !slices.Contains(ep.OutClusters, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// exactScore ranks an exact manufacturer+model match above any fingerprint.
+const exactScore = 1 << 16
+
+// score reports whether the definition matches the device and how
+// specifically: an exact manufacturer+model match, then a manufacturer
+// alias with the definition's model, then the best fingerprint.
+func (def *DeviceDefinition) score(manufacturer, model string, endpoints []store.Endpoint) (bool, int) {
+	if def.Model != "" && def.Model == model {
+		if def.Manufacturer == manufacturer {
+			return true, exactScore
+		}
+		best := 0
+		for _, alias := range def.ManufacturerAliases {
+			if ok, s := matchString(alias, manufacturer); ok && s+2 > best {
+				best = s + 2
+			}
+		}
+		if best > 0 {
+			return true, best
+		}
+	}
+	matched, best := false, 0
+	for i := range def.Fingerprints {
+		if ok, s := def.Fingerprints[i].match(manufacturer, model, endpoints); ok && (!matched || s > best) {
+			matched, best = true, s
+		}
+	}
+	return matched, best
+}
+
+// Candidates returns every definition matching the device, best first:
+// higher priority wins, then the more specific match, then the definition
+// loaded last. Endpoints may be nil when the simple descriptors are not
+// known yet; fingerprints with endpoint conditions then never match.
+func (db *DeviceDB) Candidates(manufacturer, model string, endpoints []store.Endpoint) []*DeviceDefinition {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	type candidate struct {
+		def   *DeviceDefinition
+		score int
+		order int
+	}
+	var found []candidate
+	if def := db.exact[deviceKey(manufacturer, model)]; def != nil {
+		found = append(found, candidate{def, exactScore, slices.Index(db.defs, def)})
+	}
+	for _, def := range db.fuzzy {
+		if ok, s := def.score(manufacturer, model, endpoints); ok && s < exactScore {
+			found = append(found, candidate{def, s, slices.Index(db.defs, def)})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.def.Priority != b.def.Priority {
+			return a.def.Priority > b.def.Priority
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		return a.order > b.order
+	})
+
+	defs := make([]*DeviceDefinition, len(found))
+	for i, c := range found {
+		defs[i] = c.def
+	}
+	return defs
+}
+
+// Match returns the best definition for the device, or nil.
+func (db *DeviceDB) Match(manufacturer, model string, endpoints []store.Endpoint) *DeviceDefinition {
+	if defs := db.Candidates(manufacturer, model, endpoints); len(defs) > 0 {
+		return defs[0]
+	}
+	return nil
+}
+
+// fuzzy reports whether the definition can match other than by its exact
+// manufacturer and model.
+func (def *DeviceDefinition) fuzzy() bool {
+	return len(def.ManufacturerAliases) > 0 || len(def.Fingerprints) > 0
+}
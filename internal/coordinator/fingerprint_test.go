@@ -0,0 +1,136 @@
+package coordinator
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+func uint16p(v uint16) *uint16 { return &v }
+
+func TestDeviceDBMatch(t *testing.T) {
+	db := NewDeviceDB()
+	db.Add(DeviceDefinition{Manufacturer: "_TZ3000_exact", Model: "TS0201", FriendlyName: "exact"})
+	db.Add(DeviceDefinition{
+		Manufacturer:        "_TZ3000_bguser20",
+		Model:               "TS0201",
+		FriendlyName:        "aliased",
+		ManufacturerAliases: []string{"_TZ3000_xr3htd96", "_TZ3000_*"},
+	})
+	db.Add(DeviceDefinition{
+		FriendlyName: "prefix",
+		Fingerprints: []Fingerprint{{Manufacturer: "IKEA of Sweden", ModelPrefix: "TRADFRI bulb"}},
+	})
+	db.Add(DeviceDefinition{
+		FriendlyName: "regex",
+		Fingerprints: []Fingerprint{{ModelRegex: `^lumi\.sensor_(ht|weather)`}},
+	})
+	db.Add(DeviceDefinition{
+		FriendlyName: "signature",
+		Fingerprints: []Fingerprint{{Endpoints: []EndpointFingerprint{{
+			ID: 1, ProfileID: uint16p(0x0104), DeviceID: uint16p(0x0402), InClusters: []uint16{0x0500},
+		}}}},
+	})
+
+	sensor := []store.Endpoint{{ID: 1, ProfileID: 0x0104, DeviceID: 0x0402, InClusters: []uint16{0x0000, 0x0500}}}
+	tests := []struct {
+		name         string
+		manufacturer string
+		model        string
+		endpoints    []store.Endpoint
+		want         string
+	}{
+		{"exact", "_TZ3000_exact", "TS0201", nil, "exact"},
+		{"primary manufacturer", "_TZ3000_bguser20", "TS0201", nil, "aliased"},
+		{"exact alias", "_TZ3000_xr3htd96", "TS0201", nil, "aliased"},
+		{"pattern alias", "_TZ3000_anything", "TS0201", nil, "aliased"},
+		{"alias needs model", "_TZ3000_anything", "TS0202", nil, ""},
+		{"model prefix", "IKEA of Sweden", "TRADFRI bulb E27 WS opal 980lm", nil, "prefix"},
+		{"model regex", "LUMI", "lumi.sensor_ht.agl02", nil, "regex"},
+		{"endpoint signature", "", "", sensor, "signature"},
+		{"signature needs endpoints", "", "", nil, ""},
+		{"signature cluster missing", "", "", []store.Endpoint{{ID: 1, ProfileID: 0x0104, DeviceID: 0x0402}}, ""},
+		{"no match", "Acme", "TH1", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if def := db.Match(tt.manufacturer, tt.model, tt.endpoints); def != nil {
+				got = def.FriendlyName
+			}
+			if got != tt.want {
+				t.Errorf("Match(%q, %q) = %q, want %q", tt.manufacturer, tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeviceDBMatchPriority(t *testing.T) {
+	db := NewDeviceDB()
+	db.Add(DeviceDefinition{FriendlyName: "family", Fingerprints: []Fingerprint{{Manufacturer: "_TZE200_*", Model: "TS0601"}}})
+	db.Add(DeviceDefinition{FriendlyName: "loose", Fingerprints: []Fingerprint{{Model: "TS0601"}}})
+
+	// The more specific fingerprint wins.
+	if def := db.Lookup("_TZE200_abc", "TS0601"); def == nil || def.FriendlyName != "family" {
+		t.Fatalf("Lookup = %+v, want family", def)
+	}
+
+	// An exact definition wins over fingerprints.
+	db.Add(DeviceDefinition{Manufacturer: "_TZE200_abc", Model: "TS0601", FriendlyName: "exact"})
+	if def := db.Lookup("_TZE200_abc", "TS0601"); def.FriendlyName != "exact" {
+		t.Errorf("Lookup = %q, want exact", def.FriendlyName)
+	}
+
+	// Priority wins over everything.
+	db.Add(DeviceDefinition{FriendlyName: "override", Priority: 10, Fingerprints: []Fingerprint{{Model: "TS0601"}}})
+	candidates := db.Candidates("_TZE200_abc", "TS0601", nil)
+	var names []string
+	for _, def := range candidates {
+		names = append(names, def.FriendlyName)
+	}
+	if got := strings.Join(names, ","); got != "override,exact,family,loose" {
+		t.Errorf("candidates = %s", got)
+	}
+}
+
+func TestLoadDeviceDirFingerprints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	registry := zcl.NewRegistry(logger)
+	dir := t.TempDir()
+
+	os.WriteFile(filepath.Join(dir, "tuya.json"), []byte(`{
+		"manufacturers": [{
+			"name": "_TZ3000_bguser20",
+			"aliases": ["_TZ3000_xr3htd96"],
+			"models": [{"model": "TS0201", "friendly_name": "Tuya TH", "bind": []}]
+		}]
+	}`), 0644)
+
+	db, err := LoadDeviceDir(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def := db.Lookup("_TZ3000_xr3htd96", "TS0201"); def == nil || def.Source != "tuya.json" {
+		t.Errorf("group alias not matched: %+v", def)
+	}
+
+	// An invalid fingerprint is a per-file error on reload.
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{
+		"devices": [{"model": "X", "fingerprints": [{"model_regex": "("}]}]
+	}`), 0644)
+	result, err := db.Reload(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].File != "bad.json" {
+		t.Errorf("errors = %+v, want bad.json", result.Errors)
+	}
+	if db.Lookup("_TZ3000_xr3htd96", "TS0201") == nil {
+		t.Error("good file lost on reload")
+	}
+}
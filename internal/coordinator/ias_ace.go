@@ -0,0 +1,207 @@
+package coordinator
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+// IAS ACE Arm Mode values.
+const (
+	aceArmDisarm uint8 = 0x00
+	aceArmHome   uint8 = 0x01 // Arm Day/Home Zones Only
+	aceArmNight  uint8 = 0x02 // Arm Night/Sleep Zones Only
+	aceArmAway   uint8 = 0x03 // Arm All Zones
+)
+
+// IAS ACE Arm Notification values.
+const (
+	aceNotifyDisarmed        uint8 = 0x00
+	aceNotifyInvalidCode     uint8 = 0x04
+	aceNotifyNotReady        uint8 = 0x05
+	aceNotifyAlreadyDisarmed uint8 = 0x06
+)
+
+// IAS ACE Panel Status values.
+const (
+	acePanelDisarmed    uint8 = 0x00
+	acePanelArmedStay   uint8 = 0x01
+	acePanelArmedNight  uint8 = 0x02
+	acePanelArmedAway   uint8 = 0x03
+	acePanelEntryDelay  uint8 = 0x05
+	acePanelInAlarm     uint8 = 0x07
+	acePanelArmingStay  uint8 = 0x08
+	acePanelArmingNight uint8 = 0x09
+	acePanelArmingAway  uint8 = 0x0A
+)
+
+// acePanelStatus maps a panel state to the IAS ACE Panel Status enum.
+func acePanelStatus(st AlarmStatus) uint8 {
+	switch st.State {
+	case AlarmArmedHome:
+		return acePanelArmedStay
+	case AlarmArmedNight:
+		return acePanelArmedNight
+	case AlarmArmedAway:
+		return acePanelArmedAway
+	case AlarmPending:
+		return acePanelEntryDelay
+	case AlarmTriggered:
+		return acePanelInAlarm
+	case AlarmArming:
+		switch st.Mode {
+		case AlarmModeHome:
+			return acePanelArmingStay
+		case AlarmModeNight:
+			return acePanelArmingNight
+		}
+		return acePanelArmingAway
+	}
+	return acePanelDisarmed
+}
+
+// acePanelStatusPayload encodes the payload shared by Get Panel Status
+// Response and Panel Status Changed: panel status, seconds remaining,
+// audible notification and alarm status.
+func acePanelStatusPayload(st AlarmStatus) []byte {
+	secs := st.SecondsRemaining
+	if secs > 0xFF {
+		secs = 0xFF
+	}
+	var audible uint8 // mute
+	switch st.State {
+	case AlarmArming, AlarmPending, AlarmTriggered:
+		audible = 0x01 // default sound
+	}
+	var alarm uint8
+	if st.State == AlarmTriggered {
+		alarm = alarmCauses[st.Alarm]
+	}
+	return []byte{acePanelStatus(st), uint8(secs), audible, alarm}
+}
+
+// newACEServer returns the local IAS ACE server that keypads talk to.
+func (p *AlarmPanel) newACEServer() *LocalCluster {
+	def := clusters.IASACE
+	return &LocalCluster{
+		Def: &def,
+		Commands: map[uint8]LocalCommandHandler{
+			0x00: p.handleACEArm,
+			0x02: p.aceTrigger("emergency_panic"), // Emergency
+			0x03: p.aceTrigger("fire_panic"),      // Fire
+			0x04: p.aceTrigger("police_panic"),    // Panic
+			0x05: p.handleACEGetZoneIDMap,
+			0x06: p.handleACEGetZoneInformation,
+			0x07: func(LocalCommand) (*LocalReply, uint8) { // GetPanelStatus
+				return &LocalReply{CommandID: 0x05, Payload: acePanelStatusPayload(p.Status())}, zcl.ZCLStatusSuccess
+			},
+			0x08: func(LocalCommand) (*LocalReply, uint8) { // GetBypassedZoneList
+				// Zones are bypassed per mode in the configuration, not from keypads.
+				return &LocalReply{CommandID: 0x06, Payload: []byte{0}}, zcl.ZCLStatusSuccess
+			},
+		},
+	}
+}
+
+// handleACEArm handles Arm: arm mode(1) + arm/disarm code(char string) + zone ID(1).
+func (p *AlarmPanel) handleACEArm(cmd LocalCommand) (*LocalReply, uint8) {
+	if len(cmd.Payload) < 1 {
+		return nil, zcl.ZCLStatusMalformedCommand
+	}
+	var code string
+	if len(cmd.Payload) >= 2 {
+		if n := int(cmd.Payload[1]); n != 0xFF && 2+n <= len(cmd.Payload) {
+			code = string(cmd.Payload[2 : 2+n])
+		}
+	}
+	ieee := p.coord.devices.lookupIEEE(cmd.SrcAddr)
+
+	var err error
+	notify := cmd.Payload[0]
+	switch cmd.Payload[0] {
+	case aceArmDisarm:
+		err = p.disarm(code, "keypad", ieee)
+		notify = aceNotifyDisarmed
+	case aceArmHome:
+		err = p.arm(AlarmModeHome, code, "keypad", ieee)
+	case aceArmNight:
+		err = p.arm(AlarmModeNight, code, "keypad", ieee)
+	case aceArmAway:
+		err = p.arm(AlarmModeAway, code, "keypad", ieee)
+	default:
+		return nil, zcl.ZCLStatusInvalidValue
+	}
+	switch {
+	case errors.Is(err, ErrInvalidCode):
+		notify = aceNotifyInvalidCode
+	case errors.Is(err, ErrAlreadyDisarmed):
+		notify = aceNotifyAlreadyDisarmed
+	case err != nil:
+		notify = aceNotifyNotReady
+	}
+	return &LocalReply{CommandID: 0x00, Payload: []byte{notify}}, zcl.ZCLStatusSuccess // ArmResponse
+}
+
+// aceTrigger returns a handler for the keypad panic buttons.
+func (p *AlarmPanel) aceTrigger(cause string) LocalCommandHandler {
+	return func(cmd LocalCommand) (*LocalReply, uint8) {
+		p.trigger(cause, "keypad", p.coord.devices.lookupIEEE(cmd.SrcAddr))
+		return nil, zcl.ZCLStatusSuccess
+	}
+}
+
+// handleACEGetZoneIDMap answers with 16 bitmaps of allocated zone IDs.
+func (p *AlarmPanel) handleACEGetZoneIDMap(LocalCommand) (*LocalReply, uint8) {
+	var sections [16]uint16
+	devices, err := p.coord.store.ListDevices()
+	if err != nil {
+		return nil, zcl.ZCLStatusFailure
+	}
+	for _, d := range devices {
+		if d.IASZone != nil && d.IASZone.Enrolled {
+			id := d.IASZone.ZoneID
+			sections[id/16] |= 1 << (id % 16)
+		}
+	}
+	payload := make([]byte, 0, 32)
+	for _, s := range sections {
+		payload = binary.LittleEndian.AppendUint16(payload, s)
+	}
+	return &LocalReply{CommandID: 0x01, Payload: payload}, zcl.ZCLStatusSuccess
+}
+
+// handleACEGetZoneInformation answers with the zone type, IEEE address and
+// label of a zone ID. Unknown zones report type 0xFFFF and address all-ones.
+func (p *AlarmPanel) handleACEGetZoneInformation(cmd LocalCommand) (*LocalReply, uint8) {
+	if len(cmd.Payload) < 1 {
+		return nil, zcl.ZCLStatusMalformedCommand
+	}
+	zoneID := cmd.Payload[0]
+	payload := []byte{zoneID, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0}
+
+	devices, err := p.coord.store.ListDevices()
+	if err != nil {
+		return nil, zcl.ZCLStatusFailure
+	}
+	for _, d := range devices {
+		if d.IASZone == nil || !d.IASZone.Enrolled || d.IASZone.ZoneID != zoneID {
+			continue
+		}
+		ieee, err := ParseIEEE(d.IEEEAddress)
+		if err != nil {
+			break
+		}
+		label := d.FriendlyName
+		if len(label) > 0xFE {
+			label = label[:0xFE]
+		}
+		payload = binary.LittleEndian.AppendUint16(payload[:1], d.IASZone.ZoneType)
+		payload = append(payload, ieee[:]...)
+		payload = append(payload, uint8(len(label)))
+		payload = append(payload, label...)
+		break
+	}
+	return &LocalReply{CommandID: 0x02, Payload: payload}, zcl.ZCLStatusSuccess
+}
@@ -0,0 +1,263 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+// IAS Zone Enroll Response codes.
+const (
+	iasEnrollSuccess      uint8 = 0x00
+	iasEnrollNoPermit     uint8 = 0x02
+	iasEnrollTooManyZones uint8 = 0x03
+)
+
+// iasMaxZoneID is the highest valid zone ID (0xFF is reserved).
+const iasMaxZoneID = 0xFE
+
+// newIASZoneClient returns the local IAS Zone client (the CIE side). It
+// answers Zone Enroll Requests with a zone ID; status notifications are
+// decoded by the device manager.
+func (c *Coordinator) newIASZoneClient() *LocalCluster {
+	def := clusters.IASZone
+	return &LocalCluster{
+		Def:    &def,
+		Client: true,
+		Commands: map[uint8]LocalCommandHandler{
+			0x00: func(LocalCommand) (*LocalReply, uint8) { // ZoneStatusChangeNotification
+				return nil, zcl.ZCLStatusSuccess
+			},
+			0x01: func(cmd LocalCommand) (*LocalReply, uint8) { // ZoneEnrollRequest
+				if len(cmd.Payload) < 2 {
+					return nil, zcl.ZCLStatusMalformedCommand
+				}
+				zoneType := binary.LittleEndian.Uint16(cmd.Payload[0:2])
+				code, zoneID := c.devices.enrollIASZone(cmd.SrcAddr, cmd.SrcEP, zoneType)
+				return &LocalReply{CommandID: 0x00, Payload: []byte{code, zoneID}}, zcl.ZCLStatusSuccess
+			},
+		},
+	}
+}
+
+// enrollIASZone records a Zone Enroll Request and returns the enroll
+// response code and the zone ID assigned to the device.
+func (dm *DeviceManager) enrollIASZone(shortAddr uint16, ep uint8, zoneType uint16) (uint8, uint8) {
+	ieee := dm.lookupOrRebuild(shortAddr)
+	if ieee == "" {
+		dm.logger.Warn("IAS zone enroll from unknown device", "short", fmt.Sprintf("0x%04X", shortAddr))
+		return iasEnrollNoPermit, 0
+	}
+
+	zoneID, dev, ok := dm.saveZoneEnrollment(ieee, true, func(z *store.IASZoneState) {
+		z.Endpoint = ep
+		z.ZoneType = zoneType
+	})
+	if !ok {
+		return iasEnrollTooManyZones, 0
+	}
+	dm.logger.Info("IAS zone enrolled", "ieee", ieee, "name", deviceName(dev),
+		"zone_id", zoneID, "zone_type", fmt.Sprintf("0x%04X", zoneType))
+	return iasEnrollSuccess, zoneID
+}
+
+// saveZoneEnrollment assigns a zone ID to the device and persists it,
+// applying fn to the stored state. enrolled marks the zone enrolled; a zone
+// already enrolled stays so. Allocation and save happen under zoneMu so
+// concurrent enrollments never share an ID.
+func (dm *DeviceManager) saveZoneEnrollment(ieee string, enrolled bool, fn func(z *store.IASZoneState)) (uint8, *store.Device, bool) {
+	dm.zoneMu.Lock()
+	defer dm.zoneMu.Unlock()
+
+	zoneID, ok := dm.allocateZoneID(ieee)
+	if !ok {
+		dm.logger.Warn("IAS zone: no free zone IDs", "ieee", ieee)
+		return 0, nil, false
+	}
+
+	var dev *store.Device
+	if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if d.IASZone == nil {
+			d.IASZone = &store.IASZoneState{}
+		}
+		fn(d.IASZone)
+		d.IASZone.ZoneID = zoneID
+		if enrolled && !d.IASZone.Enrolled {
+			d.IASZone.Enrolled = true
+			d.IASZone.EnrolledAt = time.Now()
+		}
+		dev = d
+		return nil
+	}); err != nil {
+		dm.logger.Error("IAS zone: save enrollment", "err", err, "ieee", ieee)
+		return 0, nil, false
+	}
+	return zoneID, dev, true
+}
+
+// allocateZoneID returns the device's existing zone ID or the lowest one not
+// used by another device. A zone ID is in use once the device is enrolled or
+// its CIE address was written, as it may have enrolled with the ID sent in
+// the Enroll Response. Caller must hold zoneMu.
+func (dm *DeviceManager) allocateZoneID(ieee string) (uint8, bool) {
+	devices, err := dm.coord.Store().ListDevices()
+	if err != nil {
+		dm.logger.Error("IAS zone: list devices", "err", err)
+		return 0, false
+	}
+	used := make(map[uint8]bool)
+	for _, d := range devices {
+		if d.IASZone == nil || !d.IASZone.Enrolled && !d.IASZone.CIEAddressSet {
+			continue
+		}
+		if d.IEEEAddress == ieee {
+			return d.IASZone.ZoneID, true
+		}
+		used[d.IASZone.ZoneID] = true
+	}
+	for id := 0; id <= iasMaxZoneID; id++ {
+		if !used[uint8(id)] {
+			return uint8(id), true
+		}
+	}
+	return 0, false
+}
+
+// setupIASZone writes the coordinator IEEE into IAS_CIE_Address on the
+// device's IAS Zone endpoint and sends an unsolicited Zone Enroll Response
+// for devices using auto-enroll. Sending the response does not show the
+// device enrolled, so the zone is only marked enrolled when it reads back
+// as enrolled (ZoneState) or sends an Enroll Request. Must run while the
+// device is awake.
+func (dm *DeviceManager) setupIASZone(ctx context.Context, dev *store.Device) {
+	var ep uint8
+	for _, e := range dev.Endpoints {
+		if hasInCluster(e, 0x0500) {
+			ep = e.ID
+			break
+		}
+	}
+	if ep == 0 {
+		return
+	}
+	name := deviceName(dev)
+	coordIEEE := dm.coord.LocalIEEE()
+
+	// Auto-enroll devices never send their zone type in an Enroll Request.
+	zoneType, hasZoneType := dm.readZoneType(ctx, dev, ep)
+
+	err := dm.coord.NCP().WriteAttributes(ctx, ncp.WriteAttributesRequest{
+		DstAddr:   dev.ShortAddress,
+		DstEP:     ep,
+		ClusterID: 0x0500,
+		Records: []ncp.WriteRecord{
+			{AttrID: 0x0010, DataType: zcl.TypeEUI64, Value: coordIEEE[:]},
+		},
+	})
+	if err != nil {
+		dm.logger.Warn("IAS zone: write CIE address", "err", err, "ieee", dev.IEEEAddress, "name", name, "ep", ep)
+		return
+	}
+	dm.logger.Info("IAS zone: CIE address written", "ieee", dev.IEEEAddress, "name", name, "ep", ep)
+
+	zoneID, _, ok := dm.saveZoneEnrollment(dev.IEEEAddress, false, func(z *store.IASZoneState) {
+		z.Endpoint = ep
+		z.CIEAddressSet = true
+		if hasZoneType {
+			z.ZoneType = zoneType
+		}
+	})
+	if !ok {
+		return
+	}
+
+	// Auto-enroll-response devices never send an Enroll Request and wait
+	// for this instead; others ignore it and enroll via the request.
+	err = dm.coord.NCP().SendCommand(ctx, ncp.ClusterCommandRequest{
+		DstAddr:   dev.ShortAddress,
+		DstEP:     ep,
+		ClusterID: 0x0500,
+		CommandID: 0x00, // ZoneEnrollResponse
+		Payload:   []byte{iasEnrollSuccess, zoneID},
+	})
+	if err != nil {
+		dm.logger.Warn("IAS zone: enroll response", "err", err, "ieee", dev.IEEEAddress, "name", name)
+	}
+	// Enroll-request devices enroll later, through enrollIASZone.
+	if !dm.readZoneEnrolled(ctx, dev, ep) {
+		return
+	}
+	if _, _, ok := dm.saveZoneEnrollment(dev.IEEEAddress, true, func(*store.IASZoneState) {}); ok {
+		dm.logger.Info("IAS zone enrolled", "ieee", dev.IEEEAddress, "name", name, "zone_id", zoneID)
+	}
+}
+
+// readZoneEnrolled reads the IAS ZoneState attribute and reports whether
+// the device is enrolled.
+func (dm *DeviceManager) readZoneEnrolled(ctx context.Context, dev *store.Device, ep uint8) bool {
+	results, err := dm.coord.NCP().ReadAttributes(ctx, ncp.ReadAttributesRequest{
+		DstAddr:   dev.ShortAddress,
+		DstEP:     ep,
+		ClusterID: 0x0500,
+		AttrIDs:   []uint16{0x0000},
+	})
+	if err != nil {
+		dm.logger.Warn("IAS zone: read zone state", "err", err, "ieee", dev.IEEEAddress, "name", deviceName(dev))
+		return false
+	}
+	for _, r := range results {
+		if r.AttrID != 0x0000 || r.Status != 0 {
+			continue
+		}
+		if val, _, err := zcl.DecodeValue(r.DataType, r.Value); err == nil {
+			return val == uint8(1)
+		}
+	}
+	return false
+}
+
+// readZoneType reads the IAS ZoneType attribute.
+func (dm *DeviceManager) readZoneType(ctx context.Context, dev *store.Device, ep uint8) (uint16, bool) {
+	results, err := dm.coord.NCP().ReadAttributes(ctx, ncp.ReadAttributesRequest{
+		DstAddr:   dev.ShortAddress,
+		DstEP:     ep,
+		ClusterID: 0x0500,
+		AttrIDs:   []uint16{0x0001},
+	})
+	if err != nil {
+		dm.logger.Warn("IAS zone: read zone type", "err", err, "ieee", dev.IEEEAddress, "name", deviceName(dev))
+		return 0, false
+	}
+	for _, r := range results {
+		if r.AttrID != 0x0001 || r.Status != 0 {
+			continue
+		}
+		if val, _, err := zcl.DecodeValue(r.DataType, r.Value); err == nil {
+			if t, ok := val.(uint16); ok {
+				return t, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// handleZoneStatus converts the ZoneStatus carried by a Zone Status Change
+// Notification like a ZoneStatus attribute report, so both emit the same
+// flags (alarm1, tamper, ...).
+func (dm *DeviceManager) handleZoneStatus(ieee string, dev *store.Device, status uint16) {
+	props := dm.applyConverter(ieee, dev, 0x0500, 0x0002, status)
+	if len(props) == 0 {
+		return
+	}
+	args := []any{"ieee", ieee, "name", deviceName(dev), "status", fmt.Sprintf("0x%04X", status)}
+	for _, p := range props {
+		args = append(args, p.Name, p.Value)
+	}
+	dm.logger.Info("zone status", args...)
+}
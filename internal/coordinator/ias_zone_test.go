@@ -0,0 +1,206 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+func TestDecodeZoneStatus(t *testing.T) {
+	conv := clusters.IASZone.FindAttribute(0x0002).Converter
+	props := make(map[string]any)
+	for _, p := range conv.Convert(uint16(0x0005)) { // alarm1 + tamper
+		props[p.Name] = p.Value
+	}
+	want := map[string]any{"zone_status": uint16(0x0005), "alarm1": true, "alarm2": false, "tamper": true, "battery_low": false}
+	for k, v := range want {
+		if props[k] != v {
+			t.Errorf("%s: got %v, want %v", k, props[k], v)
+		}
+	}
+}
+
+func TestIASZoneEnrollRequest(t *testing.T) {
+	dm, ms := newTestDM(t)
+	dm.coord.devices = dm
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	ms.devices["00158D0001A2B3C5"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C5",
+		ShortAddress: 0x2222,
+		IASZone:      &store.IASZoneState{Enrolled: true, ZoneID: 0},
+	}
+	dm.RebuildAddrIndex()
+
+	s, sent := newTestLocalServer(t)
+	s.Register(1, dm.coord.newIASZoneClient())
+
+	handled := s.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1111, SrcEP: 1, DstEP: 1, ClusterID: 0x0500,
+		CommandID: 0x01, Seq: 9, ServerToClient: true,
+		Payload: []byte{0x0D, 0x00, 0x00, 0x00}, // motion sensor, mfr 0
+	})
+	if !handled {
+		t.Fatal("enroll request not handled")
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(*sent))
+	}
+	rsp := (*sent)[0]
+	if rsp.Global || rsp.ServerToClient || rsp.CommandID != 0x00 || rsp.Seq != 9 {
+		t.Errorf("header: %+v", rsp)
+	}
+	// Zone 0 is taken by the other device.
+	if !bytes.Equal(rsp.Payload, []byte{iasEnrollSuccess, 1}) {
+		t.Errorf("payload: got %X", rsp.Payload)
+	}
+
+	z := ms.devices["00158D0001A2B3C4"].IASZone
+	if z == nil || !z.Enrolled || z.ZoneID != 1 || z.ZoneType != 0x000D || z.Endpoint != 1 {
+		t.Errorf("stored zone: %+v", z)
+	}
+	if z != nil && z.EnrolledAt.IsZero() {
+		t.Error("EnrolledAt not set")
+	}
+}
+
+func TestIASZoneEnrollUnknownDevice(t *testing.T) {
+	dm, _ := newTestDM(t)
+	code, _ := dm.enrollIASZone(0x9999, 1, 0x0015)
+	if code != iasEnrollNoPermit {
+		t.Errorf("code: got %d, want %d", code, iasEnrollNoPermit)
+	}
+}
+
+func TestAllocateZoneIDReusesExisting(t *testing.T) {
+	dm, ms := newTestDM(t)
+	ms.devices["A"] = &store.Device{IEEEAddress: "A", IASZone: &store.IASZoneState{Enrolled: true, ZoneID: 0}}
+	ms.devices["B"] = &store.Device{IEEEAddress: "B", IASZone: &store.IASZoneState{Enrolled: true, ZoneID: 5}}
+	ms.devices["C"] = &store.Device{IEEEAddress: "C", IASZone: &store.IASZoneState{ZoneID: 1}}
+
+	if id, ok := dm.allocateZoneID("B"); !ok || id != 5 {
+		t.Errorf("B: got %d/%v, want 5", id, ok)
+	}
+	// C is not enrolled, so zone 1 is free for it.
+	if id, ok := dm.allocateZoneID("C"); !ok || id != 1 {
+		t.Errorf("C: got %d/%v, want 1", id, ok)
+	}
+}
+
+func TestZoneStatusChangeNotification(t *testing.T) {
+	dm, ms := newTestDM(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	dm.RebuildAddrIndex()
+
+	got := make(map[string]any)
+	dm.coord.Events().On(EventPropertyUpdate, func(e Event) {
+		data := e.Data.(map[string]interface{})
+		got[data["property"].(string)] = data["value"]
+	})
+
+	dm.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1111, SrcEP: 1, ClusterID: 0x0500, CommandID: 0x00, ServerToClient: true,
+		Payload: []byte{0x09, 0x00, 0x00, 0x01, 0x00, 0x00}, // alarm1 + battery_low
+	})
+
+	if got["alarm1"] != true || got["battery_low"] != true || got["tamper"] != false {
+		t.Errorf("events: %v", got)
+	}
+	props := ms.devices["00158D0001A2B3C4"].Properties
+	if props["alarm1"] != true || props["alarm2"] != false {
+		t.Errorf("stored properties: %v", props)
+	}
+}
+
+// zoneReads answers the ZoneType and ZoneState reads of an IAS zone device.
+func zoneReads(zoneState uint8) func(ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+	return func(req ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+		var rsp []ncp.AttributeResponse
+		for _, id := range req.AttrIDs {
+			switch id {
+			case 0x0000: // ZoneState
+				rsp = append(rsp, ncp.AttributeResponse{AttrID: id, DataType: zcl.TypeEnum8, Value: []byte{zoneState}})
+			case 0x0001: // ZoneType
+				rsp = append(rsp, ncp.AttributeResponse{AttrID: id, DataType: zcl.TypeEnum16, Value: []byte{0x15, 0x00}})
+			}
+		}
+		return rsp
+	}
+}
+
+func newTestIASSetup(t *testing.T, zoneState uint8) (*DeviceManager, *memStore, *fakeNCP, *store.Device) {
+	t.Helper()
+	c, ms, n := newTestCoord(t)
+	n.onRead = zoneReads(zoneState)
+	dev := &store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111,
+		Endpoints: []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0x0500}}},
+	}
+	ms.devices[dev.IEEEAddress] = dev
+	return c.devices, ms, n, dev
+}
+
+func TestSetupIASZoneEnrolls(t *testing.T) {
+	dm, ms, n, dev := newTestIASSetup(t, 1)
+	dm.setupIASZone(context.Background(), dev)
+
+	if len(n.commands) != 1 || n.commands[0].CommandID != 0x00 || !bytes.Equal(n.commands[0].Payload, []byte{iasEnrollSuccess, 0}) {
+		t.Fatalf("commands: %+v", n.commands)
+	}
+	z := ms.devices[dev.IEEEAddress].IASZone
+	if z == nil || !z.Enrolled || z.EnrolledAt.IsZero() || !z.CIEAddressSet || z.ZoneType != 0x0015 || z.Endpoint != 1 {
+		t.Errorf("stored zone: %+v", z)
+	}
+}
+
+func TestSetupIASZoneEnrollResponseIgnored(t *testing.T) {
+	// The response is acked, but the device waits to send an Enroll Request.
+	dm, ms, n, dev := newTestIASSetup(t, 0)
+	dm.setupIASZone(context.Background(), dev)
+
+	if len(n.commands) != 1 {
+		t.Fatalf("commands: %+v", n.commands)
+	}
+	z := ms.devices[dev.IEEEAddress].IASZone
+	if z == nil || z.Enrolled || !z.EnrolledAt.IsZero() || !z.CIEAddressSet {
+		t.Fatalf("stored zone with ZoneState 0: %+v", z)
+	}
+
+	dm.RebuildAddrIndex()
+	if code, id := dm.enrollIASZone(0x1111, 1, 0x0015); code != iasEnrollSuccess || id != z.ZoneID {
+		t.Errorf("enroll request: code %d, zone %d, want zone %d", code, id, z.ZoneID)
+	}
+	if z := ms.devices[dev.IEEEAddress].IASZone; !z.Enrolled || z.EnrolledAt.IsZero() {
+		t.Errorf("stored zone after the enroll request: %+v", z)
+	}
+}
+
+func TestSetupIASZoneEnrollResponseFails(t *testing.T) {
+	dm, ms, n, dev := newTestIASSetup(t, 0)
+	n.sendErr = errors.New("no route")
+	dm.setupIASZone(context.Background(), dev)
+
+	z := ms.devices[dev.IEEEAddress].IASZone
+	if z == nil || z.Enrolled || !z.EnrolledAt.IsZero() {
+		t.Fatalf("zone enrolled without an enroll response: %+v", z)
+	}
+	if !z.CIEAddressSet || z.ZoneType != 0x0015 || z.Endpoint != 1 {
+		t.Errorf("stored zone: %+v", z)
+	}
+	// The zone ID sent stays reserved for the device.
+	if id, ok := dm.allocateZoneID("00158D0001A2B3C5"); !ok || id != 1 {
+		t.Errorf("zone ID for another device: got %d/%v, want 1", id, ok)
+	}
+
+	// The device reads back as enrolled: it enrolled on its own.
+	n.onRead = zoneReads(1)
+	dm.setupIASZone(context.Background(), dev)
+	if z := ms.devices[dev.IEEEAddress].IASZone; !z.Enrolled || z.ZoneID != 0 {
+		t.Errorf("stored zone after ZoneState 1: %+v", z)
+	}
+}
@@ -0,0 +1,423 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"zigbee-go-home/internal/store"
+)
+
+const (
+	// maxInterviewRuns is how many interview runs an unfinished interview
+	// gets before it is no longer resumed automatically.
+	maxInterviewRuns = 10
+	// interviewResumeInterval paces resumes triggered by device traffic.
+	interviewResumeInterval = time.Minute
+)
+
+var (
+	ErrNotInterviewed = errors.New("device not interviewed")
+	ErrNoDefinition   = errors.New("no device definition")
+)
+
+// Interview progress steps, reported as interview_progress events.
+const (
+	StepStart            = "start"             // operation: "interview" or "configure"
+	StepEndpoints        = "endpoints"         // Active Endpoints request
+	StepBasicAttributes  = "basic_attributes"  // ManufacturerName and ModelIdentifier
+	StepSimpleDescriptor = "simple_descriptor" // per endpoint
+	StepBind             = "bind"              // per endpoint and cluster
+	StepReporting        = "reporting"         // per endpoint, cluster and attribute
+	StepComplete         = "complete"
+)
+
+// Progress statuses.
+const (
+	ProgressStarted = "started"
+	ProgressQueued  = "queued" // waits for the next Poll Control check-in
+	ProgressOK      = "ok"
+	ProgressFailed  = "failed"
+)
+
+// progress emits an interview_progress event. A non-nil err marks the step
+// failed; fields carry step details (endpoint, cluster, attribute, ...).
+func (dm *DeviceManager) progress(ieee, step string, err error, fields map[string]interface{}) {
+	data := map[string]interface{}{"status": ProgressOK}
+	for k, v := range fields {
+		data[k] = v
+	}
+	data["ieee"] = ieee
+	data["step"] = step
+	if err != nil {
+		data["status"] = ProgressFailed
+		data["error"] = err.Error()
+	}
+	dm.coord.Events().Emit(Event{Type: EventInterviewProgress, Data: data})
+}
+
+// progressStatus emits a step with an explicit status and no error.
+func (dm *DeviceManager) progressStatus(ieee, step, status string, fields map[string]interface{}) {
+	data := map[string]interface{}{"status": status}
+	for k, v := range fields {
+		data[k] = v
+	}
+	dm.progress(ieee, step, nil, data)
+}
+
+// trackInterview registers a cancellable interview or reconfiguration of a
+// device, cancelling one already running. done must be called when it ends.
+func (dm *DeviceManager) trackInterview(ieee string) (context.Context, func()) {
+	gen := dm.interviewGen.Add(1)
+	ctx, cancel := context.WithTimeout(dm.coord.Context(), interviewTimeout)
+
+	dm.interviewMu.Lock()
+	if prev, ok := dm.interviewCancels[ieee]; ok {
+		prev.cancel()
+	}
+	dm.interviewCancels[ieee] = interviewEntry{cancel: cancel, gen: gen}
+	dm.interviewMu.Unlock()
+
+	return ctx, func() {
+		cancel()
+		dm.interviewMu.Lock()
+		if entry, ok := dm.interviewCancels[ieee]; ok && entry.gen == gen {
+			delete(dm.interviewCancels, ieee)
+		}
+		dm.interviewMu.Unlock()
+	}
+}
+
+// Reinterview re-runs the full interview of a known device in the
+// background from scratch, discarding any unfinished progress. Sleepy
+// devices must be woken up first.
+func (dm *DeviceManager) Reinterview(ieee string) error {
+	if _, err := dm.coord.Store().GetDevice(ieee); err != nil {
+		return err
+	}
+	dm.interviewWg.Add(1)
+	go dm.interview(ieee, true)
+	return nil
+}
+
+// Reconfigure re-applies the bindings and reporting of the device
+// definition in the background, e.g. after the definition was fixed.
+// Poll Control devices are reconfigured at their next check-in and
+// ErrCommandQueued is returned.
+func (dm *DeviceManager) Reconfigure(ieee string) error {
+	dev, err := dm.coord.Store().GetDevice(ieee)
+	if err != nil {
+		return err
+	}
+	if !dev.Interviewed || len(dev.Endpoints) == 0 {
+		return ErrNotInterviewed
+	}
+	def := dm.definition(dev)
+	if def == nil {
+		return ErrNoDefinition
+	}
+
+	if dev.PollControl != nil {
+		if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+			if d.PollControl != nil {
+				d.PollControl.Configured = false
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("queue reconfigure: %w", err)
+		}
+		dm.logger.Info("reconfigure queued until check-in", "ieee", ieee, "name", deviceName(dev))
+		dm.progressStatus(ieee, StepStart, ProgressQueued, map[string]interface{}{"operation": "configure"})
+		return ErrCommandQueued
+	}
+
+	dm.interviewWg.Add(1)
+	go func() {
+		defer dm.interviewWg.Done()
+		ctx, done := dm.trackInterview(ieee)
+		defer done()
+		dm.runConfigure(ctx, dev, def)
+	}()
+	return nil
+}
+
+// runConfigure applies a definition with start and complete progress events.
+func (dm *DeviceManager) runConfigure(ctx context.Context, dev *store.Device, def *DeviceDefinition) {
+	ieee := dev.IEEEAddress
+	dm.logger.Info("reconfiguring device", "ieee", ieee, "name", deviceName(dev))
+	dm.progressStatus(ieee, StepStart, ProgressStarted, map[string]interface{}{"operation": "configure"})
+	failures := dm.configureDevice(ctx, dev, def, nil)
+	var err error
+	if failures > 0 {
+		err = fmt.Errorf("%d steps failed", failures)
+	}
+	dm.progress(ieee, StepComplete, err, map[string]interface{}{"operation": "configure"})
+}
+
+// beginInterview starts an interview run: an unfinished interview is
+// resumed unless restart is set or it was given up, anything else starts
+// over. Reports whether the run resumes.
+func (dm *DeviceManager) beginInterview(ieee string, restart bool) (*store.InterviewState, bool, error) {
+	var st *store.InterviewState
+	resumed := false
+	err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if cur := d.Interview; !restart && cur != nil && cur.Stage != StepComplete && !cur.GaveUp {
+			st = cloneInterviewState(cur)
+			resumed = true
+		} else {
+			st = &store.InterviewState{Stage: StepEndpoints}
+		}
+		st.Runs++
+		st.Updated = time.Now()
+		d.Interview = cloneInterviewState(st)
+		return nil
+	})
+	return st, resumed, err
+}
+
+// interviewSteps runs the interview steps not yet done in st, saving
+// progress after each one. Returns the error of the step that failed.
+func (dm *DeviceManager) interviewSteps(ctx context.Context, dev *store.Device, st *store.InterviewState) error {
+	ieee := dev.IEEEAddress
+
+	if st.EndpointIDs == nil {
+		st.Stage = StepEndpoints
+		endpoints, err := dm.coord.NCP().ActiveEndpoints(ctx, dev.ShortAddress)
+		ids := make([]int, len(endpoints))
+		eps := make([]interface{}, len(endpoints)) // []uint8 would marshal as base64
+		for i, ep := range endpoints {
+			ids[i] = int(ep)
+			eps[i] = ep
+		}
+		dm.progress(ieee, StepEndpoints, err, map[string]interface{}{"endpoints": eps})
+		if err != nil {
+			return fmt.Errorf("active endpoints: %w", err)
+		}
+		st.EndpointIDs = ids
+		dm.saveInterviewState(ctx, ieee, st, nil)
+	}
+
+	// Read model/manufacturer early so we have the real name for logging.
+	if !st.Basic {
+		st.Stage = StepBasicAttributes
+		if len(st.EndpointIDs) > 0 {
+			if err := dm.readBasicAttributes(ctx, dev, uint8(st.EndpointIDs[0])); err != nil {
+				return fmt.Errorf("basic attributes: %w", err)
+			}
+		}
+		st.Basic = true
+		manufacturer, model := dev.Manufacturer, dev.Model
+		dm.saveInterviewState(ctx, ieee, st, func(d *store.Device) {
+			if manufacturer != "" {
+				d.Manufacturer = manufacturer
+			}
+			if model != "" {
+				d.Model = model
+			}
+		})
+	}
+
+	name := deviceName(dev)
+	for _, id := range st.EndpointIDs {
+		ep := uint8(id)
+		if slices.ContainsFunc(st.Descriptors, func(e store.Endpoint) bool { return e.ID == ep }) {
+			continue
+		}
+		st.Stage = StepSimpleDescriptor
+		sd, err := dm.coord.NCP().SimpleDescriptor(ctx, dev.ShortAddress, ep)
+		dm.progress(ieee, StepSimpleDescriptor, err, map[string]interface{}{"endpoint": ep})
+		if err != nil {
+			return fmt.Errorf("simple descriptor of endpoint %d: %w", ep, err)
+		}
+		st.Descriptors = append(st.Descriptors, store.Endpoint{
+			ID:          ep,
+			ProfileID:   sd.ProfileID,
+			DeviceID:    sd.DeviceID,
+			InClusters:  sd.InClusters,
+			OutClusters: sd.OutClusters,
+		})
+		dm.saveInterviewState(ctx, ieee, st, nil)
+
+		dm.logger.Info("endpoint discovered",
+			"ieee", ieee, "name", name, "ep", ep,
+			"profile", fmt.Sprintf("0x%04X", sd.ProfileID),
+			"device", fmt.Sprintf("0x%04X", sd.DeviceID),
+			"in_clusters", len(sd.InClusters),
+			"out_clusters", len(sd.OutClusters),
+		)
+	}
+
+	// Look up the device definition. Fingerprints may match on the
+	// endpoint layout, so this waits for the simple descriptors.
+	var def *DeviceDefinition
+	if db := dm.coord.DeviceDB(); db != nil {
+		def = db.Match(dev.Manufacturer, dev.Model, st.Descriptors)
+	}
+
+	// Set friendly name: prefer definition, fall back to model.
+	if def != nil && def.FriendlyName != "" {
+		dev.FriendlyName = def.FriendlyName
+	} else if dev.FriendlyName == "" && dev.Model != "" {
+		dev.FriendlyName = dev.Model
+	}
+	name = deviceName(dev)
+
+	// Check if context was cancelled (e.g., by HandleLeave) before saving,
+	// to prevent resurrecting a deleted device.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// The device is usable from here on; configuration failures are
+	// retried on later runs. Use UpdateDevice (atomic merge) instead of
+	// SaveDevice to avoid overwriting concurrent updates from
+	// HandleAttributeReport.
+	dev.Endpoints = slices.Clone(st.Descriptors)
+	dev.Interviewed = true
+	st.Stage = StepBind
+	endpoints, friendlyName := dev.Endpoints, dev.FriendlyName
+	dm.saveInterviewState(ctx, ieee, st, func(d *store.Device) {
+		d.Endpoints = endpoints
+		d.Interviewed = true
+		d.FriendlyName = friendlyName
+	})
+
+	// Enroll IAS zones and configure bindings immediately while the device is still awake.
+	dm.setupIASZone(ctx, dev)
+	if def != nil {
+		if st.Configure == nil {
+			st.Configure = make(map[string]string)
+		}
+		if failures := dm.configureDevice(ctx, dev, def, st.Configure); failures > 0 {
+			st.Stage = StepReporting
+			for key, result := range st.Configure {
+				if strings.HasPrefix(key, StepBind+"/") && result != ProgressOK {
+					st.Stage = StepBind
+				}
+			}
+			dm.saveInterviewState(ctx, ieee, st, nil)
+			return fmt.Errorf("%d configuration steps failed", failures)
+		}
+	} else {
+		dm.logger.Info("no device definition found, skipping configure",
+			"ieee", ieee, "name", name,
+			"manufacturer", dev.Manufacturer, "model", dev.Model)
+	}
+	dm.setupPollControl(ctx, dev, def)
+	dm.queryTuya(ctx, dev)
+	return nil
+}
+
+// completeInterview records a finished interview and announces it.
+func (dm *DeviceManager) completeInterview(ctx context.Context, dev *store.Device, st *store.InterviewState) {
+	st.Stage = StepComplete
+	st.LastError = ""
+	dm.saveInterviewState(ctx, dev.IEEEAddress, st, nil)
+
+	dm.logger.Info("interview complete", "ieee", dev.IEEEAddress, "name", deviceName(dev),
+		"endpoints", len(st.Descriptors), "run", st.Runs)
+	dm.coord.Events().Emit(Event{
+		Type: EventInterviewCompleted,
+		Data: map[string]interface{}{
+			"ieee":         dev.IEEEAddress,
+			"manufacturer": dev.Manufacturer,
+			"model":        dev.Model,
+			"endpoints":    len(st.Descriptors),
+			"run":          st.Runs,
+		},
+	})
+}
+
+// failInterview records a failed run, which resumes on the device's next
+// frame unless maxInterviewRuns is reached. Runs cancelled by a newer
+// interview, a leave or shutdown leave the state alone.
+func (dm *DeviceManager) failInterview(ctx context.Context, ieee string, st *store.InterviewState, err error) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		dm.logger.Info("interview cancelled", "ieee", ieee, "stage", st.Stage)
+		return
+	}
+	st.LastError = err.Error()
+	st.GaveUp = st.Runs >= maxInterviewRuns
+	dm.saveInterviewState(context.WithoutCancel(ctx), ieee, st, nil)
+
+	dm.logger.Warn("interview failed", "err", err, "ieee", ieee, "stage", st.Stage,
+		"run", st.Runs, "gave_up", st.GaveUp)
+	dm.coord.Events().Emit(Event{
+		Type: EventInterviewFailed,
+		Data: map[string]interface{}{
+			"ieee":    ieee,
+			"stage":   st.Stage,
+			"error":   err.Error(),
+			"run":     st.Runs,
+			"gave_up": st.GaveUp,
+		},
+	})
+}
+
+// saveInterviewState persists st, and fn's device changes, unless the run
+// was cancelled.
+func (dm *DeviceManager) saveInterviewState(ctx context.Context, ieee string, st *store.InterviewState, fn func(d *store.Device)) {
+	if ctx.Err() != nil {
+		return
+	}
+	st.Updated = time.Now()
+	saved := cloneInterviewState(st)
+	if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		d.Interview = saved
+		if fn != nil {
+			fn(d)
+		}
+		return nil
+	}); err != nil {
+		dm.logger.Error("interview: save", "err", err, "ieee", ieee, "stage", st.Stage)
+	}
+}
+
+func cloneInterviewState(st *store.InterviewState) *store.InterviewState {
+	cp := *st
+	cp.EndpointIDs = slices.Clone(st.EndpointIDs)
+	cp.Descriptors = slices.Clone(st.Descriptors)
+	cp.Configure = maps.Clone(st.Configure)
+	return &cp
+}
+
+// recordResult records the outcome of a configuration step in results.
+func recordResult(results map[string]string, key string, err error) {
+	if results == nil {
+		return
+	}
+	if err != nil {
+		results[key] = err.Error()
+	} else {
+		results[key] = ProgressOK
+	}
+}
+
+// resumeInterview continues an unfinished interview when the device is
+// heard from, since sleepy devices only listen right after they sent
+// something. Resumes are paced by interviewResumeInterval.
+func (dm *DeviceManager) resumeInterview(dev *store.Device) {
+	st := dev.Interview
+	if st == nil || st.Stage == StepComplete || st.GaveUp {
+		return
+	}
+	ieee := dev.IEEEAddress
+
+	dm.interviewMu.Lock()
+	_, running := dm.interviewCancels[ieee]
+	last, ok := dm.interviewStarted[ieee]
+	if running || ok && time.Since(last) < interviewResumeInterval {
+		dm.interviewMu.Unlock()
+		return
+	}
+	dm.interviewStarted[ieee] = time.Now()
+	dm.interviewMu.Unlock()
+
+	dm.logger.Info("device awake, resuming interview", "ieee", ieee, "name", deviceName(dev), "stage", st.Stage)
+	dm.interviewWg.Add(1)
+	go dm.Interview(ieee)
+}
@@ -0,0 +1,286 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+// th1Descriptor describes the endpoints of an Acme TH1; the descriptor of
+// endpoint failEP fails.
+func th1Descriptor(failEP uint8) func(ep uint8) (*ncp.SimpleDescriptor, error) {
+	return func(ep uint8) (*ncp.SimpleDescriptor, error) {
+		if ep == failEP {
+			return nil, errors.New("timeout")
+		}
+		return &ncp.SimpleDescriptor{
+			Endpoint:    ep,
+			ProfileID:   0x0104,
+			InClusters:  []uint16{0x0000, 0x0402},
+			OutClusters: []uint16{0x0402},
+		}, nil
+	}
+}
+
+// newTestInterview answers interview requests for an Acme TH1 with
+// endpoints 1 and 2.
+func newTestInterview(t *testing.T) (*DeviceManager, *memStore, *fakeNCP, func(typ string) []map[string]interface{}) {
+	t.Helper()
+	c, ms, n := newTestCoord(t)
+	dm := c.devices
+	n.endpoints = []uint8{1, 2}
+	n.onDescribe = th1Descriptor(0)
+	n.onRead = func(ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+		return []ncp.AttributeResponse{
+			{AttrID: 0x0004, DataType: 0x42, Value: []byte("\x04Acme")},
+			{AttrID: 0x0005, DataType: 0x42, Value: []byte("\x03TH1")},
+		}
+	}
+	c.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "TH1",
+		Bind:         []uint16{0x0402, 0x0405},
+		Reporting: []ReportingEntry{
+			{Cluster: 0x0402, Attribute: 0x0000, Type: 0x29, Min: 10, Max: 300, Change: 10},
+			{Cluster: 0x0405, Attribute: 0x0000, Type: 0x21, Min: 10, Max: 300, Change: 100},
+		},
+	})
+
+	var mu sync.Mutex
+	var events []Event
+	c.events.OnAll(func(e Event) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	})
+	return dm, ms, n, func(typ string) []map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		var data []map[string]interface{}
+		for _, e := range events {
+			if e.Type == typ {
+				data = append(data, e.Data.(map[string]interface{}))
+			}
+		}
+		return data
+	}
+}
+
+func TestReconfigureProgress(t *testing.T) {
+	dm, ms, n, events := newTestInterview(t)
+	n.onReporting = func(req ncp.ConfigureReportingRequest) error {
+		if req.AttrID == 0x0000 {
+			return errors.New("unsupported attribute")
+		}
+		return nil
+	}
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		Manufacturer: "Acme",
+		Model:        "TH1",
+		Interviewed:  true,
+		Endpoints: []store.Endpoint{{
+			ID:          1,
+			InClusters:  []uint16{0x0000, 0x0402},
+			OutClusters: []uint16{0x0402},
+		}},
+	}
+
+	if err := dm.Reconfigure("00158D0001A2B3C4"); err != nil {
+		t.Fatalf("Reconfigure: %v", err)
+	}
+	dm.interviewWg.Wait()
+
+	if len(n.binds) != 1 || n.binds[0].ClusterID != 0x0402 {
+		t.Errorf("binds: %+v", n.binds)
+	}
+	got := events(EventInterviewProgress)
+	want := []struct{ step, status string }{
+		{StepStart, ProgressStarted},
+		{StepBind, ProgressOK},
+		{StepReporting, ProgressFailed},
+		{StepComplete, ProgressFailed},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events: %v", got)
+	}
+	for i, w := range want {
+		if got[i]["step"] != w.step || got[i]["status"] != w.status {
+			t.Errorf("event %d = %v, want %s/%s", i, got[i], w.step, w.status)
+		}
+	}
+	if got[1]["cluster"] != uint16(0x0402) || got[2]["error"] != "unsupported attribute" {
+		t.Errorf("step details: %v %v", got[1], got[2])
+	}
+	if got[3]["operation"] != "configure" {
+		t.Errorf("complete = %v", got[3])
+	}
+}
+
+func TestReconfigureErrors(t *testing.T) {
+	dm, ms, _, _ := newTestInterview(t)
+	ms.devices["0000000000000001"] = &store.Device{IEEEAddress: "0000000000000001", Manufacturer: "Acme", Model: "TH1"}
+	ms.devices["0000000000000002"] = &store.Device{
+		IEEEAddress: "0000000000000002",
+		Interviewed: true,
+		Endpoints:   []store.Endpoint{{ID: 1}},
+	}
+
+	for ieee, want := range map[string]error{
+		"FFFFFFFFFFFFFFFF": store.ErrNotFound,
+		"0000000000000001": ErrNotInterviewed,
+		"0000000000000002": ErrNoDefinition,
+	} {
+		if err := dm.Reconfigure(ieee); !errors.Is(err, want) {
+			t.Errorf("%s: got %v, want %v", ieee, err, want)
+		}
+	}
+}
+
+func TestReconfigureQueuedForPollControl(t *testing.T) {
+	dm, ms, n, events := newTestInterview(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		Manufacturer: "Acme",
+		Model:        "TH1",
+		Interviewed:  true,
+		Endpoints:    []store.Endpoint{{ID: 1, InClusters: []uint16{0x0020, 0x0402}}},
+		PollControl:  &store.PollControlState{Endpoint: 1, Configured: true},
+	}
+
+	if err := dm.Reconfigure("00158D0001A2B3C4"); !errors.Is(err, ErrCommandQueued) {
+		t.Fatalf("Reconfigure: got %v, want ErrCommandQueued", err)
+	}
+	if ms.devices["00158D0001A2B3C4"].PollControl.Configured {
+		t.Error("poll control still marked configured")
+	}
+	if len(n.reporting) != 0 {
+		t.Error("configured before check-in")
+	}
+	if got := events(EventInterviewProgress); len(got) != 1 || got[0]["status"] != ProgressQueued {
+		t.Errorf("events: %v", got)
+	}
+}
+
+func TestInterviewResumesFromFailedStep(t *testing.T) {
+	dm, ms, n, _ := newTestInterview(t)
+	n.onDescribe = th1Descriptor(2)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	ctx := context.Background()
+
+	// The device falls asleep before the second descriptor.
+	st, resumed, err := dm.beginInterview("00158D0001A2B3C4", false)
+	if err != nil || resumed || st.Runs != 1 {
+		t.Fatalf("begin: %+v resumed=%v err=%v", st, resumed, err)
+	}
+	if err := dm.interviewSteps(ctx, ms.devices["00158D0001A2B3C4"], st); err == nil {
+		t.Fatal("interview succeeded with a failing descriptor")
+	}
+	dev := ms.devices["00158D0001A2B3C4"]
+	saved := dev.Interview
+	if saved == nil || saved.Stage != StepSimpleDescriptor || !saved.Basic ||
+		len(saved.EndpointIDs) != 2 || len(saved.Descriptors) != 1 {
+		t.Fatalf("saved state: %+v", saved)
+	}
+	if dev.Interviewed || dev.Manufacturer != "Acme" || dev.Model != "TH1" {
+		t.Errorf("device after partial interview: %+v", dev)
+	}
+
+	// The next run only reads what is missing.
+	n.onDescribe = th1Descriptor(0)
+	n.activeEPs, n.reads, n.descriptors = 0, nil, nil
+	st, resumed, err = dm.beginInterview("00158D0001A2B3C4", false)
+	if err != nil || !resumed || st.Runs != 2 {
+		t.Fatalf("resume: %+v resumed=%v err=%v", st, resumed, err)
+	}
+	if err := dm.interviewSteps(ctx, dev, st); err != nil {
+		t.Fatalf("resumed interview: %v", err)
+	}
+	if n.activeEPs != 0 || len(n.reads) != 0 || len(n.descriptors) != 1 || n.descriptors[0] != 2 {
+		t.Errorf("resumed requests: active=%d reads=%d descriptors=%v", n.activeEPs, len(n.reads), n.descriptors)
+	}
+	if !dev.Interviewed || len(dev.Endpoints) != 2 || len(n.binds) != 2 {
+		t.Errorf("device: interviewed=%v endpoints=%d binds=%d", dev.Interviewed, len(dev.Endpoints), len(n.binds))
+	}
+
+	// A restart discards the progress.
+	if st, resumed, _ = dm.beginInterview("00158D0001A2B3C4", true); resumed || st.EndpointIDs != nil || st.Runs != 1 {
+		t.Errorf("restart: %+v resumed=%v", st, resumed)
+	}
+}
+
+func TestInterviewResumedByTraffic(t *testing.T) {
+	dm, ms, n, events := newTestInterview(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		Manufacturer: "Acme",
+		Model:        "TH1",
+		Interview: &store.InterviewState{
+			Stage:       StepSimpleDescriptor,
+			Runs:        1,
+			EndpointIDs: []int{1, 2},
+			Basic:       true,
+			Descriptors: []store.Endpoint{{ID: 1, InClusters: []uint16{0x0402}, OutClusters: []uint16{0x0402}}},
+		},
+	}
+	dm.updateAddrIndex("00158D0001A2B3C4", 0x1111)
+
+	frame := ncp.ClusterCommandEvent{SrcAddr: 0x1111, SrcEP: 1, ClusterID: 0x0006, CommandID: 0x01}
+	dm.HandleClusterCommand(frame)
+	dm.interviewWg.Wait()
+
+	started := events(EventInterviewStarted)
+	if len(started) != 1 || started[0]["resumed"] != true || started[0]["stage"] != StepSimpleDescriptor {
+		t.Fatalf("started: %v", started)
+	}
+	if completed := events(EventInterviewCompleted); len(completed) != 1 || completed[0]["endpoints"] != 2 {
+		t.Errorf("completed: %v", completed)
+	}
+	if len(n.descriptors) != 1 {
+		t.Errorf("descriptors read: %v", n.descriptors)
+	}
+	dev := ms.devices["00158D0001A2B3C4"]
+	if !dev.Interviewed || dev.Interview.Stage != StepComplete || dev.Interview.Configure["bind/1/0x0402"] != ProgressOK {
+		t.Errorf("device: interviewed=%v state=%+v", dev.Interviewed, dev.Interview)
+	}
+
+	// A finished interview is not resumed again.
+	dm.HandleClusterCommand(frame)
+	dm.interviewWg.Wait()
+	if got := len(events(EventInterviewStarted)); got != 1 {
+		t.Errorf("interviews started = %d, want 1", got)
+	}
+}
+
+func TestInterviewGivesUp(t *testing.T) {
+	dm, ms, _, events := newTestInterview(t)
+	st := &store.InterviewState{Stage: StepReporting, Runs: maxInterviewRuns, Basic: true}
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C4",
+		Interviewed: true,
+		Interview:   st,
+	}
+
+	dm.failInterview(context.Background(), "00158D0001A2B3C4", st, errors.New("1 configuration steps failed"))
+	failed := events(EventInterviewFailed)
+	if len(failed) != 1 || failed[0]["gave_up"] != true || failed[0]["stage"] != StepReporting {
+		t.Fatalf("failed: %v", failed)
+	}
+	dev := ms.devices["00158D0001A2B3C4"]
+	if !dev.Interview.GaveUp || dev.Interview.LastError == "" {
+		t.Errorf("saved state: %+v", dev.Interview)
+	}
+
+	dm.resumeInterview(dev)
+	dm.interviewWg.Wait()
+	if got := events(EventInterviewStarted); len(got) != 0 {
+		t.Errorf("resumed after giving up: %v", got)
+	}
+}
@@ -0,0 +1,38 @@
+package coordinator
+
+import (
+	"fmt"
+
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+// newBasicServer returns the local Basic cluster server describing the coordinator.
+func newBasicServer() *LocalCluster {
+	def := clusters.Basic
+	lc := &LocalCluster{Def: &def}
+	lc.SetAttribute(0x0000, uint8(8)) // ZCLVersion
+	lc.SetAttribute(0x0001, uint8(1)) // ApplicationVersion
+	lc.SetAttribute(0x0002, uint8(0)) // StackVersion
+	lc.SetAttribute(0x0003, uint8(1)) // HWVersion
+	lc.SetAttribute(0x0004, "zigbee-go-home")
+	lc.SetAttribute(0x0005, "Coordinator")
+	lc.SetAttribute(0x0007, uint8(0x01)) // PowerSource: mains (single phase)
+	return lc
+}
+
+// newOTAServer returns the local OTA Upgrade server. No firmware images are
+// hosted, so every QueryNextImageRequest is answered with NO_IMAGE_AVAILABLE.
+func (c *Coordinator) newOTAServer() *LocalCluster {
+	def := clusters.OTAUpgrade
+	return &LocalCluster{
+		Def: &def,
+		Commands: map[uint8]LocalCommandHandler{
+			0x01: func(cmd LocalCommand) (*LocalReply, uint8) { // QueryNextImageRequest
+				c.logger.Info("OTA query from device, responding NO_IMAGE_AVAILABLE",
+					"short", fmt.Sprintf("0x%04X", cmd.SrcAddr), "ep", cmd.SrcEP)
+				return &LocalReply{CommandID: 0x02, Payload: []byte{zcl.ZCLStatusNoImageAvailable}}, zcl.ZCLStatusSuccess
+			},
+		},
+	}
+}
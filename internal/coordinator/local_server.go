@@ -0,0 +1,390 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/zcl"
+)
+
+// Default coordinator endpoint: HA profile, Configuration Tool device type.
+const (
+	localEndpoint  uint8  = 1
+	localProfileHA uint16 = 0x0104
+	localDeviceID  uint16 = 0x0005
+)
+
+// LocalCommand is an incoming cluster-specific command addressed to a
+// cluster hosted on a coordinator endpoint.
+type LocalCommand struct {
+	SrcAddr          uint16
+	SrcEP            uint8
+	Endpoint         uint8
+	ClusterID        uint16
+	CommandID        uint8
+	ManufacturerCode uint16
+	Payload          []byte
+}
+
+// LocalReply is a cluster-specific response sent back to the requester with
+// the request's sequence number and the opposite direction.
+type LocalReply struct {
+	CommandID uint8
+	Payload   []byte
+}
+
+// LocalCommandHandler handles a command for a local cluster. A non-nil reply
+// is sent instead of a Default Response; otherwise a Default Response with
+// the returned status is sent (unless the sender disabled it and the status
+// is success).
+type LocalCommandHandler func(cmd LocalCommand) (*LocalReply, uint8)
+
+// LocalCluster is a cluster hosted on a coordinator endpoint. Attribute types
+// and access come from Def; values come from ReadAttr or the static table.
+type LocalCluster struct {
+	Def *zcl.ClusterDef
+
+	// Client hosts the cluster as a client (output cluster), receiving
+	// server-to-client commands such as IAS Zone Enroll Request.
+	Client bool
+
+	// ReadAttr returns dynamic attribute values (e.g., Time). Attributes it
+	// does not return fall back to the static table.
+	ReadAttr func(attrID uint16) (any, bool)
+
+	// WriteAttr, if set, validates a write to a writable attribute. A
+	// non-success status rejects the write; otherwise the value is stored.
+	WriteAttr func(attrID uint16, value any) uint8
+
+	// Commands maps command IDs (in the direction this cluster receives)
+	// to handlers.
+	Commands map[uint8]LocalCommandHandler
+
+	mu     sync.RWMutex
+	values map[uint16]any
+}
+
+// SetAttribute stores a static attribute value.
+func (lc *LocalCluster) SetAttribute(attrID uint16, value any) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.values == nil {
+		lc.values = make(map[uint16]any)
+	}
+	lc.values[attrID] = value
+}
+
+// Attribute returns the current value of an attribute.
+func (lc *LocalCluster) Attribute(attrID uint16) (any, bool) {
+	if lc.ReadAttr != nil {
+		if v, ok := lc.ReadAttr(attrID); ok {
+			return v, true
+		}
+	}
+	lc.mu.RLock()
+	defer lc.mu.RUnlock()
+	v, ok := lc.values[attrID]
+	return v, ok
+}
+
+// readRecords builds Read Attributes Response records for the given IDs.
+func (lc *LocalCluster) readRecords(attrIDs []uint16) []byte {
+	var buf []byte
+	for _, id := range attrIDs {
+		buf = binary.LittleEndian.AppendUint16(buf, id)
+		def := lc.Def.FindAttribute(id)
+		val, ok := lc.Attribute(id)
+		if def == nil || !def.IsReadable() || !ok {
+			buf = append(buf, zcl.ZCLStatusUnsupportedAttr)
+			continue
+		}
+		data, err := zcl.EncodeValue(def.Type, val)
+		if err != nil {
+			buf = append(buf, zcl.ZCLStatusFailure)
+			continue
+		}
+		buf = append(buf, zcl.ZCLStatusSuccess, def.Type)
+		buf = append(buf, data...)
+	}
+	return buf
+}
+
+// writeRecords applies Write Attributes records and returns the Write
+// Attributes Response payload. Undivided writes are all-or-nothing.
+func (lc *LocalCluster) writeRecords(data []byte, undivided bool) []byte {
+	type pending struct {
+		id    uint16
+		value any
+	}
+	var ok []pending
+	var failed []byte
+	for len(data) >= 3 {
+		id := binary.LittleEndian.Uint16(data[0:2])
+		typ := data[2]
+		val, n, err := zcl.DecodeValue(typ, data[3:])
+		if err != nil {
+			// Cannot find the next record boundary.
+			failed = append(failed, zcl.ZCLStatusMalformedCommand)
+			failed = binary.LittleEndian.AppendUint16(failed, id)
+			break
+		}
+		data = data[3+n:]
+
+		status := zcl.ZCLStatusSuccess
+		def := lc.Def.FindAttribute(id)
+		switch {
+		case def == nil:
+			status = zcl.ZCLStatusUnsupportedAttr
+		case !def.IsWritable():
+			status = zcl.ZCLStatusReadOnly
+		case def.Type != typ:
+			status = zcl.ZCLStatusInvalidDataType
+		case lc.WriteAttr != nil:
+			status = lc.WriteAttr(id, val)
+		}
+		if status != zcl.ZCLStatusSuccess {
+			failed = append(failed, status)
+			failed = binary.LittleEndian.AppendUint16(failed, id)
+			continue
+		}
+		ok = append(ok, pending{id, val})
+	}
+
+	if !undivided || len(failed) == 0 {
+		for _, p := range ok {
+			lc.SetAttribute(p.id, p.value)
+		}
+	}
+	if len(failed) == 0 {
+		return []byte{zcl.ZCLStatusSuccess}
+	}
+	return failed
+}
+
+// localEndpointDef groups the clusters hosted on one coordinator endpoint.
+type localEndpointDef struct {
+	servers map[uint16]*LocalCluster
+	clients map[uint16]*LocalCluster
+}
+
+// LocalServer dispatches ZCL frames addressed to coordinator endpoints to
+// the registered local clusters and sends their responses.
+type LocalServer struct {
+	logger *slog.Logger
+
+	mu        sync.RWMutex
+	endpoints map[uint8]*localEndpointDef
+
+	// send transmits a response frame. Called from the NCP read loop, so
+	// it must not block (see newLocalServer).
+	send func(ncp.ZCLFrameRequest)
+}
+
+func newLocalServer(c *Coordinator) *LocalServer {
+	s := &LocalServer{
+		logger:    c.logger,
+		endpoints: make(map[uint8]*localEndpointDef),
+	}
+	s.send = func(req ncp.ZCLFrameRequest) {
+		// The NCP delivers indications from its read loop and request()
+		// needs that loop for the ACK, so never send inline.
+		go func() {
+			ctx, cancel := context.WithTimeout(c.ctx, 5*time.Second)
+			defer cancel()
+			if err := c.ncp.SendZCLFrame(ctx, req); err != nil {
+				s.logger.Warn("local cluster response failed",
+					"short", fmt.Sprintf("0x%04X", req.DstAddr),
+					"cluster", fmt.Sprintf("0x%04X", req.ClusterID),
+					"cmd", fmt.Sprintf("0x%02X", req.CommandID), "err", err)
+			}
+		}()
+	}
+	return s
+}
+
+// Send transmits an unsolicited frame from a local cluster, such as a
+// status change notification. It does not block.
+func (s *LocalServer) Send(req ncp.ZCLFrameRequest) {
+	s.send(req)
+}
+
+// Register hosts a cluster on a coordinator endpoint. Clusters must be
+// registered before Coordinator.Start to appear in the simple descriptor.
+func (s *LocalServer) Register(endpoint uint8, lc *LocalCluster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ep, ok := s.endpoints[endpoint]
+	if !ok {
+		ep = &localEndpointDef{
+			servers: make(map[uint16]*LocalCluster),
+			clients: make(map[uint16]*LocalCluster),
+		}
+		s.endpoints[endpoint] = ep
+	}
+	if lc.Client {
+		ep.clients[lc.Def.ID] = lc
+	} else {
+		ep.servers[lc.Def.ID] = lc
+	}
+}
+
+// Cluster returns a registered local cluster, or nil.
+func (s *LocalServer) Cluster(endpoint uint8, clusterID uint16, client bool) *LocalCluster {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ep, ok := s.endpoints[endpoint]
+	if !ok {
+		return nil
+	}
+	if client {
+		return ep.clients[clusterID]
+	}
+	return ep.servers[clusterID]
+}
+
+// Descriptors returns the simple descriptors of all local endpoints.
+func (s *LocalServer) Descriptors() []ncp.SimpleDescriptor {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	descs := make([]ncp.SimpleDescriptor, 0, len(s.endpoints))
+	for id, ep := range s.endpoints {
+		d := ncp.SimpleDescriptor{Endpoint: id, ProfileID: localProfileHA, DeviceID: localDeviceID}
+		for cid := range ep.servers {
+			d.InClusters = append(d.InClusters, cid)
+		}
+		for cid := range ep.clients {
+			d.OutClusters = append(d.OutClusters, cid)
+		}
+		sort.Slice(d.InClusters, func(i, j int) bool { return d.InClusters[i] < d.InClusters[j] })
+		sort.Slice(d.OutClusters, func(i, j int) bool { return d.OutClusters[i] < d.OutClusters[j] })
+		descs = append(descs, d)
+	}
+	sort.Slice(descs, func(i, j int) bool { return descs[i].Endpoint < descs[j].Endpoint })
+	return descs
+}
+
+// HandleGlobalCommand answers Read/Write Attributes requests addressed to
+// local clusters. Frames for other clusters are ignored. Local clusters
+// have no manufacturer extensions, so manufacturer-specific requests get
+// UNSUP_MANUF_GENERAL_COMMAND.
+func (s *LocalServer) HandleGlobalCommand(evt ncp.GlobalCommandEvent) {
+	// Client-to-server frames target our server clusters and vice versa.
+	lc := s.Cluster(evt.DstEP, evt.ClusterID, evt.ServerToClient)
+	if lc == nil {
+		return
+	}
+
+	reply := func(cmdID uint8, payload []byte) {
+		s.send(ncp.ZCLFrameRequest{
+			DstAddr:            evt.SrcAddr,
+			DstEP:              evt.SrcEP,
+			SrcEP:              evt.DstEP,
+			ClusterID:          evt.ClusterID,
+			CommandID:          cmdID,
+			Payload:            payload,
+			Global:             true,
+			Seq:                evt.Seq,
+			ServerToClient:     !evt.ServerToClient,
+			DisableDefaultResp: true,
+			ManufacturerCode:   evt.ManufacturerCode,
+		})
+	}
+
+	switch evt.CommandID {
+	case zcl.FoundationReadAttributesResponse, zcl.FoundationWriteAttributesResp,
+		zcl.FoundationConfigReportingResp, zcl.FoundationDefaultResponse,
+		zcl.FoundationDiscoverAttributesResp, zcl.FoundationReportAttributes:
+		// Responses and reports are never answered.
+		return
+	}
+	if evt.ManufacturerCode != 0 {
+		if !evt.DisableDefaultResp {
+			reply(zcl.FoundationDefaultResponse, []byte{evt.CommandID, zcl.ZCLStatusUnsupManufGeneralCmd})
+		}
+		return
+	}
+
+	switch evt.CommandID {
+	case zcl.FoundationReadAttributes:
+		var ids []uint16
+		for pos := 0; pos+2 <= len(evt.Payload); pos += 2 {
+			ids = append(ids, binary.LittleEndian.Uint16(evt.Payload[pos:pos+2]))
+		}
+		s.logger.Debug("local read attributes",
+			"short", fmt.Sprintf("0x%04X", evt.SrcAddr),
+			"cluster", fmt.Sprintf("0x%04X", evt.ClusterID), "attrs", ids)
+		reply(zcl.FoundationReadAttributesResponse, lc.readRecords(ids))
+
+	case zcl.FoundationWriteAttributes, zcl.FoundationWriteAttrsUndivided:
+		rsp := lc.writeRecords(evt.Payload, evt.CommandID == zcl.FoundationWriteAttrsUndivided)
+		reply(zcl.FoundationWriteAttributesResp, rsp)
+
+	case zcl.FoundationWriteAttrsNoResponse:
+		lc.writeRecords(evt.Payload, false)
+
+	default:
+		if !evt.DisableDefaultResp {
+			reply(zcl.FoundationDefaultResponse, []byte{evt.CommandID, zcl.ZCLStatusUnsupGeneralCmd})
+		}
+	}
+}
+
+// HandleClusterCommand runs the handler for a cluster-specific command
+// addressed to a local cluster and sends its reply or Default Response.
+// It reports whether a local cluster accepted the command.
+func (s *LocalServer) HandleClusterCommand(evt ncp.ClusterCommandEvent) bool {
+	lc := s.Cluster(evt.DstEP, evt.ClusterID, evt.ServerToClient)
+	if lc == nil {
+		return false
+	}
+
+	req := ncp.ZCLFrameRequest{
+		DstAddr:            evt.SrcAddr,
+		DstEP:              evt.SrcEP,
+		SrcEP:              evt.DstEP,
+		ClusterID:          evt.ClusterID,
+		Seq:                evt.Seq,
+		ServerToClient:     !evt.ServerToClient,
+		DisableDefaultResp: true,
+		ManufacturerCode:   evt.ManufacturerCode,
+	}
+
+	handler, ok := lc.Commands[evt.CommandID]
+	if !ok {
+		if !evt.DisableDefaultResp {
+			req.Global = true
+			req.CommandID = zcl.FoundationDefaultResponse
+			req.Payload = []byte{evt.CommandID, zcl.ZCLStatusUnsupClusterCmd}
+			s.send(req)
+		}
+		return true
+	}
+
+	rsp, status := handler(LocalCommand{
+		SrcAddr:          evt.SrcAddr,
+		SrcEP:            evt.SrcEP,
+		Endpoint:         evt.DstEP,
+		ClusterID:        evt.ClusterID,
+		CommandID:        evt.CommandID,
+		ManufacturerCode: evt.ManufacturerCode,
+		Payload:          evt.Payload,
+	})
+	switch {
+	case rsp != nil:
+		req.CommandID = rsp.CommandID
+		req.Payload = rsp.Payload
+		s.send(req)
+	case !evt.DisableDefaultResp || status != zcl.ZCLStatusSuccess:
+		req.Global = true
+		req.CommandID = zcl.FoundationDefaultResponse
+		req.Payload = []byte{evt.CommandID, status}
+		s.send(req)
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package coordinator
+
+import (
+	"bytes"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/zcl"
+)
+
+// newTestLocalServer returns a LocalServer that records sent frames.
+func newTestLocalServer(t *testing.T) (*LocalServer, *[]ncp.ZCLFrameRequest) {
+	t.Helper()
+	var sent []ncp.ZCLFrameRequest
+	s := &LocalServer{
+		logger:    newTestLogger(),
+		endpoints: make(map[uint8]*localEndpointDef),
+		send:      func(req ncp.ZCLFrameRequest) { sent = append(sent, req) },
+	}
+	return s, &sent
+}
+
+func TestLocalServerDescriptors(t *testing.T) {
+	s, _ := newTestLocalServer(t)
+	s.Register(1, newBasicServer())
+	s.Register(1, newTimeServer())
+	s.Register(1, &LocalCluster{Def: &zcl.ClusterDef{ID: 0x0500}, Client: true})
+
+	descs := s.Descriptors()
+	if len(descs) != 1 {
+		t.Fatalf("expected 1 endpoint, got %d", len(descs))
+	}
+	d := descs[0]
+	if d.Endpoint != 1 || d.ProfileID != 0x0104 {
+		t.Errorf("endpoint=%d profile=0x%04X", d.Endpoint, d.ProfileID)
+	}
+	if len(d.InClusters) != 2 || d.InClusters[0] != 0x0000 || d.InClusters[1] != 0x000A {
+		t.Errorf("in clusters: %v", d.InClusters)
+	}
+	if len(d.OutClusters) != 1 || d.OutClusters[0] != 0x0500 {
+		t.Errorf("out clusters: %v", d.OutClusters)
+	}
+}
+
+func TestLocalServerReadAttributes(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	s.Register(1, newBasicServer())
+
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000,
+		CommandID: zcl.FoundationReadAttributes, Seq: 0x10,
+		Payload: []byte{0x05, 0x00, 0xFF, 0x00}, // ModelIdentifier, unknown 0x00FF
+	})
+
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 response, got %d", len(*sent))
+	}
+	rsp := (*sent)[0]
+	if rsp.DstAddr != 0x1234 || rsp.DstEP != 2 || rsp.SrcEP != 1 || rsp.Seq != 0x10 {
+		t.Errorf("addressing: %+v", rsp)
+	}
+	if !rsp.Global || !rsp.ServerToClient || rsp.CommandID != zcl.FoundationReadAttributesResponse {
+		t.Errorf("header: %+v", rsp)
+	}
+	want := []byte{
+		0x05, 0x00, zcl.ZCLStatusSuccess, zcl.TypeCharStr, 11, 'C', 'o', 'o', 'r', 'd', 'i', 'n', 'a', 't', 'o', 'r',
+		0xFF, 0x00, zcl.ZCLStatusUnsupportedAttr,
+	}
+	if !bytes.Equal(rsp.Payload, want) {
+		t.Errorf("payload: got %X, want %X", rsp.Payload, want)
+	}
+}
+
+func TestLocalServerIgnoresUnknownCluster(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	s.Register(1, newBasicServer())
+
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0006,
+		CommandID: zcl.FoundationReadAttributes, Payload: []byte{0x00, 0x00},
+	})
+	// Server-to-client responses to our own requests must not be answered.
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000, ServerToClient: true,
+		CommandID: zcl.FoundationDefaultResponse, Payload: []byte{0x00, 0x00},
+	})
+	if len(*sent) != 0 {
+		t.Errorf("expected no responses, got %d", len(*sent))
+	}
+}
+
+func TestLocalServerManufacturerGlobalCommand(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	s.Register(1, newBasicServer())
+
+	// Manufacturer-specific read: Default Response with
+	// UNSUP_MANUF_GENERAL_COMMAND and the same code.
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000, Seq: 5, ManufacturerCode: 0x115F,
+		CommandID: zcl.FoundationReadAttributes, Payload: []byte{0x00, 0x00},
+	})
+	// Same, but sender disabled the Default Response.
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000, Seq: 6, ManufacturerCode: 0x115F,
+		CommandID: zcl.FoundationWriteAttributes, Payload: []byte{0x00, 0x00, 0x20, 0x01},
+		DisableDefaultResp: true,
+	})
+	// Manufacturer-specific reports are not answered either.
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000, Seq: 7, ManufacturerCode: 0x115F,
+		CommandID: zcl.FoundationReportAttributes, Payload: []byte{0x00, 0x00, 0x20, 0x01},
+	})
+
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 Default Response, got %d", len(*sent))
+	}
+	rsp := (*sent)[0]
+	if !rsp.Global || rsp.CommandID != zcl.FoundationDefaultResponse || rsp.Seq != 5 || !rsp.ServerToClient {
+		t.Errorf("header: %+v", rsp)
+	}
+	if rsp.ManufacturerCode != 0x115F {
+		t.Errorf("mfr code: got 0x%04X", rsp.ManufacturerCode)
+	}
+	want := []byte{zcl.FoundationReadAttributes, zcl.ZCLStatusUnsupManufGeneralCmd}
+	if !bytes.Equal(rsp.Payload, want) {
+		t.Errorf("payload: got %X, want %X", rsp.Payload, want)
+	}
+}
+
+func TestLocalServerWriteAttributes(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	def := &zcl.ClusterDef{ID: 0x0020, Attributes: []zcl.AttributeDef{
+		{ID: 0x0000, Type: zcl.TypeUint32, Access: zcl.AccessRead | zcl.AccessWrite},
+		{ID: 0x0001, Type: zcl.TypeUint32, Access: zcl.AccessRead},
+	}}
+	lc := &LocalCluster{Def: def}
+	s.Register(1, lc)
+
+	s.HandleGlobalCommand(ncp.GlobalCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0020, Seq: 3,
+		CommandID: zcl.FoundationWriteAttributes,
+		Payload: []byte{
+			0x00, 0x00, zcl.TypeUint32, 0x10, 0x00, 0x00, 0x00, // writable
+			0x01, 0x00, zcl.TypeUint32, 0x20, 0x00, 0x00, 0x00, // read-only
+		},
+	})
+
+	if v, ok := lc.Attribute(0x0000); !ok || v.(uint32) != 0x10 {
+		t.Errorf("attr 0x0000: got %v", v)
+	}
+	if _, ok := lc.Attribute(0x0001); ok {
+		t.Error("read-only attr should not be stored")
+	}
+	if len(*sent) != 1 || (*sent)[0].CommandID != zcl.FoundationWriteAttributesResp {
+		t.Fatalf("expected Write Attributes Response, got %+v", *sent)
+	}
+	want := []byte{zcl.ZCLStatusReadOnly, 0x01, 0x00}
+	if !bytes.Equal((*sent)[0].Payload, want) {
+		t.Errorf("payload: got %X, want %X", (*sent)[0].Payload, want)
+	}
+}
+
+func TestLocalServerClusterCommand(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	c := &Coordinator{logger: newTestLogger()}
+	s.Register(1, c.newOTAServer())
+
+	handled := s.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 1, DstEP: 1, ClusterID: 0x0019,
+		CommandID: 0x01, Seq: 0x42, ManufacturerCode: 0x1037,
+	})
+	if !handled {
+		t.Fatal("OTA query not handled")
+	}
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(*sent))
+	}
+	rsp := (*sent)[0]
+	if rsp.Global || !rsp.ServerToClient || rsp.CommandID != 0x02 || rsp.Seq != 0x42 {
+		t.Errorf("header: %+v", rsp)
+	}
+	if rsp.ManufacturerCode != 0x1037 {
+		t.Errorf("mfr code: got 0x%04X", rsp.ManufacturerCode)
+	}
+	if !bytes.Equal(rsp.Payload, []byte{zcl.ZCLStatusNoImageAvailable}) {
+		t.Errorf("payload: got %X", rsp.Payload)
+	}
+}
+
+func TestLocalServerUnknownClusterCommand(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	s.Register(1, newBasicServer())
+
+	// Unsupported command: Default Response with UNSUP_CLUSTER_COMMAND.
+	s.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 1, DstEP: 1, ClusterID: 0x0000, CommandID: 0x00, Seq: 7,
+	})
+	// Same, but sender disabled the Default Response.
+	s.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1234, SrcEP: 1, DstEP: 1, ClusterID: 0x0000, CommandID: 0x00, Seq: 8,
+		DisableDefaultResp: true,
+	})
+	// Not a local cluster.
+	if s.HandleClusterCommand(ncp.ClusterCommandEvent{DstEP: 1, ClusterID: 0xEF00}) {
+		t.Error("Tuya cluster should not be handled locally")
+	}
+
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 Default Response, got %d", len(*sent))
+	}
+	rsp := (*sent)[0]
+	if !rsp.Global || rsp.CommandID != zcl.FoundationDefaultResponse || rsp.Seq != 7 {
+		t.Errorf("header: %+v", rsp)
+	}
+	if !bytes.Equal(rsp.Payload, []byte{0x00, zcl.ZCLStatusUnsupClusterCmd}) {
+		t.Errorf("payload: got %X", rsp.Payload)
+	}
+}
@@ -0,0 +1,164 @@
+package coordinator
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"strings"
+
+	"zigbee-go-home/internal/store"
+)
+
+// Device options adjust the reports and property values of one device.
+// They come from the device definition ("options") and from the device
+// itself, which wins per option. Most are named after a property:
+//
+//	<property>_calibration   offset added to the value, e.g. temperature_calibration: -1.5
+//	<property>_precision     decimals the value is rounded to, e.g. humidity_precision: 0
+//	<property>_min_interval  seconds between reports of the property's attribute
+//	<property>_debounce      seconds the attribute must stay quiet before a report is used
+//	<property>_threshold     smallest change of the value that is reported
+//	<property>_poll_interval seconds between reads of the property's attribute, 0 to stop
+//
+// min_interval and debounce without a property apply to every attribute
+// of the device (see throttle.go), poll_interval to every attribute its
+// definition polls (see poller.go). confirm (0 or 1) turns the confirmation
+// of property sets off or on for the device; see confirm.go.
+const (
+	optionCalibration = "_calibration"
+	optionPrecision   = "_precision"
+	optionMinInterval = "_min_interval"
+	optionDebounce    = "_debounce"
+	optionThreshold   = "_threshold"
+	optionConfirm     = "confirm"
+	optionPoll        = "_poll_interval"
+
+	maxPrecision       = 10
+	maxThrottleSeconds = 24 * 60 * 60
+)
+
+// ErrInvalidDeviceOption is returned by SetDeviceOptions for unknown
+// option names or out-of-range values.
+var ErrInvalidDeviceOption = errors.New("invalid device option")
+
+// ValidateDeviceOptions checks option names and values.
+func ValidateDeviceOptions(opts map[string]float64) error {
+	for name, v := range opts {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%w: %s is not a number", ErrInvalidDeviceOption, name)
+		}
+		switch {
+		case optionProperty(name, optionCalibration) != "":
+		case optionProperty(name, optionPrecision) != "":
+			if v != math.Trunc(v) || v < 0 || v > maxPrecision {
+				return fmt.Errorf("%w: %s must be a whole number of decimals from 0 to %d", ErrInvalidDeviceOption, name, maxPrecision)
+			}
+		case name == "min_interval", name == "debounce",
+			optionProperty(name, optionMinInterval) != "", optionProperty(name, optionDebounce) != "":
+			if v < 0 || v > maxThrottleSeconds {
+				return fmt.Errorf("%w: %s must be 0 to %d seconds", ErrInvalidDeviceOption, name, maxThrottleSeconds)
+			}
+		case name == "poll_interval", optionProperty(name, optionPoll) != "":
+			if v != 0 && (v < minPollInterval || v > maxThrottleSeconds) {
+				return fmt.Errorf("%w: %s must be 0 or %d to %d seconds", ErrInvalidDeviceOption, name, minPollInterval, maxThrottleSeconds)
+			}
+		case name == optionConfirm:
+			if v != 0 && v != 1 {
+				return fmt.Errorf("%w: %s must be 0 or 1", ErrInvalidDeviceOption, name)
+			}
+		case optionProperty(name, optionThreshold) != "":
+			if v < 0 {
+				return fmt.Errorf("%w: %s must not be negative", ErrInvalidDeviceOption, name)
+			}
+		default:
+			return fmt.Errorf("%w: unknown option %q", ErrInvalidDeviceOption, name)
+		}
+	}
+	return nil
+}
+
+// optionProperty returns the property an option with the suffix applies
+// to, or "".
+func optionProperty(name, suffix string) string {
+	prop, ok := strings.CutSuffix(name, suffix)
+	if !ok {
+		return ""
+	}
+	return prop
+}
+
+// SetDeviceOptions replaces the options of a device. Values reported from
+// then on are calibrated and throttled; stored values are left as they are.
+func (c *Coordinator) SetDeviceOptions(ieee string, opts map[string]float64) error {
+	if err := ValidateDeviceOptions(opts); err != nil {
+		return err
+	}
+	if len(opts) == 0 {
+		opts = nil
+	}
+	return c.store.UpdateDevice(ieee, func(d *store.Device) error {
+		d.Options = opts
+		return nil
+	})
+}
+
+// deviceOptions returns the options of the device's definition overlaid
+// with its own.
+func (dm *DeviceManager) deviceOptions(dev *store.Device) map[string]float64 {
+	if dev == nil {
+		return nil
+	}
+	def := dm.definition(dev)
+	if def == nil || len(def.Options) == 0 {
+		return dev.Options
+	}
+	if len(dev.Options) == 0 {
+		return def.Options
+	}
+	opts := maps.Clone(def.Options)
+	maps.Copy(opts, dev.Options)
+	return opts
+}
+
+// calibrate applies the calibration and precision options for a property
+// to a numeric value. Other values are returned unchanged.
+func calibrate(opts map[string]float64, name string, value any) any {
+	if len(opts) == 0 {
+		return value
+	}
+	offset, hasOffset := opts[name+optionCalibration]
+	precision, hasPrecision := opts[name+optionPrecision]
+	if !hasOffset && !hasPrecision {
+		return value
+	}
+
+	x, err := exprValue(value)
+	if err != nil {
+		return value
+	}
+	switch n := x.(type) {
+	case int64:
+		if offset == math.Trunc(offset) {
+			// Integers already have no decimals to round.
+			return n + int64(offset)
+		}
+		return roundTo(float64(n)+offset, precision, hasPrecision)
+	case float64:
+		return roundTo(n+offset, precision, hasPrecision)
+	default:
+		return value
+	}
+}
+
+// roundTo rounds f to the given decimals, returning an integer for 0.
+func roundTo(f, decimals float64, round bool) any {
+	if !round {
+		return f
+	}
+	if decimals == 0 {
+		return int64(math.Round(f))
+	}
+	p := math.Pow(10, decimals)
+	return math.Round(f*p) / p
+}
@@ -0,0 +1,111 @@
+package coordinator
+
+import (
+	"errors"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+func TestCalibrate(t *testing.T) {
+	opts := map[string]float64{
+		"temperature_calibration": -1.5,
+		"temperature_precision":   1,
+		"humidity_precision":      0,
+		"battery_calibration":     5,
+		"pressure_calibration":    0.5,
+		"contact_calibration":     1,
+	}
+	tests := []struct {
+		name  string
+		input any
+		want  any
+	}{
+		{"temperature", 23.47, 22.0},
+		{"humidity", 55.5, int64(56)},
+		{"battery", uint8(90), int64(95)},
+		{"pressure", int16(1013), 1013.5},
+		{"contact", true, true},
+		{"illuminance", uint16(300), uint16(300)},
+	}
+	for _, tt := range tests {
+		if got := calibrate(opts, tt.name, tt.input); got != tt.want {
+			t.Errorf("calibrate %s(%v) = %v (%T), want %v (%T)", tt.name, tt.input, got, got, tt.want, tt.want)
+		}
+	}
+	if got := calibrate(nil, "temperature", 20.0); got != 20.0 {
+		t.Errorf("calibrate without options = %v", got)
+	}
+}
+
+func TestValidateDeviceOptions(t *testing.T) {
+	if err := ValidateDeviceOptions(map[string]float64{
+		"temperature_calibration": -2, "temperature_precision": 2,
+		"min_interval": 1, "debounce": 0.5, "power_min_interval": 5, "power_threshold": 2, "confirm": 1,
+		"poll_interval": 0, "power_poll_interval": 30,
+	}); err != nil {
+		t.Errorf("valid options: %v", err)
+	}
+	for _, opts := range []map[string]float64{
+		{"temperature": 1},
+		{"_calibration": 1},
+		{"temperature_precision": 1.5},
+		{"temperature_precision": -1},
+		{"temperature_precision": 11},
+		{"min_interval": -1},
+		{"power_debounce": 100000},
+		{"power_threshold": -1},
+		{"threshold": 1},
+		{"confirm": 2},
+		{"poll_interval": 5},
+		{"power_poll_interval": 100000},
+	} {
+		if err := ValidateDeviceOptions(opts); !errors.Is(err, ErrInvalidDeviceOption) {
+			t.Errorf("%v: err = %v, want ErrInvalidDeviceOption", opts, err)
+		}
+	}
+}
+
+func TestDeviceOptionsCalibrateReports(t *testing.T) {
+	dm, ms := newTestDM(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	dm.RebuildAddrIndex()
+
+	if err := dm.coord.SetDeviceOptions("00158D0001A2B3C4", map[string]float64{"temperature_calibration": 1.2, "temperature_precision": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := dm.coord.SetDeviceOptions("00158D0001A2B3C4", map[string]float64{"bogus": 1}); !errors.Is(err, ErrInvalidDeviceOption) {
+		t.Errorf("invalid option: %v", err)
+	}
+	if err := dm.coord.SetDeviceOptions("0000000000000000", nil); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("unknown device: %v", err)
+	}
+
+	got := reportAttributes(dm, 0x1111, []ncp.AttributeReportEvent{
+		int16Report(0x0402, 0x0000, 2345),
+		{ClusterID: 0x0006, AttrID: 0x0000, DataType: zcl.TypeBool, Value: []byte{1}},
+	})
+	if got["temperature"] == nil || got["temperature"]["value"] != 24.7 {
+		t.Errorf("temperature event: %v", got["temperature"])
+	}
+	if got["on_off"] == nil || got["on_off"]["value"] != true {
+		t.Errorf("on_off event: %v", got["on_off"])
+	}
+	if props := ms.devices["00158D0001A2B3C4"].Properties; props["temperature"] != 24.7 {
+		t.Errorf("stored temperature: %v", props["temperature"])
+	}
+
+	// Removing the options stops calibrating.
+	if err := dm.coord.SetDeviceOptions("00158D0001A2B3C4", map[string]float64{}); err != nil {
+		t.Fatal(err)
+	}
+	if opts := ms.devices["00158D0001A2B3C4"].Options; opts != nil {
+		t.Errorf("options after reset: %v", opts)
+	}
+	got = reportAttributes(dm, 0x1111, []ncp.AttributeReportEvent{int16Report(0x0402, 0x0000, 2345)})
+	if got["temperature"]["value"] != 23.45 {
+		t.Errorf("uncalibrated temperature: %v", got["temperature"])
+	}
+}
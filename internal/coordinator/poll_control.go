@@ -0,0 +1,345 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+// Poll Control command IDs.
+const (
+	pollCheckIn              uint8 = 0x00 // server to client
+	pollCheckInResponse      uint8 = 0x00
+	pollFastPollStop         uint8 = 0x01
+	pollSetLongPollInterval  uint8 = 0x02
+	pollSetShortPollInterval uint8 = 0x03
+)
+
+const (
+	// defaultFastPollTimeout is the fast poll window requested when the
+	// device definition sets none, in quarter-seconds (10 s).
+	defaultFastPollTimeout uint16 = 40
+
+	// pollWindowTimeout bounds all work done during one fast poll window.
+	pollWindowTimeout = time.Minute
+
+	// pollCommandTimeout bounds each queued command sent during fast poll.
+	pollCommandTimeout = 10 * time.Second
+
+	// maxQueuedCommands caps the per-device queue; the oldest is dropped.
+	maxQueuedCommands = 32
+)
+
+// ErrCommandQueued is returned when a command for a sleepy Poll Control
+// device is held until the device's next check-in.
+var ErrCommandQueued = errors.New("command queued until device checks in")
+
+// PollControlConfig sets Poll Control intervals from a device definition.
+// Values are in ZCL units (quarter-seconds); zero leaves the device default.
+type PollControlConfig struct {
+	CheckInInterval   uint32 `json:"check_in_interval,omitempty"`
+	LongPollInterval  uint32 `json:"long_poll_interval,omitempty"`
+	ShortPollInterval uint16 `json:"short_poll_interval,omitempty"`
+	FastPollTimeout   uint16 `json:"fast_poll_timeout,omitempty"`
+}
+
+// queuedCommand is a write or command held for a sleepy device.
+type queuedCommand struct {
+	desc string
+	send func(ctx context.Context) error
+}
+
+// newPollControlClient returns the local Poll Control client. Check-ins are
+// answered with a fast poll request when the device has pending work.
+func (c *Coordinator) newPollControlClient() *LocalCluster {
+	def := clusters.PollControl
+	return &LocalCluster{
+		Def:    &def,
+		Client: true,
+		Commands: map[uint8]LocalCommandHandler{
+			pollCheckIn: func(cmd LocalCommand) (*LocalReply, uint8) {
+				payload := make([]byte, 3)
+				if timeout, ok := c.devices.handleCheckIn(cmd.SrcAddr, cmd.SrcEP); ok {
+					payload[0] = 1 // start fast polling
+					binary.LittleEndian.PutUint16(payload[1:], timeout)
+				}
+				return &LocalReply{CommandID: pollCheckInResponse, Payload: payload}, zcl.ZCLStatusSuccess
+			},
+		},
+	}
+}
+
+// handleCheckIn records a check-in and reports whether the device should
+// fast poll, with the fast poll timeout. When it should, the pending work
+// runs in the background and ends with Fast Poll Stop.
+func (dm *DeviceManager) handleCheckIn(shortAddr uint16, ep uint8) (uint16, bool) {
+	ieee := dm.lookupOrRebuild(shortAddr)
+	if ieee == "" {
+		dm.logger.Warn("poll control: check-in from unknown device", "short", fmt.Sprintf("0x%04X", shortAddr))
+		return 0, false
+	}
+
+	var dev *store.Device
+	if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if d.PollControl == nil {
+			d.PollControl = &store.PollControlState{}
+		}
+		d.PollControl.Endpoint = ep
+		d.PollControl.LastCheckIn = time.Now()
+		dev = d
+		return nil
+	}); err != nil {
+		dm.logger.Error("poll control: save check-in", "err", err, "ieee", ieee)
+		return 0, false
+	}
+
+	dm.resumeInterview(dev)
+
+	def := dm.definition(dev)
+	reconfigure := def != nil && !dev.PollControl.Configured
+
+	dm.pollMu.Lock()
+	pending := len(dm.pollQueue[ieee])
+	if dm.fastPoll[ieee] || (pending == 0 && !reconfigure) {
+		dm.pollMu.Unlock()
+		dm.logger.Debug("poll control: check-in", "ieee", ieee, "name", deviceName(dev))
+		return 0, false
+	}
+	dm.fastPoll[ieee] = true
+	dm.pollMu.Unlock()
+
+	timeout := defaultFastPollTimeout
+	if def != nil && def.PollControl != nil && def.PollControl.FastPollTimeout != 0 {
+		timeout = def.PollControl.FastPollTimeout
+	}
+	dm.logger.Info("poll control: check-in, starting fast poll", "ieee", ieee, "name", deviceName(dev),
+		"pending", pending, "reconfigure", reconfigure)
+
+	go dm.runFastPoll(dev, def, ep, reconfigure)
+	return timeout, true
+}
+
+// runFastPoll delivers pending work while the device fast polls, then
+// sends Fast Poll Stop so it can return to sleep.
+func (dm *DeviceManager) runFastPoll(dev *store.Device, def *DeviceDefinition, ep uint8, reconfigure bool) {
+	ieee := dev.IEEEAddress
+	name := deviceName(dev)
+	ctx, cancel := context.WithTimeout(dm.coord.Context(), pollWindowTimeout)
+	defer cancel()
+
+	if reconfigure {
+		dm.runConfigure(ctx, dev, def)
+		if dm.configurePollControl(ctx, dev, def, ep) {
+			dm.setPollControlConfigured(ieee)
+		}
+	}
+
+	for {
+		cmd, ok := dm.nextQueued(ieee)
+		if !ok {
+			break
+		}
+		cmdCtx, cmdCancel := context.WithTimeout(ctx, pollCommandTimeout)
+		err := cmd.send(cmdCtx)
+		cmdCancel()
+		if err != nil {
+			dm.logger.Warn("poll control: queued command failed", "err", err, "ieee", ieee, "name", name, "cmd", cmd.desc)
+		} else {
+			dm.logger.Info("poll control: queued command sent", "ieee", ieee, "name", name, "cmd", cmd.desc)
+		}
+	}
+
+	err := dm.coord.NCP().SendCommand(ctx, ncp.ClusterCommandRequest{
+		DstAddr:   dev.ShortAddress,
+		DstEP:     ep,
+		ClusterID: 0x0020,
+		CommandID: pollFastPollStop,
+	})
+	if err != nil {
+		dm.logger.Warn("poll control: fast poll stop", "err", err, "ieee", ieee, "name", name)
+	}
+}
+
+// nextQueued pops the oldest queued command. When the queue is empty it
+// ends the fast poll window, so later commands wait for the next check-in.
+func (dm *DeviceManager) nextQueued(ieee string) (queuedCommand, bool) {
+	dm.pollMu.Lock()
+	defer dm.pollMu.Unlock()
+	queue := dm.pollQueue[ieee]
+	if len(queue) == 0 {
+		delete(dm.pollQueue, ieee)
+		delete(dm.fastPoll, ieee)
+		return queuedCommand{}, false
+	}
+	dm.pollQueue[ieee] = queue[1:]
+	return queue[0], true
+}
+
+// deferToCheckIn queues send for a device that uses Poll Control and is not
+// fast polling, reporting whether it did. Other devices are sent to directly.
+func (dm *DeviceManager) deferToCheckIn(shortAddr uint16, desc string, send func(ctx context.Context) error) bool {
+	ieee := dm.lookupOrRebuild(shortAddr)
+	if ieee == "" {
+		return false
+	}
+	dev, err := dm.coord.Store().GetDevice(ieee)
+	if err != nil || dev.PollControl == nil {
+		return false
+	}
+
+	dm.pollMu.Lock()
+	defer dm.pollMu.Unlock()
+	if dm.fastPoll[ieee] {
+		return false
+	}
+	queue := dm.pollQueue[ieee]
+	if len(queue) >= maxQueuedCommands {
+		dm.logger.Warn("poll control: queue full, dropping oldest command", "ieee", ieee, "cmd", queue[0].desc)
+		queue = queue[1:]
+	}
+	dm.pollQueue[ieee] = append(queue, queuedCommand{desc: desc, send: send})
+	dm.logger.Info("poll control: command queued until check-in", "ieee", ieee, "name", deviceName(dev),
+		"cmd", desc, "pending", len(dm.pollQueue[ieee]))
+	return true
+}
+
+// PendingCommands returns the number of commands queued for a device.
+func (dm *DeviceManager) PendingCommands(ieee string) int {
+	dm.pollMu.Lock()
+	defer dm.pollMu.Unlock()
+	return len(dm.pollQueue[ieee])
+}
+
+// definition looks up the device definition for a device, or nil.
+func (dm *DeviceManager) definition(dev *store.Device) *DeviceDefinition {
+	db := dm.coord.DeviceDB()
+	if db == nil {
+		return nil
+	}
+	return db.Match(dev.Manufacturer, dev.Model, dev.Endpoints)
+}
+
+// setupPollControl binds the Poll Control cluster so check-ins reach the
+// coordinator and applies the definition's intervals. Must run while the
+// device is awake.
+func (dm *DeviceManager) setupPollControl(ctx context.Context, dev *store.Device, def *DeviceDefinition) {
+	var ep uint8
+	for _, e := range dev.Endpoints {
+		if hasInCluster(e, 0x0020) {
+			ep = e.ID
+			break
+		}
+	}
+	if ep == 0 {
+		return
+	}
+	name := deviceName(dev)
+
+	devIEEE, err := ParseIEEE(dev.IEEEAddress)
+	if err != nil {
+		dm.logger.Warn("poll control: parse device IEEE", "err", err)
+		return
+	}
+	err = dm.coord.NCP().Bind(ctx, ncp.BindRequest{
+		TargetShortAddr: dev.ShortAddress,
+		SrcIEEE:         devIEEE,
+		SrcEP:           ep,
+		ClusterID:       0x0020,
+		DstIEEE:         dm.coord.LocalIEEE(),
+		DstEP:           localEndpoint,
+	})
+	if err != nil {
+		// Without the binding check-ins never arrive, so keep sending directly.
+		dm.logger.Warn("poll control: bind", "err", err, "ieee", dev.IEEEAddress, "name", name, "ep", ep)
+		return
+	}
+
+	configured := dm.configurePollControl(ctx, dev, def, ep)
+	if err := dm.coord.Store().UpdateDevice(dev.IEEEAddress, func(d *store.Device) error {
+		if d.PollControl == nil {
+			d.PollControl = &store.PollControlState{}
+		}
+		d.PollControl.Endpoint = ep
+		d.PollControl.Configured = configured
+		return nil
+	}); err != nil {
+		dm.logger.Error("poll control: save", "err", err, "ieee", dev.IEEEAddress, "name", name)
+		return
+	}
+	dm.logger.Info("poll control: set up", "ieee", dev.IEEEAddress, "name", name, "ep", ep, "configured", configured)
+}
+
+// configurePollControl writes the check-in interval and fast poll timeout
+// and sets the poll intervals from the device definition. It reports
+// whether everything was applied.
+func (dm *DeviceManager) configurePollControl(ctx context.Context, dev *store.Device, def *DeviceDefinition, ep uint8) bool {
+	if def == nil || def.PollControl == nil {
+		return true
+	}
+	pc := def.PollControl
+	name := deviceName(dev)
+	ok := true
+
+	var records []ncp.WriteRecord
+	if pc.CheckInInterval != 0 {
+		records = append(records, ncp.WriteRecord{AttrID: 0x0000, DataType: zcl.TypeUint32,
+			Value: binary.LittleEndian.AppendUint32(nil, pc.CheckInInterval)})
+	}
+	if pc.FastPollTimeout != 0 {
+		records = append(records, ncp.WriteRecord{AttrID: 0x0003, DataType: zcl.TypeUint16,
+			Value: binary.LittleEndian.AppendUint16(nil, pc.FastPollTimeout)})
+	}
+	if len(records) > 0 {
+		err := dm.coord.NCP().WriteAttributes(ctx, ncp.WriteAttributesRequest{
+			DstAddr:   dev.ShortAddress,
+			DstEP:     ep,
+			ClusterID: 0x0020,
+			Records:   records,
+		})
+		if err != nil {
+			dm.logger.Warn("poll control: write intervals", "err", err, "ieee", dev.IEEEAddress, "name", name)
+			ok = false
+		}
+	}
+
+	send := func(cmdID uint8, payload []byte, what string) {
+		err := dm.coord.NCP().SendCommand(ctx, ncp.ClusterCommandRequest{
+			DstAddr:   dev.ShortAddress,
+			DstEP:     ep,
+			ClusterID: 0x0020,
+			CommandID: cmdID,
+			Payload:   payload,
+		})
+		if err != nil {
+			dm.logger.Warn("poll control: "+what, "err", err, "ieee", dev.IEEEAddress, "name", name)
+			ok = false
+		}
+	}
+	if pc.LongPollInterval != 0 {
+		send(pollSetLongPollInterval, binary.LittleEndian.AppendUint32(nil, pc.LongPollInterval), "set long poll interval")
+	}
+	if pc.ShortPollInterval != 0 {
+		send(pollSetShortPollInterval, binary.LittleEndian.AppendUint16(nil, pc.ShortPollInterval), "set short poll interval")
+	}
+	return ok
+}
+
+// setPollControlConfigured marks the definition's Poll Control settings as
+// applied.
+func (dm *DeviceManager) setPollControlConfigured(ieee string) {
+	if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if d.PollControl != nil {
+			d.PollControl.Configured = true
+		}
+		return nil
+	}); err != nil {
+		dm.logger.Error("poll control: save", "err", err, "ieee", ieee)
+	}
+}
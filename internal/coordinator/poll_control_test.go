@@ -0,0 +1,134 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/store"
+)
+
+func checkIn(t *testing.T, c *Coordinator, short uint16) []byte {
+	t.Helper()
+	rsp, status := c.newPollControlClient().Commands[pollCheckIn](LocalCommand{SrcAddr: short, SrcEP: 1, Endpoint: 1, ClusterID: 0x0020})
+	if rsp == nil || status != 0 || rsp.CommandID != pollCheckInResponse {
+		t.Fatalf("check-in reply: %+v status %d", rsp, status)
+	}
+	return rsp.Payload
+}
+
+// waitFastPollStop waits for the Fast Poll Stop sent when a device's queue
+// is drained.
+func waitFastPollStop(t *testing.T, n *fakeNCP) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		n.mu.Lock()
+		for _, cmd := range n.commands {
+			if cmd.ClusterID == 0x0020 && cmd.CommandID == pollFastPollStop {
+				n.mu.Unlock()
+				return
+			}
+		}
+		n.mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for Fast Poll Stop")
+}
+
+func TestPollControlCheckInIdle(t *testing.T) {
+	c, ms, _ := newTestCoord(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+
+	if got := checkIn(t, c, 0x1111); !bytes.Equal(got, []byte{0, 0, 0}) {
+		t.Errorf("payload: got %X, want 000000", got)
+	}
+	pc := ms.devices["00158D0001A2B3C4"].PollControl
+	if pc == nil || pc.Endpoint != 1 || pc.LastCheckIn.IsZero() {
+		t.Errorf("stored poll control: %+v", pc)
+	}
+}
+
+func TestPollControlDirectSendWithoutCheckIn(t *testing.T) {
+	c, ms, n := newTestCoord(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+
+	if err := c.SendClusterCommand(context.Background(), 0x1111, 1, 0x0006, 0x01, nil); err != nil {
+		t.Fatalf("SendClusterCommand: %v", err)
+	}
+	if len(n.commands) != 1 {
+		t.Errorf("expected direct send, got %d commands", len(n.commands))
+	}
+}
+
+func TestPollControlQueuedUntilCheckIn(t *testing.T) {
+	c, ms, n := newTestCoord(t)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		PollControl:  &store.PollControlState{Endpoint: 1, Configured: true},
+	}
+
+	err := c.WriteAttribute(context.Background(), 0x1111, 1, 0x0201, 0x0012, 0x29, int16(2100))
+	if !errors.Is(err, ErrCommandQueued) {
+		t.Fatalf("WriteAttribute: got %v, want ErrCommandQueued", err)
+	}
+	if len(n.writes) != 0 {
+		t.Fatal("write sent before check-in")
+	}
+	if got := c.devices.PendingCommands("00158D0001A2B3C4"); got != 1 {
+		t.Fatalf("pending: got %d, want 1", got)
+	}
+
+	if got := checkIn(t, c, 0x1111); !bytes.Equal(got, []byte{1, 40, 0}) {
+		t.Errorf("payload: got %X, want 012800", got)
+	}
+	waitFastPollStop(t, n)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.writes) != 1 || n.writes[0].ClusterID != 0x0201 || n.writes[0].Records[0].AttrID != 0x0012 {
+		t.Errorf("writes: %+v", n.writes)
+	}
+	if got := c.devices.PendingCommands("00158D0001A2B3C4"); got != 0 {
+		t.Errorf("pending after fast poll: got %d", got)
+	}
+}
+
+func TestPollControlReconfigureOnCheckIn(t *testing.T) {
+	c, ms, n := newTestCoord(t)
+	c.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "TRV1",
+		PollControl:  &PollControlConfig{CheckInInterval: 14400, LongPollInterval: 20, FastPollTimeout: 80},
+	})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		Manufacturer: "Acme",
+		Model:        "TRV1",
+		Endpoints:    []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0x0020, 0x0201}}},
+		PollControl:  &store.PollControlState{Endpoint: 1},
+	}
+
+	if got := checkIn(t, c, 0x1111); !bytes.Equal(got, []byte{1, 80, 0}) {
+		t.Errorf("payload: got %X, want 015000", got)
+	}
+	waitFastPollStop(t, n)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.writes) != 1 || len(n.writes[0].Records) != 2 ||
+		!bytes.Equal(n.writes[0].Records[0].Value, []byte{0x40, 0x38, 0, 0}) {
+		t.Errorf("writes: %+v", n.writes)
+	}
+	// SetLongPollInterval, then Fast Poll Stop.
+	if len(n.commands) != 2 || n.commands[0].CommandID != pollSetLongPollInterval {
+		t.Errorf("commands: %+v", n.commands)
+	}
+	if !ms.devices["00158D0001A2B3C4"].PollControl.Configured {
+		t.Error("poll control not marked configured")
+	}
+}
@@ -0,0 +1,400 @@
+package coordinator
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"zigbee-go-home/internal/store"
+)
+
+const (
+	minPollInterval   = 10 // seconds
+	pollCheckInterval = 5 * time.Second
+	defaultPollGap    = 500 * time.Millisecond
+	pollReadTimeout   = 10 * time.Second
+	pollBackoffBase   = time.Minute
+	pollMaxBackoff    = time.Hour
+
+	// pollBusyPending is the number of pending NCP requests at which
+	// polling waits for the next check.
+	pollBusyPending = 2
+)
+
+// PollConfig configures the polling of attributes.
+type PollConfig struct {
+	Enabled bool
+
+	// Gap is the pause between two reads, leaving the NCP to other
+	// traffic. Zero uses the default (500ms).
+	Gap time.Duration
+}
+
+// PollEntry is an attribute a definition reads periodically, for devices
+// that cannot report it or lose their reporting configuration.
+type PollEntry struct {
+	Cluster   uint16 `json:"cluster"`
+	Attribute uint16 `json:"attribute"`
+	Interval  uint32 `json:"interval"` // seconds
+}
+
+// pollTarget is an attribute polled on a device.
+type pollTarget struct {
+	ieee      string
+	endpoint  uint8
+	cluster   uint16
+	attribute uint16
+}
+
+type pollState struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// pollRead is one Read Attributes request of a poll round.
+type pollRead struct {
+	dev      *store.Device
+	endpoint uint8
+	cluster  uint16
+	attrs    []uint16
+}
+
+// poller reads the attributes that device definitions and options poll.
+// The values read are processed as attribute reports, so properties and
+// events behave as if the device had reported them; a report postpones
+// the next read of its attribute. Reads run one at a time with a gap
+// between them, and wait while the NCP has requests pending. A device that
+// does not answer is retried with exponential backoff (1m up to 1h) until
+// it reports or announces itself again. Sleepy devices are not polled.
+type poller struct {
+	coord  *Coordinator
+	logger *slog.Logger
+	cfg    PollConfig
+	online atomic.Bool // the network was started
+
+	mu       sync.Mutex
+	state    map[pollTarget]*pollState
+	failures map[string]int       // IEEE -> failed reads in a row
+	retryAt  map[string]time.Time // IEEE -> end of the backoff
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+	once   sync.Once
+	unsubs []func()
+}
+
+func newPoller(c *Coordinator, cfg PollConfig) *poller {
+	if cfg.Gap <= 0 {
+		cfg.Gap = defaultPollGap
+	}
+	p := &poller{
+		coord:    c,
+		logger:   c.logger.With("component", "poll"),
+		cfg:      cfg,
+		state:    make(map[pollTarget]*pollState),
+		failures: make(map[string]int),
+		retryAt:  make(map[string]time.Time),
+		stopCh:   make(chan struct{}),
+	}
+	p.unsubs = append(p.unsubs,
+		c.events.On(EventNetworkState, func(e Event) {
+			if e.Data == "started" {
+				p.online.Store(true)
+			}
+		}),
+		c.events.On(EventAttributeReport, p.handleReport),
+		c.events.On(EventDeviceAnnounce, p.handleAnnounce),
+		c.events.On(EventDeviceLeft, p.handleLeft),
+	)
+	return p
+}
+
+// start runs the poll rounds until stop.
+func (p *poller) start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(pollCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.poll(time.Now())
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func (p *poller) stop() {
+	for _, unsub := range p.unsubs {
+		unsub()
+	}
+	p.once.Do(func() { close(p.stopCh) })
+	p.wg.Wait()
+}
+
+// poll reads the attributes due at now.
+func (p *poller) poll(now time.Time) {
+	if !p.online.Load() {
+		return
+	}
+	devices, err := p.coord.store.ListDevices()
+	if err != nil {
+		p.logger.Error("list devices for polling", "err", err)
+		return
+	}
+	reads := p.due(devices, now)
+	failed := make(map[string]bool)
+	for i, r := range reads {
+		if i > 0 && !p.wait(p.cfg.Gap) {
+			return
+		}
+		if pending := p.coord.ncp.Stats().Pending; pending >= pollBusyPending {
+			p.logger.Debug("NCP busy, polling later", "pending", pending, "due", len(reads)-i)
+			return
+		}
+		if failed[r.dev.IEEEAddress] {
+			continue // the device did not answer an earlier read
+		}
+		if !p.read(r) {
+			failed[r.dev.IEEEAddress] = true
+		}
+	}
+}
+
+// due returns the reads due at now, grouped per endpoint and cluster.
+// Targets seen for the first time are due at once.
+func (p *poller) due(devices []*store.Device, now time.Time) []*pollRead {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seen := make(map[pollTarget]bool)
+	groups := make(map[pollTarget]*pollRead) // attribute unset
+	for _, dev := range devices {
+		if !pollable(dev) {
+			continue
+		}
+		plan := p.coord.devices.pollPlan(dev)
+		backingOff := p.retryAt[dev.IEEEAddress].After(now)
+		for t, interval := range plan {
+			seen[t] = true
+			st := p.state[t]
+			if st == nil {
+				st = &pollState{next: now}
+				p.state[t] = st
+			}
+			st.interval = interval
+			if backingOff || st.next.After(now) {
+				continue
+			}
+			key := t
+			key.attribute = 0
+			r := groups[key]
+			if r == nil {
+				r = &pollRead{dev: dev, endpoint: t.endpoint, cluster: t.cluster}
+				groups[key] = r
+			}
+			r.attrs = append(r.attrs, t.attribute)
+		}
+	}
+	for t := range p.state {
+		if !seen[t] {
+			delete(p.state, t)
+		}
+	}
+
+	reads := slices.SortedFunc(maps.Values(groups), func(a, b *pollRead) int {
+		return cmp.Or(
+			cmp.Compare(a.dev.IEEEAddress, b.dev.IEEEAddress),
+			cmp.Compare(a.endpoint, b.endpoint),
+			cmp.Compare(a.cluster, b.cluster),
+		)
+	})
+	for _, r := range reads {
+		slices.Sort(r.attrs)
+	}
+	return reads
+}
+
+// pollable reports whether a device can be polled: it is interviewed and
+// not sleepy.
+func pollable(dev *store.Device) bool {
+	return dev.Interviewed && dev.PowerSource != PowerSourceBattery && dev.PollControl == nil
+}
+
+// read polls the attributes of one request and schedules their next read,
+// or backs off if the device did not answer. Returns whether it answered.
+func (p *poller) read(r *pollRead) bool {
+	ieee := r.dev.IEEEAddress
+	ctx, cancel := context.WithTimeout(p.coord.ctx, pollReadTimeout)
+	defer cancel()
+	_, err := p.coord.readAttributeReports(ctx, r.dev.ShortAddress, r.endpoint, r.cluster, r.attrs)
+	now := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failures[ieee]++
+		backoff := pollMaxBackoff
+		if n := p.failures[ieee]; n <= 6 {
+			backoff = min(pollBackoffBase<<(n-1), pollMaxBackoff)
+		}
+		p.retryAt[ieee] = now.Add(backoff)
+		p.logger.Info("poll failed, backing off", "ieee", ieee, "name", deviceName(r.dev),
+			"cluster", r.cluster, "err", err, "failures", p.failures[ieee], "retry_in", backoff)
+		return false
+	}
+	delete(p.failures, ieee)
+	delete(p.retryAt, ieee)
+	for _, attr := range r.attrs {
+		if st := p.state[pollTarget{ieee, r.endpoint, r.cluster, attr}]; st != nil {
+			st.next = now.Add(st.interval)
+		}
+	}
+	return true
+}
+
+// wait pauses for d, returning false if the poller stopped meanwhile.
+func (p *poller) wait(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-p.stopCh:
+		return false
+	}
+}
+
+// handleReport postpones the next read of a reported attribute. Any report
+// shows the device is reachable again.
+func (p *poller) handleReport(e Event) {
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	if ieee == "" {
+		return
+	}
+	endpoint, _ := data["endpoint"].(uint8)
+	cluster, _ := data["cluster_id"].(uint16)
+	attr, _ := data["attr_id"].(uint16)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failures, ieee)
+	delete(p.retryAt, ieee)
+	if st := p.state[pollTarget{ieee, endpoint, cluster, attr}]; st != nil {
+		st.next = time.Now().Add(st.interval)
+	}
+}
+
+// handleAnnounce ends the backoff of a device that rejoined.
+func (p *poller) handleAnnounce(e Event) {
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failures, ieee)
+	delete(p.retryAt, ieee)
+}
+
+func (p *poller) handleLeft(e Event) {
+	data, ok := e.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.failures, ieee)
+	delete(p.retryAt, ieee)
+	for t := range p.state {
+		if t.ieee == ieee {
+			delete(p.state, t)
+		}
+	}
+}
+
+// pollPlan returns the attributes polled on a device and their intervals:
+// the poll entries of its definition, at the poll_interval option if set,
+// and the attributes of <property>_poll_interval options.
+func (dm *DeviceManager) pollPlan(dev *store.Device) map[pollTarget]time.Duration {
+	def := dm.definition(dev)
+	opts := dm.deviceOptions(dev)
+	plan := make(map[pollTarget]time.Duration)
+	set := func(cluster, attr uint16, seconds float64) {
+		t := pollTarget{dev.IEEEAddress, endpointWithCluster(dev, cluster), cluster, attr}
+		if seconds <= 0 {
+			delete(plan, t)
+			return
+		}
+		plan[t] = time.Duration(seconds * float64(time.Second))
+	}
+	if def != nil {
+		all, hasAll := opts["poll_interval"]
+		for _, e := range def.Poll {
+			if hasAll {
+				set(e.Cluster, e.Attribute, all)
+			} else {
+				set(e.Cluster, e.Attribute, float64(e.Interval))
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(opts)) {
+		prop := optionProperty(name, optionPoll)
+		if prop == "" {
+			continue
+		}
+		if ref, ok := dm.propertyAttribute(dev, def, prop); ok {
+			set(ref.Cluster, ref.Attribute, opts[name])
+		}
+	}
+	return plan
+}
+
+// propertyAttribute returns the attribute a converter property comes from:
+// the definition's converters first, then the cluster defaults of the
+// device's server clusters.
+func (dm *DeviceManager) propertyAttribute(dev *store.Device, def *DeviceDefinition, name string) (AttributeRef, bool) {
+	if def != nil {
+		for _, ac := range def.Converters {
+			if ac.Property == name {
+				return AttributeRef{ac.Cluster, ac.Attribute}, true
+			}
+		}
+	}
+	registry := dm.coord.Registry()
+	if registry == nil {
+		return AttributeRef{}, false
+	}
+	for _, ep := range dev.Endpoints {
+		for _, clusterID := range ep.InClusters {
+			cluster := registry.Get(clusterID)
+			if cluster == nil {
+				continue
+			}
+			for _, attr := range cluster.Attributes {
+				if attr.Converter == nil || attr.Converter.Property != name {
+					continue
+				}
+				// The definition may override or disable the default.
+				if conv := dm.converterFor(dev, clusterID, attr.ID); conv != nil && conv.Property == name {
+					return AttributeRef{clusterID, attr.ID}, true
+				}
+			}
+		}
+	}
+	return AttributeRef{}, false
+}
@@ -0,0 +1,187 @@
+package coordinator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+// newTestPoller answers reads with one-byte values.
+func newTestPoller(t *testing.T, opts map[string]float64) (*poller, *memStore, *fakeNCP) {
+	t.Helper()
+	c, ms, n := newTestCoord(t)
+	dm := c.devices
+	n.onRead = func(req ncp.ReadAttributesRequest) []ncp.AttributeResponse {
+		var rsp []ncp.AttributeResponse
+		for _, id := range req.AttrIDs {
+			rsp = append(rsp, ncp.AttributeResponse{AttrID: id, DataType: zcl.TypeBool, Value: []byte{1}})
+		}
+		return rsp
+	}
+	c.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "Bulb",
+		Poll:         []PollEntry{{Cluster: 0x0006, Attribute: 0x0000, Interval: 60}},
+	})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111,
+		Manufacturer: "Acme", Model: "Bulb", Interviewed: true, Options: opts,
+		Endpoints: []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0x0006, 0x0402}}},
+	}
+	dm.RebuildAddrIndex()
+	p := newPoller(c, PollConfig{Enabled: true, Gap: time.Millisecond})
+	t.Cleanup(p.stop)
+	c.events.Emit(Event{Type: EventNetworkState, Data: "started"})
+	return p, ms, n
+}
+
+func TestPollPlan(t *testing.T) {
+	p, ms, _ := newTestPoller(t, nil)
+	dm := p.coord.devices
+	onOff := pollTarget{"00158D0001A2B3C4", 1, 0x0006, 0x0000}
+	temperature := pollTarget{"00158D0001A2B3C4", 1, 0x0402, 0x0000}
+
+	plan := dm.pollPlan(ms.devices["00158D0001A2B3C4"])
+	if len(plan) != 1 || plan[onOff] != time.Minute {
+		t.Errorf("definition plan = %v", plan)
+	}
+
+	ms.devices["00158D0001A2B3C4"].Options = map[string]float64{"poll_interval": 120, "temperature_poll_interval": 30}
+	plan = dm.pollPlan(ms.devices["00158D0001A2B3C4"])
+	if len(plan) != 2 || plan[onOff] != 2*time.Minute || plan[temperature] != 30*time.Second {
+		t.Errorf("plan with options = %v", plan)
+	}
+
+	ms.devices["00158D0001A2B3C4"].Options = map[string]float64{"on_off_poll_interval": 0, "bogus_poll_interval": 30}
+	if plan = dm.pollPlan(ms.devices["00158D0001A2B3C4"]); len(plan) != 0 {
+		t.Errorf("plan with polling turned off = %v", plan)
+	}
+}
+
+func TestPollerReadsDueAttributes(t *testing.T) {
+	p, _, n := newTestPoller(t, map[string]float64{"temperature_poll_interval": 30})
+	state := recordProperty(p.coord.devices, "on_off")
+
+	now := time.Now()
+	p.poll(now)
+	if n.readCount() != 2 {
+		t.Fatalf("reads = %+v, want on_off and temperature", n.reads)
+	}
+	if r := n.reads[0]; r.DstAddr != 0x1111 || r.DstEP != 1 || r.ClusterID != 0x0006 || len(r.AttrIDs) != 1 {
+		t.Errorf("read = %+v", r)
+	}
+	// Values read are processed as reports.
+	if v := state.get(); len(v) != 1 || v[0] != true {
+		t.Errorf("on_off updates = %v", v)
+	}
+
+	p.poll(now.Add(time.Second))
+	if n.readCount() != 2 {
+		t.Errorf("reads before the interval = %d", n.readCount())
+	}
+	p.poll(time.Now().Add(31 * time.Second))
+	if n.readCount() != 3 || n.reads[2].ClusterID != 0x0402 {
+		t.Errorf("reads after 31s = %+v", n.reads)
+	}
+}
+
+func TestPollerReportPostponesRead(t *testing.T) {
+	p, _, n := newTestPoller(t, nil)
+	p.poll(time.Now())
+	// The attribute is due again, but the device reports it.
+	p.mu.Lock()
+	p.state[pollTarget{"00158D0001A2B3C4", 1, 0x0006, 0x0000}].next = time.Now()
+	p.mu.Unlock()
+	p.coord.devices.HandleAttributeReport(ncp.AttributeReportEvent{
+		SrcAddr: 0x1111, SrcEP: 1, ClusterID: 0x0006, AttrID: 0x0000, DataType: zcl.TypeBool, Value: []byte{0},
+	})
+	p.poll(time.Now().Add(time.Second))
+	if n.readCount() != 1 {
+		t.Errorf("reads = %d, want the reported attribute postponed", n.readCount())
+	}
+}
+
+func TestPollerBackoff(t *testing.T) {
+	p, _, n := newTestPoller(t, nil)
+	n.readErr = errors.New("no route")
+
+	p.poll(time.Now())
+	p.poll(time.Now().Add(30 * time.Second))
+	if n.readCount() != 1 {
+		t.Fatalf("reads during backoff = %d, want 1", n.readCount())
+	}
+	p.poll(time.Now().Add(61 * time.Second))
+	if n.readCount() != 2 {
+		t.Fatalf("reads after backoff = %d, want 2", n.readCount())
+	}
+	// The second failure doubles the backoff.
+	p.poll(time.Now().Add(90 * time.Second))
+	if n.readCount() != 2 {
+		t.Errorf("reads during doubled backoff = %d", n.readCount())
+	}
+
+	// An announce ends the backoff.
+	n.mu.Lock()
+	n.readErr = nil
+	n.mu.Unlock()
+	p.coord.events.Emit(Event{Type: EventDeviceAnnounce, Data: map[string]interface{}{"ieee": "00158D0001A2B3C4"}})
+	p.poll(time.Now())
+	if n.readCount() != 3 {
+		t.Errorf("reads after announce = %d, want 3", n.readCount())
+	}
+}
+
+func TestPollerWaitsForNCP(t *testing.T) {
+	p, ms, n := newTestPoller(t, nil)
+	n.stats.Pending = pollBusyPending
+	p.poll(time.Now())
+	if n.readCount() != 0 {
+		t.Errorf("reads while the NCP is busy = %d", n.readCount())
+	}
+
+	n.stats.Pending = 0
+	ms.devices["00158D0001A2B3C4"].PowerSource = PowerSourceBattery
+	p.poll(time.Now())
+	if n.readCount() != 0 {
+		t.Errorf("reads of a sleepy device = %d", n.readCount())
+	}
+	ms.devices["00158D0001A2B3C4"].PowerSource = PowerSourceMains
+	p.poll(time.Now())
+	if n.readCount() != 1 {
+		t.Errorf("reads = %d, want 1", n.readCount())
+	}
+}
+
+func TestDefinitionPollValidation(t *testing.T) {
+	for _, interval := range []uint32{0, 5, maxThrottleSeconds + 1} {
+		def := DeviceDefinition{Model: "Bad", Poll: []PollEntry{{Cluster: 6, Interval: interval}}}
+		if err := def.compile(); err == nil {
+			t.Errorf("interval %d: expected error", interval)
+		}
+	}
+	def := DeviceDefinition{Model: "Good", Poll: []PollEntry{{Cluster: 6, Interval: 60}}}
+	if err := def.compile(); err != nil {
+		t.Errorf("compile: %v", err)
+	}
+}
+
+func TestMergeDefinitionPoll(t *testing.T) {
+	base := DeviceDefinition{Poll: []PollEntry{{Cluster: 6, Interval: 60}, {Cluster: 8, Interval: 60}}}
+	over := DeviceDefinition{Poll: []PollEntry{{Cluster: 6, Interval: 30}}}
+	got := mergeDefinition(base, over, nil).Poll
+	if len(got) != 2 || got[0].Interval != 30 {
+		t.Errorf("merged poll = %+v", got)
+	}
+	if got := mergeDefinition(base, over, []string{listPoll}).Poll; len(got) != 1 {
+		t.Errorf("overridden poll = %+v", got)
+	}
+	merged := mergeDefinition(base, over, nil)
+	merged.removeEntries(&DefinitionRemove{Poll: []AttributeRef{{Cluster: 8}}})
+	if len(merged.Poll) != 1 || merged.Poll[0].Cluster != 6 {
+		t.Errorf("poll after remove = %+v", merged.Poll)
+	}
+}
@@ -3,210 +3,189 @@ package coordinator
 import (
 	"encoding/binary"
 	"fmt"
+	"maps"
+	"slices"
 
 	"zigbee-go-home/internal/ncp"
 	"zigbee-go-home/internal/store"
 	"zigbee-go-home/internal/zcl"
 )
 
-// processProperties checks if the device has property definitions for this
-// cluster/attribute and emits property_update events for each extracted value.
-// Accepts the already-loaded device to avoid a redundant DB read.
+// processProperties runs the property decoders of the device's definition
+// that read this attribute and emits property_update events for each
+// extracted value. Accepts the already-loaded device to avoid a redundant
+// DB read.
 func (dm *DeviceManager) processProperties(ieee string, dev *store.Device, evt ncp.AttributeReportEvent, decoded interface{}) {
-	if ieee == "" || dev == nil || dev.Manufacturer == "" || dev.Model == "" {
-		return
+	dm.decodeProperties(ieee, dev, attributeDecoderInput(evt, decoded))
+}
+
+// attributeDecoderInput is the decoder input of an attribute report.
+func attributeDecoderInput(evt ncp.AttributeReportEvent, decoded any) DecoderInput {
+	in := DecoderInput{
+		Cluster:   evt.ClusterID,
+		Attribute: evt.AttrID,
+		DataType:  evt.DataType,
+		Data:      evt.Value,
 	}
+	// Decoders of string attributes get the contents without the length.
+	switch v := decoded.(type) {
+	case []byte:
+		in.Data = v
+	case string:
+		in.Data = []byte(v)
+	}
+	return in
+}
 
-	db := dm.coord.DeviceDB()
-	if db == nil {
+// processClusterCommandProperties runs the property decoders that read
+// this cluster command (e.g., 0xEF00 Tuya DP) and emits property_update
+// events.
+func (dm *DeviceManager) processClusterCommandProperties(ieee string, dev *store.Device, evt ncp.ClusterCommandEvent) {
+	dm.decodeProperties(ieee, dev, DecoderInput{
+		Cluster:   evt.ClusterID,
+		Command:   evt.CommandID,
+		IsCommand: true,
+		Data:      evt.Payload,
+	})
+}
+
+// decodeProperties decodes the input with every matching property source
+// of the device's definition, then emits and stores the values.
+func (dm *DeviceManager) decodeProperties(ieee string, dev *store.Device, in DecoderInput) {
+	if ieee == "" || dev == nil {
 		return
 	}
-	def := db.Lookup(dev.Manufacturer, dev.Model)
+
+	def := dm.definition(dev)
 	if def == nil || len(def.Properties) == 0 {
 		return
 	}
+	in.Manufacturer, in.Model = dev.Manufacturer, dev.Model
 
-	dirty := false
-	for _, ps := range def.Properties {
-		if ps.Cluster != evt.ClusterID || ps.Attribute != evt.AttrID {
+	collected := make(map[string]any)
+	for i := range def.Properties {
+		ps := &def.Properties[i]
+		if ps.Cluster != in.Cluster {
 			continue
 		}
-
-		var tlvMap map[int]interface{}
-		switch ps.Decoder {
-		case "xiaomi_tlv":
-			var raw []byte
-			switch v := decoded.(type) {
-			case []byte:
-				raw = v
-			case string:
-				raw = []byte(v)
-			default:
-				dm.logger.Warn("property decoder expects []byte or string",
-					"ieee", ieee, "decoder", ps.Decoder,
-					"got", fmt.Sprintf("%T", decoded))
-				continue
+		dec := dm.coord.DeviceDB().Decoder(ps.Decoder)
+		if dec == nil {
+			if ps.reads(in, ps.Commands) {
+				dm.logger.Warn("unknown property decoder",
+					"ieee", ieee, "decoder", ps.Decoder)
 			}
-			var decErr error
-			tlvMap, decErr = decodeXiaomiTLV(raw)
-			if decErr != nil {
-				dm.logger.Warn("xiaomi TLV decode failed",
-					"ieee", ieee, "err", decErr)
-				continue
-			}
-		default:
-			dm.logger.Warn("unknown property decoder",
-				"ieee", ieee, "decoder", ps.Decoder)
+			continue
+		}
+		commands := ps.Commands
+		if len(commands) == 0 {
+			commands = dec.Commands
+		}
+		if !ps.reads(in, commands) {
 			continue
 		}
 
-		for _, v := range ps.Values {
-			raw, ok := tlvMap[v.Tag]
-			if !ok {
-				continue
-			}
-			value := raw
-			if v.Transform != "" {
-				value = applyTransform(v.Transform, raw)
-			}
-
-			// Collect property value (batched save after loop).
-			if dev.Properties == nil {
-				dev.Properties = make(map[string]any)
-			}
-			dev.Properties[v.Name] = value
-			dirty = true
-
-			dm.coord.Events().Emit(Event{
-				Type: EventPropertyUpdate,
-				Data: map[string]interface{}{
-					"ieee":     ieee,
-					"property": v.Name,
-					"value":    value,
-					"source": map[string]interface{}{
-						"cluster":   ps.Cluster,
-						"attribute": ps.Attribute,
-						"decoder":   ps.Decoder,
-						"tag":       v.Tag,
-					},
-				},
-			})
-
-			dm.logger.Info("property update",
-				"ieee", ieee,
-				"name", deviceName(dev),
-				"property", v.Name,
-				"value", value,
-			)
+		decoded, err := dec.Decode(in)
+		if err != nil {
+			dm.logger.Warn("property decode failed",
+				"ieee", ieee, "decoder", ps.Decoder, "err", err)
+			continue
 		}
+		dm.emitDecoded(ieee, dev, ps, in, decoded, collected)
 	}
 
 	// Persist all collected properties atomically.
-	if dirty {
-		collected := make(map[string]any)
-		for k, v := range dev.Properties {
-			collected[k] = v
+	if len(collected) == 0 {
+		return
+	}
+	if updateErr := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if d.Properties == nil {
+			d.Properties = make(map[string]any)
 		}
-		if updateErr := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
-			if d.Properties == nil {
-				d.Properties = make(map[string]any)
-			}
-			for k, v := range collected {
-				d.Properties[k] = v
-			}
-			return nil
-		}); updateErr != nil {
-			dm.logger.Error("save device properties", "err", updateErr, "ieee", ieee)
+		for k, v := range collected {
+			d.Properties[k] = v
 		}
+		return nil
+	}); updateErr != nil {
+		dm.logger.Error("save device properties", "err", updateErr, "ieee", ieee)
 	}
 }
 
-// processClusterCommandProperties checks if the device has property definitions
-// for this cluster (e.g., 0xEF00 Tuya DP) and emits property_update events.
-func (dm *DeviceManager) processClusterCommandProperties(ieee string, dev *store.Device, evt ncp.ClusterCommandEvent) {
-	if ieee == "" || dev == nil || dev.Manufacturer == "" || dev.Model == "" {
-		return
+// reads reports whether the property source decodes the input: one of the
+// cluster commands, or without commands, the attribute.
+func (ps *PropertySource) reads(in DecoderInput, commands []uint8) bool {
+	if in.IsCommand {
+		return slices.Contains(commands, in.Command)
 	}
+	return len(commands) == 0 && ps.Attribute == in.Attribute
+}
 
-	db := dm.coord.DeviceDB()
-	if db == nil {
-		return
-	}
-	def := db.Lookup(dev.Manufacturer, dev.Model)
-	if def == nil || len(def.Properties) == 0 {
-		return
+// emitDecoded emits a property_update event for each decoded value the
+// source selects by tag or key, then for the remaining named values, and
+// adds them to collected. Values are calibrated with the device options.
+// An action value also emits a device_action event.
+func (dm *DeviceManager) emitDecoded(ieee string, dev *store.Device, ps *PropertySource, in DecoderInput, decoded Decoded, collected map[string]any) {
+	opts := dm.deviceOptions(dev)
+	emit := func(name string, value any, from string, id any) {
+		source := map[string]interface{}{
+			"cluster": ps.Cluster,
+			"decoder": ps.Decoder,
+			from:      id,
+		}
+		if in.IsCommand {
+			source["command"] = in.Command
+		} else {
+			source["attribute"] = in.Attribute
+		}
+		value = calibrate(opts, name, value)
+		collected[name] = value
+
+		dm.coord.Events().Emit(Event{
+			Type: EventPropertyUpdate,
+			Data: map[string]interface{}{
+				"ieee":     ieee,
+				"property": name,
+				"value":    value,
+				"source":   source,
+			},
+		})
+
+		dm.logger.Info("property update",
+			"ieee", ieee,
+			"name", deviceName(dev),
+			"property", name,
+			"value", value,
+		)
+		if action, ok := value.(string); ok && name == "action" && action != "" {
+			dm.emitDeviceAction(ieee, dev, action, map[string]interface{}{"source": source})
+		}
 	}
 
-	dirty := false
-	for _, ps := range def.Properties {
-		if ps.Cluster != evt.ClusterID {
-			continue
+	selected := make(map[string]bool)
+	for _, v := range ps.Values {
+		var raw any
+		var ok bool
+		if v.Key != "" {
+			raw, ok = decoded.Properties[v.Key]
+			selected[v.Key] = true
+		} else {
+			raw, ok = decoded.Tags[v.Tag]
 		}
-		if ps.Decoder != "tuya_dp" {
+		if !ok {
 			continue
 		}
-
-		dpMap, decErr := decodeTuyaDPs(evt.Payload)
-		if decErr != nil {
-			dm.logger.Warn("tuya DP decode failed",
-				"ieee", ieee, "err", decErr)
-			continue
+		value := raw
+		if v.Transform != "" {
+			value = dm.transform(ieee, v.Transform, raw)
 		}
-
-		for _, v := range ps.Values {
-			raw, ok := dpMap[v.Tag]
-			if !ok {
-				continue
-			}
-			value := raw
-			if v.Transform != "" {
-				value = applyTransform(v.Transform, raw)
-			}
-
-			if dev.Properties == nil {
-				dev.Properties = make(map[string]any)
-			}
-			dev.Properties[v.Name] = value
-			dirty = true
-
-			dm.coord.Events().Emit(Event{
-				Type: EventPropertyUpdate,
-				Data: map[string]interface{}{
-					"ieee":     ieee,
-					"property": v.Name,
-					"value":    value,
-					"source": map[string]interface{}{
-						"cluster": ps.Cluster,
-						"decoder": ps.Decoder,
-						"tag":     v.Tag,
-					},
-				},
-			})
-
-			dm.logger.Info("property update",
-				"ieee", ieee,
-				"name", deviceName(dev),
-				"property", v.Name,
-				"value", value,
-			)
+		if v.Key != "" {
+			emit(v.Name, value, "key", v.Key)
+		} else {
+			emit(v.Name, value, "tag", v.Tag)
 		}
 	}
-
-	if dirty {
-		collected := make(map[string]any)
-		for k, v := range dev.Properties {
-			collected[k] = v
-		}
-		if updateErr := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
-			if d.Properties == nil {
-				d.Properties = make(map[string]any)
-			}
-			for k, v := range collected {
-				d.Properties[k] = v
-			}
-			return nil
-		}); updateErr != nil {
-			dm.logger.Error("save device properties", "err", updateErr, "ieee", ieee)
+	for _, key := range slices.Sorted(maps.Keys(decoded.Properties)) {
+		if !selected[key] {
+			emit(key, decoded.Properties[key], "key", key)
 		}
 	}
 }
@@ -293,89 +272,34 @@ func decodeXiaomiTLV(data []byte) (map[int]interface{}, error) {
 	return result, nil
 }
 
-// applyTransform converts a raw decoded value using a named transform.
+// applyTransform converts a raw decoded value using a transform expression
+// or one of its named aliases (see expr.go). A value the transform cannot
+// handle is returned unchanged.
 func applyTransform(name string, value interface{}) interface{} {
-	switch name {
-	case "lumi_battery":
-		return lumiBattery(value)
-	case "minus_one":
-		return minusOne(value)
-	case "lumi_trigger":
-		return lumiTrigger(value)
-	case "bool_invert":
-		return boolInvert(value)
-	case "divide_10":
-		return divideN(value, 10)
-	case "divide_100":
-		return divideN(value, 100)
-	default:
-		return value
-	}
-}
-
-// divideN divides a numeric value by n, returning a float64.
-func divideN(value interface{}, n int) interface{} {
-	v, ok := toNumeric(value)
-	if !ok {
-		return value
-	}
-	return float64(v) / float64(n)
-}
-
-// lumiBattery converts millivolt reading to battery percentage.
-// 2850 mV = 0%, 3000 mV = 100%, linearly interpolated and clamped.
-func lumiBattery(value interface{}) interface{} {
-	mv, ok := toNumeric(value)
-	if !ok {
+	if name == "" {
 		return value
 	}
-	const minMV, maxMV = 2850, 3000
-	pct := float64(mv-minMV) / float64(maxMV-minMV) * 100
-	if pct < 0 {
-		pct = 0
-	}
-	if pct > 100 {
-		pct = 100
-	}
-	return int(pct)
-}
-
-// minusOne subtracts 1 from a numeric value.
-func minusOne(value interface{}) interface{} {
-	n, ok := toNumeric(value)
-	if !ok {
+	out, err := evalTransform(name, value)
+	if err != nil {
 		return value
 	}
-	return n - 1
+	return out
 }
 
-// lumiTrigger extracts the lower 16 bits of a uint64, then subtracts 1.
-func lumiTrigger(value interface{}) interface{} {
-	n, ok := toNumeric(value)
-	if !ok {
+// transform is applyTransform for values about to be published: a value
+// the transform cannot handle is logged, so a broken expression leaves a
+// trace.
+func (dm *DeviceManager) transform(ieee, name string, value interface{}) interface{} {
+	if name == "" {
 		return value
 	}
-	return (n & 0xFFFF) - 1
-}
-
-// boolInvert inverts a boolean value.
-func boolInvert(value interface{}) interface{} {
-	switch v := value.(type) {
-	case bool:
-		return !v
-	case uint8:
-		return v == 0
-	case uint16:
-		return v == 0
-	case uint32:
-		return v == 0
-	case uint64:
-		return v == 0
-	case int64:
-		return v == 0
-	default:
+	out, err := evalTransform(name, value)
+	if err != nil {
+		dm.logger.Debug("transform failed, keeping the raw value",
+			"ieee", ieee, "transform", name, "value", value, "err", err)
 		return value
 	}
+	return out
 }
 
 // toNumeric converts various numeric types to int64 for transform calculations.
@@ -75,7 +75,7 @@ func TestDecodeXiaomiTLVTruncated(t *testing.T) {
 func TestApplyTransformLumiBattery(t *testing.T) {
 	tests := []struct {
 		input interface{}
-		want  int
+		want  int64
 	}{
 		{uint16(3055), 100}, // above max, clamped
 		{uint16(3000), 100},
@@ -0,0 +1,294 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/zcl"
+)
+
+// ErrShortZCLPayload is returned when a raw frame marked as ZCL has no room
+// for a ZCL header.
+var ErrShortZCLPayload = errors.New("payload too short for a ZCL header")
+
+// RawAPSRequest is an arbitrary APS frame for talking to devices the
+// definitions do not cover yet (ZLL, manufacturer profiles, ...).
+type RawAPSRequest struct {
+	ShortAddr uint16
+	DstEP     uint8
+	SrcEP     uint8 // 0 = endpoint 1
+	ProfileID uint16
+	ClusterID uint16
+	Radius    uint8 // 0 = default
+	NoACK     bool
+	Payload   []byte
+
+	// WaitResponse waits for the next frame from the device on the same
+	// profile and cluster. With ZCL set, the response must also carry the
+	// request's ZCL sequence number and is decoded.
+	WaitResponse bool
+	ZCL          bool
+}
+
+// RawAPSResponse is the frame received in reply to a raw APS request.
+type RawAPSResponse struct {
+	SrcAddr   uint16      `json:"src_addr"`
+	SrcEP     uint8       `json:"src_ep"`
+	DstEP     uint8       `json:"dst_ep"`
+	ProfileID uint16      `json:"profile_id"`
+	ClusterID uint16      `json:"cluster_id"`
+	LQI       uint8       `json:"lqi"`
+	RSSI      int8        `json:"rssi"`
+	Payload   string      `json:"payload"` // hex
+	ZCL       *DecodedZCL `json:"zcl,omitempty"`
+}
+
+// DecodedZCL is a parsed ZCL frame. Attributes is filled for attribute
+// reports, Read/Write Attributes and Discover Attributes responses; Status
+// for Default Responses.
+type DecodedZCL struct {
+	FrameType          string            `json:"frame_type"` // "global" or "cluster"
+	Direction          string            `json:"direction"`  // "to_server" or "to_client"
+	DisableDefaultResp bool              `json:"disable_default_response"`
+	ManufacturerCode   uint16            `json:"manufacturer_code,omitempty"`
+	Seq                uint8             `json:"seq"`
+	CommandID          uint8             `json:"command_id"`
+	CommandName        string            `json:"command_name,omitempty"`
+	Payload            string            `json:"payload"` // hex, after the header
+	Attributes         []AttributeResult `json:"attributes,omitempty"`
+	Status             *uint8            `json:"status,omitempty"`
+}
+
+// globalCommandNames names the foundation commands in decoded frames.
+var globalCommandNames = map[uint8]string{
+	zcl.FoundationReadAttributes:         "read_attributes",
+	zcl.FoundationReadAttributesResponse: "read_attributes_response",
+	zcl.FoundationWriteAttributes:        "write_attributes",
+	zcl.FoundationWriteAttrsUndivided:    "write_attributes_undivided",
+	zcl.FoundationWriteAttributesResp:    "write_attributes_response",
+	zcl.FoundationWriteAttrsNoResponse:   "write_attributes_no_response",
+	zcl.FoundationConfigReporting:        "configure_reporting",
+	zcl.FoundationConfigReportingResp:    "configure_reporting_response",
+	zcl.FoundationReadReportingConfig:    "read_reporting_configuration",
+	zcl.FoundationReportAttributes:       "report_attributes",
+	zcl.FoundationDefaultResponse:        "default_response",
+	zcl.FoundationDiscoverAttributes:     "discover_attributes",
+	zcl.FoundationDiscoverAttributesResp: "discover_attributes_response",
+}
+
+// zclHeaderLen returns the ZCL header length of payload, or 0 if it is too short.
+func zclHeaderLen(payload []byte) int {
+	if len(payload) < 3 {
+		return 0
+	}
+	n := 3
+	if payload[0]&0x04 != 0 {
+		n += 2 // manufacturer code
+	}
+	if len(payload) < n {
+		return 0
+	}
+	return n
+}
+
+// SendRawAPS sends an arbitrary APS payload. Without WaitResponse, frames to
+// sleepy Poll Control devices are queued until check-in (ErrCommandQueued).
+// With WaitResponse the call blocks until a matching frame arrives or ctx
+// is done.
+func (c *Coordinator) SendRawAPS(ctx context.Context, req RawAPSRequest) (*RawAPSResponse, error) {
+	var seq uint8
+	if req.ZCL {
+		hdr := zclHeaderLen(req.Payload)
+		if hdr == 0 {
+			return nil, ErrShortZCLPayload
+		}
+		seq = req.Payload[hdr-2]
+	}
+
+	apsReq := ncp.APSFrameRequest{
+		DstAddr:   req.ShortAddr,
+		DstEP:     req.DstEP,
+		SrcEP:     req.SrcEP,
+		ProfileID: req.ProfileID,
+		ClusterID: req.ClusterID,
+		Radius:    req.Radius,
+		NoACK:     req.NoACK,
+		Payload:   req.Payload,
+	}
+
+	if !req.WaitResponse {
+		desc := fmt.Sprintf("raw aps 0x%04X/0x%04X", req.ProfileID, req.ClusterID)
+		if c.deferToCheckIn(req.ShortAddr, desc, func(ctx context.Context) error {
+			_, err := c.ncp.SendAPS(ctx, apsReq, nil)
+			return err
+		}) {
+			return nil, ErrCommandQueued
+		}
+		_, err := c.ncp.SendAPS(ctx, apsReq, nil)
+		return nil, err
+	}
+
+	match := func(f ncp.APSFrame) bool {
+		if f.SrcAddr != req.ShortAddr || f.ProfileID != req.ProfileID || f.ClusterID != req.ClusterID {
+			return false
+		}
+		if !req.ZCL {
+			return true
+		}
+		hdr := zclHeaderLen(f.Payload)
+		return hdr != 0 && f.Payload[hdr-2] == seq
+	}
+	f, err := c.ncp.SendAPS(ctx, apsReq, match)
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := &RawAPSResponse{
+		SrcAddr:   f.SrcAddr,
+		SrcEP:     f.SrcEP,
+		DstEP:     f.DstEP,
+		ProfileID: f.ProfileID,
+		ClusterID: f.ClusterID,
+		LQI:       f.LQI,
+		RSSI:      f.RSSI,
+		Payload:   fmt.Sprintf("%X", f.Payload),
+	}
+	if req.ZCL {
+		rsp.ZCL = c.decodeZCL(f.ClusterID, f.Payload)
+	}
+	return rsp, nil
+}
+
+// decodeZCL parses a ZCL frame, naming commands and attributes from the
+// registry where known. Returns nil if the header is incomplete.
+func (c *Coordinator) decodeZCL(clusterID uint16, data []byte) *DecodedZCL {
+	hdr := zclHeaderLen(data)
+	if hdr == 0 {
+		return nil
+	}
+	fc := data[0]
+	d := &DecodedZCL{
+		FrameType:          "global",
+		Direction:          "to_server",
+		DisableDefaultResp: fc&0x10 != 0,
+		Seq:                data[hdr-2],
+		CommandID:          data[hdr-1],
+		Payload:            fmt.Sprintf("%X", data[hdr:]),
+	}
+	if fc&0x04 != 0 {
+		d.ManufacturerCode = binary.LittleEndian.Uint16(data[1:3])
+	}
+	dir := zcl.DirectionToServer
+	if fc&0x08 != 0 {
+		d.Direction = "to_client"
+		dir = zcl.DirectionToClient
+	}
+
+	var cluster *zcl.ClusterDef
+	if c.registry != nil {
+		cluster = c.registry.Get(clusterID)
+	}
+	if fc&0x03 == 0x01 {
+		d.FrameType = "cluster"
+		if cluster != nil {
+			if cmd := cluster.FindCommand(d.CommandID, dir); cmd != nil {
+				d.CommandName = cmd.Name
+			}
+		}
+		return d
+	}
+
+	d.CommandName = globalCommandNames[d.CommandID]
+	records := data[hdr:]
+	switch d.CommandID {
+	case zcl.FoundationReadAttributesResponse:
+		d.Attributes = decodeAttributeRecords(cluster, records, true)
+	case zcl.FoundationReportAttributes:
+		d.Attributes = decodeAttributeRecords(cluster, records, false)
+	case zcl.FoundationWriteAttributesResp:
+		// status(1) [+ attr_id(2)] per record; a lone success status covers all.
+		for len(records) >= 1 {
+			r := AttributeResult{Status: records[0]}
+			records = records[1:]
+			if len(records) >= 2 {
+				r.AttrID = binary.LittleEndian.Uint16(records[0:2])
+				r.AttrName = attributeName(cluster, r.AttrID)
+				records = records[2:]
+			}
+			if r.Status != 0 {
+				r.Error = fmt.Sprintf("status 0x%02X", r.Status)
+			}
+			d.Attributes = append(d.Attributes, r)
+		}
+	case zcl.FoundationDiscoverAttributesResp:
+		// discovery_complete(1) + (attr_id(2) + type(1))*
+		if len(records) >= 1 {
+			records = records[1:]
+		}
+		for len(records) >= 3 {
+			id := binary.LittleEndian.Uint16(records[0:2])
+			d.Attributes = append(d.Attributes, AttributeResult{
+				AttrID:   id,
+				AttrName: attributeName(cluster, id),
+				TypeID:   records[2],
+				TypeName: zcl.TypeName(records[2]),
+			})
+			records = records[3:]
+		}
+	case zcl.FoundationDefaultResponse:
+		if len(records) >= 2 {
+			status := records[1]
+			d.Status = &status
+		}
+	}
+	return d
+}
+
+// decodeAttributeRecords parses attr_id(2) [+ status(1)] + type(1) + value
+// records, stopping at the first value it cannot size.
+func decodeAttributeRecords(cluster *zcl.ClusterDef, data []byte, withStatus bool) []AttributeResult {
+	var results []AttributeResult
+	for len(data) >= 3 {
+		r := AttributeResult{AttrID: binary.LittleEndian.Uint16(data[0:2])}
+		r.AttrName = attributeName(cluster, r.AttrID)
+		data = data[2:]
+		if withStatus {
+			r.Status = data[0]
+			data = data[1:]
+			if r.Status != 0 {
+				r.Error = fmt.Sprintf("status 0x%02X", r.Status)
+				results = append(results, r)
+				continue
+			}
+		}
+		if len(data) < 1 {
+			break
+		}
+		r.TypeID = data[0]
+		r.TypeName = zcl.TypeName(r.TypeID)
+		data = data[1:]
+		val, n, err := zcl.DecodeValue(r.TypeID, data)
+		if err != nil {
+			r.Error = err.Error()
+			results = append(results, r)
+			break
+		}
+		r.Value = val
+		data = data[n:]
+		results = append(results, r)
+	}
+	return results
+}
+
+// attributeName returns the registry name of an attribute or its hex ID.
+func attributeName(cluster *zcl.ClusterDef, attrID uint16) string {
+	if cluster != nil {
+		if attr := cluster.FindAttribute(attrID); attr != nil {
+			return attr.Name
+		}
+	}
+	return fmt.Sprintf("0x%04X", attrID)
+}
@@ -0,0 +1,99 @@
+package coordinator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/zcl"
+)
+
+// newTestRawAPS answers SendAPS with the first of frames the match accepts.
+func newTestRawAPS(t *testing.T, frames ...ncp.APSFrame) (*Coordinator, *fakeNCP) {
+	t.Helper()
+	c, _, n := newTestCoord(t)
+	n.apsFrames = frames
+	c.registry = zcl.NewRegistry(newTestLogger())
+	c.registry.Register(zcl.ClusterDef{ID: 0x0000, Name: "Basic", Attributes: []zcl.AttributeDef{
+		{ID: 0x0004, Name: "ManufacturerName", Type: zcl.TypeCharStr},
+	}})
+	return c, n
+}
+
+func TestSendRawAPSWaitZCL(t *testing.T) {
+	c, n := newTestRawAPS(t,
+		// Same cluster, wrong sequence number: not the answer.
+		ncp.APSFrame{SrcAddr: 0x1234, ProfileID: 0x0104, ClusterID: 0x0000, Payload: []byte{0x18, 0x0F, 0x01}},
+		ncp.APSFrame{SrcAddr: 0x1234, SrcEP: 1, ProfileID: 0x0104, ClusterID: 0x0000,
+			Payload: []byte{0x18, 0x10, 0x01, 0x04, 0x00, 0x00, 0x42, 0x04, 'A', 'c', 'm', 'e', 0x05, 0x00, 0x86}},
+	)
+
+	rsp, err := c.SendRawAPS(context.Background(), RawAPSRequest{
+		ShortAddr: 0x1234, DstEP: 1, ProfileID: 0x0104, ClusterID: 0x0000,
+		Payload:      []byte{0x00, 0x10, 0x00, 0x04, 0x00, 0x05, 0x00},
+		WaitResponse: true, ZCL: true, NoACK: true,
+	})
+	if err != nil {
+		t.Fatalf("SendRawAPS: %v", err)
+	}
+	if len(n.aps) != 1 || !n.aps[0].NoACK || n.aps[0].ProfileID != 0x0104 {
+		t.Errorf("sent: %+v", n.aps)
+	}
+	z := rsp.ZCL
+	if z == nil || z.Seq != 0x10 || z.CommandName != "read_attributes_response" || z.Direction != "to_client" {
+		t.Fatalf("zcl: %+v", z)
+	}
+	if len(z.Attributes) != 2 {
+		t.Fatalf("attributes: %+v", z.Attributes)
+	}
+	if a := z.Attributes[0]; a.AttrName != "ManufacturerName" || a.Value != "Acme" {
+		t.Errorf("attr 0: %+v", a)
+	}
+	if a := z.Attributes[1]; a.AttrID != 0x0005 || a.Status != 0x86 || a.Error == "" {
+		t.Errorf("attr 1: %+v", a)
+	}
+}
+
+func TestSendRawAPSWaitRaw(t *testing.T) {
+	c, _ := newTestRawAPS(t,
+		ncp.APSFrame{SrcAddr: 0x1234, ProfileID: 0xC05E, ClusterID: 0x1000, Payload: []byte{0xAA}},
+	)
+	rsp, err := c.SendRawAPS(context.Background(), RawAPSRequest{
+		ShortAddr: 0x1234, ProfileID: 0xC05E, ClusterID: 0x1000, Payload: []byte{0x01},
+		WaitResponse: true,
+	})
+	if err != nil {
+		t.Fatalf("SendRawAPS: %v", err)
+	}
+	if rsp.Payload != "AA" || rsp.ZCL != nil {
+		t.Errorf("response: %+v", rsp)
+	}
+}
+
+func TestSendRawAPSNoResponse(t *testing.T) {
+	c, _ := newTestRawAPS(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.SendRawAPS(ctx, RawAPSRequest{ShortAddr: 0x1234, ClusterID: 6, Payload: []byte{0x01, 0x01, 0x02}, WaitResponse: true, ZCL: true})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestSendRawAPSShortZCL(t *testing.T) {
+	c, n := newTestRawAPS(t)
+	_, err := c.SendRawAPS(context.Background(), RawAPSRequest{ShortAddr: 0x1234, Payload: []byte{0x01}, ZCL: true})
+	if !errors.Is(err, ErrShortZCLPayload) || len(n.aps) != 0 {
+		t.Errorf("got %v with %d frames sent", err, len(n.aps))
+	}
+}
+
+func TestDecodeZCLDefaultResponse(t *testing.T) {
+	c, _ := newTestRawAPS(t)
+	// Manufacturer-specific Default Response for command 0x01 with status UNSUP_CLUSTER_COMMAND.
+	d := c.decodeZCL(0x0006, []byte{0x1C, 0x5F, 0x11, 0x22, 0x0B, 0x01, 0x81})
+	if d == nil || d.ManufacturerCode != 0x115F || d.Seq != 0x22 || d.Status == nil || *d.Status != 0x81 {
+		t.Errorf("decoded: %+v", d)
+	}
+}
@@ -0,0 +1,249 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+// propertySetter is a settable property resolved for one device.
+type propertySetter struct {
+	endpoint  uint8
+	cluster   uint16
+	attribute uint16
+	attrType  uint8
+	conv      *zcl.Converter
+}
+
+// SetProperty writes a named property back to a device using the converter
+// that produces it: a definition's converter override, a tuya_dp property
+// with a type, or a cluster default on one of the device's endpoints. The
+// value is given as the property reads (23.5, "heat", true) and converted
+// back to the raw attribute or command. Returns ErrCommandQueued for
+// sleepy Poll Control devices, ErrUnknownProperty, ErrPropertyReadOnly or
+// ErrInvalidPropertyValue. With confirmation enabled for the device, the
+// set is confirmed afterwards (see confirm.go).
+func (c *Coordinator) SetProperty(ctx context.Context, ieee, name string, value any) error {
+	dev, err := c.store.GetDevice(ieee)
+	if err != nil {
+		return err
+	}
+	def := c.devices.definition(dev)
+	if def != nil && findTuyaProperty(def, name) != nil {
+		return c.WriteTuyaDP(ctx, ieee, name, value)
+	}
+
+	set, err := c.devices.findSetter(dev, def, name)
+	if err != nil {
+		return err
+	}
+	c.logger.Info("set property", "ieee", ieee, "name", deviceName(dev),
+		"property", name, "value", value)
+
+	if set.conv.Command != nil {
+		err = c.sendPropertyCommand(ctx, dev, set, value)
+	} else {
+		err = c.writeProperty(ctx, dev, set, name, value)
+	}
+	if err == nil && c.confirm != nil {
+		c.confirm.expect(dev, set, name, value)
+	}
+	return err
+}
+
+// writeProperty sets a property by writing its attribute.
+func (c *Coordinator) writeProperty(ctx context.Context, dev *store.Device, set *propertySetter, name string, value any) error {
+	raw, err := reverseConverter(set.conv, value)
+	if err != nil {
+		return err
+	}
+	if set.attrType == 0 {
+		return fmt.Errorf("%w: no attribute type for %s", ErrPropertyReadOnly, name)
+	}
+	if set.conv.ManufacturerCode != 0 {
+		return c.writeManufacturerAttribute(ctx, dev.ShortAddress, set, raw)
+	}
+	if _, err := zcl.EncodeValue(set.attrType, raw); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+	}
+	return c.WriteAttribute(ctx, dev.ShortAddress, set.endpoint, set.cluster, set.attribute, set.attrType, raw)
+}
+
+// findSetter resolves the converter producing the named property: the
+// definition's converters first, then the cluster defaults of the device's
+// server clusters. Defaults turned off by the definition are skipped.
+func (dm *DeviceManager) findSetter(dev *store.Device, def *DeviceDefinition, name string) (*propertySetter, error) {
+	readOnly := false
+	if def != nil {
+		for i := range def.Converters {
+			ac := &def.Converters[i]
+			if ac.Property != name {
+				continue
+			}
+			if !ac.Settable() {
+				readOnly = true
+				continue
+			}
+			set := &propertySetter{
+				endpoint:  endpointWithCluster(dev, ac.Cluster),
+				cluster:   ac.Cluster,
+				attribute: ac.Attribute,
+				attrType:  ac.Type,
+				conv:      &ac.Converter,
+			}
+			if set.attrType == 0 {
+				set.attrType = dm.attributeType(ac.Cluster, ac.Attribute)
+			}
+			return set, nil
+		}
+	}
+
+	if registry := dm.coord.Registry(); registry != nil {
+		for _, ep := range dev.Endpoints {
+			for _, clusterID := range ep.InClusters {
+				cluster := registry.Get(clusterID)
+				if cluster == nil {
+					continue
+				}
+				for _, attr := range cluster.Attributes {
+					if attr.Converter == nil || attr.Converter.Property != name {
+						continue
+					}
+					// The definition may override or disable the default.
+					conv := dm.converterFor(dev, clusterID, attr.ID)
+					if conv == nil || conv.Property != name {
+						continue
+					}
+					if !conv.Settable() {
+						readOnly = true
+						continue
+					}
+					return &propertySetter{
+						endpoint:  ep.ID,
+						cluster:   clusterID,
+						attribute: attr.ID,
+						attrType:  attr.Type,
+						conv:      conv,
+					}, nil
+				}
+			}
+		}
+	}
+
+	if readOnly {
+		return nil, ErrPropertyReadOnly
+	}
+	return nil, ErrUnknownProperty
+}
+
+// attributeType returns the registered ZCL type of an attribute, or 0.
+func (dm *DeviceManager) attributeType(clusterID, attrID uint16) uint8 {
+	registry := dm.coord.Registry()
+	if registry == nil {
+		return 0
+	}
+	if cluster := registry.Get(clusterID); cluster != nil {
+		if attr := cluster.FindAttribute(attrID); attr != nil {
+			return attr.Type
+		}
+	}
+	return 0
+}
+
+// sendPropertyCommand sets a property with the converter's cluster command.
+func (c *Coordinator) sendPropertyCommand(ctx context.Context, dev *store.Device, set *propertySetter, value any) error {
+	cmd := set.conv.Command
+	suffix, err := hex.DecodeString(cmd.Payload)
+	if err != nil {
+		return fmt.Errorf("%w: command payload: %v", ErrInvalidPropertyValue, err)
+	}
+
+	// Named values ("toggle", "locked") map to their own command.
+	if s, ok := value.(string); ok {
+		if id, ok := cmd.Commands[strings.ToLower(s)]; ok {
+			return c.SendClusterCommand(ctx, dev.ShortAddress, set.endpoint, set.cluster, id, suffix)
+		}
+	}
+	raw, err := reverseConverter(set.conv, value)
+	if err != nil {
+		return err
+	}
+	if b, ok := raw.(bool); ok {
+		if id, ok := cmd.Commands[strconv.FormatBool(b)]; ok {
+			return c.SendClusterCommand(ctx, dev.ShortAddress, set.endpoint, set.cluster, id, suffix)
+		}
+	}
+	if cmd.Type == 0 {
+		return fmt.Errorf("%w: %s: unsupported value %v", ErrInvalidPropertyValue, set.conv.Property, value)
+	}
+	encoded, err := zcl.EncodeValue(cmd.Type, raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+	}
+	return c.SendClusterCommand(ctx, dev.ShortAddress, set.endpoint, set.cluster, cmd.ID, append(encoded, suffix...))
+}
+
+// writeManufacturerAttribute writes a manufacturer-specific attribute, such
+// as the operation mode of Xiaomi switches. Queued like WriteAttribute for
+// sleepy Poll Control devices.
+func (c *Coordinator) writeManufacturerAttribute(ctx context.Context, shortAddr uint16, set *propertySetter, value any) error {
+	encoded, err := zcl.EncodeValue(set.attrType, value)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+	}
+	payload := binary.LittleEndian.AppendUint16(nil, set.attribute)
+	payload = append(payload, set.attrType)
+	req := ncp.ZCLFrameRequest{
+		DstAddr:          shortAddr,
+		DstEP:            set.endpoint,
+		SrcEP:            localEndpoint,
+		ClusterID:        set.cluster,
+		CommandID:        zcl.FoundationWriteAttributes,
+		Payload:          append(payload, encoded...),
+		Global:           true,
+		ManufacturerCode: set.conv.ManufacturerCode,
+	}
+	desc := fmt.Sprintf("write 0x%04X/0x%04X", set.cluster, set.attribute)
+	if c.deferToCheckIn(shortAddr, desc, func(ctx context.Context) error {
+		return c.ncp.SendZCLFrame(ctx, req)
+	}) {
+		return ErrCommandQueued
+	}
+	return c.ncp.SendZCLFrame(ctx, req)
+}
+
+// reverseConverter converts a property value back to the raw value: the
+// converter's scale, bool or enum, then its transform.
+func reverseConverter(conv *zcl.Converter, value any) (any, error) {
+	raw, err := conv.Reverse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+	}
+	if conv.Transform != "" || conv.ReverseTransform != "" {
+		if raw, err = reverseTransform(conv.Transform, conv.ReverseTransform, raw); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+		}
+	}
+	return raw, nil
+}
+
+// endpointWithCluster returns the first endpoint with the server cluster,
+// or the first endpoint (1 if none are known).
+func endpointWithCluster(dev *store.Device, clusterID uint16) uint8 {
+	for _, ep := range dev.Endpoints {
+		if hasInCluster(ep, clusterID) {
+			return ep.ID
+		}
+	}
+	if len(dev.Endpoints) > 0 {
+		return dev.Endpoints[0].ID
+	}
+	return 1
+}
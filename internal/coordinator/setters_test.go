@@ -0,0 +1,129 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+func newTestSetters(t *testing.T) (*Coordinator, *fakeNCP) {
+	t.Helper()
+	c, ms, n := newTestCoord(t)
+	c.registry.Register(clusters.LevelControl)
+	c.registry.Register(clusters.Thermostat)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		Endpoints: []store.Endpoint{
+			{ID: 1, InClusters: []uint16{0x0000, 0x0201}},
+			{ID: 2, InClusters: []uint16{0x0006, 0x0008}},
+		},
+	}
+	return c, n
+}
+
+func TestSetPropertyCommands(t *testing.T) {
+	c, n := newTestSetters(t)
+	ctx := context.Background()
+
+	for _, v := range []any{true, "OFF", "toggle"} {
+		if err := c.SetProperty(ctx, "00158D0001A2B3C4", "on_off", v); err != nil {
+			t.Fatalf("on_off %v: %v", v, err)
+		}
+	}
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "brightness", 128.0); err != nil {
+		t.Fatalf("brightness: %v", err)
+	}
+
+	if len(n.commands) != 4 {
+		t.Fatalf("expected 4 commands, got %d", len(n.commands))
+	}
+	for i, want := range []uint8{0x01, 0x00, 0x02} {
+		if cmd := n.commands[i]; cmd.ClusterID != 0x0006 || cmd.CommandID != want || cmd.DstEP != 2 {
+			t.Errorf("on_off command %d: %+v", i, cmd)
+		}
+	}
+	level := n.commands[3]
+	if level.ClusterID != 0x0008 || level.CommandID != 0x04 || !bytes.Equal(level.Payload, []byte{128, 0x05, 0x00}) {
+		t.Errorf("brightness command: %+v", level)
+	}
+}
+
+func TestSetPropertyWrite(t *testing.T) {
+	c, n := newTestSetters(t)
+	ctx := context.Background()
+
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "heating_setpoint", 21.5); err != nil {
+		t.Fatalf("heating_setpoint: %v", err)
+	}
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "system_mode", "Heat"); err != nil {
+		t.Fatalf("system_mode: %v", err)
+	}
+	if len(n.writes) != 2 {
+		t.Fatalf("expected 2 writes, got %d", len(n.writes))
+	}
+	if w := n.writes[0]; w.ClusterID != 0x0201 || w.DstEP != 1 || w.Records[0].AttrID != 0x0012 ||
+		!bytes.Equal(w.Records[0].Value, []byte{0x66, 0x08}) { // 2150
+		t.Errorf("setpoint write: %+v", w)
+	}
+	if w := n.writes[1]; w.Records[0].AttrID != 0x001C || !bytes.Equal(w.Records[0].Value, []byte{4}) {
+		t.Errorf("system mode write: %+v", w)
+	}
+
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "local_temperature", 20); !errors.Is(err, ErrPropertyReadOnly) {
+		t.Errorf("read-only: got %v", err)
+	}
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "system_mode", "warp"); !errors.Is(err, ErrInvalidPropertyValue) {
+		t.Errorf("invalid enum: got %v", err)
+	}
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "nope", 1); !errors.Is(err, ErrUnknownProperty) {
+		t.Errorf("unknown: got %v", err)
+	}
+	if err := c.SetProperty(ctx, "0000000000000000", "on_off", true); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("unknown device: got %v", err)
+	}
+}
+
+func TestSetPropertyDefinitionConverter(t *testing.T) {
+	var def DeviceDefinition
+	if err := json.Unmarshal([]byte(`{
+		"manufacturer": "LUMI",
+		"model": "lumi.switch.n1aeu1",
+		"converters": [
+			{"cluster": 64704, "attribute": 512, "type": 32, "property": "operation_mode",
+			 "enum": {"0": "decoupled", "1": "control_relay"}, "write": true, "manufacturer_code": 4447},
+			{"cluster": 8, "attribute": 0, "property": "brightness"}
+		]
+	}`), &def); err != nil {
+		t.Fatal(err)
+	}
+
+	c, f := newTestSetters(t)
+	c.deviceDB.Add(def)
+	dev := c.store.(*memStore).devices["00158D0001A2B3C4"]
+	dev.Manufacturer, dev.Model = "LUMI", "lumi.switch.n1aeu1"
+	dev.Endpoints = append(dev.Endpoints, store.Endpoint{ID: 3, InClusters: []uint16{0xFCC0}})
+
+	ctx := context.Background()
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "operation_mode", "decoupled"); err != nil {
+		t.Fatalf("operation_mode: %v", err)
+	}
+	if len(f.frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(f.frames))
+	}
+	fr := f.frames[0]
+	if fr.ClusterID != 0xFCC0 || fr.DstEP != 3 || !fr.Global || fr.ManufacturerCode != 0x115F ||
+		fr.CommandID != 0x02 || !bytes.Equal(fr.Payload, []byte{0x00, 0x02, 0x20, 0x00}) {
+		t.Errorf("frame: %+v", fr)
+	}
+
+	// The override drops the default command, so brightness is read-only.
+	if err := c.SetProperty(ctx, "00158D0001A2B3C4", "brightness", 10); !errors.Is(err, ErrPropertyReadOnly) {
+		t.Errorf("overridden brightness: got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// extendList is the "extend" field of a definition: one name or a list.
+type extendList []string
+
+func (e *extendList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*e = extendList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("extend must be a string or an array of strings")
+	}
+	*e = many
+	return nil
+}
+
+// DefinitionRemove lists inherited entries a definition drops.
+type DefinitionRemove struct {
+	Bind       []uint16       `json:"bind,omitempty"`
+	Reporting  []AttributeRef `json:"reporting,omitempty"`
+	Properties []AttributeRef `json:"properties,omitempty"`
+	Converters []AttributeRef `json:"converters,omitempty"`
+	Poll       []AttributeRef `json:"poll,omitempty"`
+}
+
+// AttributeRef identifies a reporting entry, property source, converter or
+// polled attribute.
+type AttributeRef struct {
+	Cluster   uint16 `json:"cluster"`
+	Attribute uint16 `json:"attribute"`
+}
+
+// Lists that a definition can inherit, override and remove from.
+const (
+	listBind       = "bind"
+	listReporting  = "reporting"
+	listProperties = "properties"
+	listConverters = "converters"
+	listActions    = "actions"
+	listPoll       = "poll"
+)
+
+// definitionResolver resolves "extend" across all device files. Templates
+// are looked up by name; other names refer to a model, preferring one from
+// the same manufacturer.
+type definitionResolver struct {
+	templates map[string]*DeviceDefinition
+	models    map[string][]*DeviceDefinition
+
+	resolved map[*DeviceDefinition]*DeviceDefinition
+	visiting map[*DeviceDefinition]bool
+}
+
+func newDefinitionResolver(templates map[string]*DeviceDefinition, defs []*DeviceDefinition) *definitionResolver {
+	r := &definitionResolver{
+		templates: templates,
+		models:    make(map[string][]*DeviceDefinition),
+		resolved:  make(map[*DeviceDefinition]*DeviceDefinition),
+		visiting:  make(map[*DeviceDefinition]bool),
+	}
+	for _, def := range defs {
+		if def.Model != "" {
+			r.models[def.Model] = append(r.models[def.Model], def)
+		}
+	}
+	return r
+}
+
+// resolve returns def with everything it extends merged in. The result
+// no longer has Extend, Override or Remove set.
+func (r *definitionResolver) resolve(def *DeviceDefinition, label string) (*DeviceDefinition, error) {
+	if out := r.resolved[def]; out != nil {
+		return out, nil
+	}
+	if len(def.Extend) == 0 && len(def.Override) == 0 && def.Remove == nil {
+		r.resolved[def] = def
+		return def, nil
+	}
+	if r.visiting[def] {
+		return nil, fmt.Errorf("%s: extend cycle", label)
+	}
+	r.visiting[def] = true
+	defer delete(r.visiting, def)
+
+	for _, name := range def.Override {
+		if name != listBind && name != listReporting && name != listProperties && name != listConverters && name != listActions && name != listPoll {
+			return nil, fmt.Errorf("%s: cannot override %q", label, name)
+		}
+	}
+
+	var base DeviceDefinition
+	for _, name := range def.Extend {
+		parent, err := r.lookup(name, def.Manufacturer)
+		if err != nil {
+			return nil, fmt.Errorf("%s: extend %q: %w", label, name, err)
+		}
+		parent, err = r.resolve(parent, name)
+		if err != nil {
+			return nil, err
+		}
+		base = mergeDefinition(base, *parent, nil)
+	}
+
+	out := mergeDefinition(base, *def, def.Override)
+	out.removeEntries(def.Remove)
+	out.Extend, out.Override, out.Remove = nil, nil, nil
+	r.resolved[def] = &out
+	return &out, nil
+}
+
+func (r *definitionResolver) lookup(name, manufacturer string) (*DeviceDefinition, error) {
+	if t := r.templates[name]; t != nil {
+		return t, nil
+	}
+	candidates := r.models[name]
+	for _, def := range candidates {
+		if def.Manufacturer == manufacturer {
+			return def, nil
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, errors.New("no such template or model")
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, errors.New("model exists for several manufacturers")
+	}
+}
+
+// mergeDefinition returns base with over applied. Settings of over win when
+// set. Lists are appended to the base's, an entry with the same cluster
+// (and attribute) replacing the inherited one, unless the list is named in
+// override, which replaces it entirely. Options are merged by name.
+// Identity fields (manufacturer, model, aliases, fingerprints, priority)
+// come from over only.
+func mergeDefinition(base, over DeviceDefinition, override []string) DeviceDefinition {
+	out := over
+	if out.FriendlyName == "" {
+		out.FriendlyName = base.FriendlyName
+	}
+	if out.PollControl == nil {
+		out.PollControl = base.PollControl
+	}
+	if out.DefaultResponse == nil {
+		out.DefaultResponse = base.DefaultResponse
+	}
+	if out.Availability == nil {
+		out.Availability = base.Availability
+	}
+	if len(base.Options) > 0 {
+		out.Options = maps.Clone(base.Options)
+		maps.Copy(out.Options, over.Options)
+	}
+
+	if !slices.Contains(override, listBind) {
+		out.Bind = slices.Clone(base.Bind)
+		for _, c := range over.Bind {
+			if !slices.Contains(out.Bind, c) {
+				out.Bind = append(out.Bind, c)
+			}
+		}
+	}
+	if !slices.Contains(override, listReporting) {
+		out.Reporting = mergeEntries(base.Reporting, over.Reporting, func(e ReportingEntry) AttributeRef {
+			return AttributeRef{e.Cluster, e.Attribute}
+		})
+	}
+	if !slices.Contains(override, listProperties) {
+		out.Properties = mergeEntries(base.Properties, over.Properties, func(p PropertySource) AttributeRef {
+			return AttributeRef{p.Cluster, p.Attribute}
+		})
+	}
+	if !slices.Contains(override, listConverters) {
+		out.Converters = mergeEntries(base.Converters, over.Converters, func(c AttributeConverter) AttributeRef {
+			return AttributeRef{c.Cluster, c.Attribute}
+		})
+	}
+	if !slices.Contains(override, listActions) {
+		// The first matching entry wins, so the child's go first.
+		out.Actions = append(slices.Clone(over.Actions), base.Actions...)
+	}
+	if !slices.Contains(override, listPoll) {
+		out.Poll = mergeEntries(base.Poll, over.Poll, func(e PollEntry) AttributeRef {
+			return AttributeRef{e.Cluster, e.Attribute}
+		})
+	}
+	return out
+}
+
+func mergeEntries[T any](base, over []T, key func(T) AttributeRef) []T {
+	out := slices.Clone(base)
+	for _, e := range over {
+		if i := slices.IndexFunc(out, func(b T) bool { return key(b) == key(e) }); i >= 0 {
+			out[i] = e
+		} else {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// removeEntries drops the listed entries from the definition's lists.
+func (def *DeviceDefinition) removeEntries(rm *DefinitionRemove) {
+	if rm == nil {
+		return
+	}
+	def.Bind = slices.DeleteFunc(slices.Clone(def.Bind), func(c uint16) bool {
+		return slices.Contains(rm.Bind, c)
+	})
+	def.Reporting = slices.DeleteFunc(slices.Clone(def.Reporting), func(e ReportingEntry) bool {
+		return slices.Contains(rm.Reporting, AttributeRef{e.Cluster, e.Attribute})
+	})
+	def.Properties = slices.DeleteFunc(slices.Clone(def.Properties), func(p PropertySource) bool {
+		return slices.Contains(rm.Properties, AttributeRef{p.Cluster, p.Attribute})
+	})
+	def.Converters = slices.DeleteFunc(slices.Clone(def.Converters), func(c AttributeConverter) bool {
+		return slices.Contains(rm.Converters, AttributeRef{c.Cluster, c.Attribute})
+	})
+	def.Poll = slices.DeleteFunc(slices.Clone(def.Poll), func(e PollEntry) bool {
+		return slices.Contains(rm.Poll, AttributeRef{e.Cluster, e.Attribute})
+	})
+}
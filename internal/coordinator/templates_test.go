@@ -0,0 +1,153 @@
+package coordinator
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"zigbee-go-home/internal/zcl"
+)
+
+func loadTestDir(t *testing.T, files map[string]string) (*DeviceDB, error) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	for name, data := range files {
+		os.WriteFile(filepath.Join(dir, name), []byte(data), 0644)
+	}
+	return LoadDeviceDir(dir, zcl.NewRegistry(logger), logger)
+}
+
+func TestDefinitionExtend(t *testing.T) {
+	db, err := loadTestDir(t, map[string]string{
+		"common.json": `{
+			"templates": {
+				"battery": {
+					"bind": [1],
+					"reporting": [{"cluster": 1, "attribute": 33, "type": 32, "min": 3600, "max": 62000}]
+				},
+				"tlv": {
+					"default_response": false,
+					"properties": [{"cluster": 0, "attribute": 65281, "decoder": "xiaomi_tlv", "values": [{"tag": 1, "name": "battery_voltage"}]}]
+				},
+				"sensor": {"extend": ["battery", "tlv"], "friendly_name": "Sensor"}
+			}
+		}`,
+		"lumi.json": `{
+			"manufacturers": [{
+				"name": "LUMI",
+				"models": [
+					{
+						"model": "lumi.sensor_ht",
+						"extend": "sensor",
+						"bind": [1026],
+						"reporting": [{"cluster": 1, "attribute": 33, "type": 32, "min": 60, "max": 3600}]
+					},
+					{
+						"model": "lumi.weather",
+						"friendly_name": "Weather",
+						"extend": "lumi.sensor_ht",
+						"override": ["bind"],
+						"bind": [1027],
+						"remove": {"reporting": [{"cluster": 1, "attribute": 33}]}
+					}
+				]
+			}]
+		}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Len() != 2 {
+		t.Fatalf("len = %d, want 2 (templates are not devices)", db.Len())
+	}
+
+	ht := db.Lookup("LUMI", "lumi.sensor_ht")
+	if ht.FriendlyName != "Sensor" || !slices.Equal(ht.Bind, []uint16{1, 1026}) {
+		t.Errorf("sensor_ht: name=%q bind=%v", ht.FriendlyName, ht.Bind)
+	}
+	if len(ht.Reporting) != 1 || ht.Reporting[0].Min != 60 {
+		t.Errorf("sensor_ht reporting = %+v, want the child's entry to replace the template's", ht.Reporting)
+	}
+	if len(ht.Properties) != 1 || ht.DefaultResponse == nil || *ht.DefaultResponse {
+		t.Errorf("sensor_ht: properties=%d default_response=%v", len(ht.Properties), ht.DefaultResponse)
+	}
+	if ht.Extend != nil || ht.Source != "lumi.json" {
+		t.Errorf("sensor_ht: extend=%v source=%q", ht.Extend, ht.Source)
+	}
+
+	weather := db.Lookup("LUMI", "lumi.weather")
+	if weather.FriendlyName != "Weather" || !slices.Equal(weather.Bind, []uint16{1027}) {
+		t.Errorf("weather: name=%q bind=%v", weather.FriendlyName, weather.Bind)
+	}
+	if len(weather.Reporting) != 0 || len(weather.Properties) != 1 {
+		t.Errorf("weather: reporting=%+v properties=%d", weather.Reporting, len(weather.Properties))
+	}
+}
+
+func TestDefinitionExtendErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{"unknown", `{"devices": [{"manufacturer": "A", "model": "M", "extend": "nope"}]}`, "no such template or model"},
+		{"cycle", `{"templates": {"a": {"extend": "b"}, "b": {"extend": "a"}}, "devices": [{"manufacturer": "A", "model": "M", "extend": "a"}]}`, "extend cycle"},
+		{"override", `{"devices": [{"manufacturer": "A", "model": "M", "override": ["friendly_name"]}]}`, "cannot override"},
+		{"ambiguous", `{"devices": [{"manufacturer": "A", "model": "X"}, {"manufacturer": "B", "model": "X"}, {"manufacturer": "C", "model": "M", "extend": "X"}]}`, "several manufacturers"},
+		{"extend type", `{"devices": [{"manufacturer": "A", "model": "M", "extend": 1}]}`, "string or an array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestDir(t, map[string]string{"test.json": tt.file})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadKeepsTemplatesOfBrokenFile(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	registry := zcl.NewRegistry(logger)
+	dir := t.TempDir()
+	common := filepath.Join(dir, "common.json")
+	os.WriteFile(common, []byte(`{"templates": {"switch": {"bind": [6]}}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "acme.json"), []byte(`{"devices": [{"manufacturer": "Acme", "model": "S1", "extend": "switch"}]}`), 0644)
+
+	db, err := LoadDeviceDir(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Break the template file and add a device using its template.
+	os.WriteFile(common, []byte(`{"templates": {`), 0644)
+	os.WriteFile(filepath.Join(dir, "acme.json"), []byte(`{"devices": [
+		{"manufacturer": "Acme", "model": "S1", "extend": "switch"},
+		{"manufacturer": "Acme", "model": "S2", "extend": "switch"}
+	]}`), 0644)
+	result, err := db.Reload(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].File != "common.json" {
+		t.Fatalf("errors = %+v, want common.json", result.Errors)
+	}
+	if def := db.Lookup("Acme", "S2"); def == nil || !slices.Equal(def.Bind, []uint16{6}) {
+		t.Errorf("S2 = %+v, want bind from the kept template", def)
+	}
+
+	// A second definition of the same template is an error for the later file.
+	os.WriteFile(common, []byte(`{"templates": {"switch": {"bind": [6]}}}`), 0644)
+	os.WriteFile(filepath.Join(dir, "zz.json"), []byte(`{"templates": {"switch": {"bind": [8]}}}`), 0644)
+	result, err = db.Reload(dir, registry, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].File != "zz.json" || !strings.Contains(result.Errors[0].Error, "already defined") {
+		t.Errorf("errors = %+v, want duplicate template in zz.json", result.Errors)
+	}
+}
@@ -0,0 +1,318 @@
+package coordinator
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+)
+
+// Attribute reports are throttled per device, endpoint and attribute by
+// the min_interval, debounce and threshold device options (options.go).
+// A report held back is not lost: the latest one is used once the
+// interval or quiet period is over, so the final value always arrives.
+// Every report updates the last seen time and link quality of the device;
+// throttled reports skip the rest, from the attribute report event to the
+// properties, which is what keeps chatty plugs from flooding the store,
+// WebSocket clients and MQTT.
+
+// throttleRule is the throttling of one attribute. threshold applies to
+// the value of thresholdProp.
+type throttleRule struct {
+	minInterval   time.Duration
+	debounce      time.Duration
+	threshold     float64
+	thresholdProp string
+}
+
+func (r throttleRule) zero() bool {
+	return r.minInterval == 0 && r.debounce == 0 && r.threshold == 0
+}
+
+type throttleKey struct {
+	ieee     string
+	endpoint uint8
+	cluster  uint16
+	attr     uint16
+}
+
+// throttleSlot is the state of one throttled attribute.
+type throttleSlot struct {
+	rule    throttleRule
+	last    time.Time // when a report was last let through
+	lastVal float64   // its value of rule.thresholdProp
+	hasVal  bool
+	pending *heldReport
+	timer   *time.Timer
+	gen     uint64 // invalidates timers that fire after being replaced
+}
+
+// heldReport is a report waiting for the end of an interval, with its
+// value of the threshold property.
+type heldReport struct {
+	evt     ncp.AttributeReportEvent
+	decoded any
+	value   float64
+	numeric bool
+}
+
+// throttleRule returns the rule for an attribute of the device. Options of
+// the properties the attribute produces win over the device-wide ones.
+func (dm *DeviceManager) throttleRule(dev *store.Device, clusterID, attrID uint16) throttleRule {
+	opts := dm.deviceOptions(dev)
+	if len(opts) == 0 {
+		return throttleRule{}
+	}
+	props := dm.reportProperties(dev, clusterID, attrID)
+	option := func(suffix string, deviceWide bool) (float64, string) {
+		for _, p := range props {
+			if v, ok := opts[p+suffix]; ok {
+				return v, p
+			}
+		}
+		if deviceWide {
+			return opts[suffix[1:]], ""
+		}
+		return 0, ""
+	}
+	seconds := func(v float64) time.Duration {
+		return time.Duration(v * float64(time.Second))
+	}
+
+	var r throttleRule
+	v, _ := option(optionMinInterval, true)
+	r.minInterval = seconds(v)
+	v, _ = option(optionDebounce, true)
+	r.debounce = seconds(v)
+	r.threshold, r.thresholdProp = option(optionThreshold, false)
+	return r
+}
+
+// reportProperties returns the names of the properties an attribute
+// produces: those of its converter and of the definition's property
+// sources reading it.
+func (dm *DeviceManager) reportProperties(dev *store.Device, clusterID, attrID uint16) []string {
+	var props []string
+	if conv := dm.converterFor(dev, clusterID, attrID); conv != nil && !conv.Disabled() {
+		if conv.Property != "" {
+			props = append(props, conv.Property)
+		}
+		for _, f := range conv.Flags {
+			props = append(props, f.Name)
+		}
+	}
+	if def := dm.definition(dev); def != nil {
+		for _, ps := range def.Properties {
+			if ps.Cluster != clusterID || ps.Attribute != attrID || len(ps.Commands) > 0 {
+				continue
+			}
+			for _, v := range ps.Values {
+				if !slices.Contains(props, v.Name) {
+					props = append(props, v.Name)
+				}
+			}
+		}
+	}
+	return props
+}
+
+// propertyNumber returns the numeric value of a property in a report,
+// before calibration: from the attribute's converter, or else from the
+// definition's property sources reading the attribute.
+func (dm *DeviceManager) propertyNumber(dev *store.Device, evt ncp.AttributeReportEvent, decoded any, name string) (float64, bool) {
+	if decoded == nil {
+		return 0, false
+	}
+	if conv := dm.converterFor(dev, evt.ClusterID, evt.AttrID); conv != nil && !conv.Disabled() {
+		value := decoded
+		if conv.Transform != "" {
+			value = applyTransform(conv.Transform, value)
+		}
+		for _, p := range conv.Convert(value) {
+			if p.Name == name {
+				return propertyFloat(p.Value)
+			}
+		}
+	}
+	return dm.sourceNumber(dev, attributeDecoderInput(evt, decoded), name)
+}
+
+// sourceNumber decodes the input with the definition's property sources
+// that name the property and returns its numeric value.
+func (dm *DeviceManager) sourceNumber(dev *store.Device, in DecoderInput, name string) (float64, bool) {
+	def := dm.definition(dev)
+	if def == nil {
+		return 0, false
+	}
+	in.Manufacturer, in.Model = dev.Manufacturer, dev.Model
+	for i := range def.Properties {
+		ps := &def.Properties[i]
+		if ps.Cluster != in.Cluster || !ps.reads(in, ps.Commands) {
+			continue
+		}
+		j := slices.IndexFunc(ps.Values, func(v PropertyDef) bool { return v.Name == name })
+		dec := dm.coord.DeviceDB().Decoder(ps.Decoder)
+		if j < 0 || dec == nil {
+			continue
+		}
+		decoded, err := dec.Decode(in)
+		if err != nil {
+			continue
+		}
+		v := ps.Values[j]
+		var raw any
+		var ok bool
+		if v.Key != "" {
+			raw, ok = decoded.Properties[v.Key]
+		} else {
+			raw, ok = decoded.Tags[v.Tag]
+		}
+		if !ok {
+			continue
+		}
+		if v.Transform != "" {
+			raw = applyTransform(v.Transform, raw)
+		}
+		return propertyFloat(raw)
+	}
+	return 0, false
+}
+
+// propertyFloat returns a property value as a float64, if it is a number.
+func propertyFloat(v any) (float64, bool) {
+	x, err := exprValue(v)
+	if err != nil {
+		return 0, false
+	}
+	return toFloat(x)
+}
+
+// admitReport decides whether an attribute report is processed now. A
+// report it holds back is processed later by flushReport, unless a newer
+// one replaces it.
+func (dm *DeviceManager) admitReport(ieee string, evt ncp.AttributeReportEvent, decoded any) bool {
+	dev, err := dm.coord.Store().GetDevice(ieee)
+	if err != nil {
+		return true
+	}
+	rule := dm.throttleRule(dev, evt.ClusterID, evt.AttrID)
+	key := throttleKey{ieee, evt.SrcEP, evt.ClusterID, evt.AttrID}
+	now := time.Now()
+	// Decoders may be slow (Lua): evaluate before locking.
+	value, numeric := 0.0, false
+	if rule.threshold > 0 {
+		value, numeric = dm.propertyNumber(dev, evt, decoded, rule.thresholdProp)
+	}
+
+	dm.throttleMu.Lock()
+	defer dm.throttleMu.Unlock()
+	slot := dm.throttle[key]
+	if dm.throttleStopped || rule.zero() {
+		if slot != nil {
+			slot.cancel()
+			delete(dm.throttle, key)
+		}
+		return true
+	}
+	if slot == nil {
+		slot = &throttleSlot{}
+		dm.throttle[key] = slot
+	}
+	slot.rule = rule
+
+	if numeric && slot.hasVal && math.Abs(value-slot.lastVal) < rule.threshold {
+		// The latest value is close to the last one let through.
+		slot.pending = nil
+		return false
+	}
+
+	held := &heldReport{evt: evt, decoded: decoded, value: value, numeric: numeric}
+	if rule.debounce > 0 {
+		slot.pending = held
+		dm.scheduleFlush(key, slot, rule.debounce)
+		return false
+	}
+	if wait := slot.last.Add(rule.minInterval).Sub(now); wait > 0 || slot.timer != nil {
+		slot.pending = held
+		if slot.timer == nil {
+			dm.scheduleFlush(key, slot, wait)
+		}
+		return false
+	}
+	slot.pass(now, value, numeric)
+	return true
+}
+
+// pass records a report let through at now.
+func (s *throttleSlot) pass(now time.Time, value float64, numeric bool) {
+	s.last = now
+	if numeric {
+		s.lastVal, s.hasVal = value, true
+	}
+}
+
+// cancel stops the slot's timer and drops its held report.
+func (s *throttleSlot) cancel() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.pending = nil
+	s.gen++
+}
+
+// scheduleFlush (re)starts the slot's timer. Caller holds throttleMu.
+func (dm *DeviceManager) scheduleFlush(key throttleKey, slot *throttleSlot, d time.Duration) {
+	if slot.timer != nil {
+		slot.timer.Stop()
+	}
+	slot.gen++
+	gen := slot.gen
+	slot.timer = time.AfterFunc(d, func() { dm.flushReport(key, gen) })
+}
+
+// flushReport processes the report held for an attribute once its
+// interval is over.
+func (dm *DeviceManager) flushReport(key throttleKey, gen uint64) {
+	dm.throttleMu.Lock()
+	slot := dm.throttle[key]
+	if slot == nil || slot.gen != gen || dm.throttleStopped {
+		dm.throttleMu.Unlock()
+		return
+	}
+	slot.timer = nil
+	held := slot.pending
+	if held == nil {
+		dm.throttleMu.Unlock()
+		return
+	}
+	now := time.Now()
+	if wait := slot.last.Add(slot.rule.minInterval).Sub(now); wait > 0 {
+		// A debounced report still respects the minimum interval.
+		dm.scheduleFlush(key, slot, wait)
+		dm.throttleMu.Unlock()
+		return
+	}
+	slot.pending = nil
+	slot.pass(now, held.value, held.numeric)
+	dm.throttleMu.Unlock()
+
+	var dev *store.Device
+	if d, err := dm.coord.Store().GetDevice(key.ieee); err == nil {
+		dev = d
+	}
+	dm.processAttributeReport(key.ieee, dev, held.evt, held.decoded)
+}
+
+// stopThrottle drops the held reports. Reports are no longer throttled.
+func (dm *DeviceManager) stopThrottle() {
+	dm.throttleMu.Lock()
+	defer dm.throttleMu.Unlock()
+	dm.throttleStopped = true
+	for key, slot := range dm.throttle {
+		slot.cancel()
+		delete(dm.throttle, key)
+	}
+}
@@ -0,0 +1,274 @@
+package coordinator
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+// propertyRecorder collects property_update values of one property,
+// including those emitted from throttle timers.
+type propertyRecorder struct {
+	mu     sync.Mutex
+	values []any
+}
+
+func recordProperty(dm *DeviceManager, name string) *propertyRecorder {
+	r := &propertyRecorder{}
+	dm.coord.Events().On(EventPropertyUpdate, func(e Event) {
+		data := e.Data.(map[string]interface{})
+		if data["property"] == name {
+			r.mu.Lock()
+			r.values = append(r.values, data["value"])
+			r.mu.Unlock()
+		}
+	})
+	return r
+}
+
+func (r *propertyRecorder) get() []any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]any(nil), r.values...)
+}
+
+// waitValues waits until n values were recorded.
+func (r *propertyRecorder) waitValues(t *testing.T, n int) []any {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if v := r.get(); len(v) >= n {
+			return v
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("got values %v, want %d", r.get(), n)
+	return nil
+}
+
+func newThrottleDM(t *testing.T, opts map[string]float64) (*DeviceManager, *memStore) {
+	t.Helper()
+	dm, ms := newTestDM(t)
+	t.Cleanup(dm.stopThrottle)
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111, Options: opts}
+	dm.RebuildAddrIndex()
+	return dm, ms
+}
+
+func sendTemperatures(dm *DeviceManager, values ...int16) {
+	for _, v := range values {
+		r := int16Report(0x0402, 0x0000, v)
+		r.SrcAddr, r.SrcEP = 0x1111, 1
+		dm.HandleAttributeReport(r)
+	}
+}
+
+func TestThrottleMinInterval(t *testing.T) {
+	dm, _ := newThrottleDM(t, map[string]float64{"temperature_min_interval": 0.1})
+	rec := recordProperty(dm, "temperature")
+
+	sendTemperatures(dm, 2345, 2350, 2360)
+	if v := rec.get(); len(v) != 1 || v[0] != 23.45 {
+		t.Fatalf("immediate values = %v, want [23.45]", v)
+	}
+	// The latest held report wins once the interval is over.
+	if v := rec.waitValues(t, 2); v[1] != 23.6 {
+		t.Errorf("held value = %v, want 23.6", v[1])
+	}
+	time.Sleep(150 * time.Millisecond)
+	if v := rec.get(); len(v) != 2 {
+		t.Errorf("values = %v, want 2", v)
+	}
+}
+
+func TestThrottleDebounce(t *testing.T) {
+	dm, ms := newThrottleDM(t, map[string]float64{"debounce": 0.05})
+	rec := recordProperty(dm, "temperature")
+
+	sendTemperatures(dm, 2345, 2350)
+	time.Sleep(20 * time.Millisecond)
+	sendTemperatures(dm, 2360)
+	if v := rec.get(); len(v) != 0 {
+		t.Fatalf("values while reports keep coming = %v", v)
+	}
+	if v := rec.waitValues(t, 1); v[0] != 23.6 {
+		t.Errorf("debounced value = %v, want 23.6", v[0])
+	}
+	if props := ms.devices["00158D0001A2B3C4"].Properties; props["temperature"] != 23.6 {
+		t.Errorf("stored temperature = %v", props["temperature"])
+	}
+}
+
+func TestThrottleThreshold(t *testing.T) {
+	dm, _ := newThrottleDM(t, map[string]float64{"temperature_threshold": 0.5})
+	rec := recordProperty(dm, "temperature")
+
+	sendTemperatures(dm, 2345, 2360, 2300, 2400)
+	v := rec.get()
+	if len(v) != 2 || v[0] != 23.45 || v[1] != 24.0 {
+		t.Errorf("values = %v, want [23.45 24]", v)
+	}
+}
+
+func TestThrottleThresholdPropertySource(t *testing.T) {
+	RegisterDecoder("test_power", &PropertyDecoder{
+		Decode: func(in DecoderInput) (Decoded, error) {
+			if len(in.Data) < 2 {
+				return Decoded{}, errors.New("short")
+			}
+			return Decoded{Tags: map[int]any{1: binary.LittleEndian.Uint16(in.Data)}}, nil
+		},
+	})
+	dm, ms := newThrottleDM(t, map[string]float64{"power_threshold": 1})
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "Plug",
+		Properties: []PropertySource{{
+			Cluster:   0xFCC0,
+			Attribute: 0x00F7,
+			Decoder:   "test_power",
+			Values:    []PropertyDef{{Tag: 1, Name: "power", Transform: "divide_10"}},
+		}},
+	})
+	dev := ms.devices["00158D0001A2B3C4"]
+	dev.Manufacturer, dev.Model = "Acme", "Plug"
+	rec := recordProperty(dm, "power")
+
+	for _, v := range []uint16{1000, 1005, 1020} {
+		dm.HandleAttributeReport(ncp.AttributeReportEvent{
+			SrcAddr: 0x1111, SrcEP: 1, ClusterID: 0xFCC0, AttrID: 0x00F7,
+			DataType: zcl.TypeUint16, Value: binary.LittleEndian.AppendUint16(nil, v),
+		})
+	}
+	if v := rec.get(); len(v) != 2 || v[0] != 100.0 || v[1] != 102.0 {
+		t.Errorf("values = %v, want [100 102]", v)
+	}
+}
+
+func TestThrottleSlowDecoderDoesNotBlock(t *testing.T) {
+	decoding, release := make(chan struct{}), make(chan struct{})
+	RegisterDecoder("test_slow_power", &PropertyDecoder{
+		Decode: func(DecoderInput) (Decoded, error) {
+			decoding <- struct{}{}
+			<-release
+			return Decoded{Tags: map[int]any{1: uint16(1000)}}, nil
+		},
+	})
+	dm, ms := newThrottleDM(t, map[string]float64{"power_threshold": 1})
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "Plug",
+		Properties: []PropertySource{{
+			Cluster: 0xFCC0, Attribute: 0x00F7, Decoder: "test_slow_power",
+			Values: []PropertyDef{{Tag: 1, Name: "power"}},
+		}},
+	})
+	dev := ms.devices["00158D0001A2B3C4"]
+	dev.Manufacturer, dev.Model = "Acme", "Plug"
+	rec := recordProperty(dm, "temperature")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		dm.HandleAttributeReport(ncp.AttributeReportEvent{
+			SrcAddr: 0x1111, SrcEP: 1, ClusterID: 0xFCC0, AttrID: 0x00F7,
+			DataType: zcl.TypeUint16, Value: []byte{0xE8, 0x03},
+		})
+	}()
+	<-decoding // the threshold value is being decoded
+
+	// Other reports pass meanwhile.
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		sendTemperatures(dm, 2345)
+	}()
+	select {
+	case <-sent:
+		if v := rec.get(); len(v) != 1 {
+			t.Errorf("temperature values while decoding = %v", v)
+		}
+	case <-time.After(time.Second):
+		t.Error("report blocked behind the decoder")
+	}
+	close(release)
+	<-decoding // decoded again for the property update
+	<-done
+}
+
+func TestThrottledReportUpdatesLastSeen(t *testing.T) {
+	dm, ms := newThrottleDM(t, map[string]float64{"debounce": 10})
+	rec := recordProperty(dm, "temperature")
+
+	r := int16Report(0x0402, 0x0000, 2345)
+	r.SrcAddr, r.SrcEP, r.LQI = 0x1111, 1, 120
+	dm.HandleAttributeReport(r)
+	if v := rec.get(); len(v) != 0 {
+		t.Fatalf("values while debounced = %v", v)
+	}
+	if dev := ms.devices["00158D0001A2B3C4"]; dev.LastSeen.IsZero() || dev.LQI != 120 {
+		t.Errorf("last seen %v, LQI %d after a held report", dev.LastSeen, dev.LQI)
+	}
+}
+
+func TestThrottleDefinitionOptions(t *testing.T) {
+	dm, ms := newThrottleDM(t, nil)
+	dm.coord.deviceDB = NewDeviceDB()
+	dm.coord.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "Acme",
+		Model:        "Plug",
+		Options:      map[string]float64{"temperature_min_interval": 60, "temperature_calibration": 1},
+	})
+	dev := ms.devices["00158D0001A2B3C4"]
+	dev.Manufacturer, dev.Model = "Acme", "Plug"
+	rec := recordProperty(dm, "temperature")
+
+	sendTemperatures(dm, 2345, 2350)
+	if v := rec.get(); len(v) != 1 || v[0] != 24.45 {
+		t.Errorf("values with definition options = %v, want [24.45]", v)
+	}
+
+	// The device's own options win per option.
+	ms.devices["00158D0001A2B3C4"].Options = map[string]float64{"temperature_min_interval": 0}
+	sendTemperatures(dm, 2360)
+	if v := rec.get(); len(v) != 2 || v[1] != 24.6 {
+		t.Errorf("values with device override = %v", v)
+	}
+}
+
+func TestThrottleStopDropsHeldReports(t *testing.T) {
+	dm, _ := newThrottleDM(t, map[string]float64{"min_interval": 0.05})
+	rec := recordProperty(dm, "temperature")
+
+	sendTemperatures(dm, 2345, 2350)
+	dm.stopThrottle()
+	time.Sleep(100 * time.Millisecond)
+	if v := rec.get(); len(v) != 1 {
+		t.Errorf("values = %v, want only the first", v)
+	}
+	// Stopped: reports pass unthrottled.
+	sendTemperatures(dm, 2360)
+	if v := rec.get(); len(v) != 2 {
+		t.Errorf("values after stop = %v", v)
+	}
+}
+
+func TestMergeDefinitionOptions(t *testing.T) {
+	base := DeviceDefinition{Options: map[string]float64{"power_min_interval": 5, "power_threshold": 2}}
+	over := DeviceDefinition{Options: map[string]float64{"power_threshold": 1}}
+	got := mergeDefinition(base, over, nil).Options
+	if len(got) != 2 || got["power_min_interval"] != 5 || got["power_threshold"] != 1 {
+		t.Errorf("merged options = %v", got)
+	}
+	if base.Options["power_threshold"] != 2 {
+		t.Error("merge changed the base options")
+	}
+}
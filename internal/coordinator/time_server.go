@@ -0,0 +1,82 @@
+package coordinator
+
+import (
+	"time"
+
+	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
+)
+
+// zclEpoch is the ZCL UTCTime epoch (2000-01-01 00:00:00 UTC).
+var zclEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Time cluster TimeStatus bits.
+const (
+	timeStatusMaster        = 0x01
+	timeStatusSynchronized  = 0x02
+	timeStatusMasterZoneDst = 0x04
+)
+
+// zclTime converts a wall-clock time to seconds since the ZCL epoch.
+func zclTime(t time.Time) uint32 {
+	if t.Before(zclEpoch) {
+		return 0
+	}
+	return uint32(t.Sub(zclEpoch) / time.Second)
+}
+
+// timeAttributeValues computes the Time cluster attributes for the given
+// instant. now.Location() supplies the timezone and DST rules.
+func timeAttributeValues(now time.Time) map[uint16]any {
+	_, offset := now.Zone()
+	stdOffset := offset
+	var dstStart, dstEnd uint32
+	var dstShift int32
+
+	start, end := now.ZoneBounds()
+	if now.IsDST() {
+		// Currently in DST: the standard offset is the one after the period ends.
+		if !end.IsZero() {
+			_, stdOffset = end.Zone()
+		}
+		dstStart, dstEnd = zclTime(start), zclTime(end)
+		dstShift = int32(offset - stdOffset)
+	} else if !end.IsZero() && end.IsDST() {
+		// Standard time now, DST period starts at the next transition.
+		_, dstOffset := end.Zone()
+		_, nextEnd := end.ZoneBounds()
+		dstStart, dstEnd = zclTime(end), zclTime(nextEnd)
+		dstShift = int32(dstOffset - offset)
+	}
+
+	utc := zclTime(now)
+	return map[uint16]any{
+		0x0000: utc,
+		0x0001: uint8(timeStatusMaster | timeStatusSynchronized | timeStatusMasterZoneDst),
+		0x0002: int32(stdOffset),
+		0x0003: dstStart,
+		0x0004: dstEnd,
+		0x0005: dstShift,
+		0x0006: uint32(int64(utc) + int64(stdOffset)),
+		0x0007: uint32(int64(utc) + int64(offset)),
+		0x0008: utc,
+		0x0009: utc + 24*60*60,
+	}
+}
+
+// newTimeServer returns the local Time cluster server. Values are computed
+// from the host clock on every read; the coordinator is the time master, so
+// devices cannot set the clock.
+func newTimeServer() *LocalCluster {
+	def := clusters.Time
+	return &LocalCluster{
+		Def: &def,
+		ReadAttr: func(attrID uint16) (any, bool) {
+			v, ok := timeAttributeValues(time.Now())[attrID]
+			return v, ok
+		},
+		WriteAttr: func(uint16, any) uint8 {
+			return zcl.ZCLStatusReadOnly
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package coordinator
+
+import (
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/zcl"
+)
+
+func TestZCLTime(t *testing.T) {
+	if got := zclTime(zclEpoch); got != 0 {
+		t.Errorf("epoch: got %d, want 0", got)
+	}
+	if got := zclTime(zclEpoch.Add(90 * time.Second)); got != 90 {
+		t.Errorf("epoch+90s: got %d, want 90", got)
+	}
+	if got := zclTime(time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)); got != 0 {
+		t.Errorf("before epoch: got %d, want 0", got)
+	}
+}
+
+func TestTimeAttributeValuesFixedZone(t *testing.T) {
+	loc := time.FixedZone("UTC+3", 3*3600)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, loc)
+	v := timeAttributeValues(now)
+
+	utc := v[0x0000].(uint32)
+	if utc != zclTime(now) {
+		t.Errorf("Time: got %d, want %d", utc, zclTime(now))
+	}
+	if tz := v[0x0002].(int32); tz != 3*3600 {
+		t.Errorf("TimeZone: got %d, want %d", tz, 3*3600)
+	}
+	if shift := v[0x0005].(int32); shift != 0 {
+		t.Errorf("DstShift: got %d, want 0", shift)
+	}
+	if local := v[0x0007].(uint32); local != utc+3*3600 {
+		t.Errorf("LocalTime: got %d, want %d", local, utc+3*3600)
+	}
+}
+
+func TestTimeAttributeValuesDST(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tzdata not available:", err)
+	}
+
+	// Summer: DST active, standard offset +1h, shift +1h.
+	summer := time.Date(2024, 7, 1, 12, 0, 0, 0, loc)
+	v := timeAttributeValues(summer)
+	if tz := v[0x0002].(int32); tz != 3600 {
+		t.Errorf("summer TimeZone: got %d, want 3600", tz)
+	}
+	if shift := v[0x0005].(int32); shift != 3600 {
+		t.Errorf("summer DstShift: got %d, want 3600", shift)
+	}
+	wantStart := zclTime(time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC))
+	wantEnd := zclTime(time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC))
+	if got := v[0x0003].(uint32); got != wantStart {
+		t.Errorf("summer DstStart: got %d, want %d", got, wantStart)
+	}
+	if got := v[0x0004].(uint32); got != wantEnd {
+		t.Errorf("summer DstEnd: got %d, want %d", got, wantEnd)
+	}
+	if local := v[0x0007].(uint32); local != v[0x0000].(uint32)+7200 {
+		t.Errorf("summer LocalTime: got %d, want Time+7200", local)
+	}
+
+	// Winter: DST not active yet, next period announced.
+	winter := time.Date(2024, 1, 15, 12, 0, 0, 0, loc)
+	v = timeAttributeValues(winter)
+	if got := v[0x0003].(uint32); got != wantStart {
+		t.Errorf("winter DstStart: got %d, want %d", got, wantStart)
+	}
+	if shift := v[0x0005].(int32); shift != 3600 {
+		t.Errorf("winter DstShift: got %d, want 3600", shift)
+	}
+	if local := v[0x0007].(uint32); local != v[0x0000].(uint32)+3600 {
+		t.Errorf("winter LocalTime: got %d, want Time+3600", local)
+	}
+}
+
+func TestTimeServerReadOnly(t *testing.T) {
+	lc := newTimeServer()
+	// Write Time (UTC) = 0.
+	rsp := lc.writeRecords([]byte{0x00, 0x00, zcl.TypeUTC, 0, 0, 0, 0}, false)
+	want := []byte{zcl.ZCLStatusReadOnly, 0x00, 0x00}
+	if string(rsp) != string(want) {
+		t.Errorf("write rsp: got %X, want %X", rsp, want)
+	}
+	if v, ok := lc.Attribute(0x0000); !ok || v.(uint32) == 0 {
+		t.Errorf("Time should still come from the host clock, got %v", v)
+	}
+}
@@ -0,0 +1,389 @@
+package coordinator
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+// tuyaCluster is the Tuya MCU manufacturer-specific cluster.
+const tuyaCluster uint16 = 0xEF00
+
+// Tuya MCU command IDs.
+const (
+	tuyaCmdSetData            uint8 = 0x00 // client to server
+	tuyaCmdDataResponse       uint8 = 0x01
+	tuyaCmdDataReport         uint8 = 0x02
+	tuyaCmdQueryData          uint8 = 0x03 // client to server
+	tuyaCmdActiveStatusReport uint8 = 0x06
+	tuyaCmdMCUVersionRequest  uint8 = 0x10 // client to server
+	tuyaCmdMCUVersionResponse uint8 = 0x11
+	tuyaCmdTimeSync           uint8 = 0x24 // request and response
+)
+
+// Tuya DP types.
+const (
+	tuyaDPRaw    uint8 = 0x00
+	tuyaDPBool   uint8 = 0x01
+	tuyaDPValue  uint8 = 0x02
+	tuyaDPString uint8 = 0x03
+	tuyaDPEnum   uint8 = 0x04
+	tuyaDPBitmap uint8 = 0x05
+)
+
+// tuyaDPTypes maps the "type" of a tuya_dp property value to its DP type.
+var tuyaDPTypes = map[string]uint8{
+	"raw":    tuyaDPRaw,
+	"bool":   tuyaDPBool,
+	"value":  tuyaDPValue,
+	"number": tuyaDPValue,
+	"string": tuyaDPString,
+	"enum":   tuyaDPEnum,
+	"bitmap": tuyaDPBitmap,
+}
+
+// Errors returned by SetProperty and WriteTuyaDP.
+var (
+	ErrUnknownProperty      = errors.New("unknown property")
+	ErrPropertyReadOnly     = errors.New("property is read-only")
+	ErrInvalidPropertyValue = errors.New("invalid property value")
+)
+
+// isTuyaDPCommand reports whether a 0xEF00 command carries datapoints.
+func isTuyaDPCommand(cmdID uint8) bool {
+	switch cmdID {
+	case tuyaCmdDataResponse, tuyaCmdDataReport, tuyaCmdActiveStatusReport:
+		return true
+	}
+	return false
+}
+
+// newTuyaClient returns the local Tuya MCU client. Datapoint reports are
+// decoded by the device manager; time sync requests are answered here.
+func (c *Coordinator) newTuyaClient() *LocalCluster {
+	ack := func(LocalCommand) (*LocalReply, uint8) { return nil, zcl.ZCLStatusSuccess }
+	return &LocalCluster{
+		Def:    &zcl.ClusterDef{ID: tuyaCluster, Name: "Tuya MCU"},
+		Client: true,
+		Commands: map[uint8]LocalCommandHandler{
+			tuyaCmdDataResponse:       ack,
+			tuyaCmdDataReport:         ack,
+			tuyaCmdActiveStatusReport: ack,
+			tuyaCmdMCUVersionResponse: func(cmd LocalCommand) (*LocalReply, uint8) {
+				if len(cmd.Payload) < 3 {
+					return nil, zcl.ZCLStatusMalformedCommand
+				}
+				c.devices.handleTuyaMCUVersion(cmd.SrcAddr, cmd.Payload[2])
+				return nil, zcl.ZCLStatusSuccess
+			},
+			tuyaCmdTimeSync: func(LocalCommand) (*LocalReply, uint8) {
+				return &LocalReply{CommandID: tuyaCmdTimeSync, Payload: tuyaTimePayload(time.Now())}, zcl.ZCLStatusSuccess
+			},
+		},
+	}
+}
+
+// tuyaTimePayload encodes a time sync response: payload length (2 LE)
+// followed by UTC and local Unix time (4 BE each).
+func tuyaTimePayload(now time.Time) []byte {
+	utc := now.Unix()
+	_, offset := now.Zone()
+	payload := []byte{8, 0}
+	payload = binary.BigEndian.AppendUint32(payload, uint32(utc))
+	payload = binary.BigEndian.AppendUint32(payload, uint32(utc+int64(offset)))
+	return payload
+}
+
+// tuyaMCUVersion formats the MCU version byte as major.minor.patch.
+func tuyaMCUVersion(v uint8) string {
+	return fmt.Sprintf("%d.%d.%d", v>>6, (v>>4)&0x03, v&0x0F)
+}
+
+// handleTuyaMCUVersion stores and emits the MCU version of a Tuya device.
+func (dm *DeviceManager) handleTuyaMCUVersion(shortAddr uint16, v uint8) {
+	ieee := dm.lookupOrRebuild(shortAddr)
+	if ieee == "" {
+		return
+	}
+	version := tuyaMCUVersion(v)
+	var dev *store.Device
+	if err := dm.coord.Store().UpdateDevice(ieee, func(d *store.Device) error {
+		if d.Properties == nil {
+			d.Properties = make(map[string]any)
+		}
+		d.Properties["mcu_version"] = version
+		dev = d
+		return nil
+	}); err != nil {
+		dm.logger.Error("save tuya MCU version", "err", err, "ieee", ieee)
+		return
+	}
+
+	dm.coord.Events().Emit(Event{
+		Type: EventPropertyUpdate,
+		Data: map[string]interface{}{
+			"ieee":     ieee,
+			"property": "mcu_version",
+			"value":    version,
+			"source": map[string]interface{}{
+				"cluster": tuyaCluster,
+				"decoder": "tuya_mcu_version",
+			},
+		},
+	})
+	dm.logger.Info("tuya MCU version", "ieee", ieee, "name", deviceName(dev), "version", version)
+}
+
+// nextTuyaSeq returns the next Tuya transaction sequence number.
+func (dm *DeviceManager) nextTuyaSeq() uint16 {
+	return uint16(dm.tuyaSeq.Add(1))
+}
+
+// queryTuya asks a Tuya MCU device for its MCU version and the current
+// value of all datapoints. Must run while the device is awake.
+func (dm *DeviceManager) queryTuya(ctx context.Context, dev *store.Device) {
+	ep := findTuyaEndpoint(dev)
+	if ep == 0 {
+		return
+	}
+	name := deviceName(dev)
+	for _, cmd := range []struct {
+		id      uint8
+		payload []byte
+		what    string
+	}{
+		{tuyaCmdMCUVersionRequest, binary.BigEndian.AppendUint16(nil, dm.nextTuyaSeq()), "MCU version request"},
+		{tuyaCmdQueryData, nil, "DP query"},
+	} {
+		err := dm.coord.NCP().SendCommand(ctx, ncp.ClusterCommandRequest{
+			DstAddr:   dev.ShortAddress,
+			DstEP:     ep,
+			ClusterID: tuyaCluster,
+			CommandID: cmd.id,
+			Payload:   cmd.payload,
+		})
+		if err != nil {
+			dm.logger.Warn("tuya: "+cmd.what, "err", err, "ieee", dev.IEEEAddress, "name", name)
+		}
+	}
+}
+
+// findTuyaEndpoint returns the first endpoint with the Tuya MCU cluster, or 0.
+func findTuyaEndpoint(dev *store.Device) uint8 {
+	for _, ep := range dev.Endpoints {
+		if hasInCluster(ep, tuyaCluster) {
+			return ep.ID
+		}
+	}
+	return 0
+}
+
+// WriteTuyaDP sets a named tuya_dp property of a device. The property must
+// have a "type" in the device definition; its transform is reversed before
+// the value is encoded.
+func (c *Coordinator) WriteTuyaDP(ctx context.Context, ieee, property string, value any) error {
+	dev, err := c.store.GetDevice(ieee)
+	if err != nil {
+		return err
+	}
+	def := c.devices.definition(dev)
+	if def == nil {
+		return ErrUnknownProperty
+	}
+	pd := findTuyaProperty(def, property)
+	if pd == nil {
+		return ErrUnknownProperty
+	}
+	dpType, ok := tuyaDPTypes[pd.Type]
+	if !ok {
+		return ErrPropertyReadOnly
+	}
+	if pd.Tag < 0 || pd.Tag > 0xFF {
+		return fmt.Errorf("%w: DP %d out of range", ErrInvalidPropertyValue, pd.Tag)
+	}
+
+	raw, err := reverseTransform(pd.Transform, pd.ReverseTransform, value)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+	}
+	rec, err := encodeTuyaDP(uint8(pd.Tag), dpType, raw)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPropertyValue, err)
+	}
+
+	ep := findTuyaEndpoint(dev)
+	if ep == 0 {
+		ep = 1
+	}
+	payload := binary.BigEndian.AppendUint16(nil, c.devices.nextTuyaSeq())
+	payload = append(payload, rec...)
+	c.logger.Info("tuya DP write", "ieee", ieee, "name", deviceName(dev),
+		"property", property, "dp", pd.Tag, "value", value)
+	return c.SendClusterCommand(ctx, dev.ShortAddress, ep, tuyaCluster, tuyaCmdSetData, payload)
+}
+
+// findTuyaProperty returns the tuya_dp property value named name, or nil.
+func findTuyaProperty(def *DeviceDefinition, name string) *PropertyDef {
+	for i := range def.Properties {
+		ps := &def.Properties[i]
+		if ps.Decoder != "tuya_dp" {
+			continue
+		}
+		for j := range ps.Values {
+			if ps.Values[j].Name == name {
+				return &ps.Values[j]
+			}
+		}
+	}
+	return nil
+}
+
+// encodeTuyaDP encodes one datapoint record:
+// dp_id(1) + dp_type(1) + data_len(2 BE) + data(N).
+func encodeTuyaDP(dp uint8, dpType uint8, value any) ([]byte, error) {
+	var data []byte
+	switch dpType {
+	case tuyaDPBool:
+		b, ok := value.(bool)
+		if !ok {
+			n, isNum := toNumeric(value)
+			if !isNum {
+				return nil, fmt.Errorf("bool DP %d: got %T", dp, value)
+			}
+			b = n != 0
+		}
+		data = []byte{0}
+		if b {
+			data[0] = 1
+		}
+	case tuyaDPValue:
+		n, ok := toNumeric(value)
+		if !ok || n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("value DP %d: got %v", dp, value)
+		}
+		data = binary.BigEndian.AppendUint32(nil, uint32(int32(n)))
+	case tuyaDPEnum:
+		n, ok := toNumeric(value)
+		if !ok || n < 0 || n > 0xFF {
+			return nil, fmt.Errorf("enum DP %d: got %v", dp, value)
+		}
+		data = []byte{uint8(n)}
+	case tuyaDPBitmap:
+		n, ok := toNumeric(value)
+		switch {
+		case !ok || n < 0 || n > math.MaxUint32:
+			return nil, fmt.Errorf("bitmap DP %d: got %v", dp, value)
+		case n <= 0xFF:
+			data = []byte{uint8(n)}
+		case n <= 0xFFFF:
+			data = binary.BigEndian.AppendUint16(nil, uint16(n))
+		default:
+			data = binary.BigEndian.AppendUint32(nil, uint32(n))
+		}
+	case tuyaDPString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("string DP %d: got %T", dp, value)
+		}
+		data = []byte(s)
+	case tuyaDPRaw:
+		switch v := value.(type) {
+		case []byte:
+			data = v
+		case string:
+			b, err := hex.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("raw DP %d: %w", dp, err)
+			}
+			data = b
+		default:
+			return nil, fmt.Errorf("raw DP %d: got %T", dp, value)
+		}
+	default:
+		return nil, fmt.Errorf("DP %d: unknown type %d", dp, dpType)
+	}
+	if len(data) > 0xFFFF {
+		return nil, fmt.Errorf("DP %d: %d bytes is too long", dp, len(data))
+	}
+
+	rec := []byte{dp, dpType}
+	rec = binary.BigEndian.AppendUint16(rec, uint16(len(data)))
+	return append(rec, data...), nil
+}
+
+// reverseTransform converts a property value back to the raw value the
+// device expects, with the reverse expression if set, else by undoing a
+// named transform. Other transforms cannot be reversed.
+func reverseTransform(transform, reverse string, value interface{}) (interface{}, error) {
+	if reverse != "" {
+		return evalTransform(reverse, value)
+	}
+	if transform == "" {
+		return value, nil
+	}
+	undo, ok := transformReversals[transform]
+	if !ok {
+		return nil, fmt.Errorf("transform %q cannot be reversed", transform)
+	}
+	return undo(value)
+}
+
+// checkReversible returns an error if a settable property's transform
+// cannot be reversed.
+func checkReversible(transform, reverse string) error {
+	if reverse != "" {
+		if _, err := compileTransform(reverse); err != nil {
+			return fmt.Errorf("reverse: %w", err)
+		}
+		return nil
+	}
+	if _, ok := transformReversals[transform]; transform != "" && !ok {
+		return fmt.Errorf("transform %q cannot be reversed, add a reverse expression", transform)
+	}
+	return nil
+}
+
+// transformReversals undo the named transforms that keep all information.
+var transformReversals = map[string]func(interface{}) (interface{}, error){
+	"divide_10":  func(v interface{}) (interface{}, error) { return multiplyN(v, 10) },
+	"divide_100": func(v interface{}) (interface{}, error) { return multiplyN(v, 100) },
+	"minus_one": func(v interface{}) (interface{}, error) {
+		n, ok := toNumeric(v)
+		if !ok {
+			return nil, fmt.Errorf("minus_one: got %T", v)
+		}
+		return n + 1, nil
+	},
+	"bool_invert": func(v interface{}) (interface{}, error) {
+		if b, ok := v.(bool); ok {
+			return !b, nil
+		}
+		return nil, fmt.Errorf("bool_invert: got %T", v)
+	},
+}
+
+// multiplyN multiplies a numeric value by n, rounding to the nearest integer.
+func multiplyN(value interface{}, n int) (interface{}, error) {
+	var f float64
+	switch v := value.(type) {
+	case float64:
+		f = v
+	case float32:
+		f = float64(v)
+	default:
+		i, ok := toNumeric(value)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %T", value)
+		}
+		f = float64(i)
+	}
+	return int64(math.Round(f * float64(n))), nil
+}
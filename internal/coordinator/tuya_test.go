@@ -0,0 +1,245 @@
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"zigbee-go-home/internal/ncp"
+	"zigbee-go-home/internal/store"
+	"zigbee-go-home/internal/zcl"
+)
+
+func TestEncodeTuyaDP(t *testing.T) {
+	tests := []struct {
+		name   string
+		dpType uint8
+		value  any
+		want   []byte
+	}{
+		{"bool", tuyaDPBool, true, []byte{7, 1, 0, 1, 1}},
+		{"bool numeric", tuyaDPBool, float64(0), []byte{7, 1, 0, 1, 0}},
+		{"value", tuyaDPValue, int64(215), []byte{7, 2, 0, 4, 0, 0, 0, 0xD7}},
+		{"negative value", tuyaDPValue, float64(-2), []byte{7, 2, 0, 4, 0xFF, 0xFF, 0xFF, 0xFE}},
+		{"enum", tuyaDPEnum, float64(2), []byte{7, 4, 0, 1, 2}},
+		{"string", tuyaDPString, "ab", []byte{7, 3, 0, 2, 'a', 'b'}},
+		{"raw hex", tuyaDPRaw, "0A0B", []byte{7, 0, 0, 2, 0x0A, 0x0B}},
+		{"bitmap 8", tuyaDPBitmap, 0x81, []byte{7, 5, 0, 1, 0x81}},
+		{"bitmap 16", tuyaDPBitmap, 0x0102, []byte{7, 5, 0, 2, 0x01, 0x02}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeTuyaDP(7, tt.dpType, tt.value)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("got %X, want %X", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeTuyaDPRoundTrip(t *testing.T) {
+	var payload []byte
+	payload = append(payload, 0x00, 0x05) // tuya seq
+	for _, dp := range []struct {
+		id     uint8
+		dpType uint8
+		value  any
+	}{
+		{1, tuyaDPBool, true},
+		{2, tuyaDPValue, int64(300)},
+		{4, tuyaDPEnum, int64(1)},
+	} {
+		rec, err := encodeTuyaDP(dp.id, dp.dpType, dp.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		payload = append(payload, rec...)
+	}
+	got, err := decodeTuyaDPs(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[1] != true || got[2] != int64(300) || got[4] != int64(1) {
+		t.Errorf("decoded: %v", got)
+	}
+}
+
+func TestEncodeTuyaDPInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		dpType uint8
+		value  any
+	}{
+		{tuyaDPBool, "yes"},
+		{tuyaDPEnum, 256},
+		{tuyaDPBitmap, -1},
+		{tuyaDPString, 5},
+		{tuyaDPRaw, "zz"},
+		{0x09, 1},
+	} {
+		if _, err := encodeTuyaDP(1, tt.dpType, tt.value); err == nil {
+			t.Errorf("type %d value %v: expected error", tt.dpType, tt.value)
+		}
+	}
+}
+
+func TestReverseTransform(t *testing.T) {
+	tests := []struct {
+		name    string
+		reverse string
+		value   any
+		want    any
+	}{
+		{"", "", float64(3), float64(3)},
+		{"divide_10", "", 21.5, int64(215)},
+		{"divide_100", "", float64(1.23), int64(123)},
+		{"minus_one", "", float64(4), int64(5)},
+		{"bool_invert", "", true, false},
+		{"x / 2 + 10", "round((x - 10) * 2)", float64(21.5), int64(23)},
+	}
+	for _, tt := range tests {
+		got, err := reverseTransform(tt.name, tt.reverse, tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("%q(%v) = %v, %v; want %v", tt.name, tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := reverseTransform("lumi_battery", "", 50); err == nil {
+		t.Error("lumi_battery: expected error")
+	}
+}
+
+func TestDefinitionIrreversibleTransform(t *testing.T) {
+	tuya := DeviceDefinition{Model: "TRV", Properties: []PropertySource{{
+		Cluster: 0xEF00, Decoder: "tuya_dp",
+		Values: []PropertyDef{{Tag: 16, Name: "temperature_setpoint", Transform: "x / 2", Type: "value"}},
+	}}}
+	if err := tuya.compile(); err == nil || !strings.Contains(err.Error(), "cannot be reversed") {
+		t.Errorf("writable DP without reverse: %v", err)
+	}
+	tuya.Properties[0].Values[0].ReverseTransform = "round(x * 2)"
+	if err := tuya.compile(); err != nil {
+		t.Errorf("writable DP with reverse: %v", err)
+	}
+	tuya.Properties[0].Values[0].ReverseTransform = "round(x *"
+	if err := tuya.compile(); err == nil {
+		t.Error("broken reverse expression: expected error")
+	}
+
+	conv := DeviceDefinition{Model: "Fan", Converters: []AttributeConverter{{
+		Cluster: 0x0202, Attribute: 0x0000,
+		Converter: zcl.Converter{Property: "fan_mode", Transform: "x + 1", Write: true},
+	}}}
+	if err := conv.compile(); err == nil {
+		t.Error("settable converter without reverse: expected error")
+	}
+	conv.Converters[0].Write = false
+	if err := conv.compile(); err != nil {
+		t.Errorf("read-only converter: %v", err)
+	}
+}
+
+func TestTuyaTimePayload(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.FixedZone("UTC+3", 3*3600))
+	got := tuyaTimePayload(now)
+	utc := now.Unix()
+	want := []byte{8, 0,
+		byte(utc >> 24), byte(utc >> 16), byte(utc >> 8), byte(utc),
+		byte((utc + 10800) >> 24), byte((utc + 10800) >> 16), byte((utc + 10800) >> 8), byte(utc + 10800)}
+	if !bytes.Equal(got, want) {
+		t.Errorf("got %X, want %X", got, want)
+	}
+}
+
+func TestTuyaTimeSyncRequest(t *testing.T) {
+	s, sent := newTestLocalServer(t)
+	dm, _ := newTestDM(t)
+	s.Register(1, dm.coord.newTuyaClient())
+
+	s.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1111, SrcEP: 1, DstEP: 1, ClusterID: tuyaCluster,
+		CommandID: tuyaCmdTimeSync, ServerToClient: true, DisableDefaultResp: true,
+		Payload: []byte{0x00, 0x01},
+	})
+	if len(*sent) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(*sent))
+	}
+	rsp := (*sent)[0]
+	if rsp.Global || rsp.ServerToClient || rsp.CommandID != tuyaCmdTimeSync || len(rsp.Payload) != 10 {
+		t.Errorf("response: %+v", rsp)
+	}
+}
+
+func TestTuyaMCUVersion(t *testing.T) {
+	if got := tuyaMCUVersion(0x43); got != "1.0.3" {
+		t.Errorf("got %q, want 1.0.3", got)
+	}
+
+	dm, ms := newTestDM(t)
+	dm.coord.devices = dm
+	ms.devices["00158D0001A2B3C4"] = &store.Device{IEEEAddress: "00158D0001A2B3C4", ShortAddress: 0x1111}
+	dm.RebuildAddrIndex()
+	s, _ := newTestLocalServer(t)
+	s.Register(1, dm.coord.newTuyaClient())
+
+	s.HandleClusterCommand(ncp.ClusterCommandEvent{
+		SrcAddr: 0x1111, SrcEP: 1, DstEP: 1, ClusterID: tuyaCluster,
+		CommandID: tuyaCmdMCUVersionResponse, ServerToClient: true, DisableDefaultResp: true,
+		Payload: []byte{0x00, 0x01, 0x43},
+	})
+	if v := ms.devices["00158D0001A2B3C4"].Properties["mcu_version"]; v != "1.0.3" {
+		t.Errorf("mcu_version = %v", v)
+	}
+}
+
+func TestWriteTuyaDP(t *testing.T) {
+	c, ms, n := newTestCoord(t)
+	c.deviceDB.Add(DeviceDefinition{
+		Manufacturer: "_TZE200_test",
+		Model:        "TS0601",
+		Properties: []PropertySource{{
+			Cluster: 0xEF00,
+			Decoder: "tuya_dp",
+			Values: []PropertyDef{
+				{Tag: 16, Name: "temperature_setpoint", Transform: "divide_10", Type: "value"},
+				{Tag: 24, Name: "temperature", Transform: "divide_10"},
+			},
+		}},
+	})
+	ms.devices["00158D0001A2B3C4"] = &store.Device{
+		IEEEAddress:  "00158D0001A2B3C4",
+		ShortAddress: 0x1111,
+		Manufacturer: "_TZE200_test",
+		Model:        "TS0601",
+		Endpoints:    []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0xEF00}}},
+	}
+
+	ctx := context.Background()
+	if err := c.WriteTuyaDP(ctx, "00158D0001A2B3C4", "temperature_setpoint", 21.5); err != nil {
+		t.Fatalf("WriteTuyaDP: %v", err)
+	}
+	if len(n.commands) != 1 {
+		t.Fatalf("expected 1 command, got %d", len(n.commands))
+	}
+	cmd := n.commands[0]
+	if cmd.ClusterID != 0xEF00 || cmd.CommandID != tuyaCmdSetData || cmd.DstEP != 1 {
+		t.Errorf("command: %+v", cmd)
+	}
+	if want := []byte{16, 2, 0, 4, 0, 0, 0, 0xD7}; len(cmd.Payload) != 10 || !bytes.Equal(cmd.Payload[2:], want) {
+		t.Errorf("payload: got %X, want seq + %X", cmd.Payload, want)
+	}
+
+	if err := c.WriteTuyaDP(ctx, "00158D0001A2B3C4", "temperature", 20); !errors.Is(err, ErrPropertyReadOnly) {
+		t.Errorf("read-only: got %v", err)
+	}
+	if err := c.WriteTuyaDP(ctx, "00158D0001A2B3C4", "nope", 1); !errors.Is(err, ErrUnknownProperty) {
+		t.Errorf("unknown: got %v", err)
+	}
+	if err := c.WriteTuyaDP(ctx, "00158D0001A2B3C4", "temperature_setpoint", "hot"); !errors.Is(err, ErrInvalidPropertyValue) {
+		t.Errorf("invalid: got %v", err)
+	}
+}
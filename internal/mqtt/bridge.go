@@ -7,8 +7,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -48,6 +51,9 @@ type Bridge struct {
 	// Cached topic names to avoid DB reads on every publish.
 	topicNames map[string]string // IEEE -> topic name
 
+	// HA device triggers published per device, by action.
+	triggers map[string]map[string]bool
+
 	// Track pending delayed discovery goroutines per IEEE to avoid duplicates.
 	pendingDiscovery map[string]context.CancelFunc
 	discoveryGen     map[string]uint64
@@ -69,6 +75,7 @@ func NewBridge(coord *coordinator.Coordinator, cfg Config, logger *slog.Logger)
 		eventCh:          make(chan coordinator.Event, 256),
 		states:           make(map[string]map[string]any),
 		topicNames:       make(map[string]string),
+		triggers:         make(map[string]map[string]bool),
 		pendingDiscovery: make(map[string]context.CancelFunc),
 		discoveryGen:     make(map[string]uint64),
 		ctx:              ctx,
@@ -87,6 +94,7 @@ func NewBridge(coord *coordinator.Coordinator, cfg Config, logger *slog.Logger)
 			b.publishBridgeState("online")
 			b.publishAllDiscovery()
 			b.subscribeCommands()
+			b.setupAlarmPanel()
 		}).
 		SetConnectionLostHandler(func(_ pahomqtt.Client, err error) {
 			b.logger.Warn("MQTT connection lost", "err", err)
@@ -155,10 +163,10 @@ func (b *Bridge) eventLoop() {
 
 func (b *Bridge) handleEvent(event coordinator.Event) {
 	switch event.Type {
-	case coordinator.EventAttributeReport:
-		b.handleAttributeReport(event)
 	case coordinator.EventPropertyUpdate:
 		b.handlePropertyUpdate(event)
+	case coordinator.EventDeviceAction:
+		b.handleDeviceAction(event)
 	case coordinator.EventDeviceAnnounce:
 		// Publish discovery after a delay to let interview complete.
 		b.discWg.Add(1)
@@ -168,67 +176,78 @@ func (b *Bridge) handleEvent(event coordinator.Event) {
 		}()
 	case coordinator.EventDeviceLeft:
 		b.handleDeviceLeft(event)
+	case coordinator.EventAlarmPanel:
+		b.publishAlarmState()
+	case coordinator.EventDeviceAvailability:
+		b.handleAvailability(event)
 	}
 }
 
-func (b *Bridge) handleAttributeReport(event coordinator.Event) {
+func (b *Bridge) handlePropertyUpdate(event coordinator.Event) {
 	data, ok := event.Data.(map[string]interface{})
 	if !ok {
 		return
 	}
 	ieee, _ := data["ieee"].(string)
-	if ieee == "" {
-		return
-	}
-
-	clusterID, _ := data["cluster_id"].(uint16)
-	attrName, _ := data["attr_name"].(string)
+	prop, _ := data["property"].(string)
 	value := data["value"]
-
-	// Map known cluster/attribute combos to state property names.
-	propName := mapAttributeToProperty(clusterID, attrName)
-	if propName == "" {
+	if ieee == "" || prop == "" {
 		return
 	}
 
-	// Normalize values for Home Assistant compatibility.
-	switch propName {
-	case "state":
-		// Convert bool OnOff to "ON"/"OFF" strings.
-		if bv, ok := value.(bool); ok {
-			if bv {
-				value = "ON"
-			} else {
-				value = "OFF"
-			}
+	// Properties arrive already normalized by the coordinator's converters;
+	// only add the fields Home Assistant entities read besides them.
+	switch prop {
+	case "action":
+		// Published by handleDeviceAction.
+		return
+	case "on_off":
+		// Light and switch entities read an ON/OFF "state".
+		state := "OFF"
+		if toBool(value) {
+			state = "ON"
 		}
-	case "occupancy":
-		// Normalize occupancy to bool for HA binary_sensor.
-		value = toBool(value)
-	case "zone_status":
-		// Normalize zone_status (bitmap16) to bool for HA binary_sensor.
-		value = toBool(value)
+		b.updateAndPublishState(ieee, "state", state)
 	case "brightness":
 		// HA JSON schema light needs color_mode alongside brightness.
 		b.updateAndPublishState(ieee, "color_mode", "brightness")
 	}
 
-	b.updateAndPublishState(ieee, propName, value)
+	b.updateAndPublishState(ieee, prop, value)
 }
 
-func (b *Bridge) handlePropertyUpdate(event coordinator.Event) {
+// handleDeviceAction publishes the action in the device state and clears
+// it again, so the retained state does not repeat it to new subscribers.
+// The HA device trigger of an action is published when it is first seen.
+func (b *Bridge) handleDeviceAction(event coordinator.Event) {
 	data, ok := event.Data.(map[string]interface{})
 	if !ok {
 		return
 	}
 	ieee, _ := data["ieee"].(string)
-	prop, _ := data["property"].(string)
-	value := data["value"]
-	if ieee == "" || prop == "" {
+	action, _ := data["action"].(string)
+	if ieee == "" || action == "" {
 		return
 	}
 
-	b.updateAndPublishState(ieee, prop, value)
+	if dev, err := b.coord.Devices().GetDevice(ieee); err == nil && dev.Interviewed {
+		b.mu.Lock()
+		published := b.triggers[ieee][action]
+		if !published {
+			if b.triggers[ieee] == nil {
+				b.triggers[ieee] = make(map[string]bool)
+			}
+			b.triggers[ieee][action] = true
+		}
+		b.mu.Unlock()
+		if !published {
+			msg := buildTriggerDiscovery(dev, b.prefix, action)
+			b.publish(msg.Topic, msg.Payload, true)
+		}
+	}
+
+	b.updateAndPublishState(ieee, "action", action)
+	b.updateAndPublishState(ieee, "action", "")
 }
 
 func (b *Bridge) updateAndPublishState(ieee, prop string, value any) {
@@ -265,9 +284,10 @@ func (b *Bridge) handleDeviceLeft(event coordinator.Event) {
 		return
 	}
 
-	// Clear retained state topic (publish empty payload).
-	stateTopic := b.prefix + "/" + b.cachedTopicName(ieee)
-	b.publish(stateTopic, nil, true)
+	// Clear retained state and availability topics (publish empty payload).
+	topicName := b.cachedTopicName(ieee)
+	b.publish(b.prefix+"/"+topicName, nil, true)
+	b.publish(deviceAvailabilityTopic(b.prefix, topicName), nil, true)
 
 	// Remove discovery entries.
 	dev := &store.Device{IEEEAddress: ieee}
@@ -275,11 +295,36 @@ func (b *Bridge) handleDeviceLeft(event coordinator.Event) {
 		b.publish(msg.Topic, msg.Payload, true)
 	}
 
-	// Clear accumulated state and cached topic name.
+	// Clear accumulated state, cached topic name and triggers.
 	b.mu.Lock()
+	triggers := b.triggers[ieee]
 	delete(b.states, ieee)
 	delete(b.topicNames, ieee)
+	delete(b.triggers, ieee)
 	b.mu.Unlock()
+	for action := range triggers {
+		b.publish(triggerTopic(dev, action), nil, true)
+	}
+}
+
+func (b *Bridge) handleAvailability(event coordinator.Event) {
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	ieee, _ := data["ieee"].(string)
+	state, _ := data["state"].(string)
+	if ieee == "" || state == "" {
+		return
+	}
+	b.publish(deviceAvailabilityTopic(b.prefix, b.cachedTopicName(ieee)), []byte(state), true)
+}
+
+// availabilityTracked reports whether the coordinator decides availability
+// for the device, so its discovery should include the device topic.
+func (b *Bridge) availabilityTracked(dev *store.Device) bool {
+	a := b.coord.Availability()
+	return a != nil && a.Timeout(dev) > 0
 }
 
 func (b *Bridge) delayedDiscovery(event coordinator.Event) {
@@ -369,12 +414,15 @@ func (b *Bridge) publishAllDiscovery() {
 			b.topicNames[dev.IEEEAddress] = deviceTopicName(dev)
 			b.mu.Unlock()
 			b.publishDeviceDiscovery(dev)
+			if b.availabilityTracked(dev) && dev.Availability != nil {
+				b.publish(deviceAvailabilityTopic(b.prefix, deviceTopicName(dev)), []byte(dev.Availability.State), true)
+			}
 		}
 	}
 }
 
 func (b *Bridge) publishDeviceDiscovery(dev *store.Device) {
-	for _, msg := range buildDiscovery(dev, b.prefix) {
+	for _, msg := range buildDiscovery(dev, b.prefix, b.availabilityTracked(dev)) {
 		b.publish(msg.Topic, msg.Payload, true)
 	}
 	b.logger.Info("published HA discovery", "ieee", dev.IEEEAddress, "name", deviceDisplayName(dev))
@@ -403,14 +451,10 @@ func (b *Bridge) subscribeDeviceCommands(dev *store.Device) {
 }
 
 func (b *Bridge) handleCommand(ieee string, payload []byte) {
-	dev, err := b.coord.Devices().GetDevice(ieee)
-	if err != nil {
+	if _, err := b.coord.Devices().GetDevice(ieee); err != nil {
 		b.logger.Warn("command for unknown device", "ieee", ieee)
 		return
 	}
-	if len(dev.Endpoints) == 0 {
-		return
-	}
 
 	var cmd map[string]interface{}
 	if err := json.Unmarshal(payload, &cmd); err != nil {
@@ -421,65 +465,127 @@ func (b *Bridge) handleCommand(ieee string, payload []byte) {
 	ctx, cancel := context.WithTimeout(b.coord.Context(), 10*time.Second)
 	defer cancel()
 
-	// Handle state command (ON/OFF).
+	// Sets the coordinator confirms are published once the device reports
+	// them, instead of assuming the new state.
+	optimistic := !b.coord.ConfirmsCommands(ieee)
+
+	// Handle state (ON/OFF/TOGGLE) first so that {"state":"ON","brightness":200}
+	// turns the light on before dimming it.
 	if state, ok := cmd["state"].(string); ok {
-		ep := findEndpointWithCluster(dev, 0x0006)
-		switch strings.ToUpper(state) {
-		case "ON":
-			if err := b.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0006, 0x01, nil); err != nil {
-				b.logger.Warn("on command failed", "ieee", ieee, "err", err)
-			} else {
-				b.updateAndPublishState(ieee, "state", "ON")
-			}
-		case "OFF":
-			if err := b.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0006, 0x00, nil); err != nil {
-				b.logger.Warn("off command failed", "ieee", ieee, "err", err)
-			} else {
-				b.updateAndPublishState(ieee, "state", "OFF")
-			}
-		case "TOGGLE":
-			if err := b.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0006, 0x02, nil); err != nil {
-				b.logger.Warn("toggle command failed", "ieee", ieee, "err", err)
-			}
+		state = strings.ToUpper(state)
+		if b.commandSent(ieee, "state", b.coord.SetProperty(ctx, ieee, "on_off", state)) && optimistic && state != "TOGGLE" {
+			b.updateAndPublishState(ieee, "state", state)
 		}
 	}
 
-	// Handle brightness command.
-	if brightness, ok := toFloat64(cmd["brightness"]); ok {
-		ep := findEndpointWithCluster(dev, 0x0008)
-		if brightness < 0 {
-			brightness = 0
+	// Every other key is a property name, set through the same converters
+	// as the REST API and Lua.
+	for _, name := range slices.Sorted(maps.Keys(cmd)) {
+		if name == "state" {
+			continue
+		}
+		value := cmd[name]
+		if name == "brightness" {
+			brightness, ok := toFloat64(value)
+			if !ok {
+				continue
+			}
+			value = uint8(min(max(brightness, 0), 254))
 		}
-		if brightness > 254 {
-			brightness = 254
+		err := b.coord.SetProperty(ctx, ieee, name, value)
+		if errors.Is(err, coordinator.ErrUnknownProperty) {
+			b.logger.Debug("command for unknown property", "ieee", ieee, "property", name)
+			continue
 		}
-		level := uint8(brightness)
-		// Move to Level with On/Off, transition time 5 (0.5s).
-		cmdPayload := []byte{level, 0x05, 0x00}
-		if err := b.coord.SendClusterCommand(ctx, dev.ShortAddress, ep, 0x0008, 0x04, cmdPayload); err != nil {
-			b.logger.Warn("brightness command failed", "ieee", ieee, "err", err)
-		} else {
-			b.updateAndPublishState(ieee, "brightness", level)
+		if !b.commandSent(ieee, name, err) || !optimistic {
+			continue
+		}
+		b.updateAndPublishState(ieee, name, value)
+		if name == "brightness" {
 			b.updateAndPublishState(ieee, "color_mode", "brightness")
 		}
 	}
 }
 
-// findEndpointWithCluster returns the endpoint ID that has the given cluster
-// as an input cluster. Falls back to the first endpoint if not found,
-// or endpoint 1 if the device has no endpoints.
-func findEndpointWithCluster(dev *store.Device, clusterID uint16) uint8 {
-	for _, ep := range dev.Endpoints {
-		for _, cid := range ep.InClusters {
-			if cid == clusterID {
-				return ep.ID
-			}
-		}
+// commandSent reports whether a command reached the device, logging why
+// not. Commands queued for a sleepy device are delivered at its next
+// check-in, so no state is assumed for them yet.
+func (b *Bridge) commandSent(ieee, what string, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, coordinator.ErrCommandQueued):
+		b.logger.Info(what+" command queued until device checks in", "ieee", ieee)
+	default:
+		b.logger.Warn(what+" command failed", "ieee", ieee, "err", err)
+	}
+	return false
+}
+
+// setupAlarmPanel publishes alarm panel discovery and state and subscribes
+// to its command topic. No-op when the alarm panel is disabled.
+func (b *Bridge) setupAlarmPanel() {
+	panel := b.coord.AlarmPanel()
+	if panel == nil {
+		return
+	}
+	msg := buildAlarmPanelDiscovery(b.prefix, panel.Config())
+	b.publish(msg.Topic, msg.Payload, true)
+	b.publishAlarmState()
+	b.client.Subscribe(alarmPanelTopic(b.prefix)+"/set", 1, func(_ pahomqtt.Client, msg pahomqtt.Message) {
+		b.handleAlarmCommand(msg.Payload())
+	})
+}
+
+func (b *Bridge) publishAlarmState() {
+	panel := b.coord.AlarmPanel()
+	if panel == nil {
+		return
+	}
+	b.publish(alarmPanelTopic(b.prefix), mustJSON(panel.Status()), true)
+}
+
+// parseAlarmCommand accepts {"action":"ARM_AWAY","code":"1234"} as sent by
+// the HA command template, or a bare action such as DISARM.
+func parseAlarmCommand(payload []byte) (action, code string) {
+	var cmd struct {
+		Action string `json:"action"`
+		Code   string `json:"code"`
 	}
-	if len(dev.Endpoints) == 0 {
-		return 1
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		return strings.ToUpper(strings.TrimSpace(string(payload))), ""
+	}
+	return strings.ToUpper(cmd.Action), cmd.Code
+}
+
+func (b *Bridge) handleAlarmCommand(payload []byte) {
+	panel := b.coord.AlarmPanel()
+	if panel == nil {
+		return
+	}
+	action, code := parseAlarmCommand(payload)
+
+	var err error
+	switch action {
+	case "ARM_HOME":
+		err = panel.Arm(coordinator.AlarmModeHome, code, "mqtt")
+	case "ARM_NIGHT":
+		err = panel.Arm(coordinator.AlarmModeNight, code, "mqtt")
+	case "ARM_AWAY":
+		err = panel.Arm(coordinator.AlarmModeAway, code, "mqtt")
+	case "DISARM":
+		err = panel.Disarm(code, "mqtt")
+	case "TRIGGER":
+		err = panel.Trigger("burglar", "mqtt")
+	default:
+		b.logger.Warn("unknown alarm panel command", "action", action)
+		return
+	}
+	if err != nil {
+		b.logger.Warn("alarm panel command failed", "action", action, "err", err)
+		// Republish so HA shows the unchanged state again.
+		b.publishAlarmState()
 	}
-	return dev.Endpoints[0].ID
 }
 
 func (b *Bridge) publish(topic string, payload []byte, retained bool) {
@@ -515,49 +621,6 @@ func (b *Bridge) cachedTopicName(ieee string) string {
 	return name
 }
 
-// mapAttributeToProperty maps well-known cluster/attribute combos to property names.
-func mapAttributeToProperty(clusterID uint16, attrName string) string {
-	switch clusterID {
-	case 0x0006: // On/Off
-		if attrName == "OnOff" || attrName == "on_off" {
-			return "state"
-		}
-	case 0x0008: // Level Control
-		if attrName == "CurrentLevel" || attrName == "current_level" {
-			return "brightness"
-		}
-	case 0x0402: // Temperature
-		if attrName == "MeasuredValue" || attrName == "measured_value" {
-			return "temperature"
-		}
-	case 0x0405: // Humidity
-		if attrName == "MeasuredValue" || attrName == "measured_value" {
-			return "humidity"
-		}
-	case 0x0403: // Pressure
-		if attrName == "MeasuredValue" || attrName == "measured_value" {
-			return "pressure"
-		}
-	case 0x0400: // Illuminance
-		if attrName == "MeasuredValue" || attrName == "measured_value" {
-			return "illuminance"
-		}
-	case 0x0406: // Occupancy
-		if attrName == "Occupancy" || attrName == "occupancy" {
-			return "occupancy"
-		}
-	case 0x0500: // IAS Zone
-		if attrName == "ZoneStatus" || attrName == "zone_status" {
-			return "zone_status"
-		}
-	case 0x0001: // Power Configuration
-		if attrName == "BatteryPercentageRemaining" || attrName == "battery_percentage_remaining" {
-			return "battery"
-		}
-	}
-	return ""
-}
-
 // toBool converts various types to a boolean (non-zero = true).
 func toBool(v interface{}) bool {
 	switch n := v.(type) {
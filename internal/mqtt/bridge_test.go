@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"zigbee-go-home/internal/coordinator"
 	"zigbee-go-home/internal/store"
 )
 
@@ -26,7 +27,7 @@ func TestDiscoveryTemperatureSensor(t *testing.T) {
 		},
 	}
 
-	msgs := buildDiscovery(dev, "zigbee2mqtt")
+	msgs := buildDiscovery(dev, "zigbee2mqtt", false)
 	if len(msgs) == 0 {
 		t.Fatal("expected discovery messages")
 	}
@@ -100,7 +101,7 @@ func TestDiscoveryLightVsSwitch(t *testing.T) {
 		},
 	}
 
-	msgs := buildDiscovery(lightDev, "zigbee2mqtt")
+	msgs := buildDiscovery(lightDev, "zigbee2mqtt", false)
 	topics := extractTopics(msgs)
 
 	if !topics["homeassistant/light/zigbee_AABBCCDDEEFF0011/light/config"] {
@@ -123,7 +124,7 @@ func TestDiscoveryLightVsSwitch(t *testing.T) {
 		},
 	}
 
-	msgs = buildDiscovery(switchDev, "zigbee2mqtt")
+	msgs = buildDiscovery(switchDev, "zigbee2mqtt", false)
 	topics = extractTopics(msgs)
 
 	if !topics["homeassistant/switch/zigbee_1122334455667788/switch/config"] {
@@ -144,7 +145,7 @@ func TestDiscoveryLightHasCommandTopic(t *testing.T) {
 		},
 	}
 
-	msgs := buildDiscovery(dev, "zigbee2mqtt")
+	msgs := buildDiscovery(dev, "zigbee2mqtt", false)
 	for _, m := range msgs {
 		if m.Topic == "homeassistant/light/zigbee_AABBCCDDEEFF0011/light/config" {
 			var payload haDiscovery
@@ -168,7 +169,7 @@ func TestDiscoveryUninterviewedDevice(t *testing.T) {
 		IEEEAddress: "0000000000000001",
 		Interviewed: false,
 	}
-	msgs := buildDiscovery(dev, "zigbee2mqtt")
+	msgs := buildDiscovery(dev, "zigbee2mqtt", false)
 	if len(msgs) != 0 {
 		t.Errorf("expected no discovery for uninterviewed device, got %d", len(msgs))
 	}
@@ -240,33 +241,6 @@ func TestDeviceTopicName(t *testing.T) {
 	}
 }
 
-func TestMapAttributeToProperty(t *testing.T) {
-	tests := []struct {
-		cluster  uint16
-		attrName string
-		want     string
-	}{
-		{0x0006, "OnOff", "state"},
-		{0x0008, "CurrentLevel", "brightness"},
-		{0x0402, "MeasuredValue", "temperature"},
-		{0x0405, "MeasuredValue", "humidity"},
-		{0x0403, "MeasuredValue", "pressure"},
-		{0x0400, "MeasuredValue", "illuminance"},
-		{0x0406, "Occupancy", "occupancy"},
-		{0x0001, "BatteryPercentageRemaining", "battery"},
-		{0x0000, "ModelIdentifier", ""}, // unmapped
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.want, func(t *testing.T) {
-			got := mapAttributeToProperty(tt.cluster, tt.attrName)
-			if got != tt.want {
-				t.Errorf("mapAttributeToProperty(0x%04X, %q) = %q, want %q", tt.cluster, tt.attrName, got, tt.want)
-			}
-		})
-	}
-}
-
 func TestRemoveDiscovery(t *testing.T) {
 	dev := &store.Device{IEEEAddress: "AABBCCDD11223344"}
 	msgs := buildRemoveDiscovery(dev)
@@ -323,13 +297,73 @@ func TestBatteryPropertyDiscovery(t *testing.T) {
 		},
 	}
 
-	msgs := buildDiscovery(dev, "zigbee2mqtt")
+	msgs := buildDiscovery(dev, "zigbee2mqtt", false)
 	topics := extractTopics(msgs)
 	if !topics["homeassistant/sensor/zigbee_AABBCCDD00112233/battery/config"] {
 		t.Error("expected battery discovery for device with battery property")
 	}
 }
 
+func TestDiscoveryDeviceAvailability(t *testing.T) {
+	dev := &store.Device{
+		IEEEAddress:  "00158D00012A3B4C",
+		FriendlyName: "Hall Plug",
+		Interviewed:  true,
+		Endpoints: []store.Endpoint{
+			{ID: 1, InClusters: []uint16{0x0000, 0x0006}},
+		},
+	}
+
+	for _, msg := range buildDiscovery(dev, "zigbee2mqtt", true) {
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(msg.Payload, &raw); err != nil {
+			t.Fatalf("unmarshal %s: %v", msg.Topic, err)
+		}
+		if _, ok := raw["availability_topic"]; ok {
+			t.Errorf("%s: availability_topic set together with availability", msg.Topic)
+		}
+		var payload haDiscovery
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			t.Fatalf("unmarshal %s: %v", msg.Topic, err)
+		}
+		if payload.AvailabilityMode != "all" || len(payload.Availability) != 2 ||
+			payload.Availability[0].Topic != "zigbee2mqtt/bridge/state" ||
+			payload.Availability[1].Topic != "zigbee2mqtt/hall_plug/availability" {
+			t.Errorf("%s: availability = %+v mode %q", msg.Topic, payload.Availability, payload.AvailabilityMode)
+		}
+	}
+}
+
+func TestTriggerDiscovery(t *testing.T) {
+	dev := &store.Device{
+		IEEEAddress:  "00158D00012A3B4C",
+		FriendlyName: "Hall Remote",
+		Manufacturer: "IKEA of Sweden",
+		Model:        "TRADFRI remote control",
+		Interviewed:  true,
+	}
+	msg := buildTriggerDiscovery(dev, "zigbee2mqtt", "arrow_left_click")
+	if msg.Topic != "homeassistant/device_automation/zigbee_00158D00012A3B4C/action_arrow_left_click/config" {
+		t.Errorf("topic = %q", msg.Topic)
+	}
+	var p haTrigger
+	if err := json.Unmarshal(msg.Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.AutomationType != "trigger" || p.Type != "action" || p.Subtype != "arrow_left_click" || p.Payload != "arrow_left_click" {
+		t.Errorf("trigger = %+v", p)
+	}
+	if p.Topic != "zigbee2mqtt/hall_remote" || p.ValueTemplate != "{{ value_json.action }}" {
+		t.Errorf("topic %q, template %q", p.Topic, p.ValueTemplate)
+	}
+	if len(p.Device.Identifiers) != 1 || p.Device.Identifiers[0] != "zigbee_00158D00012A3B4C" {
+		t.Errorf("device = %+v", p.Device)
+	}
+	if got := triggerTopic(dev, "On Press/1"); got != "homeassistant/device_automation/zigbee_00158D00012A3B4C/action_on_press_1/config" {
+		t.Errorf("sanitized topic = %q", got)
+	}
+}
+
 func TestMustJSON(t *testing.T) {
 	result := mustJSON(map[string]string{"hello": "world"})
 	var parsed map[string]string
@@ -353,7 +387,7 @@ func TestStateTopicFormat(t *testing.T) {
 		},
 	}
 
-	msgs := buildDiscovery(dev, "zigbee2mqtt")
+	msgs := buildDiscovery(dev, "zigbee2mqtt", false)
 	for _, m := range msgs {
 		var payload haDiscovery
 		if err := json.Unmarshal(m.Payload, &payload); err != nil {
@@ -372,3 +406,47 @@ func extractTopics(msgs []discoveryMsg) map[string]bool {
 	}
 	return topics
 }
+
+func TestAlarmPanelDiscovery(t *testing.T) {
+	msg := buildAlarmPanelDiscovery("zigbee2mqtt", coordinator.AlarmConfig{Codes: []string{"1234"}})
+	if msg.Topic != "homeassistant/alarm_control_panel/zigbee_go_home/alarm_panel/config" {
+		t.Errorf("topic = %q", msg.Topic)
+	}
+	var p map[string]interface{}
+	if err := json.Unmarshal(msg.Payload, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p["state_topic"] != "zigbee2mqtt/bridge/alarm_panel" || p["command_topic"] != "zigbee2mqtt/bridge/alarm_panel/set" {
+		t.Errorf("topics: %v %v", p["state_topic"], p["command_topic"])
+	}
+	if p["code"] != "REMOTE_CODE" || p["code_arm_required"] != false || p["code_disarm_required"] != true {
+		t.Errorf("code settings: %v %v %v", p["code"], p["code_arm_required"], p["code_disarm_required"])
+	}
+
+	// Without codes HA must not ask for one.
+	msg = buildAlarmPanelDiscovery("zigbee2mqtt", coordinator.AlarmConfig{})
+	p = nil
+	json.Unmarshal(msg.Payload, &p)
+	if _, ok := p["code"]; ok || p["code_disarm_required"] != false {
+		t.Errorf("no codes: code=%v disarm_required=%v", p["code"], p["code_disarm_required"])
+	}
+}
+
+func TestParseAlarmCommand(t *testing.T) {
+	tests := []struct {
+		payload string
+		action  string
+		code    string
+	}{
+		{`{"action":"ARM_AWAY","code":"1234"}`, "ARM_AWAY", "1234"},
+		{`{"action":"disarm","code":""}`, "DISARM", ""},
+		{`ARM_HOME`, "ARM_HOME", ""},
+		{" disarm\n", "DISARM", ""},
+	}
+	for _, tt := range tests {
+		action, code := parseAlarmCommand([]byte(tt.payload))
+		if action != tt.action || code != tt.code {
+			t.Errorf("%q: got (%q, %q), want (%q, %q)", tt.payload, action, code, tt.action, tt.code)
+		}
+	}
+}
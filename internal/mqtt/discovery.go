@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"zigbee-go-home/internal/coordinator"
 	"zigbee-go-home/internal/store"
 )
 
@@ -29,7 +30,7 @@ type haDiscovery struct {
 	UniqueID            string   `json:"unique_id"`
 	StateTopic          string   `json:"state_topic"`
 	CommandTopic        string   `json:"command_topic,omitempty"`
-	AvailabilityTopic   string   `json:"availability_topic"`
+	haAvailability
 	ValueTemplate       string   `json:"value_template,omitempty"`
 	UnitOfMeasurement   string   `json:"unit_of_measurement,omitempty"`
 	DeviceClass         string   `json:"device_class,omitempty"`
@@ -44,6 +45,49 @@ type haDiscovery struct {
 	Device              haDevice `json:"device"`
 }
 
+// haAvailability is the availability part of a discovery payload: the
+// bridge state alone, or together with the device's own availability topic.
+// HA rejects payloads that set both forms.
+type haAvailability struct {
+	AvailabilityTopic string         `json:"availability_topic,omitempty"`
+	Availability      []haAvailTopic `json:"availability,omitempty"`
+	AvailabilityMode  string         `json:"availability_mode,omitempty"`
+}
+
+type haAvailTopic struct {
+	Topic string `json:"topic"`
+}
+
+// haAlarmPanel is the HA alarm_control_panel discovery payload. The code
+// flags default to true in HA, so they are always sent.
+type haAlarmPanel struct {
+	Name                string   `json:"name"`
+	UniqueID            string   `json:"unique_id"`
+	StateTopic          string   `json:"state_topic"`
+	CommandTopic        string   `json:"command_topic"`
+	AvailabilityTopic   string   `json:"availability_topic"`
+	ValueTemplate       string   `json:"value_template"`
+	CommandTemplate     string   `json:"command_template"`
+	Code                string   `json:"code,omitempty"`
+	CodeArmRequired     bool     `json:"code_arm_required"`
+	CodeDisarmRequired  bool     `json:"code_disarm_required"`
+	CodeTriggerRequired bool     `json:"code_trigger_required"`
+	SupportedFeatures   []string `json:"supported_features"`
+	Device              haDevice `json:"device"`
+}
+
+// haTrigger is the HA device_automation discovery payload of an action.
+// The trigger fires when the state's action equals Payload.
+type haTrigger struct {
+	AutomationType string   `json:"automation_type"`
+	Type           string   `json:"type"`
+	Subtype        string   `json:"subtype"`
+	Topic          string   `json:"topic"`
+	ValueTemplate  string   `json:"value_template"`
+	Payload        string   `json:"payload"`
+	Device         haDevice `json:"device"`
+}
+
 // deviceDisplayName returns a display name for the device.
 func deviceDisplayName(dev *store.Device) string {
 	if dev.FriendlyName != "" {
@@ -66,26 +110,44 @@ func deviceIdentifier(dev *store.Device) string {
 // deviceTopicName returns the topic name for a device (friendly name or IEEE).
 func deviceTopicName(dev *store.Device) string {
 	if dev.FriendlyName != "" {
-		// Sanitize: lowercase and keep only safe chars for MQTT topics.
-		name := strings.ToLower(dev.FriendlyName)
-		name = strings.Map(func(r rune) rune {
-			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
-				return r
-			}
-			return '_'
-		}, name)
-		return name
+		return sanitizeTopic(dev.FriendlyName)
 	}
 	return dev.IEEEAddress
 }
 
-// buildDiscovery generates HA discovery messages for a device based on its clusters.
-func buildDiscovery(dev *store.Device, prefix string) []discoveryMsg {
+// sanitizeTopic lowercases a name and keeps only safe chars for MQTT topics.
+func sanitizeTopic(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, strings.ToLower(name))
+}
+
+// deviceAvailabilityTopic returns the retained online/offline topic of a device.
+func deviceAvailabilityTopic(prefix, topicName string) string {
+	return prefix + "/" + topicName + "/availability"
+}
+
+// buildDiscovery generates HA discovery messages for a device based on its
+// clusters. Entities of tracked devices are available only while both the
+// bridge and the device are online.
+func buildDiscovery(dev *store.Device, prefix string, tracked bool) []discoveryMsg {
 	if !dev.Interviewed || len(dev.Endpoints) == 0 {
 		return nil
 	}
 
-	avail := prefix + "/bridge/state"
+	avail := haAvailability{AvailabilityTopic: prefix + "/bridge/state"}
+	if tracked {
+		avail = haAvailability{
+			Availability: []haAvailTopic{
+				{Topic: prefix + "/bridge/state"},
+				{Topic: deviceAvailabilityTopic(prefix, deviceTopicName(dev))},
+			},
+			AvailabilityMode: "all",
+		}
+	}
 	stateTopic := prefix + "/" + deviceTopicName(dev)
 	nodeID := deviceIdentifier(dev)
 	displayName := deviceDisplayName(dev)
@@ -157,7 +219,13 @@ func buildDiscovery(dev *store.Device, prefix string) []discoveryMsg {
 	if hasCluster[0x0500] {
 		msgs = append(msgs, buildBinarySensor(nodeID, displayName, stateTopic, avail, haDev,
 			"zone", "Zone", "safety",
-			"{{ 'ON' if value_json.zone_status else 'OFF' }}"))
+			"{{ 'ON' if value_json.alarm1 else 'OFF' }}"))
+		msgs = append(msgs, buildBinarySensor(nodeID, displayName, stateTopic, avail, haDev,
+			"tamper", "Tamper", "tamper",
+			"{{ 'ON' if value_json.tamper else 'OFF' }}"))
+		msgs = append(msgs, buildBinarySensor(nodeID, displayName, stateTopic, avail, haDev,
+			"battery_low", "Battery Low", "battery",
+			"{{ 'ON' if value_json.battery_low else 'OFF' }}"))
 	}
 
 	// Power Configuration (0x0001) → battery sensor
@@ -180,7 +248,7 @@ func buildDiscovery(dev *store.Device, prefix string) []discoveryMsg {
 	if hasCluster[0x000C] {
 		msgs = append(msgs, buildSensor(nodeID, displayName, stateTopic, avail, haDev,
 			"analog", "Analog Input", "", "", "measurement",
-			"{{ value_json.analog }}"))
+			"{{ value_json.analog_value }}"))
 	}
 
 	// Link quality sensor for all devices.
@@ -192,7 +260,7 @@ func buildDiscovery(dev *store.Device, prefix string) []discoveryMsg {
 	return msgs
 }
 
-func buildSensor(nodeID, displayName, stateTopic, avail string, haDev haDevice,
+func buildSensor(nodeID, displayName, stateTopic string, avail haAvailability, haDev haDevice,
 	objectID, suffix, deviceClass, unit, stateClass, valueTmpl string) discoveryMsg {
 
 	topic := fmt.Sprintf("homeassistant/sensor/%s/%s/config", nodeID, objectID)
@@ -200,7 +268,7 @@ func buildSensor(nodeID, displayName, stateTopic, avail string, haDev haDevice,
 		Name:              displayName + " " + suffix,
 		UniqueID:          nodeID + "_" + objectID,
 		StateTopic:        stateTopic,
-		AvailabilityTopic: avail,
+		haAvailability:    avail,
 		ValueTemplate:     valueTmpl,
 		UnitOfMeasurement: unit,
 		DeviceClass:       deviceClass,
@@ -210,7 +278,7 @@ func buildSensor(nodeID, displayName, stateTopic, avail string, haDev haDevice,
 	return discoveryMsg{Topic: topic, Payload: mustJSON(payload)}
 }
 
-func buildBinarySensor(nodeID, displayName, stateTopic, avail string, haDev haDevice,
+func buildBinarySensor(nodeID, displayName, stateTopic string, avail haAvailability, haDev haDevice,
 	objectID, suffix, deviceClass, valueTmpl string) discoveryMsg {
 
 	topic := fmt.Sprintf("homeassistant/binary_sensor/%s/%s/config", nodeID, objectID)
@@ -218,7 +286,7 @@ func buildBinarySensor(nodeID, displayName, stateTopic, avail string, haDev haDe
 		Name:              displayName + " " + suffix,
 		UniqueID:          nodeID + "_" + objectID,
 		StateTopic:        stateTopic,
-		AvailabilityTopic: avail,
+		haAvailability:    avail,
 		ValueTemplate:     valueTmpl,
 		DeviceClass:       deviceClass,
 		PayloadOn:         "ON",
@@ -228,7 +296,7 @@ func buildBinarySensor(nodeID, displayName, stateTopic, avail string, haDev haDe
 	return discoveryMsg{Topic: topic, Payload: mustJSON(payload)}
 }
 
-func buildLight(nodeID, displayName, stateTopic, avail string, haDev haDevice, prefix string, dev *store.Device) discoveryMsg {
+func buildLight(nodeID, displayName, stateTopic string, avail haAvailability, haDev haDevice, prefix string, dev *store.Device) discoveryMsg {
 	topic := fmt.Sprintf("homeassistant/light/%s/light/config", nodeID)
 	cmdTopic := prefix + "/" + deviceTopicName(dev) + "/set"
 	payload := haDiscovery{
@@ -236,7 +304,7 @@ func buildLight(nodeID, displayName, stateTopic, avail string, haDev haDevice, p
 		UniqueID:            nodeID + "_light",
 		StateTopic:          stateTopic,
 		CommandTopic:        cmdTopic,
-		AvailabilityTopic:   avail,
+		haAvailability:      avail,
 		SupportedColorModes: []string{"brightness"},
 		BrightnessScale:     254,
 		Schema:              "json",
@@ -245,7 +313,7 @@ func buildLight(nodeID, displayName, stateTopic, avail string, haDev haDevice, p
 	return discoveryMsg{Topic: topic, Payload: mustJSON(payload)}
 }
 
-func buildSwitch(nodeID, displayName, stateTopic, avail string, haDev haDevice, prefix string, dev *store.Device) discoveryMsg {
+func buildSwitch(nodeID, displayName, stateTopic string, avail haAvailability, haDev haDevice, prefix string, dev *store.Device) discoveryMsg {
 	topic := fmt.Sprintf("homeassistant/switch/%s/switch/config", nodeID)
 	cmdTopic := prefix + "/" + deviceTopicName(dev) + "/set"
 	payload := haDiscovery{
@@ -253,7 +321,7 @@ func buildSwitch(nodeID, displayName, stateTopic, avail string, haDev haDevice,
 		UniqueID:          nodeID + "_switch",
 		StateTopic:        stateTopic,
 		CommandTopic:      cmdTopic,
-		AvailabilityTopic: avail,
+		haAvailability:    avail,
 		ValueTemplate:     "{{ value_json.state }}",
 		PayloadOn:         "ON",
 		PayloadOff:        "OFF",
@@ -279,6 +347,8 @@ func buildRemoveDiscovery(dev *store.Device) []discoveryMsg {
 		{"sensor", "linkquality"},
 		{"binary_sensor", "occupancy"},
 		{"binary_sensor", "zone"},
+		{"binary_sensor", "tamper"},
+		{"binary_sensor", "battery_low"},
 	}
 
 	var msgs []discoveryMsg
@@ -290,3 +360,67 @@ func buildRemoveDiscovery(dev *store.Device) []discoveryMsg {
 	}
 	return msgs
 }
+
+// triggerTopic returns the discovery topic of a device trigger.
+func triggerTopic(dev *store.Device, action string) string {
+	return fmt.Sprintf("homeassistant/device_automation/%s/action_%s/config", deviceIdentifier(dev), sanitizeTopic(action))
+}
+
+// buildTriggerDiscovery generates the HA device trigger of an action, so
+// HA automations can use the presses of remotes and buttons.
+func buildTriggerDiscovery(dev *store.Device, prefix, action string) discoveryMsg {
+	payload := haTrigger{
+		AutomationType: "trigger",
+		Type:           "action",
+		Subtype:        action,
+		Topic:          prefix + "/" + deviceTopicName(dev),
+		ValueTemplate:  "{{ value_json.action }}",
+		Payload:        action,
+		Device: haDevice{
+			Identifiers:  []string{deviceIdentifier(dev)},
+			Manufacturer: dev.Manufacturer,
+			Model:        dev.Model,
+			Name:         deviceDisplayName(dev),
+		},
+	}
+	return discoveryMsg{Topic: triggerTopic(dev, action), Payload: mustJSON(payload)}
+}
+
+// alarmPanelTopic returns the alarm panel state topic; commands go to
+// its /set subtopic.
+func alarmPanelTopic(prefix string) string {
+	return prefix + "/bridge/alarm_panel"
+}
+
+// buildAlarmPanelDiscovery generates the HA alarm_control_panel discovery
+// message for the coordinator's alarm panel. Codes are checked by the
+// panel, so HA forwards whatever the user enters.
+func buildAlarmPanelDiscovery(prefix string, cfg coordinator.AlarmConfig) discoveryMsg {
+	const nodeID = "zigbee_go_home"
+	topic := alarmPanelTopic(prefix)
+	payload := haAlarmPanel{
+		Name:               "Alarm Panel",
+		UniqueID:           nodeID + "_alarm_panel",
+		StateTopic:         topic,
+		CommandTopic:       topic + "/set",
+		AvailabilityTopic:  prefix + "/bridge/state",
+		ValueTemplate:      "{{ value_json.state }}",
+		CommandTemplate:    `{"action":"{{ action }}","code":"{{ code }}"}`,
+		CodeArmRequired:    len(cfg.Codes) > 0 && cfg.CodeArmRequired,
+		CodeDisarmRequired: len(cfg.Codes) > 0,
+		SupportedFeatures:  []string{"arm_home", "arm_away", "arm_night", "trigger"},
+		Device: haDevice{
+			Identifiers:  []string{nodeID},
+			Manufacturer: "zigbee-go-home",
+			Model:        "Alarm panel",
+			Name:         "zigbee-go-home",
+		},
+	}
+	if len(cfg.Codes) > 0 {
+		payload.Code = "REMOTE_CODE"
+	}
+	return discoveryMsg{
+		Topic:   fmt.Sprintf("homeassistant/alarm_control_panel/%s/alarm_panel/config", nodeID),
+		Payload: mustJSON(payload),
+	}
+}
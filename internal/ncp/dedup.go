@@ -0,0 +1,95 @@
+package ncp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// dedupWindow is how long a frame is remembered per source.
+	dedupWindow = 10 * time.Second
+
+	// dedupSlots is the number of recent frames remembered per source.
+	dedupSlots = 16
+
+	// dedupSweepEvery is how many frames pass between sweeps of idle sources.
+	dedupSweepEvery = 256
+)
+
+// frameKey identifies a frame from one source. Copies delivered over
+// different routes share the APS counter and the ZCL header.
+type frameKey struct {
+	apsCounter uint8
+	clusterID  uint16
+	zclSeq     uint8
+	cmdID      uint8
+}
+
+type seenFrame struct {
+	key frameKey
+	at  time.Time
+}
+
+// dedupRing holds the most recent frames from one source.
+type dedupRing struct {
+	frames [dedupSlots]seenFrame
+	next   int
+	last   time.Time
+}
+
+// dedupCache drops repeated copies of incoming frames using a per-source
+// sliding window. The zero value is ready to use.
+type dedupCache struct {
+	mu      sync.Mutex
+	sources map[uint16]*dedupRing
+	frames  int
+	dropped atomic.Uint64
+
+	now func() time.Time // for tests; nil means time.Now
+}
+
+// seen records a frame and reports whether the same frame from the same
+// source was already seen within the window.
+func (d *dedupCache) seen(src uint16, key frameKey) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if d.now != nil {
+		now = d.now()
+	}
+	if d.sources == nil {
+		d.sources = make(map[uint16]*dedupRing)
+	}
+
+	r := d.sources[src]
+	if r == nil {
+		r = &dedupRing{}
+		d.sources[src] = r
+	}
+	for _, f := range r.frames {
+		if f.key == key && !f.at.IsZero() && now.Sub(f.at) < dedupWindow {
+			d.dropped.Add(1)
+			return true
+		}
+	}
+	r.frames[r.next] = seenFrame{key: key, at: now}
+	r.next = (r.next + 1) % dedupSlots
+	r.last = now
+
+	d.frames++
+	if d.frames%dedupSweepEvery == 0 {
+		for addr, ring := range d.sources {
+			if now.Sub(ring.last) >= dedupWindow {
+				delete(d.sources, addr)
+			}
+		}
+	}
+	return false
+}
+
+// Dropped returns the number of duplicate frames dropped.
+func (d *dedupCache) Dropped() uint64 {
+	return d.dropped.Load()
+}
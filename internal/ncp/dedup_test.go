@@ -0,0 +1,99 @@
+package ncp
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestDedupCacheWindow(t *testing.T) {
+	now := time.Unix(1000, 0)
+	d := &dedupCache{now: func() time.Time { return now }}
+	key := frameKey{apsCounter: 7, clusterID: 0x0006, zclSeq: 3, cmdID: 0x02}
+
+	if d.seen(0x1234, key) {
+		t.Fatal("first frame reported as duplicate")
+	}
+	if !d.seen(0x1234, key) {
+		t.Error("second copy not detected")
+	}
+	if d.seen(0x5678, key) {
+		t.Error("same key from another source reported as duplicate")
+	}
+	other := key
+	other.zclSeq = 4
+	if d.seen(0x1234, other) {
+		t.Error("different ZCL seq reported as duplicate")
+	}
+
+	now = now.Add(dedupWindow)
+	if d.seen(0x1234, key) {
+		t.Error("frame outside the window reported as duplicate")
+	}
+	if got := d.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+}
+
+func TestDedupCacheSlots(t *testing.T) {
+	d := &dedupCache{}
+	first := frameKey{apsCounter: 0}
+	d.seen(1, first)
+	// Push the first frame out of the ring.
+	for i := 1; i <= dedupSlots; i++ {
+		d.seen(1, frameKey{apsCounter: uint8(i)})
+	}
+	if d.seen(1, first) {
+		t.Error("evicted frame reported as duplicate")
+	}
+}
+
+func TestDedupCacheSweepsIdleSources(t *testing.T) {
+	now := time.Unix(1000, 0)
+	d := &dedupCache{now: func() time.Time { return now }}
+	d.seen(1, frameKey{})
+	now = now.Add(dedupWindow)
+	for i := 0; i < dedupSweepEvery; i++ {
+		d.seen(2, frameKey{apsCounter: uint8(i)})
+	}
+	if _, ok := d.sources[1]; ok {
+		t.Error("idle source not swept")
+	}
+}
+
+func TestHandleAPSDEDataIndDropsDuplicates(t *testing.T) {
+	n := &NRF52840NCP{
+		zclPending: make(map[uint8]chan []byte),
+		logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	report := []byte{
+		zclFrameTypeGlobal | zclDirServerToClient | zclDisableDefaultResp,
+		0x10, zclCmdReportAttributes,
+		0x00, 0x00, 0x20, 0x01,
+	}
+	command := []byte{zclFrameTypeCluster | zclDisableDefaultResp, 0x11, 0x02}
+
+	var reports, commands int
+	onReport := func(AttributeReportEvent) { reports++ }
+	onCmd := func(ClusterCommandEvent) { commands++ }
+	deliver := func(counter uint8, frame []byte) {
+		payload := apsDataInd(0, 0x1234, 0x0000, 0x0006, frame)
+		payload[16] = counter
+		n.handleAPSDEDataInd(payload, onReport, onCmd)
+	}
+
+	deliver(5, report)
+	deliver(5, report) // second route
+	deliver(5, report) // third route
+	deliver(6, command)
+	deliver(6, command)
+	deliver(7, report) // new APS counter: a real repeat
+
+	if reports != 2 || commands != 1 {
+		t.Errorf("delivered %d reports and %d commands, want 2 and 1", reports, commands)
+	}
+	if got := n.Stats().DuplicateFrames; got != 3 {
+		t.Errorf("DuplicateFrames = %d, want 3", got)
+	}
+}
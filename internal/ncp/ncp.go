@@ -29,6 +29,14 @@ type NCP interface {
 	WriteAttributes(ctx context.Context, req WriteAttributesRequest) error
 	SendCommand(ctx context.Context, req ClusterCommandRequest) error
 	ConfigureReporting(ctx context.Context, req ConfigureReportingRequest) error
+	SendZCLFrame(ctx context.Context, req ZCLFrameRequest) error
+
+	// SendAPS sends a raw APS payload with any profile and cluster. If match
+	// is non-nil it then waits for the first incoming frame it accepts.
+	SendAPS(ctx context.Context, req APSFrameRequest, match func(APSFrame) bool) (*APSFrame, error)
+
+	// Local endpoints (registered with the stack on StartNetwork)
+	RegisterLocalEndpoint(desc SimpleDescriptor)
 
 	// Indication callbacks
 	OnDeviceJoined(handler func(DeviceJoinedEvent))
@@ -37,14 +45,47 @@ type NCP interface {
 	OnAttributeReport(handler func(AttributeReportEvent))
 	OnClusterCommand(handler func(ClusterCommandEvent))
 	OnNwkAddrUpdate(handler func(uint16))
+	OnGlobalCommand(handler func(GlobalCommandEvent))
+
+	// SetDefaultResponsePolicy decides which incoming attribute reports and
+	// cluster commands are acknowledged with a ZCL Default Response. Frames
+	// with the disable-default-response bit set never are. nil disables.
+	SetDefaultResponsePolicy(policy func(DefaultResponseInfo) bool)
 
 	// Info
 	GetNCPInfo() *NCPInfo
 
+	// Stats returns a snapshot of the transport counters since start.
+	Stats() Stats
+
 	// Lifecycle
 	Close() error
 }
 
+// APSFrameRequest is a raw APSDE-DATA request; Payload is sent as-is.
+type APSFrameRequest struct {
+	DstAddr   uint16
+	DstEP     uint8
+	SrcEP     uint8 // 0 = endpoint 1
+	ProfileID uint16
+	ClusterID uint16
+	Radius    uint8 // 0 = default
+	NoACK     bool  // send without requesting an APS acknowledgement
+	Payload   []byte
+}
+
+// APSFrame is an incoming APS data frame.
+type APSFrame struct {
+	SrcAddr   uint16
+	SrcEP     uint8
+	DstEP     uint8
+	ProfileID uint16
+	ClusterID uint16
+	LQI       uint8
+	RSSI      int8
+	Payload   []byte
+}
+
 // NCPInfo holds firmware/stack version information from the NCP.
 type NCPInfo struct {
 	FWVersion       uint32
@@ -53,6 +94,27 @@ type NCPInfo struct {
 	NetworkKey      []byte // 16-byte network key, set during FormNetwork
 }
 
+// Stats holds NCP transport counters, cumulative since the backend started,
+// and the number of requests still pending.
+type Stats struct {
+	FramesSent      uint64 `json:"frames_sent"`      // data frames written to the NCP (first attempts)
+	FramesReceived  uint64 `json:"frames_received"`  // data frames read from the NCP
+	Retransmits     uint64 `json:"retransmits"`      // LL frames written again after an ACK timeout
+	ACKTimeouts     uint64 `json:"ack_timeouts"`     // LL ACKs not received in time
+	CRCErrors       uint64 `json:"crc_errors"`       // frames dropped on a CRC mismatch
+	DecodeErrors    uint64 `json:"decode_errors"`    // other malformed frames
+	DuplicateFrames uint64 `json:"duplicate_frames"` // reports and commands dropped as repeats
+
+	// Pending is the number of requests waiting for the NCP or a device
+	// to respond, a measure of the current TX load.
+	Pending int `json:"pending"`
+
+	// APSFailures counts failed APSDE-DATA requests by destination short address.
+	APSFailures map[uint16]uint64 `json:"aps_failures"`
+	// UnhandledIndications counts indications with no handler, by command name.
+	UnhandledIndications map[string]uint64 `json:"unhandled_indications"`
+}
+
 // NetworkConfig holds parameters for network formation.
 type NetworkConfig struct {
 	Channel  uint8
@@ -188,9 +250,84 @@ type AttributeReportEvent struct {
 type ClusterCommandEvent struct {
 	SrcAddr   uint16
 	SrcEP     uint8
+	DstEP     uint8
 	ClusterID uint16
 	CommandID uint8
 	Payload   []byte
 	LQI       uint8
 	RSSI      int8
+
+	// ZCL header fields, needed to answer the command.
+	Seq                uint8
+	ServerToClient     bool
+	DisableDefaultResp bool
+	ManufacturerCode   uint16 // 0 if not manufacturer-specific
+}
+
+// GlobalCommandEvent is emitted for incoming global (profile-wide) ZCL
+// commands other than Read Attributes Responses and attribute reports,
+// e.g. Read/Write Attributes requests addressed to coordinator endpoints.
+type GlobalCommandEvent struct {
+	SrcAddr   uint16
+	SrcEP     uint8
+	DstEP     uint8
+	ClusterID uint16
+	CommandID uint8
+	Payload   []byte
+
+	Seq                uint8
+	ServerToClient     bool
+	DisableDefaultResp bool
+	ManufacturerCode   uint16
+}
+
+// DefaultResponseInfo describes an incoming Report Attributes frame or
+// cluster-specific command that may be acknowledged with a Default Response.
+type DefaultResponseInfo struct {
+	SrcAddr          uint16
+	SrcEP            uint8
+	DstEP            uint8
+	ClusterID        uint16
+	CommandID        uint8
+	Global           bool // Report Attributes rather than a cluster command
+	Seq              uint8
+	ServerToClient   bool
+	ManufacturerCode uint16
 }
+
+// DefaultResponse returns the success Default Response acknowledging a frame:
+// same sequence number and manufacturer code, opposite direction.
+func (info DefaultResponseInfo) DefaultResponse() ZCLFrameRequest {
+	return ZCLFrameRequest{
+		DstAddr:            info.SrcAddr,
+		DstEP:              info.SrcEP,
+		SrcEP:              info.DstEP,
+		ClusterID:          info.ClusterID,
+		CommandID:          zclCmdDefaultResponse,
+		Payload:            []byte{info.CommandID, 0x00}, // status SUCCESS
+		Global:             true,
+		Seq:                info.Seq,
+		ServerToClient:     !info.ServerToClient,
+		DisableDefaultResp: true,
+		ManufacturerCode:   info.ManufacturerCode,
+	}
+}
+
+// ZCLFrameRequest sends a ZCL frame with explicit header fields. Used for
+// responses from coordinator-hosted clusters, where the sequence number,
+// direction and source endpoint must match the request.
+type ZCLFrameRequest struct {
+	DstAddr   uint16
+	DstEP     uint8
+	SrcEP     uint8
+	ClusterID uint16
+	CommandID uint8
+	Payload   []byte
+
+	Global             bool // profile-wide command (e.g., Read Attributes Response)
+	Seq                uint8
+	ServerToClient     bool
+	DisableDefaultResp bool
+	ManufacturerCode   uint16 // 0 if not manufacturer-specific
+}
+
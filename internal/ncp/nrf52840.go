@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -42,6 +43,10 @@ type NRF52840NCP struct {
 	zclPending map[uint8]chan []byte
 	zclMu      sync.Mutex
 
+	// Raw APS response waiters registered by SendAPS.
+	apsWaiters map[*apsWaiter]struct{}
+	apsMu      sync.Mutex
+
 	// Indication callbacks.
 	handlerMu    sync.RWMutex
 	onJoined     func(DeviceJoinedEvent)
@@ -50,13 +55,26 @@ type NRF52840NCP struct {
 	onReport     func(AttributeReportEvent)
 	onClusterCmd    func(ClusterCommandEvent)
 	onNwkAddrUpdate func(uint16)
+	onGlobalCmd     func(GlobalCommandEvent)
 	onReset         func()
 
+	// Decides which reports and cluster commands get a Default Response.
+	defaultRespPolicy func(DefaultResponseInfo) bool
+
+	// Drops copies of reports and cluster commands received over several routes.
+	dedup dedupCache
+
+	// Transport counters reported by Stats.
+	stats ncpStats
+
 	// Signaled when NCPResetInd is received (used by resetAndReconnect).
 	resetIndCh chan struct{}
 
 	ncpInfo NCPInfo
 
+	// Endpoints registered on StartNetwork (guarded by handlerMu).
+	localEPs []SimpleDescriptor
+
 	// lifecycleMu protects concurrent resetState/Close access to port, done,
 	// llAckCh, closeOnce. Must be held when transitioning between states.
 	lifecycleMu sync.Mutex
@@ -182,6 +200,11 @@ func (n *NRF52840NCP) writeWithACK(ctx context.Context, frame []byte, pktSeq uin
 		if err != nil {
 			return fmt.Errorf("serial write: %w", err)
 		}
+		if attempt == 0 {
+			n.stats.framesSent.Add(1)
+		} else {
+			n.stats.retransmits.Add(1)
+		}
 		n.logger.Debug("nrf52840 frame sent", "len", len(frame))
 
 		// Wait for matching ACK, draining stale ACKs within the timeout window.
@@ -197,6 +220,7 @@ func (n *NRF52840NCP) writeWithACK(ctx context.Context, frame []byte, pktSeq uin
 				// Wrong ACK seq (stale from previous frame), drain and keep waiting.
 				n.logger.Debug("zboss LL stale ACK drained", "got", ackSeq, "want", pktSeq)
 			case <-deadline.C:
+				n.stats.ackTimeouts.Add(1)
 				n.logger.Warn("zboss LL ACK timeout", "attempt", attempt+1, "pktSeq", pktSeq)
 				break waitACK
 			case <-ctx.Done():
@@ -266,6 +290,11 @@ func (n *NRF52840NCP) readLoop() {
 
 		frame, err := zbossDecodeFrame(raw)
 		if err != nil {
+			if errors.Is(err, errZBOSSBadCRC) {
+				n.stats.crcErrors.Add(1)
+			} else {
+				n.stats.decodeErrors.Add(1)
+			}
 			n.logger.Warn("nrf52840 zboss decode error", "err", err)
 			continue
 		}
@@ -282,6 +311,7 @@ func (n *NRF52840NCP) readLoop() {
 		}
 
 		// Data frame — send LL ACK.
+		n.stats.framesReceived.Add(1)
 		pktSeq := zbossLLPktSeq(frame.LL.Flags)
 		n.sendACK(pktSeq)
 
@@ -443,6 +473,7 @@ func (n *NRF52840NCP) handleIndication(f *zbossFrame) {
 		}
 
 	default:
+		n.stats.unhandledIndication(zbossCmdName(f.HL.CallID))
 		n.logger.Warn("zboss unhandled indication",
 			"cmd", zbossCmdName(f.HL.CallID),
 			"payload", fmt.Sprintf("%X", f.Payload))
@@ -458,9 +489,14 @@ func (n *NRF52840NCP) handleAPSDEDataInd(payload []byte, onReport func(Attribute
 	// group_addr(2) + dst_endpoint(1) + src_endpoint(1) + cluster_id(2) + profile_id(2) +
 	// aps_counter(1) + src_mac_addr(2) + dst_mac_addr(2) + lqi(1) + rssi(1) + aps_key_attr(1) + data[]
 	dataLen := binary.LittleEndian.Uint16(payload[1:3])
+	apsFC := payload[3]
 	srcAddr := binary.LittleEndian.Uint16(payload[4:6])
+	dstAddr := binary.LittleEndian.Uint16(payload[6:8])
+	dstEP := payload[10]
 	srcEP := payload[11]
 	clusterID := binary.LittleEndian.Uint16(payload[12:14])
+	profileID := binary.LittleEndian.Uint16(payload[14:16])
+	apsCounter := payload[16]
 	lqi := payload[21]
 	rssi := int8(payload[22])
 
@@ -470,6 +506,17 @@ func (n *NRF52840NCP) handleAPSDEDataInd(payload []byte, onReport func(Attribute
 	}
 	zclData := payload[apsHdrSize : apsHdrSize+int(dataLen)]
 
+	n.deliverAPS(APSFrame{
+		SrcAddr:   srcAddr,
+		SrcEP:     srcEP,
+		DstEP:     dstEP,
+		ProfileID: profileID,
+		ClusterID: clusterID,
+		LQI:       lqi,
+		RSSI:      rssi,
+		Payload:   zclData,
+	})
+
 	// Parse ZCL frame header, accounting for manufacturer-specific frames.
 	// Format: frame_control(1) + [mfr_code(2)] + seq(1) + cmd_id(1)
 	if len(zclData) < 3 {
@@ -485,27 +532,65 @@ func (n *NRF52840NCP) handleAPSDEDataInd(payload []byte, onReport func(Attribute
 	}
 	zclSeq := zclData[hdrLen-2]
 	cmdID := zclData[hdrLen-1]
+	var mfrCode uint16
+	if frameCtrl&zclFlagMfrSpecific != 0 {
+		mfrCode = binary.LittleEndian.Uint16(zclData[1:3])
+	}
+	serverToClient := frameCtrl&zclDirServerToClient != 0
+	disableDefaultResp := frameCtrl&zclDisableDefaultResp != 0
 
 	frameType := frameCtrl & 0x03
 
-	// Handle cluster-specific commands (e.g., OTA QueryNextImageRequest, Tuya DP).
+	// Default Responses are only sent for unicast frames that asked for one.
+	// APS delivery mode (frame control bits 2-3) 0 is unicast.
+	ackInfo := DefaultResponseInfo{
+		SrcAddr:          srcAddr,
+		SrcEP:            srcEP,
+		DstEP:            dstEP,
+		ClusterID:        clusterID,
+		CommandID:        cmdID,
+		Global:           frameType == zclFrameTypeGlobal,
+		Seq:              zclSeq,
+		ServerToClient:   serverToClient,
+		ManufacturerCode: mfrCode,
+	}
+	canAck := !disableDefaultResp && (apsFC>>2)&0x03 == 0 && dstAddr < 0xFFF8
+
+	// duplicate reports whether this frame is a copy of one already
+	// delivered. Copies are still acknowledged.
+	duplicate := func() bool {
+		if !n.dedup.seen(srcAddr, frameKey{apsCounter: apsCounter, clusterID: clusterID, zclSeq: zclSeq, cmdID: cmdID}) {
+			return false
+		}
+		n.logger.Debug("duplicate frame dropped",
+			"short", fmt.Sprintf("0x%04X", srcAddr),
+			"cluster", fmt.Sprintf("0x%04X", clusterID),
+			"aps_counter", apsCounter, "zcl_seq", zclSeq)
+		return true
+	}
+
+	// Cluster-specific commands (e.g., Tuya DP, OTA queries to the coordinator).
 	if frameType == zclFrameTypeCluster {
-		if clusterID == 0x0019 && cmdID == 0x01 {
-			// OTA QueryNextImageRequest — respond with NO_IMAGE_AVAILABLE (0x98).
-			// Must run in a goroutine: request() waits for ACK/response that
-			// can only be delivered by this readLoop, so calling it inline deadlocks.
-			n.logger.Info("OTA query from device, responding NO_IMAGE_AVAILABLE",
-				"short", fmt.Sprintf("0x%04X", srcAddr), "ep", srcEP)
-			go n.sendOTANoImageAvailable(srcAddr, srcEP, zclSeq)
-		} else if onClusterCmd != nil {
+		if canAck {
+			defer n.maybeDefaultResponse(ackInfo)
+		}
+		if duplicate() {
+			return
+		}
+		if onClusterCmd != nil {
 			onClusterCmd(ClusterCommandEvent{
-				SrcAddr:   srcAddr,
-				SrcEP:     srcEP,
-				ClusterID: clusterID,
-				CommandID: cmdID,
-				Payload:   zclData[hdrLen:],
-				LQI:       lqi,
-				RSSI:      rssi,
+				SrcAddr:            srcAddr,
+				SrcEP:              srcEP,
+				DstEP:              dstEP,
+				ClusterID:          clusterID,
+				CommandID:          cmdID,
+				Payload:            zclData[hdrLen:],
+				LQI:                lqi,
+				RSSI:               rssi,
+				Seq:                zclSeq,
+				ServerToClient:     serverToClient,
+				DisableDefaultResp: disableDefaultResp,
+				ManufacturerCode:   mfrCode,
 			})
 		}
 		return
@@ -531,7 +616,10 @@ func (n *NRF52840NCP) handleAPSDEDataInd(payload []byte, onReport func(Attribute
 		}
 
 	case zclCmdReportAttributes:
-		if onReport == nil {
+		if canAck {
+			defer n.maybeDefaultResponse(ackInfo)
+		}
+		if onReport == nil || duplicate() {
 			return
 		}
 		reports := zclParseAttributeReports(records)
@@ -543,24 +631,50 @@ func (n *NRF52840NCP) handleAPSDEDataInd(payload []byte, onReport func(Attribute
 			rpt.RSSI = rssi
 			onReport(rpt)
 		}
+
+	default:
+		// Requests to coordinator-hosted clusters (Read/Write Attributes, ...)
+		// and responses not tracked above.
+		n.handlerMu.RLock()
+		onGlobalCmd := n.onGlobalCmd
+		n.handlerMu.RUnlock()
+		if onGlobalCmd != nil {
+			onGlobalCmd(GlobalCommandEvent{
+				SrcAddr:            srcAddr,
+				SrcEP:              srcEP,
+				DstEP:              dstEP,
+				ClusterID:          clusterID,
+				CommandID:          cmdID,
+				Payload:            records,
+				Seq:                zclSeq,
+				ServerToClient:     serverToClient,
+				DisableDefaultResp: disableDefaultResp,
+				ManufacturerCode:   mfrCode,
+			})
+		}
 	}
 }
 
-// sendOTANoImageAvailable responds to an OTA QueryNextImageRequest with NO_IMAGE_AVAILABLE.
-func (n *NRF52840NCP) sendOTANoImageAvailable(dstAddr uint16, dstEP uint8, zclSeq uint8) {
-	// OTA QueryNextImageResponse (cmd 0x02): status(1) = 0x98 (NO_IMAGE_AVAILABLE)
-	// Direction: server-to-client (coordinator is the OTA server).
-	zclFrame := make([]byte, 4)
-	zclFrame[0] = zclFrameTypeCluster | zclDirServerToClient | zclDisableDefaultResp
-	zclFrame[1] = zclSeq
-	zclFrame[2] = 0x02 // QueryNextImageResponse
-	zclFrame[3] = 0x98 // NO_IMAGE_AVAILABLE
-	apsPayload := buildAPSDEDataReq(dstAddr, dstEP, 1, 0x0019, zclProfileHA, 30, zclFrame)
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if _, err := n.request(ctx, zbossCmdAPSDEDataReq, apsPayload); err != nil {
-		n.logger.Warn("OTA no-image response failed", "err", err)
+// maybeDefaultResponse acknowledges a report or cluster command with a
+// Default Response if the policy asks for one. Called from the read loop,
+// so the frame is sent asynchronously.
+func (n *NRF52840NCP) maybeDefaultResponse(info DefaultResponseInfo) {
+	n.handlerMu.RLock()
+	policy := n.defaultRespPolicy
+	n.handlerMu.RUnlock()
+	if policy == nil || !policy(info) {
+		return
 	}
+	req := info.DefaultResponse()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := n.SendZCLFrame(ctx, req); err != nil {
+			n.logger.Debug("default response failed",
+				"short", fmt.Sprintf("0x%04X", req.DstAddr),
+				"cluster", fmt.Sprintf("0x%04X", req.ClusterID), "err", err)
+		}
+	}()
 }
 
 // --- NCP interface: Network management ---
@@ -849,10 +963,19 @@ func (n *NRF52840NCP) StartNetwork(ctx context.Context) error {
 		return err
 	}
 
-	// Register endpoint 1 with HA profile (after start, matching zigpy-zboss order).
-	epDesc := buildSimpleDescPayload(1, zclProfileHA, 0x0005, 0, nil, nil)
-	if _, err := n.request(ctx, zbossCmdAFSetSimpleDesc, epDesc); err != nil {
-		return fmt.Errorf("register EP1: %w", err)
+	// Register local endpoints (after start, matching zigpy-zboss order).
+	// Default: endpoint 1 with HA profile and no clusters.
+	n.handlerMu.RLock()
+	eps := append([]SimpleDescriptor(nil), n.localEPs...)
+	n.handlerMu.RUnlock()
+	if len(eps) == 0 {
+		eps = []SimpleDescriptor{{Endpoint: 1, ProfileID: zclProfileHA, DeviceID: 0x0005}}
+	}
+	for _, ep := range eps {
+		epDesc := buildSimpleDescPayload(ep.Endpoint, ep.ProfileID, ep.DeviceID, 0, ep.InClusters, ep.OutClusters)
+		if _, err := n.request(ctx, zbossCmdAFSetSimpleDesc, epDesc); err != nil {
+			return fmt.Errorf("register EP%d: %w", ep.Endpoint, err)
+		}
 	}
 
 	return nil
@@ -1074,6 +1197,15 @@ func (n *NRF52840NCP) Unbind(ctx context.Context, req BindRequest) error {
 
 // --- NCP interface: ZCL (all via APSDE_DATA_REQ) ---
 
+// apsDataRequest sends an APSDE_DATA_REQ and counts failures per destination.
+func (n *NRF52840NCP) apsDataRequest(ctx context.Context, dstAddr uint16, payload []byte) error {
+	_, err := n.request(ctx, zbossCmdAPSDEDataReq, payload)
+	if err != nil {
+		n.stats.apsFailure(dstAddr)
+	}
+	return err
+}
+
 func (n *NRF52840NCP) ReadAttributes(ctx context.Context, req ReadAttributesRequest) ([]AttributeResponse, error) {
 	n.logger.Info("ZCL read attrs TX",
 		"short", fmt.Sprintf("0x%04X", req.DstAddr),
@@ -1097,7 +1229,7 @@ func (n *NRF52840NCP) ReadAttributes(ctx context.Context, req ReadAttributesRequ
 	}()
 
 	// Send the APSDE_DATA_REQ (this confirms transmission, not the ZCL response).
-	if _, err := n.request(ctx, zbossCmdAPSDEDataReq, apsPayload); err != nil {
+	if err := n.apsDataRequest(ctx, req.DstAddr, apsPayload); err != nil {
 		return nil, err
 	}
 
@@ -1128,22 +1260,117 @@ func (n *NRF52840NCP) ReadAttributes(ctx context.Context, req ReadAttributesRequ
 func (n *NRF52840NCP) WriteAttributes(ctx context.Context, req WriteAttributesRequest) error {
 	zclFrame := zclBuildWriteAttributes(n.nextZCLSeq(), req.Records)
 	apsPayload := buildAPSDEDataReq(req.DstAddr, req.DstEP, 1, req.ClusterID, zclProfileHA, 30, zclFrame)
-	_, err := n.request(ctx, zbossCmdAPSDEDataReq, apsPayload)
-	return err
+	return n.apsDataRequest(ctx, req.DstAddr, apsPayload)
 }
 
 func (n *NRF52840NCP) SendCommand(ctx context.Context, req ClusterCommandRequest) error {
 	zclFrame := zclBuildClusterCommand(n.nextZCLSeq(), req.CommandID, req.Payload)
 	apsPayload := buildAPSDEDataReq(req.DstAddr, req.DstEP, 1, req.ClusterID, zclProfileHA, 30, zclFrame)
-	_, err := n.request(ctx, zbossCmdAPSDEDataReq, apsPayload)
-	return err
+	return n.apsDataRequest(ctx, req.DstAddr, apsPayload)
 }
 
 func (n *NRF52840NCP) ConfigureReporting(ctx context.Context, req ConfigureReportingRequest) error {
 	zclFrame := zclBuildConfigureReporting(n.nextZCLSeq(), req.AttrID, req.DataType, req.MinInterval, req.MaxInterval, req.ReportChange)
 	apsPayload := buildAPSDEDataReq(req.DstAddr, req.DstEP, 1, req.ClusterID, zclProfileHA, 30, zclFrame)
-	_, err := n.request(ctx, zbossCmdAPSDEDataReq, apsPayload)
-	return err
+	return n.apsDataRequest(ctx, req.DstAddr, apsPayload)
+}
+
+// SendZCLFrame sends a ZCL frame with caller-provided header fields
+// (sequence number, direction, manufacturer code) from a local endpoint.
+func (n *NRF52840NCP) SendZCLFrame(ctx context.Context, req ZCLFrameRequest) error {
+	srcEP := req.SrcEP
+	if srcEP == 0 {
+		srcEP = 1
+	}
+	apsPayload := buildAPSDEDataReq(req.DstAddr, req.DstEP, srcEP, req.ClusterID, zclProfileHA, 30, zclBuildFrame(req))
+	return n.apsDataRequest(ctx, req.DstAddr, apsPayload)
+}
+
+// apsWaiter receives the first incoming frame accepted by match.
+type apsWaiter struct {
+	match func(APSFrame) bool
+	ch    chan APSFrame
+}
+
+// SendAPS sends a raw APSDE_DATA_REQ. With a non-nil match it waits for the
+// first incoming frame the match accepts, until ctx is done.
+func (n *NRF52840NCP) SendAPS(ctx context.Context, req APSFrameRequest, match func(APSFrame) bool) (*APSFrame, error) {
+	srcEP := req.SrcEP
+	if srcEP == 0 {
+		srcEP = 1
+	}
+	radius := req.Radius
+	if radius == 0 {
+		radius = 30
+	}
+	apsPayload := buildAPSDEDataReq(req.DstAddr, req.DstEP, srcEP, req.ClusterID, req.ProfileID, radius, req.Payload)
+	if req.NoACK {
+		apsPayload[19] = 0x00 // tx_options: no APS ACK
+	}
+
+	if match == nil {
+		return nil, n.apsDataRequest(ctx, req.DstAddr, apsPayload)
+	}
+
+	// Register before sending so a fast response is not missed.
+	w := &apsWaiter{match: match, ch: make(chan APSFrame, 1)}
+	n.apsMu.Lock()
+	if n.apsWaiters == nil {
+		n.apsWaiters = make(map[*apsWaiter]struct{})
+	}
+	n.apsWaiters[w] = struct{}{}
+	n.apsMu.Unlock()
+	defer func() {
+		n.apsMu.Lock()
+		delete(n.apsWaiters, w)
+		n.apsMu.Unlock()
+	}()
+
+	if err := n.apsDataRequest(ctx, req.DstAddr, apsPayload); err != nil {
+		return nil, err
+	}
+
+	select {
+	case f := <-w.ch:
+		return &f, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-n.done:
+		return nil, fmt.Errorf("ncp closed")
+	}
+}
+
+// deliverAPS hands an incoming frame to the SendAPS waiters that accept it.
+// Each waiter gets at most one frame; normal dispatch is not affected.
+func (n *NRF52840NCP) deliverAPS(f APSFrame) {
+	n.apsMu.Lock()
+	defer n.apsMu.Unlock()
+	for w := range n.apsWaiters {
+		if !w.match(f) {
+			continue
+		}
+		frame := f
+		frame.Payload = append([]byte(nil), f.Payload...)
+		select {
+		case w.ch <- frame:
+		default:
+		}
+		delete(n.apsWaiters, w)
+	}
+}
+
+// RegisterLocalEndpoint adds or replaces a coordinator endpoint descriptor.
+// Takes effect on the next StartNetwork.
+func (n *NRF52840NCP) RegisterLocalEndpoint(desc SimpleDescriptor) {
+	n.handlerMu.Lock()
+	defer n.handlerMu.Unlock()
+	for i := range n.localEPs {
+		if n.localEPs[i].Endpoint == desc.Endpoint {
+			n.localEPs[i] = desc
+			return
+		}
+	}
+	n.localEPs = append(n.localEPs, desc)
 }
 
 // --- Indication callback setters ---
@@ -1180,6 +1407,35 @@ func (n *NRF52840NCP) OnNwkAddrUpdate(handler func(uint16)) {
 	n.onNwkAddrUpdate = handler
 }
 
+// OnGlobalCommand registers a handler for incoming global ZCL commands
+// (e.g., Read Attributes requests to coordinator endpoints).
+func (n *NRF52840NCP) OnGlobalCommand(handler func(GlobalCommandEvent)) {
+	n.handlerMu.Lock()
+	defer n.handlerMu.Unlock()
+	n.onGlobalCmd = handler
+}
+
+// Stats returns a snapshot of the transport counters.
+func (n *NRF52840NCP) Stats() Stats {
+	st := n.stats.snapshot()
+	st.DuplicateFrames = n.dedup.Dropped()
+	n.hlMu.Lock()
+	st.Pending = len(n.hlPending)
+	n.hlMu.Unlock()
+	n.zclMu.Lock()
+	st.Pending += len(n.zclPending)
+	n.zclMu.Unlock()
+	return st
+}
+
+// SetDefaultResponsePolicy installs the policy deciding which incoming
+// reports and cluster commands are acknowledged with a Default Response.
+func (n *NRF52840NCP) SetDefaultResponsePolicy(policy func(DefaultResponseInfo) bool) {
+	n.handlerMu.Lock()
+	defer n.handlerMu.Unlock()
+	n.defaultRespPolicy = policy
+}
+
 // OnNCPReset registers a callback for spontaneous NCP reset events.
 func (n *NRF52840NCP) OnNCPReset(handler func()) {
 	n.handlerMu.Lock()
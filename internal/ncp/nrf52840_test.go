@@ -320,3 +320,235 @@ func TestBuildSimpleDescPayload(t *testing.T) {
 		t.Errorf("total length: got %d, want %d", len(buf), 8+4+2)
 	}
 }
+
+func TestHandleAPSDEDataIndGlobalCommand(t *testing.T) {
+	// ZCL Read Attributes (client-to-server) on Time cluster, seq=0x21.
+	zclReq := []byte{
+		zclFrameTypeGlobal, // frame control
+		0x21,               // zcl seq
+		zclCmdReadAttributes,
+		0x00, 0x00, // Time
+		0x07, 0x00, // LocalTime
+	}
+
+	payload := make([]byte, 24+len(zclReq))
+	payload[0] = 21
+	binary.LittleEndian.PutUint16(payload[1:3], uint16(len(zclReq)))
+	binary.LittleEndian.PutUint16(payload[4:6], 0x4321)
+	payload[10] = 1 // dst_endpoint (coordinator)
+	payload[11] = 2 // src_endpoint
+	binary.LittleEndian.PutUint16(payload[12:14], 0x000A)
+	binary.LittleEndian.PutUint16(payload[14:16], zclProfileHA)
+	copy(payload[24:], zclReq)
+
+	n := &NRF52840NCP{
+		zclPending: make(map[uint8]chan []byte),
+	}
+	var got GlobalCommandEvent
+	called := false
+	n.OnGlobalCommand(func(evt GlobalCommandEvent) {
+		got = evt
+		called = true
+	})
+	n.handleAPSDEDataInd(payload, nil, nil)
+
+	if !called {
+		t.Fatal("global command handler not called")
+	}
+	if got.SrcAddr != 0x4321 || got.SrcEP != 2 || got.DstEP != 1 {
+		t.Errorf("addressing: got src=0x%04X/%d dst=%d", got.SrcAddr, got.SrcEP, got.DstEP)
+	}
+	if got.ClusterID != 0x000A || got.CommandID != zclCmdReadAttributes || got.Seq != 0x21 {
+		t.Errorf("header: cluster=0x%04X cmd=0x%02X seq=0x%02X", got.ClusterID, got.CommandID, got.Seq)
+	}
+	if got.ServerToClient || got.DisableDefaultResp || got.ManufacturerCode != 0 {
+		t.Errorf("flags: %+v", got)
+	}
+	if !bytes.Equal(got.Payload, []byte{0x00, 0x00, 0x07, 0x00}) {
+		t.Errorf("Payload: got %X", got.Payload)
+	}
+}
+
+func TestHandleAPSDEDataIndClusterCommandHeader(t *testing.T) {
+	// Manufacturer-specific server-to-client cluster command, mfr=0x115F, seq=0x33.
+	zclCmd := []byte{
+		zclFrameTypeCluster | zclFlagMfrSpecific | zclDirServerToClient | zclDisableDefaultResp,
+		0x5F, 0x11, // mfr code
+		0x33, // seq
+		0x01, // cmd
+		0xAA,
+	}
+
+	payload := make([]byte, 24+len(zclCmd))
+	payload[0] = 21
+	binary.LittleEndian.PutUint16(payload[1:3], uint16(len(zclCmd)))
+	binary.LittleEndian.PutUint16(payload[4:6], 0x1111)
+	payload[10] = 1
+	payload[11] = 3
+	binary.LittleEndian.PutUint16(payload[12:14], 0x0500)
+	binary.LittleEndian.PutUint16(payload[14:16], zclProfileHA)
+	copy(payload[24:], zclCmd)
+
+	n := &NRF52840NCP{zclPending: make(map[uint8]chan []byte)}
+	var got ClusterCommandEvent
+	n.handleAPSDEDataInd(payload, nil, func(evt ClusterCommandEvent) { got = evt })
+
+	if got.Seq != 0x33 || got.CommandID != 0x01 || got.DstEP != 1 {
+		t.Errorf("header: seq=0x%02X cmd=0x%02X dstEP=%d", got.Seq, got.CommandID, got.DstEP)
+	}
+	if !got.ServerToClient || !got.DisableDefaultResp || got.ManufacturerCode != 0x115F {
+		t.Errorf("flags: %+v", got)
+	}
+	if !bytes.Equal(got.Payload, []byte{0xAA}) {
+		t.Errorf("Payload: got %X", got.Payload)
+	}
+}
+
+func TestZCLBuildFrame(t *testing.T) {
+	buf := zclBuildFrame(ZCLFrameRequest{
+		Global:             true,
+		Seq:                0x21,
+		ServerToClient:     true,
+		DisableDefaultResp: true,
+		CommandID:          zclCmdReadAttributesRsp,
+		Payload:            []byte{0x08, 0x00, 0x86},
+	})
+	want := []byte{
+		zclFrameTypeGlobal | zclDirServerToClient | zclDisableDefaultResp,
+		0x21, zclCmdReadAttributesRsp, 0x08, 0x00, 0x86,
+	}
+	if !bytes.Equal(buf, want) {
+		t.Errorf("global: got %X, want %X", buf, want)
+	}
+
+	buf = zclBuildFrame(ZCLFrameRequest{Seq: 0x05, CommandID: 0x02, ManufacturerCode: 0x1037, Payload: []byte{0x98}})
+	want = []byte{zclFrameTypeCluster | zclFlagMfrSpecific, 0x37, 0x10, 0x05, 0x02, 0x98}
+	if !bytes.Equal(buf, want) {
+		t.Errorf("mfr cluster: got %X, want %X", buf, want)
+	}
+}
+
+// apsDataInd builds an APSDE_DATA_IND payload carrying a ZCL frame.
+func apsDataInd(apsFC uint8, src, dst uint16, cluster uint16, zclFrame []byte) []byte {
+	payload := make([]byte, 24+len(zclFrame))
+	payload[0] = 21
+	binary.LittleEndian.PutUint16(payload[1:3], uint16(len(zclFrame)))
+	payload[3] = apsFC
+	binary.LittleEndian.PutUint16(payload[4:6], src)
+	binary.LittleEndian.PutUint16(payload[6:8], dst)
+	payload[10] = 1
+	payload[11] = 2
+	binary.LittleEndian.PutUint16(payload[12:14], cluster)
+	binary.LittleEndian.PutUint16(payload[14:16], zclProfileHA)
+	copy(payload[24:], zclFrame)
+	return payload
+}
+
+func TestHandleAPSDEDataIndDefaultResponsePolicy(t *testing.T) {
+	// Manufacturer-specific report from server, seq=0x44, uint8 attr 0x0000 = 1.
+	report := []byte{
+		zclFrameTypeGlobal | zclFlagMfrSpecific | zclDirServerToClient,
+		0x5F, 0x11, 0x44, zclCmdReportAttributes,
+		0x00, 0x00, 0x20, 0x01,
+	}
+	noAck := append([]byte(nil), report...)
+	noAck[0] |= zclDisableDefaultResp
+	command := []byte{zclFrameTypeCluster, 0x45, 0x02}
+
+	tests := []struct {
+		name  string
+		apsFC uint8
+		dst   uint16
+		frame []byte
+		want  *DefaultResponseInfo
+	}{
+		{"report", 0, 0x0000, report, &DefaultResponseInfo{
+			SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000, CommandID: zclCmdReportAttributes,
+			Global: true, Seq: 0x44, ServerToClient: true, ManufacturerCode: 0x115F,
+		}},
+		{"cluster command", 0, 0x0000, command, &DefaultResponseInfo{
+			SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0x0000, CommandID: 0x02, Seq: 0x45,
+		}},
+		{"disabled", 0, 0x0000, noAck, nil},
+		{"broadcast", 0x08, 0xFFFD, report, nil},
+		{"group", 0x0C, 0x0000, command, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &NRF52840NCP{zclPending: make(map[uint8]chan []byte)}
+			var got *DefaultResponseInfo
+			n.SetDefaultResponsePolicy(func(info DefaultResponseInfo) bool {
+				got = &info
+				return false // nothing to send to in tests
+			})
+			n.handleAPSDEDataInd(apsDataInd(tt.apsFC, 0x1234, tt.dst, 0x0000, tt.frame),
+				func(AttributeReportEvent) {}, func(ClusterCommandEvent) {})
+
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("policy called: %+v", *got)
+			case tt.want != nil && got == nil:
+				t.Error("policy not called")
+			case tt.want != nil && *got != *tt.want:
+				t.Errorf("info: got %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestDefaultResponseFrame(t *testing.T) {
+	info := DefaultResponseInfo{
+		SrcAddr: 0x1234, SrcEP: 2, DstEP: 1, ClusterID: 0xFCC0, CommandID: zclCmdReportAttributes,
+		Global: true, Seq: 0x44, ServerToClient: true, ManufacturerCode: 0x115F,
+	}
+	req := info.DefaultResponse()
+	if req.DstAddr != 0x1234 || req.DstEP != 2 || req.SrcEP != 1 || req.ClusterID != 0xFCC0 {
+		t.Errorf("addressing: %+v", req)
+	}
+	want := []byte{
+		zclFrameTypeGlobal | zclFlagMfrSpecific | zclDisableDefaultResp,
+		0x5F, 0x11, 0x44, zclCmdDefaultResponse, zclCmdReportAttributes, 0x00,
+	}
+	if got := zclBuildFrame(req); !bytes.Equal(got, want) {
+		t.Errorf("frame: got %X, want %X", got, want)
+	}
+}
+
+func TestDeliverAPSWaiters(t *testing.T) {
+	n := &NRF52840NCP{zclPending: make(map[uint8]chan []byte)}
+	match := &apsWaiter{
+		match: func(f APSFrame) bool { return f.SrcAddr == 0x1234 && f.ClusterID == 0x1000 },
+		ch:    make(chan APSFrame, 1),
+	}
+	other := &apsWaiter{
+		match: func(f APSFrame) bool { return f.SrcAddr == 0x5678 },
+		ch:    make(chan APSFrame, 1),
+	}
+	n.apsWaiters = map[*apsWaiter]struct{}{match: {}, other: {}}
+
+	var cmds int
+	payload := apsDataInd(0, 0x1234, 0x0000, 0x1000, []byte{zclFrameTypeCluster | zclDisableDefaultResp, 0x07, 0x01, 0xAA})
+	n.handleAPSDEDataInd(payload, nil, func(ClusterCommandEvent) { cmds++ })
+
+	select {
+	case f := <-match.ch:
+		if f.ProfileID != zclProfileHA || f.SrcEP != 2 || !bytes.Equal(f.Payload, []byte{0x11, 0x07, 0x01, 0xAA}) {
+			t.Errorf("frame: %+v", f)
+		}
+		payload[24] = 0 // the waiter must get a copy
+		if f.Payload[0] != 0x11 {
+			t.Error("frame payload aliases the indication buffer")
+		}
+	default:
+		t.Fatal("matching waiter got no frame")
+	}
+	if len(other.ch) != 0 {
+		t.Error("non-matching waiter got a frame")
+	}
+	if _, ok := n.apsWaiters[match]; ok {
+		t.Error("satisfied waiter not removed")
+	}
+	if cmds != 1 {
+		t.Errorf("cluster command dispatched %d times, want 1", cmds)
+	}
+}
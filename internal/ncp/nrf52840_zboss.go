@@ -5,9 +5,13 @@ package ncp
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 )
 
+// errZBOSSBadCRC marks frames rejected by the LL CRC8 or body CRC16 check.
+var errZBOSSBadCRC = errors.New("zboss: CRC mismatch")
+
 // --- LL (Low-Level) header constants ---
 
 const (
@@ -251,6 +255,7 @@ const (
 	zclCmdWriteAttributes    = 0x02
 	zclCmdConfigReporting    = 0x06
 	zclCmdReportAttributes   = 0x0A
+	zclCmdDefaultResponse    = 0x0B
 )
 
 // HA profile ID.
@@ -406,7 +411,7 @@ func zbossDecodeFrame(data []byte) (*zbossFrame, error) {
 	llCRC := data[6]
 
 	if got := zbossCRC8(data[2:6]); llCRC != got {
-		return nil, fmt.Errorf("zboss: LL CRC8 mismatch: got 0x%02X, want 0x%02X", llCRC, got)
+		return nil, fmt.Errorf("%w: LL CRC8 got 0x%02X, want 0x%02X", errZBOSSBadCRC, llCRC, got)
 	}
 
 	if llType != zbossLLType {
@@ -439,7 +444,7 @@ func zbossDecodeFrame(data []byte) (*zbossFrame, error) {
 	bodyCRC := binary.LittleEndian.Uint16(body[0:2])
 	hlData := body[2:]
 	if got := zbossCRC16(hlData); bodyCRC != got {
-		return nil, fmt.Errorf("zboss: body CRC16 mismatch: got 0x%04X, want 0x%04X", bodyCRC, got)
+		return nil, fmt.Errorf("%w: body CRC16 got 0x%04X, want 0x%04X", errZBOSSBadCRC, bodyCRC, got)
 	}
 
 	if len(hlData) < 4 {
@@ -494,6 +499,27 @@ func zclBuildReadAttributes(seqNum uint8, attrIDs []uint16) []byte {
 	return buf
 }
 
+// zclBuildFrame builds a ZCL frame with the header fields from req.
+func zclBuildFrame(req ZCLFrameRequest) []byte {
+	fc := uint8(zclFrameTypeCluster)
+	if req.Global {
+		fc = zclFrameTypeGlobal
+	}
+	if req.ServerToClient {
+		fc |= zclDirServerToClient
+	}
+	if req.DisableDefaultResp {
+		fc |= zclDisableDefaultResp
+	}
+	buf := []byte{fc}
+	if req.ManufacturerCode != 0 {
+		buf[0] |= zclFlagMfrSpecific
+		buf = append(buf, byte(req.ManufacturerCode), byte(req.ManufacturerCode>>8))
+	}
+	buf = append(buf, req.Seq, req.CommandID)
+	return append(buf, req.Payload...)
+}
+
 // zclBuildWriteAttributes builds a ZCL Write Attributes frame.
 func zclBuildWriteAttributes(seqNum uint8, records []WriteRecord) []byte {
 	buf := []byte{
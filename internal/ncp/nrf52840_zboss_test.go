@@ -3,6 +3,7 @@ package ncp
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"testing"
 )
 
@@ -108,8 +109,8 @@ func TestDecodeFrameBadCRC8(t *testing.T) {
 	encoded := zbossEncodeACK(0)
 	encoded[6] ^= 0xFF // corrupt CRC8
 	_, err := zbossDecodeFrame(encoded)
-	if err == nil {
-		t.Error("expected CRC8 error")
+	if !errors.Is(err, errZBOSSBadCRC) {
+		t.Errorf("expected CRC8 error, got %v", err)
 	}
 }
 
@@ -118,8 +119,8 @@ func TestDecodeFrameBadCRC16(t *testing.T) {
 	// Corrupt body CRC16 (at offset 7).
 	encoded[7] ^= 0xFF
 	_, err := zbossDecodeFrame(encoded)
-	if err == nil {
-		t.Error("expected CRC16 error")
+	if !errors.Is(err, errZBOSSBadCRC) {
+		t.Errorf("expected CRC16 error, got %v", err)
 	}
 }
 
@@ -0,0 +1,62 @@
+package ncp
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// ncpStats collects transport counters for an NCP backend. The zero value is
+// ready to use.
+type ncpStats struct {
+	framesSent     atomic.Uint64
+	framesReceived atomic.Uint64
+	retransmits    atomic.Uint64
+	ackTimeouts    atomic.Uint64
+	crcErrors      atomic.Uint64
+	decodeErrors   atomic.Uint64
+
+	mu          sync.Mutex
+	apsFailures map[uint16]uint64 // destination short address -> failed APSDE-DATA requests
+	unhandled   map[string]uint64 // indication name -> count
+}
+
+func (s *ncpStats) apsFailure(dst uint16) {
+	s.mu.Lock()
+	if s.apsFailures == nil {
+		s.apsFailures = make(map[uint16]uint64)
+	}
+	s.apsFailures[dst]++
+	s.mu.Unlock()
+}
+
+func (s *ncpStats) unhandledIndication(name string) {
+	s.mu.Lock()
+	if s.unhandled == nil {
+		s.unhandled = make(map[string]uint64)
+	}
+	s.unhandled[name]++
+	s.mu.Unlock()
+}
+
+// snapshot copies the counters into a Stats value.
+func (s *ncpStats) snapshot() Stats {
+	st := Stats{
+		FramesSent:           s.framesSent.Load(),
+		FramesReceived:       s.framesReceived.Load(),
+		Retransmits:          s.retransmits.Load(),
+		ACKTimeouts:          s.ackTimeouts.Load(),
+		CRCErrors:            s.crcErrors.Load(),
+		DecodeErrors:         s.decodeErrors.Load(),
+		APSFailures:          make(map[uint16]uint64),
+		UnhandledIndications: make(map[string]uint64),
+	}
+	s.mu.Lock()
+	for k, v := range s.apsFailures {
+		st.APSFailures[k] = v
+	}
+	for k, v := range s.unhandled {
+		st.UnhandledIndications[k] = v
+	}
+	s.mu.Unlock()
+	return st
+}
@@ -0,0 +1,84 @@
+package ncp
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"go.bug.st/serial"
+)
+
+// ackPort is a serial port that acknowledges the nth write.
+type ackPort struct {
+	serial.Port
+	n      *NRF52840NCP
+	ackOn  int
+	writes int
+}
+
+func (p *ackPort) Write(b []byte) (int, error) {
+	p.writes++
+	if p.writes == p.ackOn {
+		p.n.llAckCh <- zbossLLPktSeq(b[5])
+	}
+	return len(b), nil
+}
+
+func TestStatsRetransmit(t *testing.T) {
+	n := &NRF52840NCP{
+		llAckCh: make(chan uint8, 4),
+		done:    make(chan struct{}),
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	n.port = &ackPort{n: n, ackOn: 2}
+
+	pktSeq := n.nextPktSeq()
+	if err := n.writeWithACK(context.Background(), zbossEncodeRequest(0x0001, 1, pktSeq, nil), pktSeq); err != nil {
+		t.Fatalf("writeWithACK: %v", err)
+	}
+	st := n.Stats()
+	if st.FramesSent != 1 || st.Retransmits != 1 || st.ACKTimeouts != 1 {
+		t.Errorf("stats = %+v, want 1 sent, 1 retransmit, 1 ACK timeout", st)
+	}
+}
+
+func TestStatsUnhandledIndication(t *testing.T) {
+	n := &NRF52840NCP{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	f := &zbossFrame{}
+	f.HL.CallID = 0x0FFF
+	n.handleIndication(f)
+	n.handleIndication(f)
+
+	st := n.Stats()
+	if got := st.UnhandledIndications[zbossCmdName(0x0FFF)]; got != 2 {
+		t.Errorf("unhandled = %v, want 2 for %s", st.UnhandledIndications, zbossCmdName(0x0FFF))
+	}
+}
+
+func TestStatsAPSFailuresSnapshot(t *testing.T) {
+	var s ncpStats
+	s.apsFailure(0x1234)
+	s.apsFailure(0x1234)
+	s.apsFailure(0x5678)
+
+	st := s.snapshot()
+	if st.APSFailures[0x1234] != 2 || st.APSFailures[0x5678] != 1 {
+		t.Errorf("aps failures = %v", st.APSFailures)
+	}
+	// The snapshot must not alias the live map.
+	st.APSFailures[0x1234] = 99
+	if got := s.snapshot().APSFailures[0x1234]; got != 2 {
+		t.Errorf("snapshot aliases live counters: got %d", got)
+	}
+}
+
+func TestStatsPending(t *testing.T) {
+	n := &NRF52840NCP{
+		hlPending:  map[uint8]chan *zbossFrame{1: nil, 2: nil},
+		zclPending: map[uint8]chan []byte{7: nil},
+	}
+	if got := n.Stats().Pending; got != 3 {
+		t.Errorf("pending = %d, want 3", got)
+	}
+}
@@ -12,6 +12,7 @@ var (
 	bucketDevices = []byte("devices")
 	bucketNetwork = []byte("network")
 	keyNetState   = []byte("state")
+	keyAlarmPanel = []byte("alarm_panel")
 )
 
 // BoltStore implements Store using BoltDB.
@@ -184,6 +185,39 @@ func (s *BoltStore) GetNetworkState() (*NetworkState, error) {
 	return &state, nil
 }
 
+func (s *BoltStore) SaveAlarmPanel(state *AlarmPanelState) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketNetwork)
+		if b == nil {
+			return fmt.Errorf("bucket %q not found", bucketNetwork)
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+		return b.Put(keyAlarmPanel, data)
+	})
+}
+
+func (s *BoltStore) GetAlarmPanel() (*AlarmPanelState, error) {
+	var state AlarmPanelState
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketNetwork)
+		if b == nil {
+			return fmt.Errorf("bucket %q not found", bucketNetwork)
+		}
+		data := b.Get(keyAlarmPanel)
+		if data == nil {
+			return fmt.Errorf("alarm panel state: %w", ErrNotFound)
+		}
+		return json.Unmarshal(data, &state)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
 func (s *BoltStore) Close() error {
 	return s.db.Close()
 }
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"errors"
 	"path/filepath"
 	"testing"
 	"time"
@@ -162,3 +163,28 @@ func TestSaveAndGetNetworkState(t *testing.T) {
 		t.Error("formed = false, want true")
 	}
 }
+
+func TestSaveAndGetAlarmPanel(t *testing.T) {
+	s := newTestStore(t)
+
+	if _, err := s.GetAlarmPanel(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("empty store: err = %v, want ErrNotFound", err)
+	}
+
+	state := &AlarmPanelState{State: "armed_away", Mode: "away", Changed: time.Now().Truncate(time.Second)}
+	if err := s.SaveAlarmPanel(state); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetAlarmPanel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != state.State || got.Mode != state.Mode || !got.Changed.Equal(state.Changed) {
+		t.Errorf("got %+v, want %+v", got, state)
+	}
+
+	// Network state shares the bucket and must be unaffected.
+	if _, err := s.GetNetworkState(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("network state: err = %v, want ErrNotFound", err)
+	}
+}
@@ -4,18 +4,70 @@ import "time"
 
 // Device represents a Zigbee device.
 type Device struct {
-	IEEEAddress  string            `json:"ieee_address"`
-	ShortAddress uint16            `json:"short_address"`
-	Manufacturer string            `json:"manufacturer,omitempty"`
-	Model        string            `json:"model,omitempty"`
-	FriendlyName string            `json:"friendly_name,omitempty"`
-	Endpoints    []Endpoint        `json:"endpoints,omitempty"`
-	Interviewed  bool              `json:"interviewed"`
-	JoinedAt     time.Time         `json:"joined_at"`
-	LastSeen     time.Time         `json:"last_seen"`
-	LQI          uint8             `json:"lqi,omitempty"`
-	RSSI         int8              `json:"rssi,omitempty"`
-	Properties   map[string]any    `json:"properties,omitempty"`
+	IEEEAddress  string             `json:"ieee_address"`
+	ShortAddress uint16             `json:"short_address"`
+	Manufacturer string             `json:"manufacturer,omitempty"`
+	Model        string             `json:"model,omitempty"`
+	FriendlyName string             `json:"friendly_name,omitempty"`
+	Endpoints    []Endpoint         `json:"endpoints,omitempty"`
+	Interviewed  bool               `json:"interviewed"`
+	JoinedAt     time.Time          `json:"joined_at"`
+	LastSeen     time.Time          `json:"last_seen"`
+	LQI          uint8              `json:"lqi,omitempty"`
+	RSSI         int8               `json:"rssi,omitempty"`
+	Properties   map[string]any     `json:"properties,omitempty"`
+	IASZone      *IASZoneState      `json:"ias_zone,omitempty"`
+	PollControl  *PollControlState  `json:"poll_control,omitempty"`
+	PowerSource  string             `json:"power_source,omitempty"` // "mains" or "battery", from the announce capability
+	Availability *AvailabilityState `json:"availability,omitempty"`
+	Interview    *InterviewState    `json:"interview,omitempty"`
+	Options      map[string]float64 `json:"options,omitempty"` // e.g. "temperature_calibration": -1.5
+}
+
+// IASZoneState holds IAS Zone enrollment state for a device.
+type IASZoneState struct {
+	Endpoint      uint8     `json:"endpoint"`
+	ZoneType      uint16    `json:"zone_type"`
+	ZoneID        uint8     `json:"zone_id"`
+	CIEAddressSet bool      `json:"cie_address_set"`
+	Enrolled      bool      `json:"enrolled"`
+	EnrolledAt    time.Time `json:"enrolled_at,omitempty"`
+}
+
+// PollControlState holds Poll Control check-in state for a sleepy device.
+type PollControlState struct {
+	Endpoint    uint8     `json:"endpoint"`
+	Configured  bool      `json:"configured"`
+	LastCheckIn time.Time `json:"last_check_in,omitempty"`
+}
+
+// AvailabilityState is the last availability verdict for a device.
+type AvailabilityState struct {
+	State   string    `json:"state"` // "online" or "offline"
+	Changed time.Time `json:"changed"`
+}
+
+// InterviewState is the persisted progress of a device interview, so an
+// interview interrupted by a sleepy device resumes where it stopped.
+type InterviewState struct {
+	Stage       string            `json:"stage"`             // step to resume from, or "complete"
+	Runs        int               `json:"runs"`              // interview runs so far
+	GaveUp      bool              `json:"gave_up,omitempty"` // no more automatic resumes
+	LastError   string            `json:"last_error,omitempty"`
+	Updated     time.Time         `json:"updated"`
+	EndpointIDs []int             `json:"endpoint_ids,omitempty"` // from Active Endpoints
+	Descriptors []Endpoint        `json:"descriptors,omitempty"`  // simple descriptors read so far
+	Basic       bool              `json:"basic_attributes"`       // manufacturer and model read
+	Configure   map[string]string `json:"configure,omitempty"`    // "bind/1/0x0006" -> "ok" or error
+}
+
+// AlarmPanelState is the persisted state of the alarm panel.
+type AlarmPanelState struct {
+	State    string    `json:"state"`
+	Mode     string    `json:"mode,omitempty"`
+	Alarm    string    `json:"alarm,omitempty"`
+	Changed  time.Time `json:"changed"`
+	Deadline time.Time `json:"deadline,omitempty"`
 }
 
 // Endpoint represents a device endpoint.
@@ -21,6 +21,10 @@ type Store interface {
 	SaveNetworkState(state *NetworkState) error
 	GetNetworkState() (*NetworkState, error)
 
+	// Alarm panel state
+	SaveAlarmPanel(state *AlarmPanelState) error
+	GetAlarmPanel() (*AlarmPanelState, error)
+
 	// Close the store
 	Close() error
 }
@@ -1,11 +1,18 @@
 package web
 
 import (
+	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"zigbee-go-home/internal/coordinator"
 	"zigbee-go-home/internal/store"
 )
 
@@ -79,6 +86,42 @@ func (s *Server) handleAPIDeleteDevice(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// handleAPIReinterview re-runs the full interview in the background.
+// Progress is reported as interview_progress events.
+func (s *Server) handleAPIReinterview(w http.ResponseWriter, r *http.Request) {
+	ieee := r.PathValue("ieee")
+	if err := s.coord.Devices().Reinterview(ieee); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+			return
+		}
+		s.logger.Error("reinterview device", "err", err, "ieee", ieee)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return
+	}
+	s.writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+}
+
+// handleAPIReconfigure re-applies the bindings and reporting of the device
+// definition in the background.
+func (s *Server) handleAPIReconfigure(w http.ResponseWriter, r *http.Request) {
+	ieee := r.PathValue("ieee")
+	err := s.coord.Devices().Reconfigure(ieee)
+	switch {
+	case err == nil:
+		s.writeJSON(w, http.StatusAccepted, map[string]string{"status": "started"})
+	case errors.Is(err, coordinator.ErrCommandQueued):
+		s.writeJSON(w, http.StatusAccepted, map[string]string{"status": "queued"})
+	case errors.Is(err, store.ErrNotFound):
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+	case errors.Is(err, coordinator.ErrNotInterviewed), errors.Is(err, coordinator.ErrNoDefinition):
+		s.writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+	default:
+		s.logger.Error("reconfigure device", "err", err, "ieee", ieee)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
+
 type readAttributesRequest struct {
 	Endpoint  uint8    `json:"endpoint"`
 	ClusterID uint16   `json:"cluster_id"`
@@ -151,7 +194,10 @@ func (s *Server) handleAPIWriteAttribute(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := s.coord.WriteAttribute(r.Context(), dev.ShortAddress, req.Endpoint, req.ClusterID, req.AttrID, req.DataType, req.Value); err != nil {
+	if err := s.coord.WriteAttribute(r.Context(), dev.ShortAddress, req.Endpoint, req.ClusterID, req.AttrID, req.DataType, req.Value); errors.Is(err, coordinator.ErrCommandQueued) {
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"status": "queued", "pending": s.coord.Devices().PendingCommands(dev.IEEEAddress)})
+		return
+	} else if err != nil {
 		s.logger.Error("write attribute", "err", err, "ieee", ieee)
 		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
 		return
@@ -191,7 +237,10 @@ func (s *Server) handleAPISendCommand(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.coord.SendClusterCommand(r.Context(), dev.ShortAddress, req.Endpoint, req.ClusterID, req.CommandID, req.Payload); err != nil {
+	if err := s.coord.SendClusterCommand(r.Context(), dev.ShortAddress, req.Endpoint, req.ClusterID, req.CommandID, req.Payload); errors.Is(err, coordinator.ErrCommandQueued) {
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"status": "queued", "pending": s.coord.Devices().PendingCommands(dev.IEEEAddress)})
+		return
+	} else if err != nil {
 		s.logger.Error("send command", "err", err, "ieee", ieee)
 		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
 		return
@@ -200,6 +249,202 @@ func (s *Server) handleAPISendCommand(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+type tuyaWriteRequest struct {
+	Property string `json:"property"`
+	Value    any    `json:"value"`
+}
+
+func (s *Server) handleAPITuyaWrite(w http.ResponseWriter, r *http.Request) {
+	ieee := r.PathValue("ieee")
+	var req tuyaWriteRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Property == "" {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	err := s.coord.WriteTuyaDP(r.Context(), ieee, req.Property, req.Value)
+	switch {
+	case err == nil:
+		s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case errors.Is(err, coordinator.ErrCommandQueued):
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"status": "queued", "pending": s.coord.Devices().PendingCommands(ieee)})
+	case errors.Is(err, store.ErrNotFound):
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+	case errors.Is(err, coordinator.ErrUnknownProperty):
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown property"})
+	case errors.Is(err, coordinator.ErrPropertyReadOnly), errors.Is(err, coordinator.ErrInvalidPropertyValue):
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		s.logger.Error("tuya write", "err", err, "ieee", ieee)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
+
+// handleAPISetProperties sets named properties, e.g. {"on_off": true,
+// "brightness": 128}, in name order. It stops at the first error; the
+// properties set before it stay set.
+func (s *Server) handleAPISetProperties(w http.ResponseWriter, r *http.Request) {
+	ieee := r.PathValue("ieee")
+	var req map[string]any
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req) == 0 {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	queued := false
+	for _, name := range slices.Sorted(maps.Keys(req)) {
+		err := s.coord.SetProperty(r.Context(), ieee, name, req[name])
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, coordinator.ErrCommandQueued):
+			queued = true
+			continue
+		case errors.Is(err, store.ErrNotFound):
+			s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+		case errors.Is(err, coordinator.ErrUnknownProperty):
+			s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown property: " + name})
+		case errors.Is(err, coordinator.ErrPropertyReadOnly), errors.Is(err, coordinator.ErrInvalidPropertyValue):
+			s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": name + ": " + err.Error()})
+		default:
+			s.logger.Error("set property", "err", err, "ieee", ieee, "property", name)
+			s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		}
+		return
+	}
+	if queued {
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"status": "queued", "pending": s.coord.Devices().PendingCommands(ieee)})
+		return
+	}
+	s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleAPISetDeviceOptions replaces the options of a device, e.g.
+// {"temperature_calibration": -1.5, "temperature_precision": 1}. An empty
+// object removes them.
+func (s *Server) handleAPISetDeviceOptions(w http.ResponseWriter, r *http.Request) {
+	ieee := r.PathValue("ieee")
+	var req map[string]float64
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	err := s.coord.SetDeviceOptions(ieee, req)
+	switch {
+	case err == nil:
+	case errors.Is(err, store.ErrNotFound):
+		s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+		return
+	case errors.Is(err, coordinator.ErrInvalidDeviceOption):
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	default:
+		s.logger.Error("set device options", "err", err, "ieee", ieee)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return
+	}
+	if req == nil {
+		req = map[string]float64{}
+	}
+	s.writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "options": req})
+}
+
+type rawAPSRequest struct {
+	Endpoint    uint8   `json:"endpoint"`
+	SrcEndpoint uint8   `json:"src_endpoint,omitempty"`
+	ProfileID   *uint16 `json:"profile_id,omitempty"` // default HA (0x0104)
+	ClusterID   uint16  `json:"cluster_id"`
+	Radius      uint8   `json:"radius,omitempty"`
+	ACK         *bool   `json:"ack,omitempty"` // default true
+	Payload     string  `json:"payload"`       // hex
+	Wait        bool    `json:"wait,omitempty"`
+	ZCL         *bool   `json:"zcl,omitempty"`     // default true
+	Timeout     float64 `json:"timeout,omitempty"` // seconds to wait, default 10
+}
+
+const (
+	maxRawAPSPayload = 100
+	maxRawAPSTimeout = 60 * time.Second
+)
+
+func (s *Server) handleAPIRawAPS(w http.ResponseWriter, r *http.Request) {
+	ieee := r.PathValue("ieee")
+	dev, err := s.coord.Devices().GetDevice(ieee)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			s.writeJSON(w, http.StatusNotFound, map[string]string{"error": "device not found"})
+			return
+		}
+		s.logger.Error("get device for raw aps", "err", err, "ieee", ieee)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return
+	}
+
+	var req rawAPSRequest
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	payload, err := hex.DecodeString(strings.ReplaceAll(req.Payload, " ", ""))
+	if err != nil {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "payload must be hex"})
+		return
+	}
+	if len(payload) > maxRawAPSPayload {
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("payload limited to %d bytes", maxRawAPSPayload)})
+		return
+	}
+
+	aps := coordinator.RawAPSRequest{
+		ShortAddr:    dev.ShortAddress,
+		DstEP:        req.Endpoint,
+		SrcEP:        req.SrcEndpoint,
+		ProfileID:    0x0104,
+		ClusterID:    req.ClusterID,
+		Radius:       req.Radius,
+		NoACK:        req.ACK != nil && !*req.ACK,
+		Payload:      payload,
+		WaitResponse: req.Wait,
+		ZCL:          req.ZCL == nil || *req.ZCL,
+	}
+	if req.ProfileID != nil {
+		aps.ProfileID = *req.ProfileID
+	}
+
+	ctx := r.Context()
+	if req.Wait {
+		timeout := 10 * time.Second
+		if req.Timeout > 0 {
+			timeout = min(time.Duration(req.Timeout*float64(time.Second)), maxRawAPSTimeout)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	rsp, err := s.coord.SendRawAPS(ctx, aps)
+	switch {
+	case err == nil && rsp == nil:
+		s.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	case err == nil:
+		s.writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "response": rsp})
+	case errors.Is(err, coordinator.ErrCommandQueued):
+		s.writeJSON(w, http.StatusAccepted, map[string]any{"status": "queued", "pending": s.coord.Devices().PendingCommands(dev.IEEEAddress)})
+	case errors.Is(err, coordinator.ErrShortZCLPayload):
+		s.writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, context.DeadlineExceeded):
+		s.writeJSON(w, http.StatusGatewayTimeout, map[string]string{"error": "no response"})
+	default:
+		s.logger.Error("raw aps", "err", err, "ieee", ieee)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+	}
+}
+
 func (s *Server) handleAPINetworkInfo(w http.ResponseWriter, r *http.Request) {
 	info := s.coord.NetworkInfo()
 	s.writeJSON(w, http.StatusOK, info)
@@ -234,6 +479,20 @@ func (s *Server) handleAPIListClusters(w http.ResponseWriter, r *http.Request) {
 	s.writeJSON(w, http.StatusOK, clusters)
 }
 
+func (s *Server) handleAPIReloadDefinitions(w http.ResponseWriter, r *http.Request) {
+	result, err := s.coord.ReloadDefinitions()
+	if err != nil {
+		if errors.Is(err, coordinator.ErrNoDevicesDir) {
+			s.writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		s.logger.Error("reload device definitions", "err", err)
+		s.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return
+	}
+	s.writeJSON(w, http.StatusOK, result)
+}
+
 func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -9,12 +9,15 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"zigbee-go-home/internal/coordinator"
 	"zigbee-go-home/internal/ncp"
 	"zigbee-go-home/internal/store"
 	"zigbee-go-home/internal/zcl"
+	"zigbee-go-home/internal/zcl/clusters"
 )
 
 // stubNCP implements ncp.NCP with minimal stubs for testing.
@@ -24,6 +27,9 @@ type stubNCP struct {
 	readAttrsErr  error
 	sendCmdErr    error
 	writeAttrErr  error
+	stats         ncp.Stats
+	apsSent       []ncp.APSFrameRequest
+	apsFrame      *ncp.APSFrame
 }
 
 func (s *stubNCP) Reset(context.Context) error                                { return nil }
@@ -47,6 +53,8 @@ func (s *stubNCP) OnDeviceAnnounce(func(ncp.DeviceAnnounceEvent))            {}
 func (s *stubNCP) OnAttributeReport(func(ncp.AttributeReportEvent))          {}
 func (s *stubNCP) OnClusterCommand(func(ncp.ClusterCommandEvent))            {}
 func (s *stubNCP) OnNwkAddrUpdate(func(uint16))                              {}
+func (s *stubNCP) OnGlobalCommand(func(ncp.GlobalCommandEvent))              {}
+func (s *stubNCP) RegisterLocalEndpoint(ncp.SimpleDescriptor)                {}
 func (s *stubNCP) GetNCPInfo() *ncp.NCPInfo                                  { return nil }
 func (s *stubNCP) Close() error                                              { return nil }
 
@@ -63,6 +71,20 @@ func (s *stubNCP) SendCommand(_ context.Context, _ ncp.ClusterCommandRequest) er
 func (s *stubNCP) ConfigureReporting(context.Context, ncp.ConfigureReportingRequest) error {
 	return nil
 }
+func (s *stubNCP) SendZCLFrame(context.Context, ncp.ZCLFrameRequest) error { return nil }
+func (s *stubNCP) SendAPS(ctx context.Context, req ncp.APSFrameRequest, match func(ncp.APSFrame) bool) (*ncp.APSFrame, error) {
+	s.apsSent = append(s.apsSent, req)
+	if match == nil {
+		return nil, nil
+	}
+	if s.apsFrame != nil && match(*s.apsFrame) {
+		return s.apsFrame, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+func (s *stubNCP) SetDefaultResponsePolicy(func(ncp.DefaultResponseInfo) bool) {}
+func (s *stubNCP) Stats() ncp.Stats { return s.stats }
 
 func setupTestServer(t *testing.T, apiKey string) (*Server, *store.BoltStore, *stubNCP) {
 	t.Helper()
@@ -182,6 +204,66 @@ func TestAPIDeleteDevice(t *testing.T) {
 	}
 }
 
+func TestAPIReinterview(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+
+	done := make(chan map[string]interface{}, 1)
+	unsub := srv.coord.Events().On(coordinator.EventInterviewProgress, func(e coordinator.Event) {
+		if data := e.Data.(map[string]interface{}); data["step"] == coordinator.StepComplete {
+			done <- data
+		}
+	})
+	defer unsub()
+
+	req := httptest.NewRequest("POST", "/api/devices/00158D00012A3B4C/interview", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	select {
+	case data := <-done:
+		if data["operation"] != "interview" || data["status"] != coordinator.ProgressOK {
+			t.Errorf("complete event = %v", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("interview did not complete")
+	}
+
+	req = httptest.NewRequest("POST", "/api/devices/FFFFFFFFFFFFFFFF/interview", nil)
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown device: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAPIReconfigureErrors(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+
+	tests := []struct {
+		name string
+		ieee string
+		want int
+	}{
+		{"unknown device", "FFFFFFFFFFFFFFFF", http.StatusNotFound},
+		{"no endpoints", "00158D00012A3B4C", http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/devices/"+tt.ieee+"/configure", nil)
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestAPIPermitJoin(t *testing.T) {
 	srv, _, _ := setupTestServer(t, "")
 
@@ -259,6 +341,139 @@ func TestAPISendCommand(t *testing.T) {
 	}
 }
 
+func TestAPITuyaWrite(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+
+	tests := []struct {
+		name string
+		ieee string
+		body string
+		want int
+	}{
+		{"invalid body", "00158D00012A3B4C", `{`, http.StatusBadRequest},
+		{"missing property", "00158D00012A3B4C", `{"value": 1}`, http.StatusBadRequest},
+		{"unknown device", "00158D0000000000", `{"property": "child_lock", "value": true}`, http.StatusNotFound},
+		{"unknown property", "00158D00012A3B4C", `{"property": "child_lock", "value": true}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/devices/"+tt.ieee+"/tuya", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPISetProperties(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	srv.coord.Registry().Register(clusters.OnOff)
+	if err := db.SaveDevice(&store.Device{
+		IEEEAddress:  "00158D00012A3B4C",
+		ShortAddress: 0x1234,
+		Interviewed:  true,
+		Endpoints:    []store.Endpoint{{ID: 1, InClusters: []uint16{0x0000, 0x0006}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		ieee string
+		body string
+		want int
+	}{
+		{"invalid body", "00158D00012A3B4C", `{`, http.StatusBadRequest},
+		{"empty body", "00158D00012A3B4C", `{}`, http.StatusBadRequest},
+		{"unknown device", "00158D0000000000", `{"on_off": true}`, http.StatusNotFound},
+		{"unknown property", "00158D00012A3B4C", `{"child_lock": true}`, http.StatusNotFound},
+		{"invalid value", "00158D00012A3B4C", `{"on_off": "maybe"}`, http.StatusBadRequest},
+		{"ok", "00158D00012A3B4C", `{"on_off": "toggle"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/devices/"+tt.ieee+"/properties", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPISetDeviceOptions(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+
+	tests := []struct {
+		name string
+		ieee string
+		body string
+		want int
+	}{
+		{"invalid body", "00158D00012A3B4C", `{"temperature_calibration": "x"}`, http.StatusBadRequest},
+		{"unknown option", "00158D00012A3B4C", `{"temperature_offset": 1}`, http.StatusBadRequest},
+		{"bad precision", "00158D00012A3B4C", `{"temperature_precision": 1.5}`, http.StatusBadRequest},
+		{"unknown device", "00158D0000000000", `{}`, http.StatusNotFound},
+		{"ok", "00158D00012A3B4C", `{"temperature_calibration": -1.5, "temperature_precision": 1}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/devices/"+tt.ieee+"/options", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+
+	dev, err := db.GetDevice("00158D00012A3B4C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev.Options["temperature_calibration"] != -1.5 || dev.Options["temperature_precision"] != 1 {
+		t.Errorf("stored options: %v", dev.Options)
+	}
+}
+
+func TestDeviceDetailOptions(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	if err := db.SaveDevice(&store.Device{
+		IEEEAddress:  "00158D00012A3B4C",
+		ShortAddress: 0x1234,
+		Properties:   map[string]any{"temperature": 21.5, "contact": true},
+		Options:      map[string]float64{"temperature_calibration": -1.5, "pressure_precision": 0, "power_min_interval": 5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/devices/00158D00012A3B4C", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`data-option="temperature_calibration" value="-1.5"`,
+		`data-option="pressure_precision" value="0"`,
+		`data-option="power_min_interval" value="5"`,
+		`window.deviceOptions = {"power_min_interval":5,`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page lacks %s", want)
+		}
+	}
+	if strings.Contains(body, `data-option="contact_calibration"`) {
+		t.Error("option row for a non-numeric property")
+	}
+}
+
 func TestAPISendCommandPayloadLimit(t *testing.T) {
 	srv, db, _ := setupTestServer(t, "")
 	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
@@ -303,6 +518,118 @@ func TestAPINetworkInfo(t *testing.T) {
 	}
 }
 
+func TestAPINetworkStats(t *testing.T) {
+	srv, _, stub := setupTestServer(t, "")
+	stub.stats = ncp.Stats{
+		FramesSent:  10,
+		Retransmits: 2,
+		APSFailures: map[uint16]uint64{0x1234: 3},
+	}
+
+	req := httptest.NewRequest("GET", "/api/network", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	var info struct {
+		Stats struct {
+			FramesSent  uint64            `json:"frames_sent"`
+			Retransmits uint64            `json:"retransmits"`
+			APSFailures map[string]uint64 `json:"aps_failures"`
+		} `json:"stats"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Stats.FramesSent != 10 || info.Stats.Retransmits != 2 || info.Stats.APSFailures["4660"] != 3 {
+		t.Errorf("stats = %+v", info.Stats)
+	}
+}
+
+func TestNetworkPageAPSFailures(t *testing.T) {
+	srv, db, stub := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+	stub.stats = ncp.Stats{
+		APSFailures:          map[uint16]uint64{0x1234: 3, 0x9999: 5},
+		UnhandledIndications: map[string]uint64{"ZDO_SOMETHING": 1},
+	}
+
+	req := httptest.NewRequest("GET", "/network", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"0x1234", "Test TestModel", "0x9999", "ZDO_SOMETHING"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page missing %q", want)
+		}
+	}
+	// Worst destination first.
+	if strings.Index(body, "0x9999") > strings.Index(body, "0x1234") {
+		t.Error("APS failures not sorted by count")
+	}
+}
+
+func TestAPIRawAPS(t *testing.T) {
+	srv, db, stub := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+	stub.apsFrame = &ncp.APSFrame{SrcAddr: 0x1234, SrcEP: 1, ProfileID: 0xC05E, ClusterID: 0x1000, Payload: []byte{0x19, 0x2A, 0x41}}
+
+	body := `{"endpoint": 1, "profile_id": 49246, "cluster_id": 4096, "payload": "11 2A 41", "ack": false, "wait": true}`
+	req := httptest.NewRequest("POST", "/api/devices/00158D00012A3B4C/aps", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if len(stub.apsSent) != 1 {
+		t.Fatalf("sent %d frames", len(stub.apsSent))
+	}
+	if sent := stub.apsSent[0]; sent.DstAddr != 0x1234 || sent.ProfileID != 0xC05E || !sent.NoACK || !bytes.Equal(sent.Payload, []byte{0x11, 0x2A, 0x41}) {
+		t.Errorf("sent: %+v", sent)
+	}
+	var resp struct {
+		Response coordinator.RawAPSResponse `json:"response"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if z := resp.Response.ZCL; z == nil || z.Seq != 0x2A || z.FrameType != "cluster" || z.Direction != "to_client" {
+		t.Errorf("response: %+v", resp.Response)
+	}
+}
+
+func TestAPIRawAPSErrors(t *testing.T) {
+	srv, db, _ := setupTestServer(t, "")
+	seedDevice(t, db, "00158D00012A3B4C", 0x1234)
+
+	tests := []struct {
+		name string
+		ieee string
+		body string
+		want int
+	}{
+		{"unknown device", "00158D00012A3B4D", `{"cluster_id": 6, "payload": "010001"}`, http.StatusNotFound},
+		{"bad hex", "00158D00012A3B4C", `{"cluster_id": 6, "payload": "zz"}`, http.StatusBadRequest},
+		{"short zcl", "00158D00012A3B4C", `{"cluster_id": 6, "payload": "01"}`, http.StatusBadRequest},
+		{"raw without wait", "00158D00012A3B4C", `{"cluster_id": 6, "payload": "01", "zcl": false}`, http.StatusOK},
+		{"no response", "00158D00012A3B4C", `{"cluster_id": 6, "payload": "010001", "wait": true, "timeout": 0.01}`, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/devices/"+tt.ieee+"/aps", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+			srv.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
 func TestAPIListClusters(t *testing.T) {
 	srv, _, _ := setupTestServer(t, "")
 
@@ -445,3 +772,48 @@ func repeatN(s string, n int) string {
 	}
 	return buf.String()
 }
+
+func TestAPIReloadDefinitions(t *testing.T) {
+	srv, _, _ := setupTestServer(t, "")
+
+	// The test coordinator has no devices directory.
+	req := httptest.NewRequest("POST", "/api/definitions/reload", nil)
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409: %s", w.Code, w.Body.String())
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "acme.json"), []byte(`{"devices": [{"manufacturer": "Acme", "model": "TH1", "bind": []}]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{`), 0644)
+	db, err := store.NewBoltStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	coord := coordinator.New(&stubNCP{}, db, zcl.NewRegistry(logger), nil, coordinator.NewEventBus(logger),
+		coordinator.Config{DevicesDir: dir}, coordinator.NCPConfig{}, logger)
+	srv2, err := NewServer(coord, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv2.Stop() })
+
+	w = httptest.NewRecorder()
+	srv2.ServeHTTP(w, httptest.NewRequest("POST", "/api/definitions/reload", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var result coordinator.ReloadResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Files != 2 || result.Devices != 1 || len(result.Errors) != 1 || result.Errors[0].File != "broken.json" {
+		t.Errorf("result = %+v", result)
+	}
+	if coord.DeviceDB().Lookup("Acme", "TH1") == nil {
+		t.Error("Acme TH1 not loaded")
+	}
+}
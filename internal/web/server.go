@@ -8,15 +8,19 @@ import (
 	"fmt"
 	"html/template"
 	"log/slog"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"zigbee-go-home/internal/automation"
 	"zigbee-go-home/internal/coordinator"
+	"zigbee-go-home/internal/ncp"
 	"zigbee-go-home/internal/store"
 	"zigbee-go-home/internal/zcl"
 )
@@ -118,9 +122,30 @@ type DeviceView struct {
 	Humidity        int    // 0-100, 0 if not available
 	HasHumidity     bool
 	LQIQuality      string // "good", "fair", "poor"
+	Availability    string // "online", "offline", "" if not tracked
 	Properties      map[string]any
 }
 
+// OptionView is one property row of the device options form. Empty
+// strings are unset options.
+type OptionView struct {
+	Property     string
+	Calibration  string
+	Precision    string
+	MinInterval  string
+	Debounce     string
+	Threshold    string
+	PollInterval string
+}
+
+// APSFailureView is one destination row of the APS failure counters.
+type APSFailureView struct {
+	ShortAddress uint16
+	IEEEAddress  string
+	Name         string
+	Count        uint64
+}
+
 // ClusterInfo is the enriched cluster info for templates.
 type ClusterInfo struct {
 	ID   uint16
@@ -217,12 +242,19 @@ func (s *Server) routes() {
 	s.mux.HandleFunc("GET /api/devices/{ieee}", s.handleAPIGetDevice)
 	s.mux.HandleFunc("PATCH /api/devices/{ieee}", s.handleAPIRenameDevice)
 	s.mux.HandleFunc("DELETE /api/devices/{ieee}", s.handleAPIDeleteDevice)
+	s.mux.HandleFunc("POST /api/devices/{ieee}/interview", s.handleAPIReinterview)
+	s.mux.HandleFunc("POST /api/devices/{ieee}/configure", s.handleAPIReconfigure)
 	s.mux.HandleFunc("POST /api/devices/{ieee}/read", s.handleAPIReadAttributes)
 	s.mux.HandleFunc("POST /api/devices/{ieee}/write", s.handleAPIWriteAttribute)
 	s.mux.HandleFunc("POST /api/devices/{ieee}/command", s.handleAPISendCommand)
+	s.mux.HandleFunc("POST /api/devices/{ieee}/tuya", s.handleAPITuyaWrite)
+	s.mux.HandleFunc("PUT /api/devices/{ieee}/properties", s.handleAPISetProperties)
+	s.mux.HandleFunc("PUT /api/devices/{ieee}/options", s.handleAPISetDeviceOptions)
+	s.mux.HandleFunc("POST /api/devices/{ieee}/aps", s.handleAPIRawAPS)
 	s.mux.HandleFunc("GET /api/network", s.handleAPINetworkInfo)
 	s.mux.HandleFunc("POST /api/network/permit-join", s.handleAPIPermitJoin)
 	s.mux.HandleFunc("GET /api/clusters", s.handleAPIListClusters)
+	s.mux.HandleFunc("POST /api/definitions/reload", s.handleAPIReloadDefinitions)
 	s.mux.HandleFunc("GET /api/version", s.handleAPIVersion)
 
 	// Automations
@@ -327,9 +359,13 @@ func (s *Server) enrichDevice(dev *store.Device) DeviceView {
 		v.LQIQuality = "poor"
 	}
 
+	if a := s.coord.Availability(); a != nil {
+		v.Availability = a.State(dev.IEEEAddress)
+	}
+
 	// Check if device has a known definition.
-	if db := s.coord.DeviceDB(); db != nil && dev.Manufacturer != "" && dev.Model != "" {
-		v.IsKnown = db.Lookup(dev.Manufacturer, dev.Model) != nil
+	if db := s.coord.DeviceDB(); db != nil {
+		v.IsKnown = db.Match(dev.Manufacturer, dev.Model, dev.Endpoints) != nil
 	}
 
 	// Check if device photo exists (cached to avoid repeated stat calls).
@@ -399,8 +435,12 @@ func (s *Server) enrichDevice(dev *store.Device) DeviceView {
 			}
 		}
 		if val, ok := dev.Properties["occupancy"]; ok {
+			detected, known := val.(bool)
 			if n, ok := toInt(val); ok {
-				if n != 0 {
+				detected, known = n != 0, true
+			}
+			if known {
+				if detected {
 					v.Occupancy = "detected"
 				} else {
 					v.Occupancy = "clear"
@@ -502,14 +542,21 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var views []DeviceView
+	offline := 0
 	for _, dev := range devices {
-		views = append(views, s.enrichDevice(dev))
+		v := s.enrichDevice(dev)
+		if v.Availability == coordinator.AvailabilityOffline {
+			offline++
+		}
+		views = append(views, v)
 	}
 
 	s.renderTemplate(w, "index.html", map[string]interface{}{
-		"PageTitle":   "Overview",
-		"Devices":     views,
-		"DeviceCount": len(views),
+		"PageTitle":    "Overview",
+		"Devices":      views,
+		"DeviceCount":  len(views),
+		"OnlineCount":  len(views) - offline,
+		"OfflineCount": offline,
 	})
 }
 
@@ -634,9 +681,54 @@ func (s *Server) handleDeviceDetailPage(w http.ResponseWriter, r *http.Request)
 		"RawDevice":    dev,
 		"Endpoints":    endpoints,
 		"EndpointMeta": template.JS(metaJSON),
+		"Options":      deviceOptionViews(dev),
 	})
 }
 
+// deviceOptionViews returns option rows for the numeric properties of a
+// device and the properties it has options for, sorted by name.
+func deviceOptionViews(dev *store.Device) []OptionView {
+	props := make(map[string]bool)
+	for name, v := range dev.Properties {
+		switch v.(type) {
+		case float64, float32, int, int64, int32, int16, int8, uint64, uint32, uint16, uint8:
+			props[name] = true
+		}
+	}
+	format := func(name string) string {
+		v, ok := dev.Options[name]
+		if !ok {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	for name := range dev.Options {
+		for _, suffix := range []string{"_calibration", "_precision", "_min_interval", "_debounce", "_threshold", "_poll_interval"} {
+			if prop, ok := strings.CutSuffix(name, suffix); ok {
+				props[prop] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([]OptionView, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, OptionView{
+			Property:     name,
+			Calibration:  format(name + "_calibration"),
+			Precision:    format(name + "_precision"),
+			MinInterval:  format(name + "_min_interval"),
+			Debounce:     format(name + "_debounce"),
+			Threshold:    format(name + "_threshold"),
+			PollInterval: format(name + "_poll_interval"),
+		})
+	}
+	return rows
+}
+
 func (s *Server) handleNetworkPage(w http.ResponseWriter, r *http.Request) {
 	info := s.coord.NetworkInfo()
 
@@ -644,10 +736,38 @@ func (s *Server) handleNetworkPage(w http.ResponseWriter, r *http.Request) {
 	info["device_count"] = len(devices)
 	info["PageTitle"] = "Network"
 
+	// Resolve APS failure destinations to devices, worst first.
+	if st, ok := info["stats"].(ncp.Stats); ok && len(st.APSFailures) > 0 {
+		byShort := make(map[uint16]*store.Device, len(devices))
+		for _, dev := range devices {
+			byShort[dev.ShortAddress] = dev
+		}
+		rows := make([]APSFailureView, 0, len(st.APSFailures))
+		for addr, count := range st.APSFailures {
+			row := APSFailureView{ShortAddress: addr, Count: count}
+			if dev := byShort[addr]; dev != nil {
+				row.IEEEAddress = dev.IEEEAddress
+				row.Name = dev.FriendlyName
+				if row.Name == "" {
+					row.Name = strings.TrimSpace(dev.Manufacturer + " " + dev.Model)
+				}
+			}
+			rows = append(rows, row)
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			if rows[i].Count != rows[j].Count {
+				return rows[i].Count > rows[j].Count
+			}
+			return rows[i].ShortAddress < rows[j].ShortAddress
+		})
+		info["aps_failures"] = rows
+	}
+
 	s.renderTemplate(w, "network.html", info)
 }
 
-// toInt converts various numeric types (including JSON-deserialized float64) to int.
+// toInt converts various numeric types (including JSON-deserialized and
+// scaled float64 properties) to the nearest int.
 func toInt(v any) (int, bool) {
 	switch n := v.(type) {
 	case int:
@@ -655,7 +775,7 @@ func toInt(v any) (int, bool) {
 	case int64:
 		return int(n), true
 	case float64:
-		return int(n), true
+		return int(math.Round(n)), true
 	case uint8:
 		return int(n), true
 	case uint16:
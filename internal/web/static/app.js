@@ -62,6 +62,12 @@ function handleEvent(event) {
         case "property_update":
             handlePropertyUpdate(event.data);
             break;
+        case "device_action":
+            handleDeviceAction(event.data);
+            break;
+        case "command_failed":
+            showToast(t("toast.command_not_applied", (event.data.ieee || "unknown") + " " + (event.data.property || "")), true);
+            break;
         case "device_joined":
             showToast(t("toast.device_joined", event.data.ieee || "unknown"));
             break;
@@ -74,6 +80,78 @@ function handleEvent(event) {
         case "permit_join":
             showToast(t("toast.permit_join_updated"));
             break;
+        case "device_availability":
+            handleAvailability(event.data);
+            break;
+        case "interview_progress":
+            handleInterviewProgress(event.data);
+            break;
+    }
+}
+
+// Shows the presses of the remote whose page is open.
+function handleDeviceAction(data) {
+    if (!data || data.ieee !== window.currentDeviceIEEE) return;
+    showToast(t("toast.action", data.action));
+}
+
+function handleAvailability(data) {
+    if (!data || !data.ieee) return;
+
+    var offline = data.state === "offline";
+    showToast(t(offline ? "toast.device_offline" : "toast.device_online", data.ieee));
+
+    document.querySelectorAll('.device-tile[data-ieee="' + data.ieee + '"]').forEach(function(tile) {
+        tile.classList.toggle("offline", offline);
+    });
+
+    // Tile badges only show "Offline"; the detail badge shows both states.
+    document.querySelectorAll('[data-availability-ieee="' + data.ieee + '"]').forEach(function(badge) {
+        if (badge.classList.contains("badge-availability")) {
+            badge.style.display = offline ? "" : "none";
+            return;
+        }
+        badge.classList.toggle("badge-error", offline);
+        badge.classList.toggle("badge-success", !offline);
+        badge.setAttribute("data-i18n", "availability." + data.state);
+        badge.textContent = t("availability." + data.state);
+    });
+
+    var total = document.getElementById("total-count");
+    var online = document.getElementById("online-count");
+    var offlineCount = document.getElementById("offline-count");
+    if (total && online && offlineCount) {
+        var n = document.querySelectorAll(".device-tile.offline").length;
+        offlineCount.textContent = n;
+        online.textContent = parseInt(total.textContent, 10) - n;
+    }
+}
+
+// Appends interview and reconfiguration steps to the log on the device page.
+function handleInterviewProgress(data) {
+    if (!data || data.ieee !== window.currentDeviceIEEE) return;
+    var log = document.getElementById("interview-log");
+    if (!log) return;
+
+    var line = new Date().toLocaleTimeString() + " " + data.step;
+    if (data.operation) line += " " + data.operation;
+    if (data.endpoint !== undefined) line += " ep " + data.endpoint;
+    if (data.cluster !== undefined) line += " cluster 0x" + data.cluster.toString(16).toUpperCase().padStart(4, "0");
+    if (data.attribute !== undefined) line += " attr 0x" + data.attribute.toString(16).toUpperCase().padStart(4, "0");
+    if (data.endpoints) line += " [" + data.endpoints.join(", ") + "]";
+    line += ": " + data.status;
+    if (data.error) line += " (" + data.error + ")";
+    log.style.display = "";
+    log.textContent += line + "\n";
+    log.scrollTop = log.scrollHeight;
+
+    if (data.step === "complete") {
+        var op = data.operation === "configure" ? "configure" : "interview";
+        if (data.status === "failed") {
+            showToast(t("toast." + op + "_failed", data.error), true);
+        } else {
+            showToast(t("toast." + op + "_complete"));
+        }
     }
 }
 
@@ -580,6 +658,26 @@ function cancelRename() {
     if (edit) edit.style.display = "none";
 }
 
+// === Device options ===
+async function saveOptions(ieee) {
+    // Keep options without a field, such as device-wide min_interval.
+    var options = Object.assign({}, window.deviceOptions || {});
+    var inputs = document.querySelectorAll("#options-table input[data-option]");
+    for (var i = 0; i < inputs.length; i++) {
+        var value = inputs[i].value.trim();
+        delete options[inputs[i].dataset.option];
+        if (value === "") continue;
+        options[inputs[i].dataset.option] = Number(value);
+    }
+    try {
+        var res = await apiCall("PUT", "/api/devices/" + ieee + "/options", options);
+        window.deviceOptions = res.options;
+        showToast(t("toast.options_saved"));
+    } catch(e) {
+        showToast(t("toast.options_failed", e.message), true);
+    }
+}
+
 // === Delete device ===
 async function deleteDevice(ieee) {
     if (!confirm(t("toast.delete_confirm", ieee))) return;
@@ -591,6 +689,32 @@ async function deleteDevice(ieee) {
     }
 }
 
+// === Maintenance ===
+function clearInterviewLog() {
+    var log = document.getElementById("interview-log");
+    if (log) log.textContent = "";
+}
+
+async function reinterviewDevice(ieee) {
+    clearInterviewLog();
+    try {
+        await apiCall("POST", "/api/devices/" + ieee + "/interview");
+        showToast(t("toast.interview_started"));
+    } catch(e) {
+        showToast(t("toast.interview_failed", e.message), true);
+    }
+}
+
+async function reconfigureDevice(ieee) {
+    clearInterviewLog();
+    try {
+        const result = await apiCall("POST", "/api/devices/" + ieee + "/configure");
+        showToast(t(result.status === "queued" ? "toast.configure_queued" : "toast.configure_started"));
+    } catch(e) {
+        showToast(t("toast.configure_failed", e.message), true);
+    }
+}
+
 // === Read attribute ===
 async function readAttribute(evt, ieee) {
     evt.preventDefault();
@@ -72,6 +72,19 @@ function defineBlocks() {
         }
     };
 
+    Blockly.Blocks['zigbee_on_action'] = {
+        init: function() {
+            this.appendStatementInput('DO')
+                .appendField(t('block.when'))
+                .appendField(new Blockly.FieldDropdown(deviceDropdown), 'DEVICE')
+                .appendField(t('block.sends_action'))
+                .appendField(new Blockly.FieldTextInput('single'), 'ACTION')
+                .appendField(t('block.do'));
+            this.setColour(210);
+            this.setTooltip('Trigger when a remote or button sends an action, e.g. single, double, hold, brightness_up');
+        }
+    };
+
     // --- Device Actions ---
     Blockly.Blocks['zigbee_turn_on'] = {
         init: function() {
@@ -352,6 +365,14 @@ function defineGenerators() {
                stmts + 'end)\n';
     };
 
+    G.forBlock['zigbee_on_action'] = function(block, generator) {
+        var device = block.getFieldValue('DEVICE');
+        var action = block.getFieldValue('ACTION').replace(/["\\]/g, '');
+        var stmts = generator.statementToCode(block, 'DO');
+        return 'zigbee.on("device_action", {ieee="' + device + '", action="' + action + '"}, function(event)\n' +
+               stmts + 'end)\n';
+    };
+
     G.forBlock['zigbee_turn_on'] = function(block) {
         return 'zigbee.turn_on("' + block.getFieldValue('DEVICE') + '")\n';
     };
@@ -457,7 +478,8 @@ function buildToolbox() {
                 colour: 210,
                 contents: [
                     { kind: 'block', type: 'zigbee_on_property' },
-                    { kind: 'block', type: 'zigbee_on_any_property' }
+                    { kind: 'block', type: 'zigbee_on_any_property' },
+                    { kind: 'block', type: 'zigbee_on_action' }
                 ]
             },
             {
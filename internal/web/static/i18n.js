@@ -70,6 +70,17 @@ var _i18n = {
         "detail.off": "Off",
         "detail.toggle": "Toggle",
         "detail.delete_device": "Delete Device",
+        "detail.maintenance": "Maintenance",
+        "detail.options": "Options",
+        "detail.property": "Property",
+        "detail.calibration": "Calibration",
+        "detail.precision": "Precision",
+        "detail.min_interval": "Min interval (s)",
+        "detail.debounce": "Debounce (s)",
+        "detail.threshold": "Threshold",
+        "detail.poll_interval": "Poll interval (s)",
+        "detail.reinterview": "Re-interview",
+        "detail.reconfigure": "Reconfigure",
         "detail.save": "Save",
         "detail.cancel": "Cancel",
         "detail.open": "Open",
@@ -89,6 +100,22 @@ var _i18n = {
         "network.serial_port": "Serial Port",
         "network.baud_rate": "Baud Rate",
         "network.coordinator_ieee": "Coordinator IEEE",
+        "network.duplicate_frames": "Duplicate Frames Dropped",
+        "network.pending": "Pending Requests",
+        "network.stats": "Statistics",
+        "network.frames_sent": "Frames Sent",
+        "network.frames_received": "Frames Received",
+        "network.retransmits": "Retransmits",
+        "network.ack_timeouts": "ACK Timeouts",
+        "network.crc_errors": "CRC Errors",
+        "network.decode_errors": "Malformed Frames",
+        "network.aps_failures": "APS Failures by Destination",
+        "network.short_addr": "Short Address",
+        "network.device": "Device",
+        "network.failures": "Failures",
+        "network.unhandled_indications": "Unhandled Indications",
+        "network.indication": "Indication",
+        "network.count": "Count",
         "network.fw_version": "Firmware Version",
         "network.stack_version": "Stack Version",
         "network.protocol_version": "Protocol Version",
@@ -125,6 +152,10 @@ var _i18n = {
         "auto.generated_lua": "Generated Lua",
         "auto.close": "Close",
 
+        // Availability
+        "availability.online": "Online",
+        "availability.offline": "Offline",
+
         // Page titles
         "page.overview": "Overview",
         "page.devices": "Devices",
@@ -135,8 +166,12 @@ var _i18n = {
         "toast.device_joined": "Device joined: ${v}",
         "toast.device_left": "Device left: ${v}",
         "toast.device_announce": "Device announce: ${v}",
+        "toast.device_online": "Device online: ${v}",
+        "toast.device_offline": "Device offline: ${v}",
         "toast.permit_join_updated": "Permit join updated",
         "toast.contact": "Contact: ${v}",
+        "toast.action": "Action: ${v}",
+        "toast.command_not_applied": "Command not applied: ${v}",
         "toast.contact_open": "open",
         "toast.contact_closed": "closed",
         "toast.occupancy": "Occupancy: ${v}",
@@ -149,8 +184,17 @@ var _i18n = {
         "toast.permit_join_failed": "Permit join failed: ${v}",
         "toast.device_renamed": "Device renamed",
         "toast.rename_failed": "Rename failed: ${v}",
+        "toast.options_saved": "Options saved",
+        "toast.options_failed": "Saving options failed: ${v}",
         "toast.delete_confirm": "Delete device ${v}?",
         "toast.delete_failed": "Delete failed: ${v}",
+        "toast.interview_started": "Interview started",
+        "toast.interview_complete": "Interview complete",
+        "toast.interview_failed": "Interview failed: ${v}",
+        "toast.configure_started": "Reconfiguration started",
+        "toast.configure_queued": "Reconfiguration queued until the device checks in",
+        "toast.configure_complete": "Reconfiguration complete",
+        "toast.configure_failed": "Reconfiguration failed: ${v}",
         "toast.no_attr_selected": "No attribute selected",
         "toast.command_sent": "Command sent",
 
@@ -204,6 +248,7 @@ var _i18n = {
         "block.becomes": "becomes",
         "block.do": "do",
         "block.changes_do": "changes, do",
+        "block.sends_action": "sends action",
         "block.turn_on": "Turn on",
         "block.turn_off": "Turn off",
         "block.toggle": "Toggle",
@@ -299,6 +344,17 @@ var _i18n = {
         "detail.off": "\u0412\u044B\u043A\u043B",
         "detail.toggle": "\u041F\u0435\u0440\u0435\u043A\u043B\u044E\u0447\u0438\u0442\u044C",
         "detail.delete_device": "\u0423\u0434\u0430\u043B\u0438\u0442\u044C \u0443\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E",
+        "detail.maintenance": "\u041E\u0431\u0441\u043B\u0443\u0436\u0438\u0432\u0430\u043D\u0438\u0435",
+        "detail.options": "\u041F\u0430\u0440\u0430\u043C\u0435\u0442\u0440\u044B",
+        "detail.property": "\u0421\u0432\u043E\u0439\u0441\u0442\u0432\u043E",
+        "detail.calibration": "\u041A\u0430\u043B\u0438\u0431\u0440\u043E\u0432\u043A\u0430",
+        "detail.precision": "\u0422\u043E\u0447\u043D\u043E\u0441\u0442\u044C",
+        "detail.min_interval": "\u041C\u0438\u043D. \u0438\u043D\u0442\u0435\u0440\u0432\u0430\u043B (\u0441)",
+        "detail.debounce": "\u0417\u0430\u0434\u0435\u0440\u0436\u043A\u0430 (\u0441)",
+        "detail.threshold": "\u041F\u043E\u0440\u043E\u0433",
+        "detail.poll_interval": "\u0418\u043D\u0442\u0435\u0440\u0432\u0430\u043B \u043E\u043F\u0440\u043E\u0441\u0430 (\u0441)",
+        "detail.reinterview": "\u041F\u043E\u0432\u0442\u043E\u0440\u043D\u044B\u0439 \u043E\u043F\u0440\u043E\u0441",
+        "detail.reconfigure": "\u041F\u0435\u0440\u0435\u043D\u0430\u0441\u0442\u0440\u043E\u0438\u0442\u044C",
         "detail.save": "\u0421\u043E\u0445\u0440\u0430\u043D\u0438\u0442\u044C",
         "detail.cancel": "\u041E\u0442\u043C\u0435\u043D\u0430",
         "detail.open": "\u041E\u0442\u043A\u0440\u044B\u0442",
@@ -318,6 +374,22 @@ var _i18n = {
         "network.serial_port": "\u041F\u043E\u0440\u0442",
         "network.baud_rate": "\u0421\u043A\u043E\u0440\u043E\u0441\u0442\u044C",
         "network.coordinator_ieee": "IEEE \u043A\u043E\u043E\u0440\u0434\u0438\u043D\u0430\u0442\u043E\u0440\u0430",
+        "network.pending": "\u041E\u0436\u0438\u0434\u0430\u044E\u0449\u0438\u0435 \u0437\u0430\u043F\u0440\u043E\u0441\u044B",
+        "network.duplicate_frames": "\u041E\u0442\u0431\u0440\u043E\u0448\u0435\u043D\u043E \u0434\u0443\u0431\u043B\u0438\u043A\u0430\u0442\u043E\u0432",
+        "network.stats": "\u0421\u0442\u0430\u0442\u0438\u0441\u0442\u0438\u043A\u0430",
+        "network.frames_sent": "\u041E\u0442\u043F\u0440\u0430\u0432\u043B\u0435\u043D\u043E \u043A\u0430\u0434\u0440\u043E\u0432",
+        "network.frames_received": "\u041F\u0440\u0438\u043D\u044F\u0442\u043E \u043A\u0430\u0434\u0440\u043E\u0432",
+        "network.retransmits": "\u041F\u043E\u0432\u0442\u043E\u0440\u043D\u044B\u0435 \u043F\u0435\u0440\u0435\u0434\u0430\u0447\u0438",
+        "network.ack_timeouts": "\u0422\u0430\u0439\u043C\u0430\u0443\u0442\u044B ACK",
+        "network.crc_errors": "\u041E\u0448\u0438\u0431\u043A\u0438 CRC",
+        "network.decode_errors": "\u041F\u043E\u0432\u0440\u0435\u0436\u0434\u0451\u043D\u043D\u044B\u0435 \u043A\u0430\u0434\u0440\u044B",
+        "network.aps_failures": "\u041E\u0448\u0438\u0431\u043A\u0438 APS \u043F\u043E \u0430\u0434\u0440\u0435\u0441\u0430\u0442\u0430\u043C",
+        "network.short_addr": "\u041A\u043E\u0440\u043E\u0442\u043A\u0438\u0439 \u0430\u0434\u0440\u0435\u0441",
+        "network.device": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E",
+        "network.failures": "\u041E\u0448\u0438\u0431\u043A\u0438",
+        "network.unhandled_indications": "\u041D\u0435\u043E\u0431\u0440\u0430\u0431\u043E\u0442\u0430\u043D\u043D\u044B\u0435 \u0438\u043D\u0434\u0438\u043A\u0430\u0446\u0438\u0438",
+        "network.indication": "\u0418\u043D\u0434\u0438\u043A\u0430\u0446\u0438\u044F",
+        "network.count": "\u041A\u043E\u043B\u0438\u0447\u0435\u0441\u0442\u0432\u043E",
         "network.fw_version": "\u0412\u0435\u0440\u0441\u0438\u044F \u043F\u0440\u043E\u0448\u0438\u0432\u043A\u0438",
         "network.stack_version": "\u0412\u0435\u0440\u0441\u0438\u044F \u0441\u0442\u0435\u043A\u0430",
         "network.protocol_version": "\u0412\u0435\u0440\u0441\u0438\u044F \u043F\u0440\u043E\u0442\u043E\u043A\u043E\u043B\u0430",
@@ -354,6 +426,10 @@ var _i18n = {
         "auto.generated_lua": "\u0421\u0433\u0435\u043D\u0435\u0440\u0438\u0440\u043E\u0432\u0430\u043D\u043D\u044B\u0439 Lua",
         "auto.close": "\u0417\u0430\u043A\u0440\u044B\u0442\u044C",
 
+        // Availability
+        "availability.online": "\u0412 \u0441\u0435\u0442\u0438",
+        "availability.offline": "\u041D\u0435 \u0432 \u0441\u0435\u0442\u0438",
+
         // Page titles
         "page.overview": "\u041E\u0431\u0437\u043E\u0440",
         "page.devices": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u0430",
@@ -364,8 +440,12 @@ var _i18n = {
         "toast.device_joined": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E \u043F\u043E\u0434\u043A\u043B\u044E\u0447\u0435\u043D\u043E: ${v}",
         "toast.device_left": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E \u043E\u0442\u043A\u043B\u044E\u0447\u0435\u043D\u043E: ${v}",
         "toast.device_announce": "\u041E\u0431\u044A\u044F\u0432\u043B\u0435\u043D\u0438\u0435 \u0443\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u0430: ${v}",
+        "toast.device_online": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E \u0432 \u0441\u0435\u0442\u0438: ${v}",
+        "toast.device_offline": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E \u043D\u0435 \u0432 \u0441\u0435\u0442\u0438: ${v}",
         "toast.permit_join_updated": "\u041F\u043E\u0434\u043A\u043B\u044E\u0447\u0435\u043D\u0438\u0435 \u043E\u0431\u043D\u043E\u0432\u043B\u0435\u043D\u043E",
         "toast.contact": "\u041A\u043E\u043D\u0442\u0430\u043A\u0442: ${v}",
+        "toast.action": "\u0414\u0435\u0439\u0441\u0442\u0432\u0438\u0435: ${v}",
+        "toast.command_not_applied": "\u041A\u043E\u043C\u0430\u043D\u0434\u0430 \u043D\u0435 \u0432\u044B\u043F\u043E\u043B\u043D\u0435\u043D\u0430: ${v}",
         "toast.contact_open": "\u043E\u0442\u043A\u0440\u044B\u0442",
         "toast.contact_closed": "\u0437\u0430\u043A\u0440\u044B\u0442",
         "toast.occupancy": "\u041F\u0440\u0438\u0441\u0443\u0442\u0441\u0442\u0432\u0438\u0435: ${v}",
@@ -378,8 +458,17 @@ var _i18n = {
         "toast.permit_join_failed": "\u041E\u0448\u0438\u0431\u043A\u0430 \u043F\u043E\u0434\u043A\u043B\u044E\u0447\u0435\u043D\u0438\u044F: ${v}",
         "toast.device_renamed": "\u0423\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E \u043F\u0435\u0440\u0435\u0438\u043C\u0435\u043D\u043E\u0432\u0430\u043D\u043E",
         "toast.rename_failed": "\u041E\u0448\u0438\u0431\u043A\u0430 \u043F\u0435\u0440\u0435\u0438\u043C\u0435\u043D\u043E\u0432\u0430\u043D\u0438\u044F: ${v}",
+        "toast.options_saved": "\u041F\u0430\u0440\u0430\u043C\u0435\u0442\u0440\u044B \u0441\u043E\u0445\u0440\u0430\u043D\u0435\u043D\u044B",
+        "toast.options_failed": "\u041E\u0448\u0438\u0431\u043A\u0430 \u0441\u043E\u0445\u0440\u0430\u043D\u0435\u043D\u0438\u044F \u043F\u0430\u0440\u0430\u043C\u0435\u0442\u0440\u043E\u0432: ${v}",
         "toast.delete_confirm": "\u0423\u0434\u0430\u043B\u0438\u0442\u044C \u0443\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E ${v}?",
         "toast.delete_failed": "\u041E\u0448\u0438\u0431\u043A\u0430 \u0443\u0434\u0430\u043B\u0435\u043D\u0438\u044F: ${v}",
+        "toast.interview_started": "\u041E\u043F\u0440\u043E\u0441 \u0437\u0430\u043F\u0443\u0449\u0435\u043D",
+        "toast.interview_complete": "\u041E\u043F\u0440\u043E\u0441 \u0437\u0430\u0432\u0435\u0440\u0448\u0451\u043D",
+        "toast.interview_failed": "\u041E\u0448\u0438\u0431\u043A\u0430 \u043E\u043F\u0440\u043E\u0441\u0430: ${v}",
+        "toast.configure_started": "\u041F\u0435\u0440\u0435\u043D\u0430\u0441\u0442\u0440\u043E\u0439\u043A\u0430 \u0437\u0430\u043F\u0443\u0449\u0435\u043D\u0430",
+        "toast.configure_queued": "\u041F\u0435\u0440\u0435\u043D\u0430\u0441\u0442\u0440\u043E\u0439\u043A\u0430 \u0432\u044B\u043F\u043E\u043B\u043D\u0438\u0442\u0441\u044F, \u043A\u043E\u0433\u0434\u0430 \u0443\u0441\u0442\u0440\u043E\u0439\u0441\u0442\u0432\u043E \u0432\u044B\u0439\u0434\u0435\u0442 \u043D\u0430 \u0441\u0432\u044F\u0437\u044C",
+        "toast.configure_complete": "\u041F\u0435\u0440\u0435\u043D\u0430\u0441\u0442\u0440\u043E\u0439\u043A\u0430 \u0437\u0430\u0432\u0435\u0440\u0448\u0435\u043D\u0430",
+        "toast.configure_failed": "\u041E\u0448\u0438\u0431\u043A\u0430 \u043F\u0435\u0440\u0435\u043D\u0430\u0441\u0442\u0440\u043E\u0439\u043A\u0438: ${v}",
         "toast.no_attr_selected": "\u0410\u0442\u0440\u0438\u0431\u0443\u0442 \u043D\u0435 \u0432\u044B\u0431\u0440\u0430\u043D",
         "toast.command_sent": "\u041A\u043E\u043C\u0430\u043D\u0434\u0430 \u043E\u0442\u043F\u0440\u0430\u0432\u043B\u0435\u043D\u0430",
 
@@ -433,6 +522,7 @@ var _i18n = {
         "block.becomes": "\u0441\u0442\u0430\u043D\u0435\u0442",
         "block.do": "\u0432\u044B\u043F\u043E\u043B\u043D\u0438\u0442\u044C",
         "block.changes_do": "\u0438\u0437\u043C\u0435\u043D\u0438\u0442\u0441\u044F, \u0432\u044B\u043F\u043E\u043B\u043D\u0438\u0442\u044C",
+        "block.sends_action": "\u043E\u0442\u043F\u0440\u0430\u0432\u0438\u0442 \u0434\u0435\u0439\u0441\u0442\u0432\u0438\u0435",
         "block.turn_on": "\u0412\u043A\u043B\u044E\u0447\u0438\u0442\u044C",
         "block.turn_off": "\u0412\u044B\u043A\u043B\u044E\u0447\u0438\u0442\u044C",
         "block.toggle": "\u041F\u0435\u0440\u0435\u043A\u043B\u044E\u0447\u0438\u0442\u044C",
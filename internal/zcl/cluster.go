@@ -13,6 +13,9 @@ type AttributeDef struct {
 	Name   string `json:"name"`
 	Type   uint8  `json:"type"`
 	Access uint8  `json:"access"` // bitmask: 1=read, 2=write, 4=reportable
+
+	// Converter maps the attribute to a normalized property, if any.
+	Converter *Converter `json:"converter,omitempty"`
 }
 
 // IsReadable returns true if the attribute can be read.
@@ -79,6 +82,9 @@ func (c *ClusterDef) DeepCopy() *ClusterDef {
 	if c.Attributes != nil {
 		cp.Attributes = make([]AttributeDef, len(c.Attributes))
 		copy(cp.Attributes, c.Attributes)
+		for i := range cp.Attributes {
+			cp.Attributes[i].Converter = cp.Attributes[i].Converter.clone()
+		}
 	}
 	if c.Commands != nil {
 		cp.Commands = make([]CommandDef, len(c.Commands))
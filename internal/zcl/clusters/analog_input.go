@@ -10,7 +10,7 @@ var AnalogInput = zcl.ClusterDef{
 		{ID: 0x0041, Name: "MaxPresentValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0045, Name: "MinPresentValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0051, Name: "OutOfService", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessWrite},
-		{ID: 0x0055, Name: "PresentValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessWrite | zcl.AccessReport},
+		{ID: 0x0055, Name: "PresentValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessWrite | zcl.AccessReport, Converter: &zcl.Converter{Property: "analog_value"}},
 		{ID: 0x0067, Name: "Reliability", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x006A, Name: "Resolution", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x006F, Name: "StatusFlags", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessReport},
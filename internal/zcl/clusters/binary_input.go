@@ -11,7 +11,7 @@ var BinaryInput = zcl.ClusterDef{
 		{ID: 0x002E, Name: "InactiveText", Type: zcl.TypeCharStr, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0051, Name: "OutOfService", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0054, Name: "Polarity", Type: zcl.TypeEnum8, Access: zcl.AccessRead},
-		{ID: 0x0055, Name: "PresentValue", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessWrite | zcl.AccessReport},
+		{ID: 0x0055, Name: "PresentValue", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessWrite | zcl.AccessReport, Converter: &zcl.Converter{Property: "binary_value", Bool: true}},
 		{ID: 0x0067, Name: "Reliability", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x006F, Name: "StatusFlags", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessReport},
 		{ID: 0x0100, Name: "ApplicationType", Type: zcl.TypeUint32, Access: zcl.AccessRead},
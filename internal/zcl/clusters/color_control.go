@@ -6,13 +6,13 @@ var ColorControl = zcl.ClusterDef{
 	ID:   0x0300,
 	Name: "Color Control",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "CurrentHue", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport},
-		{ID: 0x0001, Name: "CurrentSaturation", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "CurrentHue", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "hue", Command: &zcl.CommandSetter{ID: 0x00, Type: zcl.TypeUint8, Payload: "000500"}}},
+		{ID: 0x0001, Name: "CurrentSaturation", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "saturation", Command: &zcl.CommandSetter{ID: 0x03, Type: zcl.TypeUint8, Payload: "0500"}}},
 		{ID: 0x0002, Name: "RemainingTime", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "CurrentX", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
 		{ID: 0x0004, Name: "CurrentY", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
-		{ID: 0x0007, Name: "ColorTemperatureMireds", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
-		{ID: 0x0008, Name: "ColorMode", Type: zcl.TypeEnum8, Access: zcl.AccessRead},
+		{ID: 0x0007, Name: "ColorTemperatureMireds", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "color_temp", Unit: "mired", Command: &zcl.CommandSetter{ID: 0x0A, Type: zcl.TypeUint16, Payload: "0500"}}},
+		{ID: 0x0008, Name: "ColorMode", Type: zcl.TypeEnum8, Access: zcl.AccessRead, Converter: &zcl.Converter{Property: "color_mode", Enum: map[int]string{0: "hs", 1: "xy", 2: "color_temp"}}},
 		{ID: 0x000F, Name: "Options", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x4001, Name: "EnhancedCurrentHue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x4002, Name: "EnhancedColorMode", Type: zcl.TypeEnum8, Access: zcl.AccessRead},
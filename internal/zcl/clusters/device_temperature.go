@@ -6,7 +6,7 @@ var DeviceTemperatureConfiguration = zcl.ClusterDef{
 	ID:   0x0002,
 	Name: "Device Temperature Configuration",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "CurrentTemperature", Type: zcl.TypeInt16, Access: zcl.AccessRead},
+		{ID: 0x0000, Name: "CurrentTemperature", Type: zcl.TypeInt16, Access: zcl.AccessRead, Converter: &zcl.Converter{Property: "device_temperature", Unit: "°C"}},
 		{ID: 0x0001, Name: "MinTempExperienced", Type: zcl.TypeInt16, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxTempExperienced", Type: zcl.TypeInt16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "OverTempTotalDwell", Type: zcl.TypeUint16, Access: zcl.AccessRead},
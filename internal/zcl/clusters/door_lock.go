@@ -6,7 +6,7 @@ var DoorLock = zcl.ClusterDef{
 	ID:   0x0101,
 	Name: "Door Lock",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "LockState", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "LockState", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "lock_state", Enum: map[int]string{0: "not_fully_locked", 1: "locked", 2: "unlocked"}, Command: &zcl.CommandSetter{Commands: map[string]uint8{"locked": 0x00, "unlocked": 0x01}, Payload: "00"}}},
 		{ID: 0x0001, Name: "LockType", Type: zcl.TypeEnum8, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "ActuatorEnabled", Type: zcl.TypeBool, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "DoorState", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessReport},
@@ -7,9 +7,9 @@ var ElectricalMeasurement = zcl.ClusterDef{
 	Name: "Electrical Measurement",
 	Attributes: []zcl.AttributeDef{
 		{ID: 0x0000, Name: "MeasurementType", Type: zcl.TypeBitmap32, Access: zcl.AccessRead},
-		{ID: 0x0505, Name: "RMSVoltage", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
-		{ID: 0x0508, Name: "RMSCurrent", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
-		{ID: 0x050B, Name: "ActivePower", Type: zcl.TypeInt16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0505, Name: "RMSVoltage", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "voltage", Unit: "V"}},
+		{ID: 0x0508, Name: "RMSCurrent", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "current", Scale: 0.001, Unit: "A"}},
+		{ID: 0x050B, Name: "ActivePower", Type: zcl.TypeInt16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "power", Unit: "W"}},
 		{ID: 0x050E, Name: "PowerFactor", Type: zcl.TypeInt8, Access: zcl.AccessRead},
 		{ID: 0x0600, Name: "ACVoltageMultiplier", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0601, Name: "ACVoltageDivisor", Type: zcl.TypeUint16, Access: zcl.AccessRead},
@@ -6,7 +6,7 @@ var FanControl = zcl.ClusterDef{
 	ID:   0x0202,
 	Name: "Fan Control",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "FanMode", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessWrite},
+		{ID: 0x0000, Name: "FanMode", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessWrite, Converter: &zcl.Converter{Property: "fan_mode", Enum: map[int]string{0: "off", 1: "low", 2: "medium", 3: "high", 4: "on", 5: "auto", 6: "smart"}, Write: true}},
 		{ID: 0x0001, Name: "FanModeSequence", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessWrite},
 	},
 }
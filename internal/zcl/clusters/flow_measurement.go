@@ -6,7 +6,7 @@ var FlowMeasurement = zcl.ClusterDef{
 	ID:   0x0404,
 	Name: "Flow Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "flow", Scale: 0.1, Unit: "m³/h"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeUint16, Access: zcl.AccessRead},
@@ -17,7 +17,7 @@ var SoilMoisture = zcl.ClusterDef{
 	ID:   0x0408,
 	Name: "Soil Moisture",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "soil_moisture", Scale: 0.01, Unit: "%"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeUint16, Access: zcl.AccessRead},
@@ -28,7 +28,7 @@ var PHMeasurement = zcl.ClusterDef{
 	ID:   0x0409,
 	Name: "pH Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "ph", Scale: 0.01}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeUint16, Access: zcl.AccessRead},
@@ -39,7 +39,7 @@ var CarbonMonoxide = zcl.ClusterDef{
 	ID:   0x040C,
 	Name: "Carbon Monoxide (CO) Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "co", Scale: 1e6, Unit: "ppm"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
@@ -50,7 +50,7 @@ var CarbonDioxide = zcl.ClusterDef{
 	ID:   0x040D,
 	Name: "Carbon Dioxide (CO2) Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "co2", Scale: 1e6, Unit: "ppm"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
@@ -61,7 +61,7 @@ var PM25Measurement = zcl.ClusterDef{
 	ID:   0x042A,
 	Name: "PM2.5 Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "pm25", Unit: "µg/m³"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
@@ -72,7 +72,7 @@ var FormaldehydeMeasurement = zcl.ClusterDef{
 	ID:   0x042B,
 	Name: "Formaldehyde Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "formaldehyde", Scale: 1e6, Unit: "ppm"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeFloat32, Access: zcl.AccessRead},
@@ -6,7 +6,7 @@ var RelativeHumidity = zcl.ClusterDef{
 	ID:   0x0405,
 	Name: "Relative Humidity",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "humidity", Scale: 0.01, Unit: "%"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeUint16, Access: zcl.AccessRead},
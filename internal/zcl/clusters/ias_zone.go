@@ -8,7 +8,7 @@ var IASZone = zcl.ClusterDef{
 	Attributes: []zcl.AttributeDef{
 		{ID: 0x0000, Name: "ZoneState", Type: zcl.TypeEnum8, Access: zcl.AccessRead},
 		{ID: 0x0001, Name: "ZoneType", Type: zcl.TypeEnum16, Access: zcl.AccessRead},
-		{ID: 0x0002, Name: "ZoneStatus", Type: zcl.TypeBitmap16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0002, Name: "ZoneStatus", Type: zcl.TypeBitmap16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "zone_status", Flags: []zcl.Flag{{Mask: 0x0001, Name: "alarm1"}, {Mask: 0x0002, Name: "alarm2"}, {Mask: 0x0004, Name: "tamper"}, {Mask: 0x0008, Name: "battery_low"}}}},
 		{ID: 0x0010, Name: "IASCIEAddress", Type: zcl.TypeEUI64, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0011, Name: "ZoneID", Type: zcl.TypeUint8, Access: zcl.AccessRead},
 	},
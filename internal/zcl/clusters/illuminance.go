@@ -6,7 +6,7 @@ var IlluminanceMeasurement = zcl.ClusterDef{
 	ID:   0x0400,
 	Name: "Illuminance Measurement",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "MeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "illuminance", Transform: "illuminance_lux", Unit: "lx"}},
 		{ID: 0x0001, Name: "MinMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "MaxMeasuredValue", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "Tolerance", Type: zcl.TypeUint16, Access: zcl.AccessRead},
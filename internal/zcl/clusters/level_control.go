@@ -6,7 +6,7 @@ var LevelControl = zcl.ClusterDef{
 	ID:   0x0008,
 	Name: "Level Control",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "CurrentLevel", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "CurrentLevel", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "brightness", Command: &zcl.CommandSetter{ID: 0x04, Type: zcl.TypeUint8, Payload: "0500"}}},
 		{ID: 0x0001, Name: "RemainingTime", Type: zcl.TypeUint16, Access: zcl.AccessRead},
 		{ID: 0x000F, Name: "Options", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0010, Name: "OnOffTransitionTime", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite},
@@ -7,7 +7,7 @@ var Metering = zcl.ClusterDef{
 	Name: "Metering",
 	Attributes: []zcl.AttributeDef{
 		// Reading information set
-		{ID: 0x0000, Name: "CurrentSummationDelivered", Type: zcl.TypeUint48, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "CurrentSummationDelivered", Type: zcl.TypeUint48, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "energy"}},
 		{ID: 0x0001, Name: "CurrentSummationReceived", Type: zcl.TypeUint48, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "CurrentMaxDemandDelivered", Type: zcl.TypeUint48, Access: zcl.AccessRead},
 		{ID: 0x0003, Name: "CurrentMaxDemandReceived", Type: zcl.TypeUint48, Access: zcl.AccessRead},
@@ -10,7 +10,7 @@ var MultistateInput = zcl.ClusterDef{
 		{ID: 0x001C, Name: "Description", Type: zcl.TypeCharStr, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x004A, Name: "NumberOfStates", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x0051, Name: "OutOfService", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessWrite},
-		{ID: 0x0055, Name: "PresentValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite | zcl.AccessReport},
+		{ID: 0x0055, Name: "PresentValue", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite | zcl.AccessReport, Converter: &zcl.Converter{Property: "multistate_value"}},
 		{ID: 0x0067, Name: "Reliability", Type: zcl.TypeEnum8, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x006F, Name: "StatusFlags", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessReport},
 		{ID: 0x0100, Name: "ApplicationType", Type: zcl.TypeUint32, Access: zcl.AccessRead},
@@ -6,7 +6,7 @@ var OccupancySensing = zcl.ClusterDef{
 	ID:   0x0406,
 	Name: "Occupancy Sensing",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "Occupancy", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "Occupancy", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "occupancy", Bool: true}},
 		{ID: 0x0001, Name: "OccupancySensorType", Type: zcl.TypeEnum8, Access: zcl.AccessRead},
 		{ID: 0x0002, Name: "OccupancySensorTypeBitmap", Type: zcl.TypeBitmap8, Access: zcl.AccessRead},
 		{ID: 0x0010, Name: "PIROccupiedToUnoccupiedDelay", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite},
@@ -6,7 +6,7 @@ var OnOff = zcl.ClusterDef{
 	ID:   0x0006,
 	Name: "On/Off",
 	Attributes: []zcl.AttributeDef{
-		{ID: 0x0000, Name: "OnOff", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0000, Name: "OnOff", Type: zcl.TypeBool, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "on_off", Bool: true, Command: &zcl.CommandSetter{Commands: map[string]uint8{"false": 0x00, "true": 0x01, "toggle": 0x02}}}},
 		{ID: 0x4000, Name: "GlobalSceneControl", Type: zcl.TypeBool, Access: zcl.AccessRead},
 		{ID: 0x4001, Name: "OnTime", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite},
 		{ID: 0x4002, Name: "OffWaitTime", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessWrite},
@@ -10,8 +10,8 @@ var WindowCovering = zcl.ClusterDef{
 		{ID: 0x0003, Name: "CurrentPositionLiftPercent", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport},
 		{ID: 0x0004, Name: "CurrentPositionTiltPercent", Type: zcl.TypeUint8, Access: zcl.AccessRead | zcl.AccessReport},
 		{ID: 0x0007, Name: "ConfigStatus", Type: zcl.TypeBitmap8, Access: zcl.AccessRead},
-		{ID: 0x0008, Name: "CurrentPositionLiftPercentage", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
-		{ID: 0x0009, Name: "CurrentPositionTiltPercentage", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport},
+		{ID: 0x0008, Name: "CurrentPositionLiftPercentage", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "position", Unit: "%", Command: &zcl.CommandSetter{ID: 0x05, Type: zcl.TypeUint8}}},
+		{ID: 0x0009, Name: "CurrentPositionTiltPercentage", Type: zcl.TypeUint16, Access: zcl.AccessRead | zcl.AccessReport, Converter: &zcl.Converter{Property: "tilt", Unit: "%", Command: &zcl.CommandSetter{ID: 0x08, Type: zcl.TypeUint8}}},
 		{ID: 0x0017, Name: "Mode", Type: zcl.TypeBitmap8, Access: zcl.AccessRead | zcl.AccessWrite},
 	},
 	Commands: []zcl.CommandDef{
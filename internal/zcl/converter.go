@@ -0,0 +1,239 @@
+package zcl
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Converter maps a raw attribute value to one or more named properties.
+// Scale multiplies numeric values (0.01 turns a MeasuredValue of 2345
+// into 23.45), Bool turns a value into true when non-zero, Enum names
+// values, and Flags splits a bitmap into one boolean property per bit.
+// Transform is a device-specific expression (or named alias) applied
+// before the others; transforms are implemented by the coordinator.
+//
+// Write and Command make the property settable: Write writes the attribute
+// back, Command sends a cluster command instead. They need a
+// ReverseTransform unless Transform is a named alias the coordinator can
+// reverse. ManufacturerCode marks the attribute write as
+// manufacturer-specific.
+type Converter struct {
+	Property  string         `json:"property,omitempty"`
+	Scale     float64        `json:"scale,omitempty"`
+	Unit      string         `json:"unit,omitempty"`
+	Bool      bool           `json:"bool,omitempty"`
+	Enum      map[int]string `json:"enum,omitempty"`
+	Flags     []Flag         `json:"flags,omitempty"`
+	Transform string         `json:"transform,omitempty"`
+
+	// ReverseTransform undoes Transform when the property is set.
+	ReverseTransform string `json:"reverse,omitempty"`
+
+	Write            bool           `json:"write,omitempty"`
+	Command          *CommandSetter `json:"command,omitempty"`
+	ManufacturerCode uint16         `json:"manufacturer_code,omitempty"`
+}
+
+// CommandSetter sets a property with a cluster command. Commands maps
+// property values ("true", "false", enum names, or extras like "toggle")
+// to commands sent without the value; any other value is sent with
+// command ID, encoded as Type. Payload (hex) is appended to either, e.g.
+// a transition time.
+type CommandSetter struct {
+	ID       uint8            `json:"id,omitempty"`
+	Type     uint8            `json:"type,omitempty"`
+	Payload  string           `json:"payload,omitempty"`
+	Commands map[string]uint8 `json:"commands,omitempty"`
+}
+
+// Flag is one named bit (or group of bits) of a bitmap attribute.
+type Flag struct {
+	Mask uint32 `json:"mask"`
+	Name string `json:"name"`
+}
+
+// PropertyValue is one normalized property produced by a converter.
+type PropertyValue struct {
+	Name  string
+	Value any
+	Unit  string
+}
+
+// Disabled reports whether the converter produces no properties. Device
+// definitions use an empty converter to turn off a cluster default.
+func (c *Converter) Disabled() bool {
+	return c.Property == "" && len(c.Flags) == 0
+}
+
+// Settable reports whether the property can be written back to the device.
+func (c *Converter) Settable() bool {
+	return c.Property != "" && (c.Write || c.Command != nil)
+}
+
+// Convert applies the converter to a decoded attribute value. The main
+// property comes first, followed by one boolean per flag.
+func (c *Converter) Convert(value any) []PropertyValue {
+	var out []PropertyValue
+	if c.Property != "" {
+		out = append(out, PropertyValue{Name: c.Property, Value: c.value(value), Unit: c.Unit})
+	}
+	if len(c.Flags) > 0 {
+		bits, ok := toUint(value)
+		for _, f := range c.Flags {
+			if ok {
+				out = append(out, PropertyValue{Name: f.Name, Value: bits&uint64(f.Mask) != 0})
+			}
+		}
+	}
+	return out
+}
+
+func (c *Converter) value(value any) any {
+	switch {
+	case c.Bool:
+		if b, ok := value.(bool); ok {
+			return b
+		}
+		if n, ok := toFloat(value); ok {
+			return n != 0
+		}
+	case c.Enum != nil:
+		if n, ok := toFloat(value); ok {
+			if name, ok := c.Enum[int(n)]; ok {
+				return name
+			}
+		}
+	case c.Scale != 0 && c.Scale != 1:
+		if n, ok := toFloat(value); ok {
+			return scale(n, c.Scale)
+		}
+	}
+	return value
+}
+
+// scale multiplies n by factor. Factors like 0.01 are applied as a division
+// by their exact inverse so that 2345 becomes 23.45 rather than
+// 23.450000000000003; other results are rounded to four decimals, which
+// also hides the float32 error of concentrations scaled to ppm.
+func scale(n, factor float64) float64 {
+	if inv := 1 / factor; factor < 1 && inv == math.Trunc(inv) {
+		return n / inv
+	}
+	return math.Round(n*factor*1e4) / 1e4
+}
+
+// Reverse converts a property value back to the raw attribute value, the
+// inverse of Convert without Transform. Whole float64 numbers, as decoded
+// from JSON or Lua, become int64.
+func (c *Converter) Reverse(value any) (any, error) {
+	switch {
+	case c.Bool:
+		if b, ok := parseBool(value); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("%s: expected a boolean, got %v", c.Property, value)
+	case c.Enum != nil:
+		if s, ok := value.(string); ok {
+			for n, name := range c.Enum {
+				if strings.EqualFold(name, s) {
+					return int64(n), nil
+				}
+			}
+			return nil, fmt.Errorf("%s: unknown value %q", c.Property, s)
+		}
+	case c.Scale != 0 && c.Scale != 1:
+		n, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a number, got %v", c.Property, value)
+		}
+		return int64(math.Round(unscale(n, c.Scale))), nil
+	}
+	if f, ok := value.(float64); ok && f == math.Trunc(f) {
+		return int64(f), nil
+	}
+	return value, nil
+}
+
+// unscale divides n by factor, multiplying by the exact inverse of factors
+// like 0.01.
+func unscale(n, factor float64) float64 {
+	if inv := 1 / factor; factor < 1 && inv == math.Trunc(inv) {
+		return n * inv
+	}
+	return n / factor
+}
+
+// parseBool accepts booleans, numbers and on/off, true/false strings.
+func parseBool(v any) (bool, bool) {
+	if b, ok := v.(bool); ok {
+		return b, true
+	}
+	if s, ok := v.(string); ok {
+		switch strings.ToLower(s) {
+		case "on", "true", "1":
+			return true, true
+		case "off", "false", "0":
+			return false, true
+		}
+		return false, false
+	}
+	if n, ok := toFloat(v); ok {
+		return n != 0, true
+	}
+	return false, false
+}
+
+// clone returns a copy that shares nothing with c.
+func (c *Converter) clone() *Converter {
+	if c == nil {
+		return nil
+	}
+	cp := *c
+	cp.Enum = maps.Clone(c.Enum)
+	cp.Flags = slices.Clone(c.Flags)
+	if c.Command != nil {
+		cmd := *c.Command
+		cmd.Commands = maps.Clone(c.Command.Commands)
+		cp.Command = &cmd
+	}
+	return &cp
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+func toUint(v any) (uint64, bool) {
+	if n, ok := toFloat(v); ok && n >= 0 {
+		return uint64(n), true
+	}
+	return 0, false
+}
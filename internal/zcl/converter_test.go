@@ -0,0 +1,95 @@
+package zcl
+
+import "testing"
+
+func TestConverterConvert(t *testing.T) {
+	tests := []struct {
+		name string
+		conv Converter
+		in   any
+		want any
+	}{
+		{"scale", Converter{Property: "temperature", Scale: 0.01}, int16(2345), 23.45},
+		{"scale negative", Converter{Property: "temperature", Scale: 0.01}, int16(-512), -5.12},
+		{"scale up", Converter{Property: "battery_voltage", Scale: 100}, uint8(30), 3000.0},
+		{"scale float32", Converter{Property: "co2", Scale: 1e6}, float32(0.000412), 412.0},
+		{"half", Converter{Property: "battery", Scale: 0.5}, uint8(199), 99.5},
+		{"raw", Converter{Property: "brightness"}, uint8(254), uint8(254)},
+		{"bool", Converter{Property: "occupancy", Bool: true}, uint8(1), true},
+		{"bool passthrough", Converter{Property: "on_off", Bool: true}, false, false},
+		{"enum", Converter{Property: "lock_state", Enum: map[int]string{1: "locked"}}, uint8(1), "locked"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.conv.Convert(tt.in)
+			if len(got) != 1 || got[0].Value != tt.want {
+				t.Errorf("Convert(%v) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverterFlags(t *testing.T) {
+	conv := Converter{Flags: []Flag{{Mask: 0x01, Name: "alarm1"}, {Mask: 0x04, Name: "tamper"}}}
+	got := conv.Convert(uint16(0x0004))
+	if len(got) != 2 || got[0].Value != false || got[1].Name != "tamper" || got[1].Value != true {
+		t.Errorf("flags: %v", got)
+	}
+	if !(&Converter{}).Disabled() || conv.Disabled() {
+		t.Error("Disabled")
+	}
+}
+
+func TestDeepCopyConverter(t *testing.T) {
+	c := ClusterDef{ID: 0x0201, Attributes: []AttributeDef{{ID: 0x001C, Converter: &Converter{
+		Property: "system_mode", Enum: map[int]string{4: "heat"},
+	}}}}
+	cp := c.DeepCopy()
+	cp.Attributes[0].Converter.Enum[4] = "changed"
+	if c.Attributes[0].Converter.Enum[4] != "heat" {
+		t.Error("DeepCopy shares the converter")
+	}
+}
+
+func TestConverterReverse(t *testing.T) {
+	tests := []struct {
+		name string
+		conv Converter
+		in   any
+		want any
+	}{
+		{"scale", Converter{Property: "heating_setpoint", Scale: 0.01}, 21.5, int64(2150)},
+		{"scale up", Converter{Property: "battery_voltage", Scale: 100}, 3000, int64(30)},
+		{"bool", Converter{Property: "on_off", Bool: true}, "ON", true},
+		{"bool number", Converter{Property: "on_off", Bool: true}, 0.0, false},
+		{"enum", Converter{Property: "system_mode", Enum: map[int]string{4: "heat"}}, "HEAT", int64(4)},
+		{"enum raw", Converter{Property: "system_mode", Enum: map[int]string{4: "heat"}}, 4.0, int64(4)},
+		{"raw", Converter{Property: "brightness"}, 128.0, int64(128)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.conv.Reverse(tt.in)
+			if err != nil || got != tt.want {
+				t.Errorf("Reverse(%v) = %v, %v, want %v", tt.in, got, err, tt.want)
+			}
+		})
+	}
+
+	bad := []struct {
+		conv Converter
+		in   any
+	}{
+		{Converter{Property: "on_off", Bool: true}, "maybe"},
+		{Converter{Property: "system_mode", Enum: map[int]string{4: "heat"}}, "warp"},
+		{Converter{Property: "heating_setpoint", Scale: 0.01}, "warm"},
+	}
+	for _, b := range bad {
+		if _, err := b.conv.Reverse(b.in); err == nil {
+			t.Errorf("Reverse(%v) for %s: expected error", b.in, b.conv.Property)
+		}
+	}
+
+	if (&Converter{Property: "temperature"}).Settable() || !(&Converter{Property: "on_off", Write: true}).Settable() {
+		t.Error("Settable")
+	}
+}
@@ -5,7 +5,9 @@ const (
 	FoundationReadAttributes         uint8 = 0x00
 	FoundationReadAttributesResponse uint8 = 0x01
 	FoundationWriteAttributes        uint8 = 0x02
+	FoundationWriteAttrsUndivided    uint8 = 0x03
 	FoundationWriteAttributesResp    uint8 = 0x04
+	FoundationWriteAttrsNoResponse   uint8 = 0x05
 	FoundationConfigReporting        uint8 = 0x06
 	FoundationConfigReportingResp    uint8 = 0x07
 	FoundationReadReportingConfig    uint8 = 0x08
@@ -17,12 +19,19 @@ const (
 
 // ZCL status codes
 const (
-	ZCLStatusSuccess            uint8 = 0x00
-	ZCLStatusFailure            uint8 = 0x01
-	ZCLStatusUnsupportedAttr    uint8 = 0x86
-	ZCLStatusInvalidDataType    uint8 = 0x8D
-	ZCLStatusReadOnly           uint8 = 0x88
-	ZCLStatusNotFound           uint8 = 0x8B
-	ZCLStatusUnreportable       uint8 = 0x8C
-	ZCLStatusInvalidValue       uint8 = 0x87
+	ZCLStatusSuccess              uint8 = 0x00
+	ZCLStatusFailure              uint8 = 0x01
+	ZCLStatusUnsupportedAttr      uint8 = 0x86
+	ZCLStatusInvalidDataType      uint8 = 0x8D
+	ZCLStatusReadOnly             uint8 = 0x88
+	ZCLStatusNotFound             uint8 = 0x8B
+	ZCLStatusUnreportable         uint8 = 0x8C
+	ZCLStatusInvalidValue         uint8 = 0x87
+	ZCLStatusMalformedCommand     uint8 = 0x80
+	ZCLStatusUnsupClusterCmd      uint8 = 0x81
+	ZCLStatusUnsupGeneralCmd      uint8 = 0x82
+	ZCLStatusUnsupManufGeneralCmd uint8 = 0x84
+	ZCLStatusNotAuthorized        uint8 = 0x7E
+	ZCLStatusNoImageAvailable     uint8 = 0x98
+	ZCLStatusUnsupportedCluster   uint8 = 0xC3
 )